@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type bisectOptions struct {
+	jsonFile string
+	failed   string
+	pkg      string
+	args     []string
+}
+
+func setupBisectFlags(name string) (*pflag.FlagSet, *bisectOptions) {
+	opts := &bisectOptions{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage:
+    %s [flags] [--] [go test flags]
+
+Find the test which causes an order dependent failure by running the failed
+test with subsets of the tests that ran before it in the same package.
+
+Flags:
+`, name)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.jsonFile, "jsonfile",
+		lookEnvWithDefault("GOTESTSUM_JSONFILE", ""),
+		"path to a file written by --jsonfile with the failed run")
+	flags.StringVar(&opts.failed, "failed", "",
+		"name or test ID (pkg#TestName) of the test which failed")
+	flags.StringVar(&opts.pkg, "package", "",
+		"package of the failed test, required when the name is not unique")
+	return flags, opts
+}
+
+func runBisect(name string, args []string) error {
+	flags, opts := setupBisectFlags(name)
+	if ok, err := parseToolFlags(flags, args); !ok {
+		return err
+	}
+	opts.args = flags.Args()
+	switch {
+	case opts.jsonFile == "":
+		return errors.New("--jsonfile is required")
+	case opts.failed == "":
+		return errors.New("--failed is required")
+	}
+
+	in, err := os.Open(opts.jsonFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to open JSON file")
+	}
+	defer in.Close() // nolint: errcheck
+
+	cfg, err := precedingTests(in, opts.pkg, opts.failed)
+	if err != nil {
+		return err
+	}
+	cfg.run = goTestRunner(opts.args)
+	return bisect(os.Stdout, cfg)
+}
+
+// rootTestName returns the name of the top level test of a subtest.
+func rootTestName(name string) string {
+	if i := strings.Index(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// precedingTests returns the package and top level name of the failed test,
+// and the names of the top level tests which ran in the package before the
+// failed test, in the order they ran.
+//
+// failed may be a test ID. It is only split into a package and a test name
+// when the package is one of the packages in the JSON file, because a subtest
+// name may also contain a '#'.
+func precedingTests(in io.Reader, pkg, failed string) (bisectConfig, error) {
+	recorder := &runRecorder{order: make(map[string][]string)}
+	_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  in,
+		Stderr:  strings.NewReader(""),
+		Handler: recorder,
+	})
+	if err != nil {
+		return bisectConfig{}, err
+	}
+
+	if idPkg, test, ok := testjson.ParseTestID(failed); ok && recorder.hasPackage(idPkg) {
+		pkg, failed = idPkg, test
+	}
+	failed = rootTestName(failed)
+
+	var found []string
+	for name, tests := range recorder.order {
+		if pkg != "" && name != pkg && testjson.TestID(name, "") != pkg+"#" {
+			continue
+		}
+		for _, test := range tests {
+			if test == failed {
+				found = append(found, name)
+				break
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return bisectConfig{}, errors.Errorf("test %s not found in JSON file", failed)
+	case 1:
+	default:
+		return bisectConfig{}, errors.Errorf("test %s found in multiple packages, use --package: %s",
+			failed, strings.Join(found, ", "))
+	}
+
+	pkg = found[0]
+	var preceding []string
+	for _, test := range recorder.order[pkg] {
+		if test == failed {
+			break
+		}
+		preceding = append(preceding, test)
+	}
+	return bisectConfig{pkg: pkg, failed: failed, candidates: preceding}, nil
+}
+
+// runRecorder records the order of the first run of each top level test.
+type runRecorder struct {
+	order map[string][]string
+}
+
+func (r *runRecorder) Event(event testjson.TestEvent, _ *testjson.Execution) error {
+	if event.Action != testjson.ActionRun || strings.Contains(event.Test, "/") {
+		return nil
+	}
+	for _, test := range r.order[event.Package] {
+		if test == event.Test {
+			return nil
+		}
+	}
+	r.order[event.Package] = append(r.order[event.Package], event.Test)
+	return nil
+}
+
+// hasPackage returns true if any test ran in the package. pkg may be the
+// import path, or the relative path used in a test ID.
+func (r *runRecorder) hasPackage(pkg string) bool {
+	for name := range r.order {
+		if name == pkg || testjson.TestID(name, "") == pkg+"#" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *runRecorder) Err(string) error {
+	return nil
+}
+
+// testRunner runs the tests in a package and returns true if all the tests
+// passed.
+type testRunner func(pkg string, tests []string) (bool, error)
+
+func goTestRunner(args []string) testRunner {
+	return func(pkg string, tests []string) (bool, error) {
+		cmdArgs := []string{"test", "-count=1", "-run", runPattern(tests)}
+		cmdArgs = append(append(cmdArgs, args...), pkg)
+		cmd := exec.Command("go", cmdArgs...)
+		log.Debugf("exec: %s", cmd.Args)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		switch err := cmd.Run().(type) {
+		case nil:
+			return true, nil
+		case *exec.ExitError:
+			return false, nil
+		default:
+			return false, errors.Wrap(err, "failed to run go test")
+		}
+	}
+}
+
+func runPattern(tests []string) string {
+	quoted := make([]string, 0, len(tests))
+	for _, test := range tests {
+		quoted = append(quoted, regexp.QuoteMeta(test))
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+type bisectConfig struct {
+	pkg        string
+	failed     string
+	candidates []string
+	run        testRunner
+}
+
+// bisect repeatedly runs the failed test with half of the candidates until it
+// finds the single candidate which causes the failure.
+func bisect(out io.Writer, conf bisectConfig) error {
+	fails := func(tests []string) (bool, error) {
+		fmt.Fprintf(out, "Running %s with %d tests\n", conf.failed, len(tests))
+		pass, err := conf.run(conf.pkg, append(tests[:len(tests):len(tests)], conf.failed))
+		return !pass, err
+	}
+
+	switch failed, err := fails(nil); {
+	case err != nil:
+		return err
+	case failed:
+		fmt.Fprintf(out, "%s fails when run alone, the failure does not depend on other tests\n",
+			conf.failed)
+		return nil
+	}
+
+	candidates := conf.candidates
+	switch failed, err := fails(candidates); {
+	case err != nil:
+		return err
+	case !failed:
+		fmt.Fprintf(out, "%s passed when run after the %d tests which preceded it\n",
+			conf.failed, len(candidates))
+		return nil
+	}
+
+	for len(candidates) > 1 {
+		half := len(candidates) / 2
+		first, second := candidates[:half], candidates[half:]
+		failed, err := fails(first)
+		if err != nil {
+			return err
+		}
+		if failed {
+			candidates = first
+			continue
+		}
+		failed, err = fails(second)
+		if err != nil {
+			return err
+		}
+		if failed {
+			candidates = second
+			continue
+		}
+		fmt.Fprintf(out, "%s fails only when run after all of: %s\n",
+			conf.failed, strings.Join(candidates, ", "))
+		return nil
+	}
+	fmt.Fprintf(out, "%s fails when run after %s in %s\n",
+		conf.failed, candidates[0], conf.pkg)
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestPrecedingTests(t *testing.T) {
+	in := strings.NewReader(`{"Action":"run","Package":"pkg/a","Test":"TestOne"}
+{"Action":"run","Package":"pkg/b","Test":"TestOther"}
+{"Action":"run","Package":"pkg/a","Test":"TestTwo"}
+{"Action":"run","Package":"pkg/a","Test":"TestTwo/sub"}
+{"Action":"run","Package":"pkg/a","Test":"TestThree"}
+{"Action":"fail","Package":"pkg/a","Test":"TestThree"}
+`)
+	cfg, err := precedingTests(in, "", "TestThree")
+	assert.NilError(t, err)
+	assert.Equal(t, cfg.pkg, "pkg/a")
+	assert.Equal(t, cfg.failed, "TestThree")
+	assert.DeepEqual(t, cfg.candidates, []string{"TestOne", "TestTwo"})
+}
+
+func TestPrecedingTests_TestID(t *testing.T) {
+	in := `{"Action":"run","Package":"pkg/a","Test":"TestOne"}
+{"Action":"run","Package":"pkg/a","Test":"TestTwo"}
+{"Action":"run","Package":"pkg/a","Test":"TestTwo/sub#01"}
+{"Action":"run","Package":"pkg/b","Test":"TestOther"}
+{"Action":"run","Package":"pkg/b","Test":"TestTwo"}
+`
+	cfg, err := precedingTests(strings.NewReader(in), "", "pkg/b#TestTwo")
+	assert.NilError(t, err)
+	assert.Equal(t, cfg.pkg, "pkg/b")
+	assert.Equal(t, cfg.failed, "TestTwo")
+	assert.DeepEqual(t, cfg.candidates, []string{"TestOther"})
+
+	// a subtest name with a '#' is not a test ID
+	cfg, err = precedingTests(strings.NewReader(in), "pkg/a", "TestTwo/sub#01")
+	assert.NilError(t, err)
+	assert.Equal(t, cfg.pkg, "pkg/a")
+	assert.Equal(t, cfg.failed, "TestTwo")
+	assert.DeepEqual(t, cfg.candidates, []string{"TestOne"})
+}
+
+func TestBisect(t *testing.T) {
+	var runs [][]string
+	run := func(pkg string, tests []string) (bool, error) {
+		runs = append(runs, tests)
+		for _, test := range tests {
+			if test == "TestD" {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	out := new(bytes.Buffer)
+	err := bisect(out, bisectConfig{
+		pkg:        "pkg/a",
+		failed:     "TestX",
+		candidates: []string{"TestA", "TestB", "TestC", "TestD", "TestE"},
+		run:        run,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasSuffix(out.String(),
+		"TestX fails when run after TestD in pkg/a\n"), out.String())
+	assert.DeepEqual(t, runs[0], []string{"TestX"})
+}
+
+func TestBisect_FailsAlone(t *testing.T) {
+	run := func(string, []string) (bool, error) {
+		return false, nil
+	}
+	out := new(bytes.Buffer)
+	err := bisect(out, bisectConfig{failed: "TestX", candidates: []string{"TestA"}, run: run})
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(out.String(), "fails when run alone"))
+}
+
+func TestRunPattern(t *testing.T) {
+	assert.Equal(t, runPattern([]string{"TestA", "TestB"}), "^(TestA|TestB)$")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// classifyRequest is the line written to the stdin of the
+// --failure-classifier for each failed test.
+type classifyRequest struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	TestID  string `json:"test_id"`
+	Output  string `json:"output"`
+}
+
+// classifyVerdict is the line read from the stdout of the
+// --failure-classifier for each classifyRequest.
+type classifyVerdict struct {
+	Category string `json:"category"`
+}
+
+// classifyFailures runs the --failure-classifier once for all of the failed
+// tests. Each failed test is written to the stdin of the command as a line of
+// JSON, and the command must write one line of JSON, with the category of the
+// failure, for each line it reads. The requests are written while the verdicts
+// are read, and stdin is closed after the last request, so the command may
+// read every request before it writes a verdict. An empty category leaves the
+// failure unclassified. The command is stopped when ctx is done, or after the
+// --failure-classifier-timeout. Returns the categories by test ID.
+func classifyFailures(ctx context.Context, opts *options, exec *testjson.Execution) (map[string]string, error) {
+	failed := exec.Failed()
+	if opts.failureClassifier == "" || len(failed) == 0 {
+		return nil, nil
+	}
+	if opts.failureClassifierTimeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, opts.failureClassifierTimeout)
+		defer cancel()
+	}
+	cmd := shellCommand(ctx, opts.failureClassifier)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("exec: %s", cmd.Args)
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, "failed to start --failure-classifier")
+	}
+
+	categories, err := classify(stdin, stdout, exec, failed)
+	if waitErr := cmd.Wait(); err == nil && waitErr != nil {
+		err = errors.Wrap(waitErr, "--failure-classifier failed")
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = errors.Errorf("--failure-classifier did not finish after %s", opts.failureClassifierTimeout)
+	}
+	return categories, err
+}
+
+// classify writes a request for each failed test to in from a goroutine, and
+// closes in after the last request. The verdict for each request is read from
+// out.
+func classify(in io.WriteCloser, out io.Reader, exec *testjson.Execution, failed []testjson.TestCase) (map[string]string, error) {
+	written := make(chan error, 1)
+	go func() {
+		written <- writeClassifyRequests(in, exec, failed)
+	}()
+
+	scanner := bufio.NewScanner(out)
+	scanner.Buffer(nil, 1024*1024)
+	categories := make(map[string]string)
+	for _, tc := range failed {
+		if !scanner.Scan() {
+			err := scanner.Err()
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			return categories, errors.Wrapf(err, "no verdict from --failure-classifier for %s", tc.ID())
+		}
+		var verdict classifyVerdict
+		if err := json.Unmarshal(scanner.Bytes(), &verdict); err != nil {
+			return categories, errors.Wrapf(err, "invalid verdict from --failure-classifier for %s", tc.ID())
+		}
+		if verdict.Category != "" {
+			categories[tc.ID()] = verdict.Category
+		}
+	}
+	return categories, <-written
+}
+
+func writeClassifyRequests(in io.WriteCloser, exec *testjson.Execution, failed []testjson.TestCase) error {
+	encoder := json.NewEncoder(in)
+	for _, tc := range failed {
+		req := classifyRequest{
+			Package: tc.Package,
+			Test:    tc.Test,
+			TestID:  tc.ID(),
+			Output:  exec.Package(tc.Package).Output(tc.Test),
+		}
+		if err := encoder.Encode(req); err != nil {
+			in.Close() // nolint: errcheck
+			return errors.Wrap(err, "failed to write to --failure-classifier")
+		}
+	}
+	return errors.Wrap(in.Close(), "failed to write to --failure-classifier")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestClassifyFailures(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestDB"}
+{"Action":"output","Package":"example.com/a","Test":"TestDB","Output":"    db_test.go:9: dial tcp: connection refused\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestDB"}
+{"Action":"run","Package":"example.com/a","Test":"TestMath"}
+{"Action":"output","Package":"example.com/a","Test":"TestMath","Output":"    math_test.go:9: got 3, want 4\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestMath"}
+{"Action":"fail","Package":"example.com/a"}
+`))
+	assert.NilError(t, err)
+
+	opts := &options{failureClassifier: `while read -r line; do
+		case "$line" in
+		*"connection refused"*) echo '{"category":"infra"}' ;;
+		*) echo '{}' ;;
+		esac
+	done`}
+	categories, err := classifyFailures(context.Background(), opts, exec)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, categories, map[string]string{"example.com/a#TestDB": "infra"})
+
+	opts.failureClassifier = "read -r line; echo 'not json'"
+	_, err = classifyFailures(context.Background(), opts, exec)
+	assert.ErrorContains(t, err, "invalid verdict from --failure-classifier for example.com/a#TestDB")
+
+	opts.failureClassifier = "read -r line"
+	_, err = classifyFailures(context.Background(), opts, exec)
+	assert.ErrorContains(t, err, "no verdict from --failure-classifier for example.com/a#TestDB")
+
+	// every request is read before the first verdict is written
+	opts.failureClassifier = `requests=$(cat); echo "$requests" | while read -r line; do echo '{"category":"batch"}'; done`
+	categories, err = classifyFailures(context.Background(), opts, exec)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, categories, map[string]string{
+		"example.com/a#TestDB":   "batch",
+		"example.com/a#TestMath": "batch",
+	})
+
+	opts.failureClassifier = "exec sleep 5"
+	opts.failureClassifierTimeout = 50 * time.Millisecond
+	_, err = classifyFailures(context.Background(), opts, exec)
+	assert.ErrorContains(t, err, "--failure-classifier did not finish after 50ms")
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type collectSpec struct {
+	pattern string
+	dest    string
+}
+
+// collectValue is a flag.Value for --collect-on-failure. Each value is a
+// single GLOB[:DEST], so that the glob may contain commas.
+type collectValue struct {
+	specs []collectSpec
+}
+
+func (v *collectValue) Set(val string) error {
+	spec := collectSpec{pattern: val}
+	if i := destSeparator(val); i >= 0 {
+		spec = collectSpec{pattern: val[:i], dest: val[i+1:]}
+	}
+	if spec.pattern == "" {
+		return errors.Errorf("value must be GLOB[:DEST], not %s", val)
+	}
+	if _, err := filepath.Glob(spec.pattern); err != nil {
+		return errors.Wrapf(err, "invalid glob %s", spec.pattern)
+	}
+	if filepath.IsAbs(spec.dest) || isParentPath(filepath.Clean(spec.dest)) {
+		return errors.Errorf("DEST must be a path in the --artifact-dir, not %s", spec.dest)
+	}
+	v.specs = append(v.specs, spec)
+	return nil
+}
+
+// destSeparator returns the index of the colon which separates the GLOB from
+// the DEST, or -1 if there is no DEST. A colon at index 1 is the drive letter
+// of a windows path.
+func destSeparator(val string) int {
+	start := 0
+	if len(val) > 2 && val[1] == ':' {
+		start = 2
+	}
+	i := strings.Index(val[start:], ":")
+	if i < 0 {
+		return -1
+	}
+	return start + i
+}
+
+// isParentPath returns true if the relative path rel is outside of the
+// directory it is relative to.
+func isParentPath(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (v *collectValue) Type() string {
+	return "glob[:dest]"
+}
+
+func (v *collectValue) String() string {
+	items := make([]string, 0, len(v.specs))
+	for _, spec := range v.specs {
+		item := spec.pattern
+		if spec.dest != "" {
+			item += ":" + spec.dest
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, " ")
+}
+
+func validateCollectOptions(opts *options) error {
+	if len(opts.collectOnFailure.specs) > 0 && opts.artifactDir == "" {
+		return errors.New("--collect-on-failure requires --artifact-dir")
+	}
+	return nil
+}
+
+// collectArtifacts copies the files and directories which match each
+// --collect-on-failure glob into the --artifact-dir. The path of each match
+// relative to the directory of the glob is kept, so that files with the same
+// name in different directories are not overwritten. The --artifact-dir is not
+// collected when a glob matches it, or a directory which contains it. Returns
+// the paths of the copies.
+func collectArtifacts(opts *options) ([]string, error) {
+	artifactDir, err := filepath.Abs(opts.artifactDir)
+	if err != nil {
+		return nil, err
+	}
+	var collected []string
+	for _, spec := range opts.collectOnFailure.specs {
+		matches, err := filepath.Glob(spec.pattern)
+		if err != nil {
+			return collected, errors.Wrapf(err, "invalid glob %s", spec.pattern)
+		}
+		if len(matches) == 0 {
+			log.Debugf("no files match --collect-on-failure %s", spec.pattern)
+		}
+		base := globBase(spec.pattern)
+		for _, match := range matches {
+			rel, err := filepath.Rel(base, match)
+			if err != nil {
+				return collected, err
+			}
+			if inDir(match, artifactDir) {
+				continue
+			}
+			dest := filepath.Join(opts.artifactDir, spec.dest, rel)
+			if err := copyPath(match, dest, artifactDir); err != nil {
+				return collected, err
+			}
+			collected = append(collected, dest)
+		}
+	}
+	return collected, nil
+}
+
+// globBase returns the directory of pattern before the first path element
+// with a glob meta character.
+func globBase(pattern string) string {
+	meta := `*?[`
+	if runtime.GOOS != "windows" {
+		meta = `*?[\`
+	}
+	base := filepath.Dir(pattern)
+	for strings.ContainsAny(base, meta) {
+		base = filepath.Dir(base)
+	}
+	return base
+}
+
+// inDir returns true if path is dir, or a path in dir. dir must be absolute.
+func inDir(path, dir string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && !isParentPath(rel)
+}
+
+// copyPath copies a file, or a directory and all of its files except for the
+// directory skip, to dest.
+func copyPath(src, dest, skip string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		switch {
+		case info.IsDir() && inDir(path, skip):
+			return filepath.SkipDir
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+func copyFile(src, dest string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return errors.Wrap(err, "failed to create artifact directory")
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrap(err, "failed to collect artifact")
+	}
+	defer in.Close() // nolint: errcheck
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return errors.Wrap(err, "failed to collect artifact")
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close() // nolint: errcheck
+		return errors.Wrapf(err, "failed to copy %s", src)
+	}
+	return out.Close()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/assert"
+	"gotest.tools/fs"
+)
+
+var cmpCollectSpec = cmp.AllowUnexported(collectSpec{})
+
+func TestCollectValue(t *testing.T) {
+	value := &collectValue{}
+	assert.NilError(t, value.Set("logs/*.log:services"))
+	assert.NilError(t, value.Set("/tmp/db-dump"))
+	assert.NilError(t, value.Set(`C:\logs\*.log`))
+	assert.NilError(t, value.Set(`C:\logs\*.log:services`))
+	assert.NilError(t, value.Set("logs/*.log:services/run:1"))
+	assert.NilError(t, value.Set("logs/*.log:..services"))
+	assert.DeepEqual(t, value.specs, []collectSpec{
+		{pattern: "logs/*.log", dest: "services"},
+		{pattern: "/tmp/db-dump"},
+		{pattern: `C:\logs\*.log`},
+		{pattern: `C:\logs\*.log`, dest: "services"},
+		{pattern: "logs/*.log", dest: "services/run:1"},
+		{pattern: "logs/*.log", dest: "..services"},
+	}, cmpCollectSpec)
+
+	assert.ErrorContains(t, value.Set(""), "value must be GLOB[:DEST]")
+	assert.ErrorContains(t, value.Set("logs/[*.log"), "invalid glob")
+	assert.ErrorContains(t, value.Set("*.log:../outside"), "DEST must be a path in the --artifact-dir")
+	assert.ErrorContains(t, value.Set("*.log:services/../.."), "DEST must be a path in the --artifact-dir")
+}
+
+func TestCollectArtifacts(t *testing.T) {
+	src := fs.NewDir(t, "collect-src",
+		fs.WithFile("api.log", "api"),
+		fs.WithFile("db.log", "db"),
+		fs.WithFile("notes.txt", "notes"),
+		fs.WithDir("tmp", fs.WithFile("state.json", "{}")),
+		fs.WithDir("e2e",
+			fs.WithDir("api", fs.WithFile("app.log", "api")),
+			fs.WithDir("db", fs.WithFile("app.log", "db"))))
+	defer src.Remove()
+	artifacts := fs.NewDir(t, "collect-dest")
+	defer artifacts.Remove()
+
+	opts := &options{artifactDir: artifacts.Path(), collectOnFailure: &collectValue{}}
+	assert.NilError(t, opts.collectOnFailure.Set(src.Join("*.log")+":services"))
+	assert.NilError(t, opts.collectOnFailure.Set(src.Join("tmp")))
+	assert.NilError(t, opts.collectOnFailure.Set(src.Join("e2e", "*", "app.log")+":e2e"))
+	assert.NilError(t, validateCollectOptions(opts))
+
+	collected, err := collectArtifacts(opts)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, collected, []string{
+		artifacts.Join("services", "api.log"),
+		artifacts.Join("services", "db.log"),
+		artifacts.Join("tmp"),
+		artifacts.Join("e2e", "api", "app.log"),
+		artifacts.Join("e2e", "db", "app.log"),
+	})
+	content, err := ioutil.ReadFile(filepath.Join(artifacts.Path(), "tmp", "state.json"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "{}")
+	content, err = ioutil.ReadFile(artifacts.Join("e2e", "db", "app.log"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "db")
+
+	opts.artifactDir = ""
+	assert.ErrorContains(t, validateCollectOptions(opts), "requires --artifact-dir")
+}
+
+func TestCollectArtifacts_ArtifactDirInGlob(t *testing.T) {
+	src := fs.NewDir(t, "collect-src",
+		fs.WithFile("api.log", "api"),
+		fs.WithDir("artifacts", fs.WithFile("old.log", "old")))
+	defer src.Remove()
+
+	opts := &options{artifactDir: src.Join("artifacts"), collectOnFailure: &collectValue{}}
+	assert.NilError(t, opts.collectOnFailure.Set(src.Join("*")+":all"))
+	assert.NilError(t, opts.collectOnFailure.Set(src.Path()+":root"))
+
+	collected, err := collectArtifacts(opts)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, collected, []string{
+		src.Join("artifacts", "all", "api.log"),
+		src.Join("artifacts", "root", filepath.Base(src.Path())),
+	})
+	expected := fs.Expected(t,
+		fs.WithFile("api.log", "api"),
+		fs.WithDir("artifacts",
+			fs.WithFile("old.log", "old"),
+			fs.WithDir("all", fs.WithFile("api.log", "api")),
+			fs.WithDir("root", fs.WithDir(filepath.Base(src.Path()), fs.WithFile("api.log", "api")))))
+	assert.Assert(t, fs.Equal(src.Path(), expected))
+}
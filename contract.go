@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// readRequiredTests reads a file of test IDs, one on each line. Empty lines,
+// and lines which start with a '#', are ignored.
+func readRequiredTests(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open required tests file")
+	}
+	defer f.Close() // nolint: errcheck
+
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, _, ok := testjson.ParseTestID(line); !ok {
+			return nil, errors.Errorf("invalid test ID in %s: %s", filename, line)
+		}
+		ids = append(ids, line)
+	}
+	return ids, errors.Wrap(scanner.Err(), "failed to read required tests file")
+}
+
+// requiredTestWarnings returns a warning for each required test which did not
+// run, or did not pass. A test ID may use the full import path of the package
+// or the relative path used by testjson.TestID.
+func requiredTestWarnings(exec *testjson.Execution, required []string) []string {
+	results := make(map[string]testjson.Action)
+	record := func(action testjson.Action, cases []testjson.TestCase) {
+		for _, tc := range cases {
+			results[tc.ID()] = action
+			results[tc.Package+"#"+tc.Test] = action
+		}
+	}
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		// passed is recorded last, so that a test which passed when it was
+		// rerun is not reported as a failure
+		record(testjson.ActionSkip, pkg.Skipped)
+		record(testjson.ActionFail, pkg.Failed)
+		record(testjson.ActionPass, pkg.Passed)
+	}
+
+	var warnings []string
+	for _, id := range required {
+		switch action, ok := results[id]; {
+		case !ok:
+			warnings = append(warnings, fmt.Sprintf("required test %s did not run", id))
+		case action != testjson.ActionPass:
+			warnings = append(warnings, fmt.Sprintf("required test %s did not pass (%s)", id, action))
+		}
+	}
+	return warnings
+}
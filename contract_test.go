@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/fs"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestReadRequiredTests(t *testing.T) {
+	dir := fs.NewDir(t, "required",
+		fs.WithFile("ok", "# contract tests\npkg/a#TestOne\n\n  pkg/b#TestTwo/sub\n"),
+		fs.WithFile("bad", "pkg/a#TestOne\nTestTwo\n"))
+	defer dir.Remove()
+
+	ids, err := readRequiredTests(dir.Join("ok"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ids, []string{"pkg/a#TestOne", "pkg/b#TestTwo/sub"})
+
+	_, err = readRequiredTests(dir.Join("bad"))
+	assert.ErrorContains(t, err, "invalid test ID")
+}
+
+func TestRequiredTestWarnings(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestPass"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestPass"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFail"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFail"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/pkg","Test":"TestSkip"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFlaky"}
+`))
+	assert.NilError(t, err)
+
+	required := []string{
+		"example.com/pkg#TestPass",
+		"example.com/pkg#TestFlaky",
+		"example.com/pkg#TestFail",
+		"example.com/pkg#TestSkip",
+		"example.com/pkg#TestRenamed",
+	}
+	expected := []string{
+		"required test example.com/pkg#TestFail did not pass (fail)",
+		"required test example.com/pkg#TestSkip did not pass (skip)",
+		"required test example.com/pkg#TestRenamed did not run",
+	}
+	assert.DeepEqual(t, requiredTestWarnings(exec, required), expected)
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func validateCoverageDiffOptions(opts *options) error {
+	switch {
+	case opts.coverageDiffFile != "" && opts.coverageDiff == "":
+		return errors.New("--coverage-diff-file requires --coverage-diff")
+	case opts.coverageDiff != "" && (opts.rawCommand || opts.stdin):
+		return errors.New("--coverage-diff can not be used with --raw-command or --stdin")
+	case opts.coverageDiff != "" && goTestCoverProfile(opts) == "":
+		return errors.New("--coverage-diff requires -coverprofile=PATH in the go test args")
+	}
+	return nil
+}
+
+// goTestCoverProfile returns the value of the -coverprofile flag from the go
+// test args, or an empty string if the flag is not set.
+func goTestCoverProfile(opts *options) string {
+	var profile string
+	for i, arg := range opts.args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		switch {
+		case name == "coverprofile" || name == "test.coverprofile":
+			if i+1 < len(opts.args) {
+				profile = opts.args[i+1]
+			}
+		case strings.HasPrefix(name, "coverprofile="), strings.HasPrefix(name, "test.coverprofile="):
+			profile = name[strings.Index(name, "=")+1:]
+		}
+	}
+	return profile
+}
+
+// changedFileCoverage is the coverage of the changed lines of a file, written
+// to the --coverage-diff-file.
+type changedFileCoverage struct {
+	File string `json:"file"`
+	// Profiled is false when the file is not in the coverage profile, for
+	// example because its package has no tests.
+	Profiled bool `json:"profiled"`
+	// ChangedLines is the number of changed lines with a statement.
+	ChangedLines   int   `json:"changed_lines"`
+	CoveredLines   int   `json:"covered_lines"`
+	UncoveredLines []int `json:"uncovered_lines"`
+	// Covered is true when every changed line with a statement was covered.
+	Covered bool `json:"covered"`
+}
+
+// coverageDiff is the content of the --coverage-diff-file.
+type coverageDiff struct {
+	Base  string                `json:"base"`
+	Files []changedFileCoverage `json:"files"`
+	// Error is the reason the coverage of the changed lines is not known.
+	Error string `json:"error,omitempty"`
+}
+
+// changedLinesCoverage runs git diff against opts.coverageDiff, and returns the
+// coverage of the changed lines of each changed go file, from the
+// -coverprofile written by go test. Every line of an untracked go file, which
+// is not ignored by git, is a changed line.
+func changedLinesCoverage(opts *options) (coverageDiff, error) {
+	result := coverageDiff{Base: opts.coverageDiff}
+	cmd := exec.Command("git", "diff", "--unified=0", "--relative", "--no-color",
+		opts.coverageDiff, "--", "*.go")
+	log.Debugf("exec: %s", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		return result, errors.Wrap(err, "failed to run git diff")
+	}
+	changed := parseDiffLines(out)
+	cmd = exec.Command("git", "ls-files", "--others", "--exclude-standard", "-z", "--", "*.go")
+	log.Debugf("exec: %s", cmd.Args)
+	if out, err = cmd.Output(); err != nil {
+		return result, errors.Wrap(err, "failed to list untracked files")
+	}
+	if err := addFileLines(changed, parseUntrackedFiles(out)); err != nil {
+		return result, err
+	}
+	f, err := os.Open(goTestCoverProfile(opts))
+	if err != nil {
+		return result, errors.Wrap(err, "failed to open coverage profile")
+	}
+	defer f.Close() // nolint: errcheck
+	profile, err := parseCoverProfile(f)
+	if err != nil {
+		return result, err
+	}
+	prefix, err := coverProfilePrefix()
+	if err != nil {
+		return result, err
+	}
+	result.Files = coverChangedLines(changed, profile, prefix)
+	return result, nil
+}
+
+// coverProfilePrefix returns the prefix of the name of a file in the coverage
+// profile, which is the import path of the package of the file, for a path
+// from git diff --relative. The prefix is the path of the module, and the path
+// of the current directory relative to the root of the module.
+func coverProfilePrefix() (string, error) {
+	cmd := exec.Command("go", "list", "-m", "-f", "{{.Path}}\t{{.Dir}}")
+	log.Debugf("exec: %s", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find the module")
+	}
+	parts := strings.SplitN(strings.TrimSpace(string(out)), "\t", 2)
+	if len(parts) != 2 || strings.Contains(parts[1], "\n") {
+		return "", errors.Errorf("failed to find the module from go list: %s", out)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(parts[1], wd)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find the directory in the module")
+	}
+	return path.Join(parts[0], filepath.ToSlash(rel)), nil
+}
+
+// parseDiffLines returns the added or changed lines of each file, from the
+// output of git diff --unified=0. Deleted files, and test files, are ignored.
+func parseDiffLines(diff []byte) map[string][]int {
+	changed := make(map[string][]int)
+	var file string
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if file == "/dev/null" || strings.HasSuffix(file, "_test.go") {
+				file = ""
+			}
+		case strings.HasPrefix(line, "@@ ") && file != "":
+			start, count, ok := parseHunkHeader(line)
+			for i := 0; ok && i < count; i++ {
+				changed[file] = append(changed[file], start+i)
+			}
+		}
+	}
+	return changed
+}
+
+// parseUntrackedFiles returns the files from the output of git ls-files -z,
+// except for test files.
+func parseUntrackedFiles(out []byte) []string {
+	var files []string
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file != "" && !strings.HasSuffix(file, "_test.go") {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// addFileLines adds every line of each file to changed.
+func addFileLines(changed map[string][]int, files []string) error {
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrap(err, "failed to read untracked file")
+		}
+		lines := bytes.Count(content, []byte("\n"))
+		if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+			lines++
+		}
+		for line := 1; line <= lines; line++ {
+			changed[file] = append(changed[file], line)
+		}
+	}
+	return nil
+}
+
+// parseHunkHeader returns the first line and the number of lines of the new
+// file from a hunk header, like @@ -10,2 +12,3 @@ func name().
+func parseHunkHeader(line string) (int, int, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(fields[2], "+"), ",", 2)
+	start, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	count := 1
+	if len(parts) == 2 {
+		if count, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, count, true
+}
+
+// coverBlock is a block of statements from a coverage profile.
+type coverBlock struct {
+	startLine, endLine int
+	count              int
+}
+
+// parseCoverProfile returns the blocks of a coverage profile, by the file name
+// from the profile, which is the import path of the package and the name of
+// the file.
+func parseCoverProfile(in io.Reader) (map[string][]coverBlock, error) {
+	profile := make(map[string][]coverBlock)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		// name.go:startLine.startCol,endLine.endCol numStmts count
+		i := strings.LastIndex(line, ":")
+		if i < 0 {
+			return nil, errors.Errorf("invalid line in coverage profile: %s", line)
+		}
+		var block coverBlock
+		var startCol, endCol, stmts int
+		_, err := fmt.Sscanf(line[i+1:], "%d.%d,%d.%d %d %d",
+			&block.startLine, &startCol, &block.endLine, &endCol, &stmts, &block.count)
+		if err != nil {
+			return nil, errors.Errorf("invalid line in coverage profile: %s", line)
+		}
+		profile[line[:i]] = append(profile[line[:i]], block)
+	}
+	return profile, errors.Wrap(scanner.Err(), "failed to read coverage profile")
+}
+
+// coverChangedLines returns the coverage of the changed lines of each file. A
+// line is covered when any block which contains it was run. Lines which are
+// not in a block, like comments, are not counted. The name of a file in the
+// profile is prefix, from coverProfilePrefix, and the path from git diff.
+func coverChangedLines(changed map[string][]int, profile map[string][]coverBlock, prefix string) []changedFileCoverage {
+	files := make([]string, 0, len(changed))
+	for file := range changed {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var result []changedFileCoverage
+	for _, file := range files {
+		blocks, ok := profile[path.Join(prefix, file)]
+		cov := changedFileCoverage{File: file, Profiled: ok, UncoveredLines: []int{}}
+		for _, line := range changed[file] {
+			inBlock, covered := false, false
+			for _, block := range blocks {
+				if line < block.startLine || line > block.endLine {
+					continue
+				}
+				inBlock = true
+				covered = covered || block.count > 0
+			}
+			switch {
+			case !inBlock:
+				continue
+			case covered:
+				cov.CoveredLines++
+			default:
+				cov.UncoveredLines = append(cov.UncoveredLines, line)
+			}
+			cov.ChangedLines++
+		}
+		cov.Covered = ok && len(cov.UncoveredLines) == 0
+		if ok && cov.ChangedLines == 0 {
+			continue
+		}
+		result = append(result, cov)
+	}
+	return result
+}
+
+// uncoveredChanges returns a line for each changed file with changed lines
+// which were not covered, for the summary, in the language of msgs.
+func uncoveredChanges(diff coverageDiff, msgs testjson.Messages) []string {
+	var lines []string
+	for _, file := range diff.Files {
+		switch {
+		case !file.Profiled:
+			lines = append(lines, fmt.Sprintf(msgs.NotInCoverageProfile, file.File))
+		case len(file.UncoveredLines) > 0:
+			lines = append(lines, fmt.Sprintf(msgs.UncoveredChangedLines,
+				file.File, len(file.UncoveredLines), file.ChangedLines, formatLineRanges(file.UncoveredLines)))
+		}
+	}
+	return lines
+}
+
+// formatLineRanges returns the sorted line numbers with consecutive lines
+// joined into a range, for example 3-5,9.
+func formatLineRanges(lines []int) string {
+	var ranges []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(lines[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", lines[i], lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+func writeCoverageDiffFile(filename string, diff coverageDiff) error {
+	if diff.Files == nil {
+		diff.Files = []changedFileCoverage{}
+	}
+	content, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode coverage diff")
+	}
+	return errors.Wrap(ioutil.WriteFile(filename, append(content, '\n'), 0644),
+		"failed to write coverage diff file")
+}
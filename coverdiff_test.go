@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/fs"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestGoTestCoverProfile(t *testing.T) {
+	opts := &options{args: []string{"-race", "-coverprofile=cover.out", "./..."}}
+	assert.Equal(t, goTestCoverProfile(opts), "cover.out")
+	opts.args = []string{"-coverprofile", "c.out", "./..."}
+	assert.Equal(t, goTestCoverProfile(opts), "c.out")
+	opts.args = []string{"./...", "--", "-coverprofile=binary.out"}
+	assert.Equal(t, goTestCoverProfile(opts), "")
+
+	opts.coverageDiff = "origin/main"
+	assert.ErrorContains(t, validateCoverageDiffOptions(opts), "requires -coverprofile=PATH")
+	opts.coverageDiff = ""
+	opts.coverageDiffFile = "coverage-diff.json"
+	assert.ErrorContains(t, validateCoverageDiffOptions(opts), "requires --coverage-diff")
+}
+
+func TestParseDiffLines(t *testing.T) {
+	diff := `diff --git a/pkg/a.go b/pkg/a.go
+index 1111111..2222222 100644
+--- a/pkg/a.go
++++ b/pkg/a.go
+@@ -3 +3,2 @@ package a
+-old
++new
++newer
+@@ -10,2 +11,0 @@ func A() {
+@@ -20,0 +20 @@ func A() {
++added
+diff --git a/pkg/a_test.go b/pkg/a_test.go
+--- a/pkg/a_test.go
++++ b/pkg/a_test.go
+@@ -1 +1 @@
++test
+diff --git a/pkg/gone.go b/pkg/gone.go
+--- a/pkg/gone.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+`
+	assert.DeepEqual(t, parseDiffLines([]byte(diff)), map[string][]int{
+		"pkg/a.go": {3, 4, 20},
+	})
+}
+
+func TestAddFileLines(t *testing.T) {
+	dir := fs.NewDir(t, "untracked",
+		fs.WithFile("new.go", "package pkg\n\nfunc New() {}\n"),
+		fs.WithFile("partial.go", "package pkg\nvar x = 1"))
+	defer dir.Remove()
+
+	files := parseUntrackedFiles([]byte(dir.Join("new.go") + "\x00" + dir.Join("new_test.go") + "\x00" +
+		dir.Join("partial.go") + "\x00"))
+	assert.DeepEqual(t, files, []string{dir.Join("new.go"), dir.Join("partial.go")})
+
+	changed := map[string][]int{"pkg/a.go": {3}}
+	assert.NilError(t, addFileLines(changed, files))
+	assert.DeepEqual(t, changed, map[string][]int{
+		"pkg/a.go":             {3},
+		dir.Join("new.go"):     {1, 2, 3},
+		dir.Join("partial.go"): {1, 2},
+	})
+}
+
+func TestCoverChangedLines(t *testing.T) {
+	profile, err := parseCoverProfile(strings.NewReader(`mode: set
+example.com/mod/pkg/a.go:3.10,5.2 2 1
+example.com/mod/pkg/a.go:7.10,9.2 1 0
+example.com/mod/pkg/a.go:8.5,8.20 1 1
+example.com/mod/pkg/b.go:1.1,2.2 1 1
+`))
+	assert.NilError(t, err)
+	changed := map[string][]int{
+		"pkg/a.go":    {1, 3, 4, 7, 8, 9},
+		"pkg/b.go":    {10},
+		"cmd/main.go": {4},
+		// only the path relative to the module matches
+		"a.go": {3},
+	}
+	expected := []changedFileCoverage{
+		{File: "a.go", UncoveredLines: []int{}},
+		{File: "cmd/main.go", UncoveredLines: []int{}},
+		{File: "pkg/a.go", Profiled: true, ChangedLines: 5, CoveredLines: 3, UncoveredLines: []int{7, 9}},
+	}
+	diff := coverageDiff{Files: coverChangedLines(changed, profile, "example.com/mod")}
+	assert.DeepEqual(t, diff.Files, expected)
+	assert.DeepEqual(t, uncoveredChanges(diff, testjson.EnglishMessages), []string{
+		"a.go: not in the coverage profile",
+		"cmd/main.go: not in the coverage profile",
+		"pkg/a.go: 2 of 5 changed lines not covered: 7,9",
+	})
+	assert.DeepEqual(t, uncoveredChanges(diff, testjson.JapaneseMessages), []string{
+		"a.go: カバレッジプロファイルにありません",
+		"cmd/main.go: カバレッジプロファイルにありません",
+		"pkg/a.go: 変更された 5 行のうち 2 行が未カバー: 7,9",
+	})
+
+	// git diff --relative in a subdirectory of the module
+	files := coverChangedLines(map[string][]int{"a.go": {3}}, profile, "example.com/mod/pkg")
+	assert.DeepEqual(t, files, []changedFileCoverage{
+		{File: "a.go", Profiled: true, ChangedLines: 1, CoveredLines: 1, UncoveredLines: []int{}, Covered: true},
+	})
+
+	_, err = parseCoverProfile(strings.NewReader("pkg/a.go 1 2\n"))
+	assert.ErrorContains(t, err, "invalid line in coverage profile")
+}
+
+func TestFormatLineRanges(t *testing.T) {
+	assert.Equal(t, formatLineRanges([]int{3, 4, 5, 9, 11, 12}), "3-5,9,11-12")
+	assert.Equal(t, formatLineRanges([]int{7}), "7")
+}
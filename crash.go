@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// crashWarnings returns a summary warning for each package where the test
+// binary crashed while tests were running. When go test reported that a core
+// file was written, the core file is found and set on the Crash, so that it is
+// included in the reports.
+func crashWarnings(execution *testjson.Execution) []string {
+	var warnings []string
+	for _, name := range execution.Packages() {
+		crash := execution.Package(name).Crash()
+		if crash == nil {
+			continue
+		}
+		if crash.CoreDumped && crash.CoreFile == "" {
+			crash.CoreFile = findCoreFile(name, execution.Started())
+		}
+		warning := fmt.Sprintf("%s: the test binary crashed while running %s",
+			testjson.RelativePackagePath(name), strings.Join(crash.Tests, ", "))
+		if crash.CoreFile != "" {
+			warning += ", core file: " + crash.CoreFile
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// findCoreFile returns the newest file named core, or core.PID, in the
+// directory of the package which was modified after since. Returns an empty
+// string if there is no core file. With the default kernel core_pattern the
+// core file is written to the working directory of the test binary, which is
+// the package directory.
+func findCoreFile(pkg string, since time.Time) string {
+	cmd := exec.Command("go", "list", "-f", "{{.Dir}}", pkg)
+	log.Debugf("exec: %s", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		log.WithError(err).Debugf("failed to find the directory of %s", pkg)
+		return ""
+	}
+	return newestCoreFile(strings.TrimSpace(string(out)), since)
+}
+
+func newestCoreFile(dir string, since time.Time) string {
+	matches, _ := filepath.Glob(filepath.Join(dir, "core*"))
+	var newest string
+	var newestTime time.Time
+	for _, path := range matches {
+		name := filepath.Base(path)
+		if name != "core" && !strings.HasPrefix(name, "core.") {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.ModTime().Before(since) {
+			continue
+		}
+		if info.ModTime().After(newestTime) {
+			newest, newestTime = path, info.ModTime()
+		}
+	}
+	return newest
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	"gotest.tools/fs"
+)
+
+func TestNewestCoreFile(t *testing.T) {
+	dir := fs.NewDir(t, "core",
+		fs.WithFile("core", ""),
+		fs.WithFile("core.1234", ""),
+		fs.WithFile("core_test.go", ""))
+	defer dir.Remove()
+
+	since := time.Now().Add(-time.Minute)
+	old := since.Add(-time.Hour)
+	assert.NilError(t, os.Chtimes(dir.Join("core"), old, old))
+	assert.Equal(t, newestCoreFile(dir.Path(), since), filepath.Join(dir.Path(), "core.1234"))
+
+	assert.NilError(t, os.Chtimes(dir.Join("core.1234"), old, old))
+	assert.Equal(t, newestCoreFile(dir.Path(), since), "")
+}
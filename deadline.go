@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// exitCodeDeadline is the exit code used when the run was stopped by
+// --deadline. It is the same exit code used by timeout(1).
+const exitCodeDeadline = 124
+
+// unfinishedPackages returns the packages from planned which did not pass or
+// fail. If no packages were planned, returns the packages which started but
+// did not finish.
+func unfinishedPackages(exec *testjson.Execution, planned []string) []string {
+	if len(planned) == 0 {
+		planned = exec.Packages()
+	}
+	var unfinished []string
+	for _, name := range planned {
+		pkg := exec.Package(name)
+		if pkg == nil || pkg.Result() == "" {
+			unfinished = append(unfinished, name)
+		}
+	}
+	return unfinished
+}
+
+// deadlineWarnings returns the summary warnings for a run stopped by
+// --deadline. The tests and packages which did not finish are also listed as
+// not run.
+func deadlineWarnings(deadline time.Duration, unfinished []string) []string {
+	warnings := []string{
+		fmt.Sprintf("deadline of %s reached, packages which did not finish:", deadline),
+	}
+	for _, pkg := range unfinished {
+		warnings = append(warnings, "    "+testjson.RelativePackagePath(pkg))
+	}
+	return warnings
+}
+
+// stopAtDeadline closes the stdout and stderr of the proc when the deadline
+// is reached, or the run is stopped by a signal. Test binaries started by go
+// test may continue to run after go test is killed, and would otherwise keep
+// the pipes open until they exit. Reads after the context is done return
+// io.EOF so the scan ends without an error.
+func stopAtDeadline(ctx context.Context, p proc) proc {
+	stdout, stderr := p.stdout, p.stderr
+	go func() {
+		<-ctx.Done()
+		for _, reader := range []io.Reader{stdout, stderr} {
+			if closer, ok := reader.(io.Closer); ok {
+				closer.Close() // nolint: errcheck
+			}
+		}
+	}()
+	p.stdout = &deadlineReader{ctx: ctx, reader: stdout}
+	p.stderr = &deadlineReader{ctx: ctx, reader: stderr}
+	return p
+}
+
+type deadlineReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && r.ctx.Err() != nil {
+		return n, io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestUnfinishedPackages(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"done","Test":"TestA"}
+{"Action":"pass","Package":"done","Test":"TestA"}
+{"Action":"pass","Package":"done"}
+{"Action":"run","Package":"running","Test":"TestB"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &runRecorder{order: map[string][]string{}},
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, unfinishedPackages(exec, nil), []string{"running"})
+	planned := []string{"done", "running", "never-started"}
+	assert.DeepEqual(t, unfinishedPackages(exec, planned), []string{"running", "never-started"})
+}
+
+func TestDeadlineWarnings(t *testing.T) {
+	warnings := deadlineWarnings(5*time.Minute, []string{"running", "never-started"})
+	assert.DeepEqual(t, warnings, []string{
+		"deadline of 5m0s reached, packages which did not finish:",
+		"    running",
+		"    never-started",
+	})
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// exitCodeEmpty is the exit code used when --fail-on-empty finds a package
+// pattern, or the whole run, with no tests.
+const exitCodeEmpty = 4
+
+// emptyWarnings returns a warning for each pattern which matches packages with
+// no tests. The pattern all matches the whole run.
+func emptyWarnings(exec *testjson.Execution, patterns []string) []string {
+	var warnings []string
+	for _, pattern := range patterns {
+		if pattern == "all" {
+			if exec.Total() == 0 {
+				warnings = append(warnings, "no tests ran")
+			}
+			continue
+		}
+		match := matchPackagePattern(pattern)
+		found, total := false, 0
+		for _, name := range exec.Packages() {
+			if match(name) {
+				found = true
+				total += exec.Package(name).Total
+			}
+		}
+		switch {
+		case !found:
+			warnings = append(warnings, fmt.Sprintf("no packages match %s", pattern))
+		case total == 0:
+			warnings = append(warnings, fmt.Sprintf("no tests ran in %s", pattern))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestEmptyWarnings(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"core","Test":"TestA"}
+{"Action":"pass","Package":"core","Test":"TestA"}
+{"Action":"pass","Package":"core"}
+{"Action":"output","Package":"tagged","Output":"testing: warning: no tests to run\n"}
+{"Action":"pass","Package":"tagged"}
+`))
+	assert.NilError(t, err)
+
+	assert.Assert(t, emptyWarnings(exec, []string{"all", "core/..."}) == nil)
+	expected := []string{"no tests ran in tagged", "no packages match ./missing/..."}
+	assert.DeepEqual(t, emptyWarnings(exec, []string{"tagged", "./missing/..."}), expected)
+
+	empty, err := testjson.ReadExecution(strings.NewReader(""))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, emptyWarnings(empty, []string{"all"}), []string{"no tests ran"})
+}
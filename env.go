@@ -0,0 +1,58 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+)
+
+// captureEnv returns the variables from environ which match one of the
+// patterns. A pattern is the name of a variable, or a prefix followed by a '*'.
+func captureEnv(patterns []string, environ []string) map[string]string {
+	if len(patterns) == 0 {
+		return nil
+	}
+	env := make(map[string]string)
+	for _, item := range environ {
+		i := strings.Index(item, "=")
+		if i <= 0 {
+			continue
+		}
+		name := item[:i]
+		if matchEnvPattern(patterns, name) {
+			env[name] = item[i+1:]
+		}
+	}
+	return env
+}
+
+func matchEnvPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(name, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+			continue
+		}
+		if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// envProperties returns a JUnit property, with an env. prefix, for each
+// variable, sorted by name.
+func envProperties(env map[string]string) []junitxml.JUnitProperty {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	props := make([]junitxml.JUnitProperty, 0, len(names))
+	for _, name := range names {
+		props = append(props, junitxml.JUnitProperty{Name: "env." + name, Value: env[name]})
+	}
+	return props
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/internal/junitxml"
+)
+
+func TestCaptureEnv(t *testing.T) {
+	environ := []string{
+		"GOFLAGS=-mod=vendor",
+		"GOPATH=/go",
+		"CI_JOB_ID=1234",
+		"CI_COMMIT_SHA=abcd",
+		"HOME=/root",
+		"EMPTY=",
+	}
+	env := captureEnv([]string{"GOFLAGS", "CI_*", "EMPTY", "MISSING"}, environ)
+	expected := map[string]string{
+		"GOFLAGS":       "-mod=vendor",
+		"CI_JOB_ID":     "1234",
+		"CI_COMMIT_SHA": "abcd",
+		"EMPTY":         "",
+	}
+	assert.DeepEqual(t, env, expected)
+	assert.Assert(t, captureEnv(nil, environ) == nil)
+
+	assert.DeepEqual(t, envProperties(env), []junitxml.JUnitProperty{
+		{Name: "env.CI_COMMIT_SHA", Value: "abcd"},
+		{Name: "env.CI_JOB_ID", Value: "1234"},
+		{Name: "env.EMPTY", Value: ""},
+		{Name: "env.GOFLAGS", Value: "-mod=vendor"},
+	})
+}
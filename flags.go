@@ -2,9 +2,13 @@ package main
 
 import (
 	"encoding/csv"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"gotest.tools/gotestsum/internal/junitxml"
 	"gotest.tools/gotestsum/testjson"
 )
 
@@ -47,3 +51,378 @@ func (s *noSummaryValue) String() string {
 	// flip all the bits, since the flag value is the negative of what is stored
 	return (testjson.SummarizeAll ^ s.value).String()
 }
+
+// rerunIsolationValue is the value of the --rerun-fails-isolation flag, a comma
+// separated list of the isolation of each rerun, ex: package,test,isolated.
+// The last one is used by any remaining reruns.
+type rerunIsolationValue struct {
+	levels []rerunIsolation
+}
+
+func (v *rerunIsolationValue) Set(val string) error {
+	items, err := readAsCSV(val)
+	if err != nil {
+		return err
+	}
+	levels := make([]rerunIsolation, 0, len(items))
+	for _, item := range items {
+		switch level := rerunIsolation(item); level {
+		case rerunPackage, rerunTest, rerunIsolated:
+			levels = append(levels, level)
+		default:
+			return errors.Errorf("%q must be one of: package, test, isolated", item)
+		}
+	}
+	v.levels = levels
+	return nil
+}
+
+// attempt returns the isolation of the rerun attempt, starting from 1.
+func (v rerunIsolationValue) attempt(n int) rerunIsolation {
+	switch {
+	case len(v.levels) == 0:
+		return rerunPackage
+	case n > len(v.levels):
+		return v.levels[len(v.levels)-1]
+	}
+	return v.levels[n-1]
+}
+
+func (v *rerunIsolationValue) Type() string {
+	return "isolation"
+}
+
+func (v *rerunIsolationValue) String() string {
+	levels := make([]string, 0, len(v.levels))
+	for _, level := range v.levels {
+		levels = append(levels, string(level))
+	}
+	return strings.Join(levels, ",")
+}
+
+// junitFileValue is the value of the --junitfile flag. The flag may be repeated
+// to write more than one file, and each value may be a comma separated list of
+// paths. A path of - writes the file to stdout. Each path is optionally
+// followed by options which override --junit-path-mode, --junit-duplicates,
+// --junit-system-out, --junit-subtests, --junit-reruns, --junit-sort, and
+// --junitfile-format for that file, write only the failed testcases, or write
+// each testsuite as soon as its package ends, ex:
+// PATH,path-mode=relative,duplicates=suffix,failures-only=true,stream=true
+// A part which is not NAME=VALUE with the name of an option is a path.
+type junitFileValue struct {
+	values  []string
+	files   []junitFileSpec
+	changed bool
+}
+
+// junitFileStdout is the path of a --junitfile which is written to stdout.
+const junitFileStdout = "-"
+
+type junitFileSpec struct {
+	path       string
+	pathMode   string
+	duplicates string
+	systemOut  string
+	subtests   string
+	reruns     string
+	sort       string
+	format     string
+	// failuresOnly writes only the testcases which failed.
+	failuresOnly bool
+	// stream writes the testsuite of each package as soon as the package
+	// ends.
+	stream bool
+}
+
+func (s junitFileSpec) stdout() bool {
+	return s.path == junitFileStdout
+}
+
+var junitFileOptions = []string{
+	"path-mode", "duplicates", "system-out", "subtests", "reruns", "sort", "format", "failures-only", "stream",
+}
+
+func (s *junitFileSpec) setOption(name, value string) error {
+	switch name {
+	case "path-mode":
+		s.pathMode = value
+	case "duplicates":
+		s.duplicates = value
+	case "system-out":
+		s.systemOut = value
+	case "subtests":
+		s.subtests = value
+	case "reruns":
+		s.reruns = value
+	case "sort":
+		s.sort = value
+	case "format":
+		s.format = value
+	case "failures-only":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Errorf("option failures-only must be true or false, not %s", value)
+		}
+		s.failuresOnly = b
+	case "stream":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Errorf("option stream must be true or false, not %s", value)
+		}
+		s.stream = b
+	default:
+		return errors.Errorf("unknown option %q, must be one of: %s",
+			name, strings.Join(junitFileOptions, ", "))
+	}
+	return nil
+}
+
+func newJUnitFileValue(defaultValue string) *junitFileValue {
+	value := &junitFileValue{}
+	if defaultValue != "" {
+		// the default is always valid, it is only a path when it has no options
+		value.Set(defaultValue) // nolint: errcheck
+		value.changed = false
+	}
+	return value
+}
+
+func (v *junitFileValue) Set(val string) error {
+	var specs []junitFileSpec
+	for _, part := range strings.Split(val, ",") {
+		// a NAME=VALUE part is an option only when NAME is an option, so that a
+		// path may contain a '='
+		kv := strings.SplitN(part, "=", 2)
+		isOption := len(kv) == 2 && isJUnitFileOption(kv[0])
+		switch {
+		case isOption && len(specs) == 0:
+			return errors.New("a path is required")
+		case isOption:
+			if err := specs[len(specs)-1].setOption(kv[0], kv[1]); err != nil {
+				return err
+			}
+		case part == "":
+			return errors.New("a path is required")
+		case isJUnitFileOption(part) && len(specs) > 0:
+			return errors.Errorf("option %q must be NAME=VALUE", part)
+		default:
+			specs = append(specs, junitFileSpec{path: part})
+		}
+	}
+	// the first value from the command line replaces the default from the
+	// environment
+	if !v.changed {
+		v.values, v.files = nil, nil
+		v.changed = true
+	}
+	v.values = append(v.values, val)
+	v.files = append(v.files, specs...)
+	return nil
+}
+
+func isJUnitFileOption(name string) bool {
+	for _, option := range junitFileOptions {
+		if name == option {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *junitFileValue) Type() string {
+	return "junitfile"
+}
+
+func (v *junitFileValue) String() string {
+	return strings.Join(v.values, " ")
+}
+
+// first returns the path of the first file which is not written to stdout, or
+// an empty string if there are no files.
+func (v *junitFileValue) first() string {
+	for _, spec := range v.files {
+		if !spec.stdout() {
+			return spec.path
+		}
+	}
+	return ""
+}
+
+// envFlagNames are the names of the environment variables which set a flag
+// before every flag could be set from the environment, when the name is not the
+// one from envFlagName.
+var envFlagNames = map[string]string{
+	"ndjson-file": "GOTESTSUM_NDJSONFILE",
+}
+
+// envFlagName returns the name of the environment variable which sets the flag
+// name. For example --rerun-fails is set by GOTESTSUM_RERUN_FAILS.
+func envFlagName(name string) string {
+	if envName, ok := envFlagNames[name]; ok {
+		return envName
+	}
+	return "GOTESTSUM_" + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// setFlagsFromEnv sets each flag which was not set on the command line from
+// the environment variable named by envFlagName. A flag set on the command line
+// takes precedence over the environment, and the environment takes precedence
+// over the default value of the flag. --version is never set from the
+// environment, because GOTESTSUM_VERSION is commonly used by CI to select the
+// version of gotestsum to install.
+func setFlagsFromEnv(flags *pflag.FlagSet, lookupEnv func(string) (string, bool)) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "version" {
+			return
+		}
+		name := envFlagName(flag.Name)
+		value, ok := lookupEnv(name)
+		if !ok || value == "" {
+			return
+		}
+		if setErr := flags.Set(flag.Name, value); setErr != nil {
+			err = errors.Wrapf(setErr, "invalid value %q for %s", value, name)
+		}
+	})
+	return err
+}
+
+// timestampValue is the value of a flag which accepts an RFC3339 timestamp.
+type timestampValue struct {
+	value time.Time
+}
+
+func (v *timestampValue) Set(val string) error {
+	if val == "" {
+		v.value = time.Time{}
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return errors.Errorf("%q is not an RFC3339 timestamp, ex: 2006-01-02T15:04:05Z", val)
+	}
+	v.value = t
+	return nil
+}
+
+func (v *timestampValue) Type() string {
+	return "timestamp"
+}
+
+func (v *timestampValue) String() string {
+	if v.value.IsZero() {
+		return ""
+	}
+	return v.value.Format(time.RFC3339)
+}
+
+// junitNamingValue is the value of the --junit-naming flag. Each value is a
+// template for the classname or the name of each JUnit testcase, ex:
+// name={{ .Package }}.{{ .Test }}
+type junitNamingValue struct {
+	values []string
+	naming junitxml.Naming
+}
+
+func (v *junitNamingValue) Set(val string) error {
+	kv := strings.SplitN(val, "=", 2)
+	if len(kv) != 2 {
+		return errors.Errorf("%q must be classname=TEMPLATE or name=TEMPLATE", val)
+	}
+	tmpl, err := junitxml.ParseNamingTemplate(kv[0], kv[1])
+	if err != nil {
+		return errors.Wrapf(err, "invalid %s template", kv[0])
+	}
+	switch kv[0] {
+	case "classname":
+		v.naming.Classname = tmpl
+	case "name":
+		v.naming.Name = tmpl
+	default:
+		return errors.Errorf("unknown template %q, must be one of: classname, name", kv[0])
+	}
+	v.values = append(v.values, val)
+	return nil
+}
+
+func (v *junitNamingValue) Type() string {
+	return "template"
+}
+
+func (v *junitNamingValue) String() string {
+	return strings.Join(v.values, " ")
+}
+
+// junitPropertyValue is the value of the --junit-property flag. Each value is
+// a comma separated list of properties, which are added to every testsuite of
+// the JUnit XML file, ex: git.sha=abc123,ci.build=42
+type junitPropertyValue struct {
+	values     []string
+	properties []junitxml.JUnitProperty
+	// envErr is the error from the properties in GOTESTSUM_JUNIT_PROPERTIES,
+	// which is returned by validateJUnitOptions.
+	envErr error
+}
+
+// newJUnitPropertyValue returns a junitPropertyValue with the properties from
+// the value of the GOTESTSUM_JUNIT_PROPERTIES environment variable. Properties
+// from the command line are added to them.
+func newJUnitPropertyValue(env string) *junitPropertyValue {
+	value := &junitPropertyValue{}
+	if env != "" {
+		value.envErr = value.Set(env)
+	}
+	return value
+}
+
+func (v *junitPropertyValue) Set(val string) error {
+	items, err := readAsCSV(val)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return errors.Errorf("property %q must be NAME=VALUE", item)
+		}
+		v.properties = append(v.properties, junitxml.JUnitProperty{Name: kv[0], Value: kv[1]})
+	}
+	v.values = append(v.values, val)
+	return nil
+}
+
+func (v *junitPropertyValue) Type() string {
+	return "name=value"
+}
+
+func (v *junitPropertyValue) String() string {
+	return strings.Join(v.values, ",")
+}
+
+// labelValue is the value of the --label flag. Each value is a comma separated
+// list of labels of the run, ex: team=payments,env=ci
+type labelValue map[string]string
+
+func (v labelValue) Set(val string) error {
+	items, err := readAsCSV(val)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return errors.Errorf("label %q must be NAME=VALUE", item)
+		}
+		v[kv[0]] = kv[1]
+	}
+	return nil
+}
+
+func (v labelValue) Type() string {
+	return "name=value"
+}
+
+func (v labelValue) String() string {
+	return formatRunLabels(v)
+}
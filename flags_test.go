@@ -3,9 +3,13 @@ package main
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"gotest.tools/assert"
+	"gotest.tools/gotestsum/internal/junitxml"
 )
 
+var cmpJUnitFileSpec = cmp.AllowUnexported(junitFileSpec{})
+
 func TestNoSummaryValue_SetAndString(t *testing.T) {
 	t.Run("none", func(t *testing.T) {
 		assert.Equal(t, newNoSummaryValue().String(), "none")
@@ -26,3 +30,244 @@ func TestNoSummaryValue_SetAndString(t *testing.T) {
 		assert.ErrorContains(t, value.Set("bogus"), "must be one or more of")
 	})
 }
+
+func TestJUnitFileValue_Set(t *testing.T) {
+	value := newJUnitFileValue("from-env.xml")
+	assert.Equal(t, value.first(), "from-env.xml")
+
+	assert.NilError(t, value.Set("jenkins.xml"))
+	assert.NilError(t, value.Set("gitlab.xml,path-mode=relative,duplicates=suffix"))
+	assert.NilError(t, value.Set("ci.xml,system-out=all"))
+	expected := []junitFileSpec{
+		{path: "jenkins.xml"},
+		{path: "gitlab.xml", pathMode: "relative", duplicates: "suffix"},
+		{path: "ci.xml", systemOut: "all"},
+	}
+	assert.DeepEqual(t, value.files, expected, cmpJUnitFileSpec)
+	assert.Equal(t, value.String(), "jenkins.xml gitlab.xml,path-mode=relative,duplicates=suffix ci.xml,system-out=all")
+
+	value = newJUnitFileValue("")
+	assert.NilError(t, value.Set("artifacts/junit.xml,-,format=otr"))
+	assert.NilError(t, value.Set("a.xml,sort=name,b.xml"))
+	expected = []junitFileSpec{
+		{path: "artifacts/junit.xml"},
+		{path: "-", format: "otr"},
+		{path: "a.xml", sort: "name"},
+		{path: "b.xml"},
+	}
+	assert.DeepEqual(t, value.files, expected, cmpJUnitFileSpec)
+	assert.Equal(t, value.first(), "artifacts/junit.xml")
+
+	// a '=' in a path which does not start with the name of an option
+	value = newJUnitFileValue("")
+	assert.NilError(t, value.Set("out/run=1/junit.xml,format=otr,flavor=jenkins.xml"))
+	expected = []junitFileSpec{
+		{path: "out/run=1/junit.xml", format: "otr"},
+		{path: "flavor=jenkins.xml"},
+	}
+	assert.DeepEqual(t, value.files, expected, cmpJUnitFileSpec)
+
+	assert.ErrorContains(t, value.Set("a.xml,,b.xml"), "a path is required")
+	assert.ErrorContains(t, value.Set("a.xml,stream=maybe"), "must be true or false")
+	assert.ErrorContains(t, value.Set("a.xml,path-mode"), "must be NAME=VALUE")
+	assert.ErrorContains(t, value.Set(",path-mode=raw"), "a path is required")
+	assert.ErrorContains(t, value.Set("a.xml,failures-only=yes please"), "must be true or false")
+}
+
+func TestJUnitFileSpecs(t *testing.T) {
+	opts := &options{junitFiles: newJUnitFileValue(""), junitFailuresOnly: newJUnitFileValue("")}
+	assert.NilError(t, opts.junitFiles.Set("all.xml"))
+	assert.NilError(t, opts.junitFiles.Set("failed.xml,failures-only=true"))
+	assert.NilError(t, opts.junitFailuresOnly.Set("fail.xml,path-mode=relative"))
+
+	expected := []junitFileSpec{
+		{path: "all.xml"},
+		{path: "failed.xml", failuresOnly: true},
+		{path: "fail.xml", pathMode: "relative", failuresOnly: true},
+	}
+	assert.DeepEqual(t, junitFileSpecs(opts), expected, cmpJUnitFileSpec)
+}
+
+func TestValidateJUnitOptions(t *testing.T) {
+	opts := &options{
+		junitPathMode:     "raw",
+		junitDuplicates:   "warn",
+		junitSystemOut:    "none",
+		junitSubtests:     "flat",
+		junitReruns:       "separate",
+		junitANSI:         "strip",
+		junitFileFormat:   "junit",
+		junitSort:         "none",
+		junitFiles:        newJUnitFileValue(""),
+		junitFailuresOnly: newJUnitFileValue(""),
+		junitProperties:   newJUnitPropertyValue(""),
+		packagePriority:   &priorityValue{},
+		skipCategories:    &skipCategoryValue{},
+	}
+	assert.NilError(t, validateJUnitOptions(opts))
+	assert.Assert(t, !junitSystemOutEnabled(opts))
+
+	assert.NilError(t, opts.junitFiles.Set("out.xml,system-out=testcase"))
+	assert.NilError(t, validateJUnitOptions(opts))
+	assert.Assert(t, junitSystemOutEnabled(opts))
+
+	assert.NilError(t, opts.junitFiles.Set("-"))
+	assert.NilError(t, validateJUnitOptions(opts))
+	assert.Assert(t, junitFileToStdout(opts))
+	assert.NilError(t, opts.junitFailuresOnly.Set("-"))
+	assert.ErrorContains(t, validateJUnitOptions(opts), "only one --junitfile can be written to stdout")
+	opts.junitFailuresOnly = newJUnitFileValue("")
+
+	opts.junitSystemOut = "stdout"
+	assert.ErrorContains(t, validateJUnitOptions(opts), "unknown JUnit system-out mode stdout")
+	opts.junitSystemOut = "none"
+
+	assert.NilError(t, opts.junitFiles.Set("tree.xml,subtests=tree"))
+	assert.ErrorContains(t, validateJUnitOptions(opts), "unknown JUnit subtests mode tree")
+	opts.junitFiles = newJUnitFileValue("")
+
+	assert.NilError(t, opts.junitFiles.Set("jenkins.xml,reruns=flaky"))
+	assert.ErrorContains(t, validateJUnitOptions(opts), "unknown JUnit reruns mode flaky")
+	opts.junitFiles = newJUnitFileValue("")
+
+	assert.NilError(t, opts.junitFiles.Set("sorted.xml,sort=duration"))
+	assert.NilError(t, validateJUnitOptions(opts))
+	assert.NilError(t, opts.junitFiles.Set("random.xml,sort=random"))
+	assert.ErrorContains(t, validateJUnitOptions(opts), "unknown JUnit sort mode random")
+	opts.junitFiles = newJUnitFileValue("")
+
+	assert.NilError(t, opts.junitFiles.Set("gradle.xml,format=open-test-reporting"))
+	assert.NilError(t, validateJUnitOptions(opts))
+	assert.NilError(t, opts.junitFiles.Set("junit5.xml,format=junit5"))
+	assert.ErrorContains(t, validateJUnitOptions(opts), "unknown JUnit file format junit5")
+	opts.junitFiles = newJUnitFileValue("")
+
+	assert.NilError(t, opts.junitFiles.Set("big.xml,stream=true"))
+	assert.NilError(t, validateJUnitOptions(opts))
+	opts.rerunFails = 2
+	assert.ErrorContains(t, validateJUnitOptions(opts), "stream=true can not be used with --rerun-fails")
+	opts.rerunFails = 0
+	opts.failOnSkip = "."
+	assert.ErrorContains(t, validateJUnitOptions(opts), "stream=true can not be used with --fail-on-skip")
+	opts.failOnSkip = ""
+	assert.NilError(t, opts.packagePriority.Set("./api/...=critical"))
+	assert.ErrorContains(t, validateJUnitOptions(opts), "stream=true can not be used with --package-priority")
+	opts.packagePriority = &priorityValue{}
+	assert.NilError(t, opts.skipCategories.Set("flaky=quarantined"))
+	assert.ErrorContains(t, validateJUnitOptions(opts), "stream=true can not be used with --skip-category")
+	opts.skipCategories = &skipCategoryValue{}
+	opts.junitDuplicates = "suffix"
+	assert.ErrorContains(t, validateJUnitOptions(opts), "stream=true can not be used with duplicates=suffix")
+	opts.junitDuplicates = "warn"
+	assert.NilError(t, opts.junitFiles.Set("big.xml,stream=true,format=open-test-reporting"))
+	assert.ErrorContains(t, validateJUnitOptions(opts), "stream=true can only be used with format=junit")
+	opts.junitFiles = newJUnitFileValue("")
+
+	assert.NilError(t, opts.junitFiles.Set("a.xml,path-mode=java"))
+	assert.ErrorContains(t, validateJUnitOptions(opts), "unknown JUnit path mode java")
+	opts.junitFiles = newJUnitFileValue("")
+
+	opts.junitANSI = "color"
+	assert.ErrorContains(t, validateJUnitOptions(opts), "unknown JUnit ANSI mode color")
+	opts.junitANSI = "strip"
+
+	opts.junitMaxOutputBytes = -1
+	assert.ErrorContains(t, validateJUnitOptions(opts), "must not be negative")
+	opts.junitMaxOutputBytes = 0
+
+	opts.junitProperties = newJUnitPropertyValue("git.sha")
+	assert.ErrorContains(t, validateJUnitOptions(opts), "invalid GOTESTSUM_JUNIT_PROPERTIES")
+}
+
+func TestJUnitPropertyValue_Set(t *testing.T) {
+	value := newJUnitPropertyValue("git.sha=abc123,ci.build=42")
+	assert.NilError(t, value.envErr)
+	assert.NilError(t, value.Set("goos=linux"))
+	assert.NilError(t, value.Set(`"ci.job=unit, race"`))
+	expected := []junitxml.JUnitProperty{
+		{Name: "git.sha", Value: "abc123"},
+		{Name: "ci.build", Value: "42"},
+		{Name: "goos", Value: "linux"},
+		{Name: "ci.job", Value: "unit, race"},
+	}
+	assert.DeepEqual(t, value.properties, expected)
+
+	assert.ErrorContains(t, value.Set("goarch"), `property "goarch" must be NAME=VALUE`)
+	assert.ErrorContains(t, value.Set("=amd64"), "must be NAME=VALUE")
+}
+
+func TestTimestampValue_Set(t *testing.T) {
+	value := &timestampValue{}
+	assert.Equal(t, value.String(), "")
+
+	assert.NilError(t, value.Set("2021-02-03T04:05:06-05:00"))
+	assert.Equal(t, value.String(), "2021-02-03T04:05:06-05:00")
+	assert.Equal(t, value.value.Unix(), int64(1612343106))
+
+	assert.ErrorContains(t, value.Set("yesterday"), "not an RFC3339 timestamp")
+	assert.NilError(t, value.Set(""))
+	assert.Assert(t, value.value.IsZero())
+}
+
+func TestJUnitNamingValue_Set(t *testing.T) {
+	value := &junitNamingValue{}
+	assert.NilError(t, value.Set("classname={{ base .Package }}_test"))
+	assert.NilError(t, value.Set("name={{ .Package }}.{{ .Test }}"))
+	assert.Assert(t, value.naming.Classname != nil)
+	assert.Assert(t, value.naming.Name != nil)
+	assert.Equal(t, value.String(), "classname={{ base .Package }}_test name={{ .Package }}.{{ .Test }}")
+
+	assert.ErrorContains(t, value.Set("{{ .Test }}"), "must be classname=TEMPLATE or name=TEMPLATE")
+	assert.ErrorContains(t, value.Set("suite={{ .Test }}"), "unknown template")
+	assert.ErrorContains(t, value.Set("name={{ .Test"), "invalid name template")
+}
+
+func TestSetFlagsFromEnv(t *testing.T) {
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse([]string{"--format=dots", "--", "-race"}))
+
+	env := map[string]string{
+		"GOTESTSUM_FORMAT":          "standard-verbose",
+		"GOTESTSUM_RERUN_FAILS":     "2",
+		"GOTESTSUM_CAPTURE_ENV":     "GOOS,CI_*",
+		"GOTESTSUM_NO_COLOR":        "true",
+		"GOTESTSUM_VERSION":         "0.3.4",
+		"GOTESTSUM_JUNIT_PATH_MODE": "",
+		"GOTESTSUM_NDJSON_FILE":     "ignored.ndjson",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+	assert.NilError(t, setFlagsFromEnv(flags, lookupEnv))
+	assert.Equal(t, opts.format, "dots")
+	assert.Equal(t, opts.rerunFails, 2)
+	assert.DeepEqual(t, opts.captureEnv, []string{"GOOS", "CI_*"})
+	assert.Assert(t, opts.noColor)
+	assert.Assert(t, !opts.version)
+	assert.Equal(t, opts.junitPathMode, "raw")
+	assert.Equal(t, opts.ndjsonFile, "")
+	assert.DeepEqual(t, flags.Args(), []string{"-race"})
+
+	env = map[string]string{"GOTESTSUM_NDJSONFILE": "results.ndjson"}
+	flags, opts = setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse(nil))
+	assert.NilError(t, setFlagsFromEnv(flags, lookupEnv))
+	assert.Equal(t, opts.ndjsonFile, "results.ndjson")
+
+	env = map[string]string{"GOTESTSUM_DEADLINE": "soon"}
+	flags, _ = setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse(nil))
+	assert.ErrorContains(t, setFlagsFromEnv(flags, lookupEnv),
+		`invalid value "soon" for GOTESTSUM_DEADLINE`)
+}
+
+func TestLabelValue_Set(t *testing.T) {
+	labels := make(map[string]string)
+	value := labelValue(labels)
+	assert.NilError(t, value.Set("team=payments,env=ci"))
+	assert.NilError(t, value.Set("shard=1"))
+	assert.DeepEqual(t, labels, map[string]string{"team": "payments", "env": "ci", "shard": "1"})
+
+	assert.ErrorContains(t, value.Set("team"), `label "team" must be NAME=VALUE`)
+}
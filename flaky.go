@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/internal/ndjson"
+)
+
+func validateFlakyScoreOptions(opts *options) error {
+	if opts.flakyScore && len(opts.history) == 0 {
+		return errors.New("--flaky-score requires --history")
+	}
+	return nil
+}
+
+// readFlakeRates returns the fraction of the runs from --history in which each
+// package had a test which failed, and passed in another run.
+func readFlakeRates(opts *options) (map[string]float64, error) {
+	rows, err := ndjson.ReadFiles(opts.history)
+	if err != nil {
+		return nil, err
+	}
+	rates := ndjson.PackageFlakeRates(rows)
+	log.Debugf("package flake rates: %v", rates)
+	return rates, nil
+}
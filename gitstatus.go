@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// gitFileStatus is the status of a file from git status --porcelain.
+type gitFileStatus struct {
+	// code is the XY status code of the file.
+	code string
+	// from is the original path of a file which was renamed or copied.
+	from string
+}
+
+// gitStatus returns the status of each modified or untracked file in the
+// working tree, by the path of the file relative to the root of the working
+// tree, as reported by git status --porcelain.
+func gitStatus() (map[string]gitFileStatus, error) {
+	cmd := exec.Command("git", "status", "--porcelain=v1", "-z", "--untracked-files=all")
+	log.Debugf("exec: %s", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run git status")
+	}
+	return parseGitStatus(out), nil
+}
+
+// parseGitStatus parses the output of git status --porcelain=v1 -z. Each entry
+// is terminated by a NUL, and paths are not quoted. The entry of a renamed or
+// copied file is followed by the original path.
+func parseGitStatus(out []byte) map[string]gitFileStatus {
+	status := make(map[string]gitFileStatus)
+	entries := strings.Split(string(bytes.TrimSuffix(out, []byte{0})), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		file := gitFileStatus{code: entry[:2]}
+		if strings.ContainsAny(file.code, "RC") && i+1 < len(entries) {
+			i++
+			file.from = entries[i]
+		}
+		status[entry[3:]] = file
+	}
+	return status
+}
+
+// gitChange is a file which has a different status after the run than it did
+// before the run.
+type gitChange struct {
+	path string
+	// line is the change formatted like a git status --porcelain line.
+	line string
+}
+
+// gitStatusChanges returns the files which have a different status after the
+// run than they did before the run, sorted by path.
+func gitStatusChanges(before, after map[string]gitFileStatus) []gitChange {
+	var changes []gitChange
+	for path, status := range after {
+		if before[path] == status {
+			continue
+		}
+		line := status.code + " " + path
+		if status.from != "" {
+			line += " (from " + status.from + ")"
+		}
+		changes = append(changes, gitChange{path: path, line: line})
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, gitChange{path: path, line: "   " + path + " (restored)"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].path < changes[j].path
+	})
+	return changes
+}
+
+// gitRoot returns the root directory of the working tree.
+func gitRoot() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	log.Debugf("exec: %s", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find the root of the git working tree")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// packageDirs returns the directory of each package, by import path. Returns
+// an empty map if the packages can not be listed, so that the changes are
+// still reported without a package.
+func packageDirs(pkgs []string) map[string]string {
+	dirs := make(map[string]string)
+	if len(pkgs) == 0 {
+		return dirs
+	}
+	args := append([]string{"list", "-e", "-f", "{{.ImportPath}}\t{{.Dir}}"}, pkgs...)
+	cmd := exec.Command("go", args...)
+	log.Debugf("exec: %s", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		log.WithError(err).Debug("failed to find the directories of the packages")
+		return dirs
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) == 2 && parts[1] != "" {
+			dirs[parts[0]] = parts[1]
+		}
+	}
+	return dirs
+}
+
+// packageOfFile returns the package with the deepest directory which contains
+// the file, or an empty string if no package contains the file. A file in a
+// subdirectory of a package which is not a package, like testdata, is
+// attributed to that package.
+func packageOfFile(path string, dirs map[string]string) string {
+	var pkg, pkgDir string
+	for name, dir := range dirs {
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			continue
+		}
+		if len(dir) > len(pkgDir) {
+			pkg, pkgDir = name, dir
+		}
+	}
+	return pkg
+}
+
+// gitStatusWarnings returns a warning for each file which changed status
+// during the run, grouped by the package which contains the file.
+func gitStatusWarnings(before map[string]gitFileStatus, pkgs []string) ([]string, error) {
+	after, err := gitStatus()
+	if err != nil {
+		return nil, err
+	}
+	changes := gitStatusChanges(before, after)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	root, err := gitRoot()
+	if err != nil {
+		return nil, err
+	}
+	return formatGitStatusWarnings(changes, root, packageDirs(pkgs)), nil
+}
+
+func formatGitStatusWarnings(changes []gitChange, root string, dirs map[string]string) []string {
+	byPackage := make(map[string][]string)
+	var pkgs []string
+	for _, change := range changes {
+		pkg := packageOfFile(filepath.Join(root, filepath.FromSlash(change.path)), dirs)
+		if _, ok := byPackage[pkg]; !ok && pkg != "" {
+			pkgs = append(pkgs, pkg)
+		}
+		byPackage[pkg] = append(byPackage[pkg], "    "+change.line)
+	}
+	sort.Strings(pkgs)
+	warnings := []string{"git working tree was modified by the test run:"}
+	for _, pkg := range pkgs {
+		warnings = append(warnings, "  package "+pkg+":")
+		warnings = append(warnings, byPackage[pkg]...)
+	}
+	if files := byPackage[""]; len(files) > 0 {
+		if len(pkgs) > 0 {
+			warnings = append(warnings, "  not in a package:")
+		}
+		warnings = append(warnings, files...)
+	}
+	return warnings
+}
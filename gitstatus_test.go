@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/assert"
+)
+
+var (
+	cmpGitFileStatus = cmp.AllowUnexported(gitFileStatus{})
+	cmpGitChange     = cmp.AllowUnexported(gitChange{})
+)
+
+func TestParseGitStatus(t *testing.T) {
+	out := " M main.go\x00?? with space.txt\x00R  new.go\x00old.go\x00?? \"quoted\".txt\x00"
+	expected := map[string]gitFileStatus{
+		"main.go":        {code: " M"},
+		"with space.txt": {code: "??"},
+		"new.go":         {code: "R ", from: "old.go"},
+		`"quoted".txt`:   {code: "??"},
+	}
+	assert.DeepEqual(t, parseGitStatus([]byte(out)), expected, cmpGitFileStatus)
+}
+
+func TestGitStatusChanges(t *testing.T) {
+	before := parseGitStatus([]byte(" M main.go\x00?? notes.txt\x00?? old.txt\x00"))
+	after := parseGitStatus([]byte(" M main.go\x00?? notes.txt\x00?? store/testdata/out.golden\x00M  go.sum\x00R  store/b.go\x00store/a.go\x00"))
+
+	changes := gitStatusChanges(before, after)
+	expected := []gitChange{
+		{path: "go.sum", line: "M  go.sum"},
+		{path: "old.txt", line: "   old.txt (restored)"},
+		{path: "store/b.go", line: "R  store/b.go (from store/a.go)"},
+		{path: "store/testdata/out.golden", line: "?? store/testdata/out.golden"},
+	}
+	assert.DeepEqual(t, changes, expected, cmpGitChange)
+
+	root := filepath.FromSlash("/src/mod")
+	dirs := map[string]string{
+		"example.com/mod":           root,
+		"example.com/mod/store":     filepath.Join(root, "store"),
+		"example.com/mod/storekeep": filepath.Join(root, "storekeep"),
+	}
+	warnings := formatGitStatusWarnings(changes, root, dirs)
+	assert.DeepEqual(t, warnings, []string{
+		"git working tree was modified by the test run:",
+		"  package example.com/mod:",
+		"    M  go.sum",
+		"       old.txt (restored)",
+		"  package example.com/mod/store:",
+		"    R  store/b.go (from store/a.go)",
+		"    ?? store/testdata/out.golden",
+	})
+
+	warnings = formatGitStatusWarnings(changes[:1], root, nil)
+	assert.DeepEqual(t, warnings, []string{
+		"git working tree was modified by the test run:",
+		"    M  go.sum",
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import "strings"
+
+// goTestValueFlags are the flags of go test, and of the test binary, which
+// take a value. When the value is not joined to the flag with a '=', it is the
+// next argument.
+var goTestValueFlags = map[string]bool{
+	// build flags
+	"C": true, "asmflags": true, "buildmode": true, "compiler": true,
+	"gccgoflags": true, "gcflags": true, "installsuffix": true, "ldflags": true,
+	"mod": true, "modfile": true, "overlay": true, "p": true, "pgo": true,
+	"pkgdir": true, "tags": true, "toolexec": true,
+	// go test flags
+	"covermode": true, "coverpkg": true, "exec": true, "o": true, "vet": true,
+	// test binary flags
+	"bench": true, "benchtime": true, "blockprofile": true,
+	"blockprofilerate": true, "count": true, "coverprofile": true, "cpu": true,
+	"cpuprofile": true, "fuzz": true, "fuzzminimizetime": true, "fuzztime": true,
+	"list": true, "memprofile": true, "memprofilerate": true,
+	"mutexprofile": true, "mutexprofilefraction": true, "outputdir": true,
+	"parallel": true, "run": true, "shuffle": true, "skip": true,
+	"timeout": true, "trace": true,
+}
+
+// goTestFlag is a flag from the go test args.
+type goTestFlag struct {
+	// name of the flag, without the leading dashes, the test. prefix, or the
+	// value.
+	name string
+	// args are the flag, and the value when it is a separate argument, as
+	// they were given.
+	args []string
+}
+
+// goTestArgs are the go test args split into flags, packages, and the
+// arguments which are passed to the test binary.
+type goTestArgs struct {
+	flags    []goTestFlag
+	packages []string
+	// testArgs are -args and all the arguments after it.
+	testArgs []string
+}
+
+// parseGoTestArgs splits the go test args so that the command can be run
+// again with different packages, or with some of the flags replaced. A flag
+// which is not known to take a value is expected to be a boolean flag, the
+// same as go test.
+func parseGoTestArgs(args []string) goTestArgs {
+	var parsed goTestArgs
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			parsed.packages = append(parsed.packages, arg)
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimLeft(arg, "-"), "test.")
+		if name == "args" {
+			parsed.testArgs = args[i:]
+			break
+		}
+		flag := goTestFlag{name: name, args: []string{arg}}
+		switch j := strings.Index(name, "="); {
+		case j >= 0:
+			flag.name = name[:j]
+		case goTestValueFlags[name] && i+1 < len(args):
+			i++
+			flag.args = append(flag.args, args[i])
+		}
+		parsed.flags = append(parsed.flags, flag)
+	}
+	return parsed
+}
+
+// flagArgs returns the args of each flag, except for the flags named in
+// exclude.
+func (a goTestArgs) flagArgs(exclude ...string) []string {
+	var args []string
+	for _, flag := range a.flags {
+		if !containsString(exclude, flag.name) {
+			args = append(args, flag.args...)
+		}
+	}
+	return args
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/assert"
+)
+
+func TestParseGoTestArgs(t *testing.T) {
+	args := parseGoTestArgs([]string{
+		"-tags", "integration", "-race", "-test.timeout=5m", "--run", "TestA",
+		"./store/...", "-v", "./cmd", "-args", "-update", "./testdata",
+	})
+	expected := goTestArgs{
+		flags: []goTestFlag{
+			{name: "tags", args: []string{"-tags", "integration"}},
+			{name: "race", args: []string{"-race"}},
+			{name: "timeout", args: []string{"-test.timeout=5m"}},
+			{name: "run", args: []string{"--run", "TestA"}},
+			{name: "v", args: []string{"-v"}},
+		},
+		packages: []string{"./store/...", "./cmd"},
+		testArgs: []string{"-args", "-update", "./testdata"},
+	}
+	assert.DeepEqual(t, args, expected, cmp.AllowUnexported(goTestArgs{}, goTestFlag{}))
+	assert.DeepEqual(t, args.flagArgs("run", "timeout"),
+		[]string{"-tags", "integration", "-race", "-v"})
+}
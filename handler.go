@@ -1,12 +1,23 @@
 package main
 
 import (
+	"compress/gzip"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/internal/bep"
 	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/liveui"
+	"gotest.tools/gotestsum/internal/ndjson"
+	"gotest.tools/gotestsum/internal/webhook"
 	"gotest.tools/gotestsum/testjson"
 )
 
@@ -15,6 +26,14 @@ type eventHandler struct {
 	out       io.Writer
 	err       io.Writer
 	jsonFile  io.WriteCloser
+	limiter   *lineRateLimiter
+	webhook   *webhook.Sender
+	ui        *liveui.Server
+	metrics   *overheadMetrics
+	tail      *testTail
+	status    *statusFile
+	// junitStreams are the --junitfile with stream=true.
+	junitStreams []*junitStream
 }
 
 func (h *eventHandler) Err(text string) error {
@@ -23,65 +42,428 @@ func (h *eventHandler) Err(text string) error {
 }
 
 func (h *eventHandler) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	if h.webhook != nil {
+		h.webhook.Send(event)
+	}
+	if h.ui != nil {
+		h.ui.Event(event)
+	}
+	if h.status != nil {
+		h.status.Event(event, execution)
+	}
 	if h.jsonFile != nil {
 		_, err := h.jsonFile.Write(append(event.Bytes(), '\n'))
 		if err != nil {
 			return errors.Wrap(err, "failed to write JSON file")
 		}
 	}
+	for _, stream := range h.junitStreams {
+		if err := stream.Event(event, execution); err != nil {
+			return err
+		}
+	}
 
+	if h.metrics != nil {
+		defer func(start time.Time) {
+			h.metrics.format += time.Since(start)
+		}(time.Now())
+	}
 	line, err := h.formatter(event, execution)
 	if err != nil {
 		return errors.Wrap(err, "failed to format event")
 	}
-	_, err = h.out.Write([]byte(line))
-	return errors.Wrap(err, "failed to write event")
+	if _, err := h.out.Write([]byte(line)); err != nil {
+		return errors.Wrap(err, "failed to write event")
+	}
+	if h.tail != nil {
+		return h.tail.Event(event, line)
+	}
+	return nil
+}
+
+// Flush writes the number of lines dropped by --max-lines-per-second, and
+// --tail-test-lines-per-second.
+func (h *eventHandler) Flush() error {
+	if h.tail != nil {
+		if err := h.tail.Flush(); err != nil {
+			return err
+		}
+	}
+	if h.limiter == nil {
+		return nil
+	}
+	return h.limiter.Flush()
 }
 
 func (h *eventHandler) Close() error {
+	if h.status != nil {
+		h.status.Close()
+	}
+	if h.webhook != nil {
+		// errors are logged by the sender
+		h.webhook.Close() // nolint: errcheck
+	}
+	if h.ui != nil {
+		if err := h.ui.Close(); err != nil {
+			log.WithError(err).Warn("failed to stop the web UI")
+		}
+	}
 	if h.jsonFile != nil {
 		if err := h.jsonFile.Close(); err != nil {
 			log.WithError(err).Error("failed to close JSON file")
 		}
 	}
+	// the streams which were not finished are left incomplete
+	closeJUnitStreams(h.junitStreams)
+	return nil
+}
+
+// finishJUnitStreams writes the end of each --junitfile with stream=true.
+func (h *eventHandler) finishJUnitStreams(execution *testjson.Execution) error {
+	streams := h.junitStreams
+	h.junitStreams = nil
+	for i, stream := range streams {
+		if err := stream.finish(execution); err != nil {
+			closeJUnitStreams(streams[i+1:])
+			return err
+		}
+	}
 	return nil
 }
 
 var _ testjson.EventHandler = &eventHandler{}
 
 func newEventHandler(opts *options, wout io.Writer, werr io.Writer) (*eventHandler, error) {
-	formatter := testjson.NewEventFormatter(opts.format)
+	formatter := testjson.NewEventFormatterWithOptions(opts.format, testjson.FormatOptions{
+		Accessible:          opts.accessible,
+		FlakeRates:          opts.flakeRates,
+		Timestamps:          testjson.TimestampFormat(opts.timestampFormat),
+		Hyperlinks:          opts.hyperlinks,
+		VerboseAfterFailure: opts.verboseAfterFailure,
+		SubtestCounts:       opts.subtestCounts,
+	})
 	if formatter == nil {
 		return nil, errors.Errorf("unknown format %s", opts.format)
 	}
+	if !testjson.TimestampFormat(opts.timestampFormat).IsValid() {
+		return nil, errors.Errorf("unknown timestamp format %s", opts.timestampFormat)
+	}
 	handler := &eventHandler{
 		formatter: formatter,
 		out:       wout,
 		err:       werr,
 	}
+	if opts.maxLinesPerSecond > 0 {
+		handler.limiter = newLineRateLimiter(wout, opts.maxLinesPerSecond, clockwork.NewRealClock())
+		handler.out = handler.limiter
+	}
+	if opts.eventWebhookURL != "" {
+		handler.webhook = webhook.New(webhook.Config{
+			URL:     opts.eventWebhookURL,
+			RunID:   opts.runID,
+			Labels:  opts.runLabels,
+			Title:   opts.webhookTitle,
+			Retries: 3,
+		})
+	}
+	handler.status = newStatusFile(opts)
 	var err error
+	handler.tail, err = newTestTail(opts, wout, clockwork.NewRealClock())
+	if err != nil {
+		return handler, err
+	}
 	if opts.jsonFile != "" {
 		handler.jsonFile, err = os.Create(opts.jsonFile)
 		if err != nil {
 			return handler, errors.Wrap(err, "failed to open JSON file")
 		}
 	}
+	if handler.junitStreams, err = openJUnitStreams(opts); err != nil {
+		return handler, err
+	}
+	if opts.serveUI != "" {
+		handler.ui, err = liveui.Start(opts.serveUI, opts.sourceLinks)
+		if err != nil {
+			return handler, err
+		}
+		log.Infof("serving the progress of the run at http://%s", handler.ui.Addr())
+	}
 	return handler, nil
 }
 
-func writeJUnitFile(filename string, execution *testjson.Execution) error {
-	if filename == "" {
-		return nil
+// writeJUnitFiles writes each --junitfile, and the file of each package to the
+// --junitfile-dir. Every file is written from the same execution, with the
+// options of the file.
+func writeJUnitFiles(opts *options, execution *testjson.Execution) error {
+	properties := junitFileProperties(opts)
+	for _, spec := range junitFileSpecs(opts) {
+		if spec.stream {
+			continue
+		}
+		config := junitFileConfig(opts, spec)
+		config.Properties = properties
+		if spec.stdout() {
+			if err := junitxml.WriteWithConfig(os.Stdout, execution, config); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeJUnitFile(spec.path, execution, config); err != nil {
+			return err
+		}
 	}
-	junitFile, err := os.Create(filename)
+	return writeJUnitDir(opts, execution)
+}
+
+// junitFileProperties returns the properties added to every testsuite.
+func junitFileProperties(opts *options) []junitxml.JUnitProperty {
+	var properties []junitxml.JUnitProperty
+	if command := testCommand(opts); command != "" {
+		properties = append(properties, junitxml.JUnitProperty{Name: "test.command", Value: command})
+	}
+	properties = append(properties, envProperties(captureEnv(opts.captureEnv, os.Environ()))...)
+	properties = append(properties, opts.junitProperties.properties...)
+	for _, name := range sortedKeys(opts.runLabels) {
+		properties = append(properties, junitxml.JUnitProperty{Name: labelPrefix + name, Value: opts.runLabels[name]})
+	}
+	return properties
+}
+
+// junitFileSpecs returns the files from --junitfile and
+// --junitfile-failures-only.
+func junitFileSpecs(opts *options) []junitFileSpec {
+	specs := append([]junitFileSpec{}, opts.junitFiles.files...)
+	for _, spec := range opts.junitFailuresOnly.files {
+		spec.failuresOnly = true
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// junitFileConfig returns the config of a --junitfile. Options which are not
+// set for the file use the value of --junit-path-mode, --junit-duplicates,
+// --junit-system-out, --junit-subtests, --junit-reruns, --junit-sort, and
+// --junitfile-format.
+func junitFileConfig(opts *options, spec junitFileSpec) junitxml.Config {
+	config := junitxml.Config{
+		PathMode:          junitxml.PathMode(opts.junitPathMode),
+		Duplicates:        junitxml.DuplicatePolicy(opts.junitDuplicates),
+		SystemOut:         junitxml.SystemOut(opts.junitSystemOut),
+		Hostname:          opts.junitHostname,
+		Timestamp:         opts.junitTimestamp.value,
+		Naming:            opts.junitNaming.naming,
+		FailureCategories: opts.failureCategories,
+		InfraErrors:       opts.infraErrorPackages,
+		Subtests:          junitxml.SubtestMode(opts.junitSubtests),
+		Reruns:            junitxml.RerunMode(opts.junitReruns),
+		Flaky:             opts.reran,
+		PathRewrite:       opts.junitRewritePaths.rules,
+		Sort:              junitxml.SortMode(opts.junitSort),
+		Format:            junitxml.Format(opts.junitFileFormat),
+		Labels:            opts.labels,
+		ANSI:              junitxml.ANSIMode(opts.junitANSI),
+		FailuresOnly:      spec.failuresOnly,
+		MaxOutputBytes:    opts.junitMaxOutputBytes,
+		HidePassed:        opts.junitHidePassed,
+	}
+	if spec.pathMode != "" {
+		config.PathMode = junitxml.PathMode(spec.pathMode)
+	}
+	if spec.duplicates != "" {
+		config.Duplicates = junitxml.DuplicatePolicy(spec.duplicates)
+	}
+	if spec.systemOut != "" {
+		config.SystemOut = junitxml.SystemOut(spec.systemOut)
+	}
+	if spec.subtests != "" {
+		config.Subtests = junitxml.SubtestMode(spec.subtests)
+	}
+	if spec.reruns != "" {
+		config.Reruns = junitxml.RerunMode(spec.reruns)
+	}
+	if spec.sort != "" {
+		config.Sort = junitxml.SortMode(spec.sort)
+	}
+	if spec.format != "" {
+		config.Format = junitxml.Format(spec.format)
+	}
+	return config
+}
+
+// junitFileToStdout returns true if a --junitfile is written to stdout. The
+// output of the run is printed to stderr instead, so that stdout can be piped
+// to another process.
+func junitFileToStdout(opts *options) bool {
+	for _, spec := range junitFileSpecs(opts) {
+		if spec.stdout() {
+			return true
+		}
+	}
+	return false
+}
+
+// junitSystemOutEnabled returns true if any --junitfile includes the output of
+// tests. The output of passed tests is only kept when it is written.
+func junitSystemOutEnabled(opts *options) bool {
+	for _, spec := range junitFileSpecs(opts) {
+		if junitFileConfig(opts, spec).SystemOut != junitxml.SystemOutNone {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJUnitFile writes the file to a temporary file in the same directory,
+// and renames it to filename, so that an interrupted run never leaves a
+// truncated file. A filename which ends in .gz is compressed with gzip.
+func writeJUnitFile(filename string, execution *testjson.Execution, config junitxml.Config) error {
+	junitFile, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
 	if err != nil {
 		return errors.Wrap(err, "failed to open JUnit file")
 	}
+	defer os.Remove(junitFile.Name()) // nolint: errcheck
+
+	if err := writeJUnitTo(junitFile, filename, execution, config); err != nil {
+		junitFile.Close() // nolint: errcheck
+		return err
+	}
+	if err := junitFile.Close(); err != nil {
+		return errors.Wrap(err, "failed to close JUnit file")
+	}
+	return errors.Wrap(os.Rename(junitFile.Name(), filename), "failed to write JUnit file")
+}
+
+func writeJUnitTo(out *os.File, filename string, execution *testjson.Execution, config junitxml.Config) error {
+	if err := out.Chmod(0644); err != nil {
+		return errors.Wrap(err, "failed to open JUnit file")
+	}
+	if !strings.HasSuffix(filename, ".gz") {
+		return junitxml.WriteWithConfig(out, execution, config)
+	}
+	gz := gzip.NewWriter(out)
+	if err := junitxml.WriteWithConfig(gz, execution, config); err != nil {
+		return err
+	}
+	return errors.Wrap(gz.Close(), "failed to compress JUnit file")
+}
+
+func validateJUnitOptions(opts *options) error {
+	if err := opts.junitProperties.envErr; err != nil {
+		return errors.Wrap(err, "invalid GOTESTSUM_JUNIT_PROPERTIES")
+	}
+	var stdout int
+	for _, spec := range junitFileSpecs(opts) {
+		if spec.stdout() {
+			stdout++
+		}
+	}
+	if stdout > 1 {
+		return errors.New("only one --junitfile can be written to stdout")
+	}
+	specs := append([]junitFileSpec{{}}, junitFileSpecs(opts)...)
+	for _, spec := range specs {
+		if err := validateJUnitStream(opts, spec); err != nil {
+			return err
+		}
+		config := junitFileConfig(opts, spec)
+		switch config.PathMode {
+		case junitxml.PathModeRaw, junitxml.PathModeRelative, junitxml.PathModeMunged:
+		default:
+			return errors.Errorf("unknown JUnit path mode %s", config.PathMode)
+		}
+		switch config.Duplicates {
+		case junitxml.DuplicatesWarn, junitxml.DuplicatesSuffix:
+		default:
+			return errors.Errorf("unknown JUnit duplicates policy %s", config.Duplicates)
+		}
+		switch config.SystemOut {
+		case junitxml.SystemOutNone, junitxml.SystemOutTestCase, junitxml.SystemOutAll:
+		default:
+			return errors.Errorf("unknown JUnit system-out mode %s", config.SystemOut)
+		}
+		switch config.Subtests {
+		case junitxml.SubtestsFlat, junitxml.SubtestsNested:
+		default:
+			return errors.Errorf("unknown JUnit subtests mode %s", config.Subtests)
+		}
+		switch config.Reruns {
+		case junitxml.RerunsSeparate, junitxml.RerunsSurefire:
+		default:
+			return errors.Errorf("unknown JUnit reruns mode %s", config.Reruns)
+		}
+		switch config.Sort {
+		case junitxml.SortNone, junitxml.SortName, junitxml.SortDuration, junitxml.SortOutcome:
+		default:
+			return errors.Errorf("unknown JUnit sort mode %s", config.Sort)
+		}
+		switch config.Format {
+		case junitxml.FormatJUnit, junitxml.FormatOpenTestReporting:
+		default:
+			return errors.Errorf("unknown JUnit file format %s", config.Format)
+		}
+		if config.MaxOutputBytes < 0 {
+			return errors.New("--junit-max-output-bytes must not be negative")
+		}
+		switch config.ANSI {
+		case junitxml.ANSIStrip, junitxml.ANSIText:
+		default:
+			return errors.Errorf("unknown JUnit ANSI mode %s", config.ANSI)
+		}
+	}
+	return nil
+}
+
+func writeNDJSONFile(opts *options, execution *testjson.Execution) error {
+	if opts.ndjsonFile == "" {
+		return nil
+	}
+	out, err := os.Create(opts.ndjsonFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to open NDJSON file")
+	}
+	defer func() {
+		if err := out.Close(); err != nil {
+			log.WithError(err).Error("failed to close NDJSON file")
+		}
+	}()
+
+	hostname, _ := os.Hostname()
+	return ndjson.Write(out, execution, ndjson.RunMetadata{
+		RunID:             opts.runID,
+		Hostname:          hostname,
+		Env:               captureEnv(opts.captureEnv, os.Environ()),
+		Command:           testCommand(opts),
+		Branch:            opts.branch,
+		SkipCategories:    opts.skipCategories.categories,
+		Timeout:           goTestTimeout(opts),
+		FailureCategories: opts.failureCategories,
+		Labels:            opts.labels,
+		RunLabels:         opts.runLabels,
+	})
+}
+
+// defaultRunID returns a run ID created from the start time of the run and the
+// process ID. It is used when --run-id is not set.
+func defaultRunID(started time.Time) string {
+	return fmt.Sprintf("%s-%d", started.UTC().Format("20060102T150405Z"), os.Getpid())
+}
+
+func writeBEPFile(opts *options, execution *testjson.Execution) error {
+	if opts.bepJSONFile == "" {
+		return nil
+	}
+	out, err := os.Create(opts.bepJSONFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to open BEP file")
+	}
 	defer func() {
-		if err := junitFile.Close(); err != nil {
-			log.WithError(err).Error("failed to close JUnit file")
+		if err := out.Close(); err != nil {
+			log.WithError(err).Error("failed to close BEP file")
 		}
 	}()
 
-	return junitxml.Write(junitFile, execution)
+	return bep.Write(out, execution, bep.Config{
+		UUID:    opts.runID,
+		Command: testCommand(opts),
+	})
 }
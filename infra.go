@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// infraErrorCategory is the failure category of the failed tests of a package
+// which still failed with an infrastructure error after it was retried.
+const infraErrorCategory = "infrastructure"
+
+// infraErrorValue is a flag.Value which adds a signature of an infrastructure
+// error. Each value is a single regexp, so that the regexp may contain commas.
+type infraErrorValue struct {
+	patterns []*regexp.Regexp
+}
+
+func (v *infraErrorValue) Set(val string) error {
+	pattern, err := regexp.Compile(val)
+	if err != nil {
+		return errors.Wrap(err, "invalid pattern for infrastructure error")
+	}
+	v.patterns = append(v.patterns, pattern)
+	return nil
+}
+
+func (v *infraErrorValue) Type() string {
+	return "regexp"
+}
+
+func (v *infraErrorValue) String() string {
+	items := make([]string, 0, len(v.patterns))
+	for _, pattern := range v.patterns {
+		items = append(items, pattern.String())
+	}
+	return strings.Join(items, " ")
+}
+
+// match returns the first line of output which matches one of the signatures,
+// or an empty string if no line matches.
+func (v *infraErrorValue) match(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		for _, pattern := range v.patterns {
+			if pattern.MatchString(line) {
+				return strings.TrimSpace(line)
+			}
+		}
+	}
+	return ""
+}
+
+// failureMatch returns the line which matched a signature in the output of
+// the package, or of one of the failed tests, from matches by test name.
+func failureMatch(matches map[string]string, failed []testjson.TestCase) string {
+	if line := matches[""]; line != "" {
+		return line
+	}
+	for _, tc := range failed {
+		if line := matches[tc.Test]; line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// findInfraErrors returns the failed packages with output which matches one of
+// the signatures, with the line which matched. Only the output of the package,
+// and of its failed tests, is matched. Returns false if a package failed
+// without an infrastructure error.
+func findInfraErrors(signatures *infraErrorValue, exec *testjson.Execution) (map[string]string, bool) {
+	infraErrors := make(map[string]string)
+	onlyInfraErrors := true
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		if pkg.Result() != testjson.ActionFail {
+			continue
+		}
+		matches := map[string]string{"": signatures.match(pkg.Output(""))}
+		for _, tc := range pkg.Failed {
+			matches[tc.Test] = signatures.match(pkg.Output(tc.Test))
+		}
+		if line := failureMatch(matches, pkg.Failed); line != "" {
+			infraErrors[name] = line
+			continue
+		}
+		onlyInfraErrors = false
+	}
+	return infraErrors, onlyInfraErrors
+}
+
+// retryInfraErrors runs each package which failed with an infrastructure
+// error again, up to opts.infraRetries times, while it fails with an
+// infrastructure error. The events are added to exec, and opts.reran is set
+// when a package is retried, so that the failed runs of the tests which then
+// passed are reported as flaky. Returns the packages which still failed with
+// an infrastructure error, with the line which matched, and true if every
+// failed package passed when it was retried.
+func retryInfraErrors(
+	ctx context.Context,
+	opts *options,
+	handler testjson.EventHandler,
+	exec *testjson.Execution,
+) (map[string]string, bool, error) {
+	infraErrors, passed := findInfraErrors(&opts.infraErrors, exec)
+	for attempt := 1; attempt <= opts.infraRetries && len(infraErrors) > 0; attempt++ {
+		log.Debugf("infrastructure error retry attempt %d of %d", attempt, opts.infraRetries)
+		opts.reran = true
+		next := make(map[string]string)
+		for _, pkg := range sortedInfraErrors(infraErrors) {
+			log.Infof("retrying %s because of an infrastructure error: %s", pkg, infraErrors[pkg])
+			line, err := retryPackage(ctx, opts, handler, exec, pkg)
+			switch {
+			case err != nil:
+				return nil, false, err
+			case line != "":
+				next[pkg] = line
+			case exec.Package(pkg).Result() == testjson.ActionFail:
+				passed = false
+			}
+		}
+		infraErrors = next
+	}
+	return infraErrors, passed && len(infraErrors) == 0, nil
+}
+
+func sortedInfraErrors(infraErrors map[string]string) []string {
+	pkgs := make([]string, 0, len(infraErrors))
+	for pkg := range infraErrors {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// infraErrorHandler records the first line of output of each test which
+// matches one of the signatures, while it handles the events of a retry. The
+// lines of stderr are recorded as the output of the package.
+type infraErrorHandler struct {
+	testjson.EventHandler
+	signatures *infraErrorValue
+	matches    map[string]string
+}
+
+func (h *infraErrorHandler) Event(event testjson.TestEvent, exec *testjson.Execution) error {
+	if h.matches[event.Test] == "" && event.Output != "" {
+		h.matches[event.Test] = h.signatures.match(event.Output)
+	}
+	return h.EventHandler.Event(event, exec)
+}
+
+func (h *infraErrorHandler) Err(text string) error {
+	if h.matches[""] == "" {
+		h.matches[""] = h.signatures.match(text)
+	}
+	return h.EventHandler.Err(text)
+}
+
+// retryPackage runs all the tests in pkg again, and returns the line which
+// matched a signature if the package failed with an infrastructure error.
+func retryPackage(
+	ctx context.Context,
+	opts *options,
+	handler testjson.EventHandler,
+	exec *testjson.Execution,
+	pkg string,
+) (string, error) {
+	before := len(exec.Package(pkg).Failed)
+	p, err := startGoTest(ctx, retryPackageArgs(opts, pkg))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to retry %s", pkg)
+	}
+	defer p.cancel()
+	infraHandler := &infraErrorHandler{
+		EventHandler: handler,
+		signatures:   &opts.infraErrors,
+		matches:      make(map[string]string),
+	}
+	if _, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    p.stdout,
+		Stderr:    p.stderr,
+		Handler:   infraHandler,
+		Execution: exec,
+		Rewrite:   opts.rewriteRules.rules,
+	}); err != nil {
+		return "", err
+	}
+	// the exit code is ignored, the result of the package is used instead.
+	p.cmd.Wait() // nolint: errcheck
+
+	result := exec.Package(pkg)
+	if result.Result() != testjson.ActionFail {
+		return "", nil
+	}
+	return failureMatch(infraHandler.matches, result.Failed[before:]), nil
+}
+
+// retryPackageArgs returns the go test command used to run all the tests in
+// pkg again. The flags from the go test args are used, except for the flags
+// which select the output and the number of runs, and any arguments after
+// -args are passed to the test binary.
+func retryPackageArgs(opts *options, pkg string) []string {
+	args := []string{"go", "test", "-json", "-count=1"}
+	goTestArgs := parseGoTestArgs(opts.args)
+	args = append(args, goTestArgs.flagArgs("json", "count")...)
+	args = append(args, pkg)
+	return append(args, goTestArgs.testArgs...)
+}
+
+// withInfraErrors returns categories with the infrastructure error category
+// set for every failed test of the packages in infraErrors.
+func withInfraErrors(categories map[string]string, exec *testjson.Execution, infraErrors map[string]string) map[string]string {
+	if len(infraErrors) == 0 {
+		return categories
+	}
+	if categories == nil {
+		categories = make(map[string]string)
+	}
+	for _, tc := range exec.Failed() {
+		if _, ok := infraErrors[tc.Package]; ok {
+			categories[tc.ID()] = infraErrorCategory
+		}
+	}
+	return categories
+}
+
+// infraErrorWarnings returns a warning for each package which still failed
+// with an infrastructure error after it was retried.
+func infraErrorWarnings(opts *options) []string {
+	var warnings []string
+	for _, pkg := range sortedInfraErrors(opts.infraErrorPackages) {
+		warnings = append(warnings, fmt.Sprintf("%s: infrastructure error after %d retries: %s",
+			testjson.RelativePackagePath(pkg), opts.infraRetries, opts.infraErrorPackages[pkg]))
+	}
+	return warnings
+}
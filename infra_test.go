@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func newInfraErrorValue(t *testing.T, patterns ...string) *infraErrorValue {
+	t.Helper()
+	v := &infraErrorValue{}
+	for _, pattern := range patterns {
+		assert.NilError(t, v.Set(pattern))
+	}
+	return v
+}
+
+func scanEvents(t *testing.T, events string) *testjson.Execution {
+	t.Helper()
+	exec, err := testjson.ReadExecution(strings.NewReader(events))
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestFindInfraErrors(t *testing.T) {
+	signatures := newInfraErrorValue(t, "connection refused .*testcontainers", "docker: no space left")
+	exec := scanEvents(t, `{"Action":"run","Package":"pkg/db","Test":"TestQuery"}
+{"Action":"output","Package":"pkg/db","Test":"TestQuery","Output":"    db_test.go:12: connection refused to testcontainers/postgres\n"}
+{"Action":"fail","Package":"pkg/db","Test":"TestQuery"}
+{"Action":"fail","Package":"pkg/db"}
+{"Action":"output","Package":"pkg/build","Output":"docker: no space left on device\n"}
+{"Action":"fail","Package":"pkg/build"}
+{"Action":"run","Package":"pkg/log","Test":"TestLog"}
+{"Action":"output","Package":"pkg/log","Test":"TestLog","Output":"docker: no space left on device\n"}
+{"Action":"pass","Package":"pkg/log","Test":"TestLog"}
+{"Action":"pass","Package":"pkg/log"}
+`)
+	infraErrors, onlyInfraErrors := findInfraErrors(signatures, exec)
+	assert.Assert(t, onlyInfraErrors)
+	assert.DeepEqual(t, infraErrors, map[string]string{
+		"pkg/db":    "db_test.go:12: connection refused to testcontainers/postgres",
+		"pkg/build": "docker: no space left on device",
+	})
+
+	exec = scanEvents(t, `{"Action":"run","Package":"pkg/math","Test":"TestAdd"}
+{"Action":"output","Package":"pkg/math","Test":"TestAdd","Output":"expected 2, got 3\n"}
+{"Action":"fail","Package":"pkg/math","Test":"TestAdd"}
+{"Action":"fail","Package":"pkg/math"}
+`)
+	infraErrors, onlyInfraErrors = findInfraErrors(signatures, exec)
+	assert.Assert(t, !onlyInfraErrors)
+	assert.Equal(t, len(infraErrors), 0)
+}
+
+// eventRecorder records the events and errors passed on by an EventHandler.
+type eventRecorder struct {
+	events []testjson.TestEvent
+	errs   []string
+}
+
+func (r *eventRecorder) Event(event testjson.TestEvent, _ *testjson.Execution) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *eventRecorder) Err(text string) error {
+	r.errs = append(r.errs, text)
+	return nil
+}
+
+func TestInfraErrorHandler(t *testing.T) {
+	recorder := &eventRecorder{}
+	handler := &infraErrorHandler{
+		EventHandler: recorder,
+		signatures:   newInfraErrorValue(t, "connection refused"),
+		matches:      make(map[string]string),
+	}
+	exec := testjson.NewExecution()
+	events := []testjson.TestEvent{
+		{Package: "pkg/db", Test: "TestQuery", Action: testjson.ActionOutput, Output: "starting\n"},
+		{Package: "pkg/db", Test: "TestQuery", Action: testjson.ActionOutput, Output: "dial: connection refused\n"},
+		{Package: "pkg/db", Test: "TestQuery", Action: testjson.ActionOutput, Output: "connection refused again\n"},
+	}
+	for _, event := range events {
+		assert.NilError(t, handler.Event(event, exec))
+	}
+	assert.NilError(t, handler.Err("go: connection refused while downloading"))
+	assert.Equal(t, len(recorder.events), len(events))
+	assert.DeepEqual(t, recorder.errs, []string{"go: connection refused while downloading"})
+	assert.DeepEqual(t, handler.matches, map[string]string{
+		"TestQuery": "dial: connection refused",
+		"":          "go: connection refused while downloading",
+	})
+	failed := []testjson.TestCase{{Package: "pkg/db", Test: "TestQuery"}}
+	assert.Equal(t, failureMatch(handler.matches, failed), "go: connection refused while downloading")
+}
+
+func TestRetryPackageArgs(t *testing.T) {
+	opts := &options{
+		args: []string{
+			"-tags", "integration", "-run=TestOne", "-count", "3", "-json", "./pkg/...",
+			"-args", "-update", "golden",
+		},
+	}
+	assert.DeepEqual(t, retryPackageArgs(opts, "pkg/a"), []string{
+		"go", "test", "-json", "-count=1", "-tags", "integration", "-run=TestOne", "pkg/a",
+		"-args", "-update", "golden",
+	})
+}
+
+func TestRerunCandidates(t *testing.T) {
+	exec := scanEvents(t, `{"Action":"run","Package":"pkg/db","Test":"TestQuery"}
+{"Action":"fail","Package":"pkg/db","Test":"TestQuery"}
+{"Action":"fail","Package":"pkg/db"}
+{"Action":"run","Package":"pkg/db","Test":"TestQuery"}
+{"Action":"pass","Package":"pkg/db","Test":"TestQuery"}
+{"Action":"pass","Package":"pkg/db"}
+{"Action":"run","Package":"pkg/cache","Test":"TestGet"}
+{"Action":"fail","Package":"pkg/cache","Test":"TestGet"}
+{"Action":"fail","Package":"pkg/cache"}
+{"Action":"run","Package":"pkg/math","Test":"TestAdd"}
+{"Action":"fail","Package":"pkg/math","Test":"TestAdd"}
+{"Action":"fail","Package":"pkg/math"}
+`)
+	opts := &options{
+		infraErrors:        *newInfraErrorValue(t, "connection refused"),
+		infraErrorPackages: map[string]string{"pkg/cache": "connection refused"},
+	}
+	var ids []string
+	for _, tc := range rerunCandidates(opts, exec) {
+		ids = append(ids, tc.ID())
+	}
+	assert.DeepEqual(t, ids, []string{"pkg/math#TestAdd"})
+
+	categories := withInfraErrors(nil, exec, opts.infraErrorPackages)
+	assert.DeepEqual(t, categories, map[string]string{"pkg/cache#TestGet": infraErrorCategory})
+}
@@ -0,0 +1,255 @@
+/*
+Package bep writes test results as Bazel Build Event Protocol (BEP) events, in
+the newline delimited JSON format written by bazel --build_event_json_file.
+Each package is reported as a test target, so that BEP consumers can show the
+results of a go test run made outside of Bazel.
+
+This package is experimental. Only the events and fields needed to report test
+results are written.
+*/
+package bep
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Status of a test target.
+const (
+	StatusPassed        = "PASSED"
+	StatusFlaky         = "FLAKY"
+	StatusFailed        = "FAILED"
+	StatusIncomplete    = "INCOMPLETE"
+	StatusFailedToBuild = "FAILED_TO_BUILD"
+)
+
+// Config used to write the events.
+type Config struct {
+	// UUID identifies the invocation.
+	UUID string
+	// Command is the go test command line of the run.
+	Command string
+}
+
+// Event is a BuildEvent from build_event_stream.proto.
+type Event struct {
+	ID          EventID        `json:"id"`
+	Children    []EventID      `json:"children,omitempty"`
+	LastMessage bool           `json:"lastMessage,omitempty"`
+	Started     *BuildStarted  `json:"started,omitempty"`
+	TestResult  *TestResult    `json:"testResult,omitempty"`
+	TestSummary *TestSummary   `json:"testSummary,omitempty"`
+	Finished    *BuildFinished `json:"finished,omitempty"`
+}
+
+// EventID identifies an Event. Exactly one field is set.
+type EventID struct {
+	Started       *struct{}      `json:"started,omitempty"`
+	TestResult    *TestResultID  `json:"testResult,omitempty"`
+	TestSummary   *TestSummaryID `json:"testSummary,omitempty"`
+	BuildFinished *struct{}      `json:"buildFinished,omitempty"`
+}
+
+// BuildStarted is the payload of the first event of the stream. The int64
+// fields are encoded as strings, as required by the proto3 JSON mapping.
+type BuildStarted struct {
+	UUID               string `json:"uuid"`
+	StartTimeMillis    int64  `json:"startTimeMillis,string"`
+	BuildToolVersion   string `json:"buildToolVersion"`
+	OptionsDescription string `json:"optionsDescription,omitempty"`
+	Command            string `json:"command"`
+}
+
+// TestResultID identifies the TestResult of a single attempt of a target.
+type TestResultID struct {
+	Label   string `json:"label"`
+	Run     int    `json:"run"`
+	Shard   int    `json:"shard"`
+	Attempt int    `json:"attempt"`
+}
+
+// TestResult is the result of a single attempt of a test target.
+type TestResult struct {
+	Status                      string `json:"status"`
+	TestAttemptStartMillisEpoch int64  `json:"testAttemptStartMillisEpoch,string"`
+	TestAttemptDurationMillis   int64  `json:"testAttemptDurationMillis,string"`
+}
+
+// TestSummaryID identifies the TestSummary of a target.
+type TestSummaryID struct {
+	Label string `json:"label"`
+}
+
+// TestSummary is the overall result of a test target.
+type TestSummary struct {
+	OverallStatus          string `json:"overallStatus"`
+	TotalRunCount          int    `json:"totalRunCount"`
+	RunCount               int    `json:"runCount"`
+	AttemptCount           int    `json:"attemptCount"`
+	ShardCount             int    `json:"shardCount"`
+	FirstStartTimeMillis   int64  `json:"firstStartTimeMillis,string"`
+	LastStopTimeMillis     int64  `json:"lastStopTimeMillis,string"`
+	TotalRunDurationMillis int64  `json:"totalRunDurationMillis,string"`
+}
+
+// BuildFinished is the payload of the last event of the stream.
+type BuildFinished struct {
+	ExitCode         ExitCode `json:"exitCode"`
+	FinishTimeMillis int64    `json:"finishTimeMillis,string"`
+}
+
+// ExitCode is the Bazel exit code of the invocation.
+type ExitCode struct {
+	Name string `json:"name"`
+	Code int    `json:"code"`
+}
+
+// Bazel exit codes used by BuildFinished.
+var (
+	ExitSuccess      = ExitCode{Name: "SUCCESS", Code: 0}
+	ExitBuildFailure = ExitCode{Name: "BUILD_FAILURE", Code: 1}
+	ExitTestsFailed  = ExitCode{Name: "TESTS_FAILED", Code: 3}
+	ExitNoTestsFound = ExitCode{Name: "NO_TESTS_FOUND", Code: 4}
+)
+
+// Label returns the Bazel label used for the test target of a package. The
+// name of the target is the one used by Gazelle for a go_test rule.
+func Label(pkg string) string {
+	dir := strings.TrimPrefix(testjson.RelativePackagePath(pkg), "./")
+	if dir == "." {
+		dir = ""
+	}
+	return "//" + dir + ":" + path.Base(pkg) + "_test"
+}
+
+// Events returns the BEP events for the execution. Packages without test
+// files are not included.
+func Events(exec *testjson.Execution, cfg Config) []Event {
+	started := exec.Started()
+	startMillis := millis(started)
+	finishMillis := millis(started.Add(exec.Elapsed()))
+
+	var results, summaries []Event
+	exitCode := ExitSuccess
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		if pkg.Result() == testjson.ActionSkip && pkg.Total == 0 {
+			continue
+		}
+		label := Label(name)
+		status := packageStatus(pkg)
+		exitCode = worseExitCode(exitCode, status)
+		duration := pkg.Elapsed().Nanoseconds() / int64(time.Millisecond)
+
+		results = append(results, Event{
+			ID: EventID{TestResult: &TestResultID{Label: label, Run: 1, Shard: 1, Attempt: 1}},
+			TestResult: &TestResult{
+				Status:                      status,
+				TestAttemptStartMillisEpoch: startMillis,
+				TestAttemptDurationMillis:   duration,
+			},
+		})
+		summaries = append(summaries, Event{
+			ID: EventID{TestSummary: &TestSummaryID{Label: label}},
+			TestSummary: &TestSummary{
+				OverallStatus:          status,
+				TotalRunCount:          1,
+				RunCount:               1,
+				AttemptCount:           1,
+				ShardCount:             1,
+				FirstStartTimeMillis:   startMillis,
+				LastStopTimeMillis:     finishMillis,
+				TotalRunDurationMillis: duration,
+			},
+		})
+	}
+	if exitCode == ExitSuccess && exec.Total() == 0 {
+		exitCode = ExitNoTestsFound
+	}
+
+	finished := Event{
+		ID:          EventID{BuildFinished: &struct{}{}},
+		LastMessage: true,
+		Finished:    &BuildFinished{ExitCode: exitCode, FinishTimeMillis: finishMillis},
+	}
+	first := Event{
+		ID: EventID{Started: &struct{}{}},
+		Started: &BuildStarted{
+			UUID:               cfg.UUID,
+			StartTimeMillis:    startMillis,
+			BuildToolVersion:   "gotestsum",
+			OptionsDescription: cfg.Command,
+			Command:            "test",
+		},
+	}
+	for _, event := range append(append(results, summaries...), finished) {
+		first.Children = append(first.Children, event.ID)
+	}
+
+	events := append([]Event{first}, results...)
+	events = append(events, summaries...)
+	return append(events, finished)
+}
+
+func packageStatus(pkg *testjson.Package) string {
+	switch {
+	case pkg.BuildFailed():
+		return StatusFailedToBuild
+	case pkg.Result() == "":
+		return StatusIncomplete
+	case pkg.Result() == testjson.ActionFail:
+		return StatusFailed
+	case hasFlakyTest(pkg):
+		return StatusFlaky
+	default:
+		return StatusPassed
+	}
+}
+
+// hasFlakyTest returns true if a test in the package failed, and then passed
+// when it was run again.
+func hasFlakyTest(pkg *testjson.Package) bool {
+	failed := make(map[string]bool, len(pkg.Failed))
+	for _, tc := range pkg.Failed {
+		failed[tc.Test] = true
+	}
+	for _, tc := range pkg.Passed {
+		if failed[tc.Test] {
+			return true
+		}
+	}
+	return false
+}
+
+func worseExitCode(current ExitCode, status string) ExitCode {
+	switch status {
+	case StatusFailedToBuild:
+		return ExitBuildFailure
+	case StatusFailed, StatusIncomplete:
+		if current == ExitSuccess {
+			return ExitTestsFailed
+		}
+	}
+	return current
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// Write the BEP events of the execution to out, one JSON object per line.
+func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
+	encoder := json.NewEncoder(out)
+	for _, event := range Events(exec, cfg) {
+		if err := encoder.Encode(event); err != nil {
+			return errors.Wrap(err, "failed to write BEP event")
+		}
+	}
+	return nil
+}
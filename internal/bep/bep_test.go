@@ -0,0 +1,67 @@
+package bep
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestEvents(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"gotest.tools/gotestsum/pkg/a","Test":"TestOne"}
+{"Action":"pass","Package":"gotest.tools/gotestsum/pkg/a","Test":"TestOne","Elapsed":0.25}
+{"Action":"pass","Package":"gotest.tools/gotestsum/pkg/a"}
+{"Action":"run","Package":"gotest.tools/gotestsum/pkg/b","Test":"TestTwo"}
+{"Action":"fail","Package":"gotest.tools/gotestsum/pkg/b","Test":"TestTwo","Elapsed":1.5}
+{"Action":"fail","Package":"gotest.tools/gotestsum/pkg/b"}
+{"Action":"output","Package":"gotest.tools/gotestsum/pkg/c","Output":"?   \tgotest.tools/gotestsum/pkg/c\t[no test files]\n"}
+{"Action":"skip","Package":"gotest.tools/gotestsum/pkg/c"}
+`))
+	assert.NilError(t, err)
+
+	events := Events(exec, Config{UUID: "run-1", Command: "go test ./..."})
+	assert.Equal(t, len(events), 6)
+
+	started := events[0]
+	assert.Equal(t, started.Started.UUID, "run-1")
+	assert.Equal(t, started.Started.OptionsDescription, "go test ./...")
+	assert.Equal(t, len(started.Children), 5)
+
+	assert.DeepEqual(t, events[1].ID.TestResult, &TestResultID{
+		Label: "//pkg/a:a_test", Run: 1, Shard: 1, Attempt: 1})
+	assert.Equal(t, events[1].TestResult.Status, StatusPassed)
+	assert.Equal(t, events[1].TestResult.TestAttemptDurationMillis, int64(250))
+	assert.Equal(t, events[2].TestResult.Status, StatusFailed)
+	assert.Equal(t, events[3].ID.TestSummary.Label, "//pkg/a:a_test")
+	assert.Equal(t, events[4].TestSummary.OverallStatus, StatusFailed)
+
+	finished := events[5]
+	assert.Assert(t, finished.LastMessage)
+	assert.Equal(t, finished.Finished.ExitCode, ExitTestsFailed)
+}
+
+func TestWrite(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"gotest.tools/gotestsum","Test":"TestOne"}
+{"Action":"pass","Package":"gotest.tools/gotestsum","Test":"TestOne","Elapsed":0.01}
+{"Action":"pass","Package":"gotest.tools/gotestsum"}
+`))
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, Write(out, exec, Config{UUID: "run-1"}))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, len(lines), 4)
+	assert.Assert(t, strings.HasPrefix(lines[1],
+		`{"id":{"testResult":{"label":"//:gotestsum_test","run":1,"shard":1,"attempt":1}},"testResult":{"status":"PASSED",`), lines[1])
+	assert.Assert(t, strings.Contains(lines[1], `"testAttemptDurationMillis":"10"`), lines[1])
+	assert.Assert(t, strings.HasPrefix(lines[3],
+		`{"id":{"buildFinished":{}},"lastMessage":true,"finished":{"exitCode":{"name":"SUCCESS","code":0},`), lines[3])
+}
+
+func TestLabel(t *testing.T) {
+	assert.Equal(t, Label("gotest.tools/gotestsum/internal/bep"), "//internal/bep:bep_test")
+	assert.Equal(t, Label("gotest.tools/gotestsum"), "//:gotestsum_test")
+	assert.Equal(t, Label("example.com/other"), "//example.com/other:other_test")
+}
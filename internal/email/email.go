@@ -0,0 +1,165 @@
+/*Package email sends a test report by email using an SMTP relay.
+ */
+package email
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TLS modes used to connect to the SMTP server.
+const (
+	// TLSStartTLS connects without TLS and upgrades the connection with
+	// STARTTLS. The message is not sent if the server does not support
+	// STARTTLS.
+	TLSStartTLS = "starttls"
+	// TLSImplicit connects with TLS.
+	TLSImplicit = "tls"
+	// TLSNone never uses TLS.
+	TLSNone = "none"
+)
+
+// Config of the SMTP server and the sender and recipients of the message.
+type Config struct {
+	// Addr of the SMTP server as host:port.
+	Addr     string
+	TLS      string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Attachment is a file attached to a Message.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Message is an email with a plain text body and attachments.
+type Message struct {
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+const boundary = "gotestsum-report-boundary"
+
+// Bytes returns the message encoded as a MIME multipart email.
+func (m Message) Bytes(from string, to []string, date time.Time) []byte {
+	buf := new(bytes.Buffer)
+	writeHeader := func(key, value string) {
+		fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+	}
+	writeHeader("From", from)
+	writeHeader("To", strings.Join(to, ", "))
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader("Date", date.Format(time.RFC1123Z))
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", "multipart/mixed; boundary="+boundary)
+	buf.WriteString("\r\n")
+
+	fmt.Fprintf(buf, "--%s\r\n", boundary)
+	writeHeader("Content-Type", "text/plain; charset=utf-8")
+	writeHeader("Content-Transfer-Encoding", "base64")
+	buf.WriteString("\r\n")
+	writeBase64(buf, []byte(m.Body))
+
+	for _, attachment := range m.Attachments {
+		fmt.Fprintf(buf, "--%s\r\n", boundary)
+		writeHeader("Content-Type", attachment.ContentType)
+		writeHeader("Content-Transfer-Encoding", "base64")
+		writeHeader("Content-Disposition",
+			mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+		buf.WriteString("\r\n")
+		writeBase64(buf, attachment.Data)
+	}
+	fmt.Fprintf(buf, "--%s--\r\n", boundary)
+	return buf.Bytes()
+}
+
+// writeBase64 writes data as base64 with lines of 76 characters.
+func writeBase64(buf *bytes.Buffer, data []byte) {
+	const lineLength = 76
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > lineLength {
+		buf.WriteString(encoded[:lineLength] + "\r\n")
+		encoded = encoded[lineLength:]
+	}
+	buf.WriteString(encoded + "\r\n")
+}
+
+// Send the message using the SMTP server from config.
+func Send(config Config, msg Message) error {
+	if len(config.To) == 0 {
+		return errors.New("no email recipients")
+	}
+	host, _, err := net.SplitHostPort(config.Addr)
+	if err != nil {
+		return errors.Wrap(err, "invalid SMTP address")
+	}
+	client, err := dial(config, host)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to SMTP server")
+	}
+	defer client.Close() // nolint: errcheck
+
+	if config.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return errors.Wrap(err, "failed to start TLS")
+		}
+	}
+	if config.Username != "" {
+		auth := smtp.PlainAuth("", config.Username, config.Password, host)
+		if err := client.Auth(auth); err != nil {
+			return errors.Wrap(err, "failed to authenticate with SMTP server")
+		}
+	}
+	if err := client.Mail(config.From); err != nil {
+		return errors.Wrap(err, "failed to set email sender")
+	}
+	for _, to := range config.To {
+		if err := client.Rcpt(to); err != nil {
+			return errors.Wrapf(err, "failed to add email recipient %s", to)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return errors.Wrap(err, "failed to send email")
+	}
+	if _, err := writer.Write(msg.Bytes(config.From, config.To, time.Now())); err != nil {
+		return errors.Wrap(err, "failed to send email")
+	}
+	if err := writer.Close(); err != nil {
+		return errors.Wrap(err, "failed to send email")
+	}
+	return client.Quit()
+}
+
+func dial(config Config, host string) (*smtp.Client, error) {
+	switch config.TLS {
+	case TLSImplicit:
+		conn, err := tls.Dial("tcp", config.Addr, &tls.Config{ServerName: host})
+		if err != nil {
+			return nil, err
+		}
+		return smtp.NewClient(conn, host)
+	case TLSStartTLS, TLSNone, "":
+		return smtp.Dial(config.Addr)
+	default:
+		return nil, errors.Errorf("unknown TLS mode %s", config.TLS)
+	}
+}
@@ -0,0 +1,97 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func readBase64(t *testing.T, part io.Reader) string {
+	raw, err := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+	assert.NilError(t, err)
+	return string(raw)
+}
+
+func TestMessage_Bytes(t *testing.T) {
+	msg := Message{
+		Subject: "gotestsum FAIL: 3 tests",
+		Body:    "DONE 3 tests, 1 failure in 0.100s\n",
+		Attachments: []Attachment{
+			{Name: "junit.xml", ContentType: "application/xml", Data: []byte("<testsuites/>")},
+		},
+	}
+	date := time.Date(2019, 4, 1, 10, 0, 0, 0, time.UTC)
+	raw := msg.Bytes("ci@example.com", []string{"a@example.com", "b@example.com"}, date)
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	assert.NilError(t, err)
+	assert.Equal(t, parsed.Header.Get("To"), "a@example.com, b@example.com")
+	assert.Equal(t, parsed.Header.Get("Date"), "Mon, 01 Apr 2019 10:00:00 +0000")
+
+	reader := multipart.NewReader(parsed.Body, boundary)
+	part, err := reader.NextPart()
+	assert.NilError(t, err)
+	body := readBase64(t, part)
+	assert.Assert(t, strings.Contains(body, "DONE 3 tests"), body)
+
+	part, err = reader.NextPart()
+	assert.NilError(t, err)
+	assert.Equal(t, part.FileName(), "junit.xml")
+	assert.Equal(t, readBase64(t, part), "<testsuites/>")
+}
+
+func TestSend_NoRecipients(t *testing.T) {
+	err := Send(Config{Addr: "localhost:25"}, Message{})
+	assert.ErrorContains(t, err, "no email recipients")
+}
+
+func TestSend_StartTLSNotSupported(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer listener.Close() // nolint: errcheck
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() // nolint: errcheck
+		text := textproto.NewConn(conn)
+		_ = text.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				_ = text.PrintfLine("250-localhost")
+				_ = text.PrintfLine("250 AUTH PLAIN")
+			case strings.HasPrefix(line, "QUIT"):
+				_ = text.PrintfLine("221 bye")
+				return
+			default:
+				_ = text.PrintfLine("250 ok")
+			}
+		}
+	}()
+
+	config := Config{
+		Addr: listener.Addr().String(),
+		TLS:  TLSStartTLS,
+		From: "ci@example.com",
+		To:   []string{"a@example.com"},
+	}
+	err = Send(config, Message{Subject: "report"})
+	assert.ErrorContains(t, err, "does not support STARTTLS")
+}
@@ -0,0 +1,31 @@
+package junitxml
+
+import (
+	"strconv"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// benchmarkPropertyPrefix is the prefix of the name of the properties with the
+// result of a benchmark.
+const benchmarkPropertyPrefix = "gotestsum.benchmark."
+
+// addBenchmarkResult adds the number of iterations, and each metric of the
+// benchmark, like ns/op, B/op, and allocs/op, to the properties of the
+// testcase, so that tools like the Jenkins Performance Plugin can chart them.
+// The time of the testcase is not changed.
+func addBenchmarkResult(tc *JUnitTestCase, result testjson.BenchmarkResult) {
+	if tc.Properties == nil {
+		tc.Properties = &JUnitProperties{}
+	}
+	tc.Properties.Property = append(tc.Properties.Property, JUnitProperty{
+		Name:  benchmarkPropertyPrefix + "iterations",
+		Value: strconv.Itoa(result.Iterations),
+	})
+	for _, metric := range result.Metrics {
+		tc.Properties.Property = append(tc.Properties.Property, JUnitProperty{
+			Name:  benchmarkPropertyPrefix + metric.Unit,
+			Value: strconv.FormatFloat(metric.Value, 'f', -1, 64),
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestWriteWithConfig_Benchmarks(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/bench","Test":"BenchmarkSprintf"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkSprintf","Output":"BenchmarkSprintf\n"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkSprintf","Output":"BenchmarkSprintf-8 \t 1000000\t       125.5 ns/op\t       3 B/op\t       1 allocs/op\n"}
+{"Action":"pass","Package":"example.com/bench","Elapsed":1.2}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{}))
+	assert.NilError(t, ValidateSchema(bytes.NewReader(out.Bytes())))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 1)
+	tc := suite.TestCases[0]
+	assert.Equal(t, tc.Name, "BenchmarkSprintf")
+	assert.Equal(t, tc.Time, "0.000000")
+	assert.Assert(t, tc.Properties != nil)
+	assert.DeepEqual(t, tc.Properties.Property, []JUnitProperty{
+		{Name: "gotestsum.benchmark.iterations", Value: "1000000"},
+		{Name: "gotestsum.benchmark.ns/op", Value: "125.5"},
+		{Name: "gotestsum.benchmark.B/op", Value: "3"},
+		{Name: "gotestsum.benchmark.allocs/op", Value: "1"},
+	})
+}
@@ -0,0 +1,39 @@
+package junitxml
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// failureLine matches a line written by t.Error or t.Fatal, which starts with
+// the file name and line number of the call.
+var failureLine = regexp.MustCompile(`(?m)^\s+(\S+\.go):(\d+): `)
+
+// failureLocation returns the file and line of the first line of output
+// written by t.Error or t.Fatal, or an empty file if there is none. The file is
+// relative to the module of the working directory when the package is in
+// that module, so that CI systems can link to the source.
+func failureLocation(pkg string, output string) (string, int) {
+	match := failureLine.FindStringSubmatch(output)
+	if match == nil {
+		return "", 0
+	}
+	line, err := strconv.Atoi(match[2])
+	if err != nil {
+		return "", 0
+	}
+	file := match[1]
+	if strings.Contains(file, "/") {
+		return file, line
+	}
+	switch dir := testjson.RelativePackagePath(pkg); {
+	case dir == ".":
+	case dir != pkg:
+		file = path.Join(dir, file)
+	}
+	return file, line
+}
@@ -0,0 +1,52 @@
+package junitxml
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestFailureLocation(t *testing.T) {
+	var testcases = []struct {
+		name   string
+		pkg    string
+		output string
+		file   string
+		line   int
+	}{
+		{
+			name:   "package in module",
+			pkg:    "gotest.tools/gotestsum/internal/junitxml",
+			output: "=== RUN   TestOne\n    report_test.go:42: got 1, expected 2\n    report_test.go:43: again\n--- FAIL: TestOne (0.00s)\n",
+			file:   "internal/junitxml/report_test.go",
+			line:   42,
+		},
+		{
+			name:   "package outside of module",
+			pkg:    "example.com/pkg",
+			output: "\tstub_test.go:34: this failed\n",
+			file:   "stub_test.go",
+			line:   34,
+		},
+		{
+			name:   "subtest",
+			pkg:    "example.com/pkg",
+			output: "=== RUN   TestOne/sub\n        one_test.go:9: failed\n",
+			file:   "one_test.go",
+			line:   9,
+		},
+		{
+			name:   "no location",
+			pkg:    "example.com/pkg",
+			output: "=== RUN   TestOne\npanic: boom\n\t/src/one_test.go:9 +0x1d\n",
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			file, line := failureLocation(tc.pkg, tc.output)
+			assert.Equal(t, file, tc.file)
+			assert.Equal(t, line, tc.line)
+		})
+	}
+}
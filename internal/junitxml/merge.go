@@ -0,0 +1,159 @@
+package junitxml
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// MergePolicy selects how Merge resolves the testcases with the same
+// classname and name in a merged testsuite, for example when a CI job was
+// retried and both reports are merged.
+type MergePolicy string
+
+const (
+	// MergeAll keeps every testcase.
+	MergeAll MergePolicy = "all"
+	// MergeLast keeps the testcase from the last report.
+	MergeLast MergePolicy = "last"
+	// MergeAnyPass keeps the testcase from the last report in which the test
+	// passed, or the last report when it never passed.
+	MergeAnyPass MergePolicy = "any-pass"
+)
+
+// IsValid returns true if p is one of the merge policies.
+func (p MergePolicy) IsValid() bool {
+	switch p {
+	case MergeAll, MergeLast, MergeAnyPass:
+		return true
+	}
+	return false
+}
+
+// Merge combines reports, for example from each shard of a test run, into a
+// single report. Testsuites with the same name are merged into one testsuite
+// with the sum of their counts and time, and the testcases of each of them.
+func Merge(reports ...JUnitTestSuites) JUnitTestSuites {
+	return MergeWithPolicy(MergeAll, reports...)
+}
+
+// MergeWithPolicy combines reports like Merge, and uses policy to resolve
+// the duplicate testcases. The tests of a testsuite with a duplicate which was
+// removed are reduced by the number removed. The failures, errors, skipped,
+// and assertions of every testsuite are counted from its testcases.
+func MergeWithPolicy(policy MergePolicy, reports ...JUnitTestSuites) JUnitTestSuites {
+	var merged JUnitTestSuites
+	for _, report := range reports {
+		merged.Suites = mergeSuites(merged.Suites, report.Suites)
+	}
+	if policy == MergeLast || policy == MergeAnyPass {
+		for i := range merged.Suites {
+			removeDuplicates(&merged.Suites[i], policy)
+		}
+	}
+	addTotals(&merged)
+	return merged
+}
+
+// removeDuplicates keeps one testcase for each classname and name in suite,
+// and its nested testsuites. Returns the number of testcases which were
+// removed.
+func removeDuplicates(suite *JUnitTestSuite, policy MergePolicy) int {
+	keep := make(map[testCaseKey]int, len(suite.TestCases))
+	var order []testCaseKey
+	for i, tc := range suite.TestCases {
+		key := testCaseKey{classname: tc.Classname, name: tc.Name}
+		prev, ok := keep[key]
+		switch {
+		case !ok:
+			order = append(order, key)
+			keep[key] = i
+		case policy == MergeLast, passed(tc), !passed(suite.TestCases[prev]):
+			keep[key] = i
+		}
+	}
+
+	removed := len(suite.TestCases) - len(order)
+	for i := range suite.Suites {
+		removed += removeDuplicates(&suite.Suites[i], policy)
+	}
+	if removed == 0 {
+		return 0
+	}
+
+	cases := make([]JUnitTestCase, 0, len(order))
+	for _, key := range order {
+		cases = append(cases, suite.TestCases[keep[key]])
+	}
+	suite.TestCases = cases
+	suite.Tests -= removed
+	return removed
+}
+
+type testCaseKey struct {
+	classname string
+	name      string
+}
+
+func passed(tc JUnitTestCase) bool {
+	return tc.Failure == nil && tc.Error == nil
+}
+
+func mergeSuites(suites []JUnitTestSuite, other []JUnitTestSuite) []JUnitTestSuite {
+	index := make(map[string]int, len(suites))
+	for i, suite := range suites {
+		index[suite.Name] = i
+	}
+	for _, suite := range other {
+		i, ok := index[suite.Name]
+		if !ok {
+			index[suite.Name] = len(suites)
+			suites = append(suites, suite)
+			continue
+		}
+		mergeSuite(&suites[i], suite)
+	}
+	return suites
+}
+
+func mergeSuite(suite *JUnitTestSuite, other JUnitTestSuite) {
+	suite.Tests += other.Tests
+	suite.Failures += other.Failures
+	suite.Errors += other.Errors
+	suite.Skipped += other.Skipped
+	suite.Assertions += other.Assertions
+	suite.Time = fmt.Sprintf("%f", parseSeconds(suite.Time)+parseSeconds(other.Time))
+	if suite.Timestamp == "" || other.Timestamp != "" && other.Timestamp < suite.Timestamp {
+		suite.Timestamp = other.Timestamp
+	}
+	if suite.Hostname == "" {
+		suite.Hostname = other.Hostname
+	}
+	for _, property := range other.Properties {
+		if !hasProperty(suite.Properties, property) {
+			suite.Properties = append(suite.Properties, property)
+		}
+	}
+	suite.TestCases = append(suite.TestCases, other.TestCases...)
+	suite.Suites = mergeSuites(suite.Suites, other.Suites)
+	if suite.SystemOut != "" && other.SystemOut != "" {
+		suite.SystemOut += "\n"
+	}
+	suite.SystemOut += other.SystemOut
+}
+
+func hasProperty(properties []JUnitProperty, property JUnitProperty) bool {
+	for _, p := range properties {
+		if p == property {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteSuites writes suites, for example a report created by Merge, as an XML
+// document to out.
+func WriteSuites(out io.Writer, suites JUnitTestSuites) error {
+	return errors.Wrap(write(out, suites), "failed to write JUnit XML")
+}
@@ -0,0 +1,103 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestMerge(t *testing.T) {
+	read := func(doc string) JUnitTestSuites {
+		suites, err := Read(strings.NewReader(doc))
+		assert.NilError(t, err)
+		return suites
+	}
+	shard1 := read(`<testsuites>
+  <testsuite name="example.com/a" tests="2" failures="1" skipped="0" time="1.5" timestamp="2020-01-02T10:00:00">
+    <properties><property name="go.version" value="go1.14"></property></properties>
+    <testcase classname="example.com/a" name="TestOne" time="1.0"><failure message="Failed"></failure></testcase>
+    <testcase classname="example.com/a" name="TestTwo" time="0.5"></testcase>
+  </testsuite>
+</testsuites>`)
+	shard2 := read(`<testsuites>
+  <testsuite name="example.com/b" tests="1" failures="0" time="0.2">
+    <testcase classname="example.com/b" name="TestB" time="0.2"></testcase>
+  </testsuite>
+  <testsuite name="example.com/a" tests="1" failures="0" time="0.5" timestamp="2020-01-02T09:00:00">
+    <properties><property name="go.version" value="go1.14"></property></properties>
+    <testcase classname="example.com/a" name="TestSkip" time="0.5"><skipped message="skip"></skipped></testcase>
+  </testsuite>
+</testsuites>`)
+
+	merged := Merge(shard1, shard2)
+	assert.Equal(t, merged.Tests, 4)
+	assert.Equal(t, merged.Failures, 1)
+	assert.Equal(t, merged.Skipped, 1)
+	assert.Equal(t, merged.Time, "2.200000")
+	assert.Equal(t, len(merged.Suites), 2)
+
+	a := merged.Suites[0]
+	assert.Equal(t, a.Name, "example.com/a")
+	assert.Equal(t, a.Tests, 3)
+	assert.Equal(t, a.Failures, 1)
+	assert.Equal(t, a.Skipped, 1)
+	assert.Equal(t, a.Time, "2.000000")
+	assert.Equal(t, a.Timestamp, "2020-01-02T09:00:00")
+	assert.Equal(t, len(a.Properties), 1)
+	assert.Equal(t, len(a.TestCases), 3)
+	assert.Equal(t, a.TestCases[2].Name, "TestSkip")
+	assert.Equal(t, merged.Suites[1].Name, "example.com/b")
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteSuites(out, merged))
+	assert.NilError(t, Validate(out))
+}
+
+func TestMergeWithPolicy(t *testing.T) {
+	read := func(doc string) JUnitTestSuites {
+		suites, err := Read(strings.NewReader(doc))
+		assert.NilError(t, err)
+		return suites
+	}
+	first := read(`<testsuites>
+  <testsuite name="example.com/a" tests="2" failures="2" time="1.0">
+    <testcase classname="example.com/a" name="TestFlaky" time="0.5"><failure message="Failed"></failure></testcase>
+    <testcase classname="example.com/a" name="TestBroken" time="0.5"><failure message="Failed"></failure></testcase>
+  </testsuite>
+</testsuites>`)
+	retry := read(`<testsuites>
+  <testsuite name="example.com/a" tests="2" failures="1" time="1.0">
+    <testcase classname="example.com/a" name="TestFlaky" time="0.5"></testcase>
+    <testcase classname="example.com/a" name="TestBroken" time="0.5"><failure message="Failed again"></failure></testcase>
+  </testsuite>
+</testsuites>`)
+	again := read(`<testsuites>
+  <testsuite name="example.com/a" tests="1" failures="1" time="0.5">
+    <testcase classname="example.com/a" name="TestFlaky" time="0.5"><failure message="Failed"></failure></testcase>
+  </testsuite>
+</testsuites>`)
+
+	t.Run("all", func(t *testing.T) {
+		merged := MergeWithPolicy(MergeAll, first, retry, again)
+		assert.Equal(t, merged.Tests, 5)
+		assert.Equal(t, merged.Failures, 4)
+	})
+	t.Run("last", func(t *testing.T) {
+		merged := MergeWithPolicy(MergeLast, first, retry, again)
+		assert.Equal(t, merged.Tests, 2)
+		assert.Equal(t, merged.Failures, 2)
+		suite := merged.Suites[0]
+		assert.Equal(t, suite.TestCases[1].Failure.Message, "Failed again")
+	})
+	t.Run("any-pass", func(t *testing.T) {
+		merged := MergeWithPolicy(MergeAnyPass, first, retry, again)
+		assert.Equal(t, merged.Tests, 2)
+		assert.Equal(t, merged.Failures, 1)
+		suite := merged.Suites[0]
+		assert.Equal(t, suite.TestCases[0].Name, "TestFlaky")
+		assert.Assert(t, suite.TestCases[0].Failure == nil)
+		assert.Equal(t, suite.TestCases[1].Failure.Message, "Failed again")
+	})
+}
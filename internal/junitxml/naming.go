@@ -0,0 +1,97 @@
+package junitxml
+
+import (
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Naming is the templates used to create the classname and name of each
+// testcase. A nil template keeps the default classname or name.
+type Naming struct {
+	Classname *template.Template
+	Name      *template.Template
+}
+
+// NamingData is the data used to execute the Naming templates.
+type NamingData struct {
+	// Package is the import path of the package.
+	Package string
+	// Test is the name of the test, including the name of any subtests.
+	Test string
+	// Strip is the package path relative to the module of the working
+	// directory, the same name used by PathModeRelative.
+	Strip string
+	// Prefix is the part of the package path removed by Strip, or an empty
+	// string for a package outside of the module.
+	Prefix string
+}
+
+func newNamingData(pkg, test string) NamingData {
+	strip := testjson.RelativePackagePath(pkg)
+	data := NamingData{Package: pkg, Test: test, Strip: strip}
+	switch {
+	case strip == ".":
+		data.Prefix = pkg
+	case strip != pkg:
+		data.Prefix = strings.TrimSuffix(strings.TrimSuffix(pkg, strip), "/")
+	}
+	return data
+}
+
+var namingFuncs = template.FuncMap{
+	"base":       path.Base,
+	"dir":        path.Dir,
+	"replace":    func(s, old, new string) string { return strings.Replace(s, old, new, -1) },
+	"trimPrefix": strings.TrimPrefix,
+	"trimSuffix": strings.TrimSuffix,
+}
+
+// ParseNamingTemplate parses the text of a Naming template. The template is
+// executed with a NamingData, and may use the functions base, dir, replace,
+// trimPrefix, and trimSuffix.
+func ParseNamingTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(namingFuncs).Parse(text)
+}
+
+// applyNaming replaces the classname and name of each testcase using the
+// templates of naming. It must be called after any step which looks up the
+// output of a test by the name of the testcase.
+func applyNaming(suites JUnitTestSuites, exec *testjson.Execution, naming Naming) error {
+	if naming.Classname == nil && naming.Name == nil {
+		return nil
+	}
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		for j := range suite.TestCases {
+			tc := &suite.TestCases[j]
+			data := newNamingData(suite.pkgname, tc.Name)
+			if naming.Classname != nil {
+				classname, err := execNaming(naming.Classname, data)
+				if err != nil {
+					return err
+				}
+				tc.Classname = classname
+			}
+			if naming.Name != nil {
+				name, err := execNaming(naming.Name, data)
+				if err != nil {
+					return err
+				}
+				tc.Name = name
+			}
+		}
+	}
+	return nil
+}
+
+func execNaming(tmpl *template.Template, data NamingData) (string, error) {
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", errors.Wrapf(err, "failed to execute the JUnit %s template", tmpl.Name())
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,59 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestWriteWithConfig_Naming(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/foo/bar","Test":"TestFoo/sub"}
+{"Action":"pass","Package":"example.com/foo/bar","Test":"TestFoo/sub"}
+{"Action":"pass","Package":"example.com/foo/bar"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	classname, err := ParseNamingTemplate("classname", `{{ base .Package }}_test`)
+	assert.NilError(t, err)
+	name, err := ParseNamingTemplate("name", `{{ replace .Package "/" "." }}.{{ .Test }}`)
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	config := Config{Naming: Naming{Classname: classname, Name: name}}
+	assert.NilError(t, WriteWithConfig(out, exec, config))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	tc := suites.Suites[0].TestCases[0]
+	assert.Equal(t, tc.Classname, "bar_test")
+	assert.Equal(t, tc.Name, "example.com.foo.bar.TestFoo/sub")
+	assert.Equal(t, suites.Suites[0].Name, "example.com/foo/bar")
+
+	name, err = ParseNamingTemplate("name", `{{ .Missing }}`)
+	assert.NilError(t, err)
+	err = WriteWithConfig(out, exec, Config{Naming: Naming{Name: name}})
+	assert.ErrorContains(t, err, "failed to execute the JUnit name template")
+}
+
+func TestNewNamingData(t *testing.T) {
+	pkg := "gotest.tools/gotestsum/internal/junitxml"
+	data := newNamingData(pkg, "TestOne")
+	assert.Equal(t, data, NamingData{
+		Package: pkg,
+		Test:    "TestOne",
+		Strip:   "internal/junitxml",
+		Prefix:  "gotest.tools/gotestsum",
+	})
+
+	data = newNamingData("example.com/other", "TestOne")
+	assert.Equal(t, data.Strip, "example.com/other")
+	assert.Equal(t, data.Prefix, "")
+}
@@ -0,0 +1,336 @@
+package junitxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Format is the format of the XML document written by WriteWithConfig.
+type Format string
+
+const (
+	// FormatJUnit writes the JUnit XML format read by most CI systems, which
+	// is the format of the Maven Surefire and Jenkins reports.
+	FormatJUnit Format = "junit"
+	// FormatOpenTestReporting writes the event based XML format of the
+	// JUnit 5 Open Test Reporting project, read by tools like Gradle and
+	// IntelliJ.
+	FormatOpenTestReporting Format = "open-test-reporting"
+)
+
+// The namespaces of the Open Test Reporting schema.
+const (
+	openTestCoreNamespace   = "https://schemas.opentest4j.org/reporting/core/0.2.0"
+	openTestEventsNamespace = "https://schemas.opentest4j.org/reporting/events/0.2.0"
+)
+
+// The status of the result of a test in an Open Test Reporting document.
+const (
+	openTestSuccessful = "SUCCESSFUL"
+	openTestSkipped    = "SKIPPED"
+	openTestAborted    = "ABORTED"
+	openTestFailed     = "FAILED"
+	openTestErrored    = "ERRORED"
+)
+
+// otrEvents is the root element of an Open Test Reporting document. The
+// elements of the events namespace use the e prefix, like the documents
+// written by the JUnit Platform.
+type otrEvents struct {
+	XMLName        xml.Name          `xml:"e:events"`
+	Namespace      string            `xml:"xmlns,attr"`
+	EventNamespace string            `xml:"xmlns:e,attr"`
+	Infrastructure otrInfrastructure `xml:"infrastructure"`
+	Events         []otrEvent
+}
+
+type otrInfrastructure struct {
+	HostName        string `xml:"hostName,omitempty"`
+	OperatingSystem string `xml:"operatingSystem"`
+}
+
+// otrEvent is an e:started, e:reported, or e:finished element.
+type otrEvent struct {
+	XMLName     xml.Name
+	ID          string          `xml:"id,attr"`
+	ParentID    string          `xml:"parentId,attr,omitempty"`
+	Name        string          `xml:"name,attr,omitempty"`
+	Time        string          `xml:"time,attr"`
+	Sources     *otrSources     `xml:"sources,omitempty"`
+	Attachments *otrAttachments `xml:"attachments,omitempty"`
+	Result      *otrResult      `xml:"result,omitempty"`
+}
+
+type otrSources struct {
+	File otrFileSource `xml:"fileSource"`
+}
+
+type otrFileSource struct {
+	Path     string               `xml:"path,attr"`
+	Position *otrFilePositionLine `xml:"filePosition,omitempty"`
+}
+
+type otrFilePositionLine struct {
+	Line int `xml:"line,attr"`
+}
+
+type otrAttachments struct {
+	Data   *otrData   `xml:"data,omitempty"`
+	Output *otrOutput `xml:"output,omitempty"`
+}
+
+type otrData struct {
+	Time    string     `xml:"time,attr"`
+	Entries []otrEntry `xml:"entry"`
+}
+
+type otrEntry struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type otrOutput struct {
+	Time   string `xml:"time,attr"`
+	Source string `xml:"source,attr"`
+	Text   string `xml:",chardata"`
+}
+
+type otrResult struct {
+	Status string `xml:"status,attr"`
+	Reason string `xml:"reason,omitempty"`
+}
+
+// openTestReport converts suites into an Open Test Reporting document. Each
+// testsuite is a container, with a started and a finished event, and each
+// testcase is a test of its testsuite. The properties, and the output of a
+// test, are attachments of a reported event. The start of each test is the
+// start of its testsuite, because the time a test started is not known.
+func openTestReport(suites JUnitTestSuites, hostname string, started time.Time) otrEvents {
+	report := &openTestWriter{}
+	for _, suite := range suites.Suites {
+		report.addSuite(suite, "", started)
+	}
+	return otrEvents{
+		Namespace:      openTestCoreNamespace,
+		EventNamespace: openTestEventsNamespace,
+		Infrastructure: otrInfrastructure{HostName: hostname, OperatingSystem: runtime.GOOS},
+		Events:         report.events,
+	}
+}
+
+type openTestWriter struct {
+	events []otrEvent
+	lastID int
+}
+
+func (w *openTestWriter) nextID() string {
+	w.lastID++
+	return strconv.Itoa(w.lastID)
+}
+
+func (w *openTestWriter) add(name string, event otrEvent) {
+	event.XMLName = xml.Name{Local: "e:" + name}
+	w.events = append(w.events, event)
+}
+
+func (w *openTestWriter) addSuite(suite JUnitTestSuite, parentID string, started time.Time) {
+	if t, err := time.Parse("2006-01-02T15:04:05", suite.Timestamp); err == nil {
+		started = t
+	}
+	id := w.nextID()
+	w.add("started", otrEvent{ID: id, ParentID: parentID, Name: suite.Name, Time: formatOpenTestTime(started)})
+	if data := openTestData(suite.Properties, started); data != nil {
+		w.add("reported", otrEvent{ID: id, Time: formatOpenTestTime(started), Attachments: &otrAttachments{Data: data}})
+	}
+	for _, tc := range suite.TestCases {
+		w.addTestCase(tc, id, started)
+	}
+	for _, nested := range suite.Suites {
+		w.addSuite(nested, id, started)
+	}
+
+	finished := started.Add(secondsDuration(suite.Time))
+	if suite.SystemOut != "" {
+		w.add("reported", otrEvent{ID: id, Time: formatOpenTestTime(finished), Attachments: &otrAttachments{
+			Output: &otrOutput{Time: formatOpenTestTime(finished), Source: "stdout", Text: suite.SystemOut},
+		}})
+	}
+	w.add("finished", otrEvent{ID: id, Time: formatOpenTestTime(finished), Result: suiteResult(suite)})
+}
+
+func (w *openTestWriter) addTestCase(tc JUnitTestCase, parentID string, started time.Time) {
+	id := w.nextID()
+	event := otrEvent{ID: id, ParentID: parentID, Name: tc.Name, Time: formatOpenTestTime(started)}
+	if tc.File != "" {
+		event.Sources = &otrSources{File: otrFileSource{Path: tc.File}}
+		if tc.Line > 0 {
+			event.Sources.File.Position = &otrFilePositionLine{Line: tc.Line}
+		}
+	}
+	w.add("started", event)
+
+	finished := started.Add(secondsDuration(tc.Time))
+	// custom attributes are written as data, like the properties
+	var properties []JUnitProperty
+	for _, attr := range tc.Attrs {
+		properties = append(properties, JUnitProperty{Name: attr.Name.Local, Value: attr.Value})
+	}
+	if tc.Properties != nil {
+		properties = append(properties, tc.Properties.Property...)
+	}
+	attachments := &otrAttachments{Data: openTestData(properties, finished)}
+	if output := testCaseOutput(tc); output != "" {
+		attachments.Output = &otrOutput{Time: formatOpenTestTime(finished), Source: "stdout", Text: output}
+	}
+	if attachments.Data != nil || attachments.Output != nil {
+		w.add("reported", otrEvent{ID: id, Time: formatOpenTestTime(finished), Attachments: attachments})
+	}
+	w.add("finished", otrEvent{ID: id, Time: formatOpenTestTime(finished), Result: testCaseResult(tc)})
+}
+
+// testCaseOutput returns the output of the test, from the failure or error,
+// or from system-out.
+func testCaseOutput(tc JUnitTestCase) string {
+	switch {
+	case tc.Error != nil && tc.Error.Contents != "":
+		return tc.Error.Contents
+	case tc.Failure != nil && tc.Failure.Contents != "":
+		return tc.Failure.Contents
+	}
+	return tc.SystemOut
+}
+
+func testCaseResult(tc JUnitTestCase) *otrResult {
+	switch {
+	case tc.Error != nil:
+		return &otrResult{Status: openTestErrored, Reason: tc.Error.Message}
+	case tc.Failure != nil:
+		return &otrResult{Status: openTestFailed, Reason: tc.Failure.Message}
+	case tc.SkipMessage != nil && hasNotRunOutcome(tc):
+		return &otrResult{Status: openTestAborted, Reason: tc.SkipMessage.Message}
+	case tc.SkipMessage != nil:
+		return &otrResult{Status: openTestSkipped, Reason: tc.SkipMessage.Message}
+	}
+	return &otrResult{Status: openTestSuccessful}
+}
+
+func hasNotRunOutcome(tc JUnitTestCase) bool {
+	return tc.Properties != nil && hasProperty(tc.Properties.Property,
+		JUnitProperty{Name: "gotestsum.outcome", Value: string(testjson.ActionNotRun)})
+}
+
+func suiteResult(suite JUnitTestSuite) *otrResult {
+	switch {
+	case suite.Errors > 0:
+		return &otrResult{Status: openTestErrored}
+	case suite.Failures > 0:
+		return &otrResult{Status: openTestFailed}
+	case suite.Tests > 0 && suite.Skipped == suite.Tests:
+		return &otrResult{Status: openTestSkipped}
+	}
+	return &otrResult{Status: openTestSuccessful}
+}
+
+func openTestData(properties []JUnitProperty, t time.Time) *otrData {
+	if len(properties) == 0 {
+		return nil
+	}
+	data := &otrData{Time: formatOpenTestTime(t)}
+	for _, property := range properties {
+		data.Entries = append(data.Entries, otrEntry{Key: property.Name, Value: property.Value})
+	}
+	return data
+}
+
+// formatOpenTestTime returns t in UTC, in the xs:dateTime format used by the
+// Open Test Reporting schema.
+func formatOpenTestTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func secondsDuration(value string) time.Duration {
+	return time.Duration(parseSeconds(value) * float64(time.Second))
+}
+
+func writeOpenTestReport(out io.Writer, report otrEvents) error {
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(out)
+	return err
+}
+
+// ValidateOpenTestReporting checks that an Open Test Reporting document is
+// well formed, that the root element is events, and that every finished
+// event is for a test with a started event.
+func ValidateOpenTestReporting(in io.Reader) error {
+	decoder := newDecoder(in)
+	started := make(map[string]bool)
+	depth := 0
+	hasRoot := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "invalid XML")
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				if hasRoot {
+					return errors.New("more than one root element")
+				}
+				hasRoot = true
+				if t.Name.Local != "events" || t.Name.Space != openTestEventsNamespace {
+					return errors.Errorf("unexpected root element <%s>", t.Name.Local)
+				}
+				continue
+			}
+			if depth != 2 || t.Name.Space != openTestEventsNamespace {
+				continue
+			}
+			id := attrValue(t.Attr, "id")
+			switch t.Name.Local {
+			case "started":
+				if id == "" {
+					return errors.New("started event without an id")
+				}
+				started[id] = true
+			case "reported", "finished":
+				if !started[id] {
+					return errors.Errorf("%s event for %q without a started event", t.Name.Local, id)
+				}
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if !hasRoot {
+		return errors.New("no root element")
+	}
+	return nil
+}
+
+func attrValue(attrs []xml.Attr, name string) string {
+	for _, attr := range attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
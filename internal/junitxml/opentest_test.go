@@ -0,0 +1,75 @@
+package junitxml
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/golden"
+)
+
+func TestWriteWithConfig_OpenTestReporting(t *testing.T) {
+	exec := createExecution(t)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	config := Config{Hostname: "ci-runner-1", Format: FormatOpenTestReporting}
+	assert.NilError(t, WriteWithConfig(out, exec, config))
+	assert.NilError(t, ValidateOpenTestReporting(bytes.NewReader(out.Bytes())))
+
+	// the operating system is the one which runs the test
+	actual := strings.Replace(out.String(),
+		"<operatingSystem>"+runtime.GOOS+"<", "<operatingSystem>GOOS<", 1)
+	golden.Assert(t, actual, "open-test-reporting.golden")
+}
+
+func TestValidateOpenTestReporting(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		doc      string
+		expected string
+	}{
+		{
+			name: "valid",
+			doc: `<e:events xmlns="` + openTestCoreNamespace + `" xmlns:e="` + openTestEventsNamespace + `">
+<e:started id="1" name="TestOne" time="2021-02-03T04:05:06.000Z"></e:started>
+<e:finished id="1" time="2021-02-03T04:05:07.000Z"><result status="SUCCESSFUL"></result></e:finished>
+</e:events>`,
+		},
+		{
+			name:     "junit document",
+			doc:      `<testsuites></testsuites>`,
+			expected: "unexpected root element <testsuites>",
+		},
+		{
+			name: "finished without started",
+			doc: `<e:events xmlns:e="` + openTestEventsNamespace + `">
+<e:finished id="2" time="2021-02-03T04:05:07.000Z"></e:finished>
+</e:events>`,
+			expected: `finished event for "2" without a started event`,
+		},
+		{
+			name:     "truncated",
+			doc:      `<e:events xmlns:e="` + openTestEventsNamespace + `"><e:started id="1">`,
+			expected: "invalid XML",
+		},
+		{
+			name:     "empty",
+			expected: "no root element",
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateOpenTestReporting(strings.NewReader(tc.doc))
+			if tc.expected == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
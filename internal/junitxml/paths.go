@@ -0,0 +1,57 @@
+package junitxml
+
+import (
+	"gotest.tools/gotestsum/testjson"
+)
+
+// rewritePaths applies rules to the classname, the file, and the output of
+// each testcase, and to the output of each testsuite, so that paths like the
+// absolute path of the CI workspace can be replaced before the report is
+// shared.
+func rewritePaths(suites JUnitTestSuites, rules testjson.RewriteRules) {
+	if len(rules) == 0 {
+		return
+	}
+	for i := range suites.Suites {
+		rewriteSuitePaths(&suites.Suites[i], rules)
+	}
+}
+
+func rewriteSuitePaths(suite *JUnitTestSuite, rules testjson.RewriteRules) {
+	suite.SystemOut = rules.Apply(suite.SystemOut)
+	for i := range suite.TestCases {
+		tc := &suite.TestCases[i]
+		tc.Classname = rules.Apply(tc.Classname)
+		tc.File = rules.Apply(tc.File)
+		tc.SystemOut = rules.Apply(tc.SystemOut)
+		if tc.SkipMessage != nil {
+			tc.SkipMessage.Message = rules.Apply(tc.SkipMessage.Message)
+		}
+		rewriteFailurePaths(tc.Failure, rules)
+		rewriteFailurePaths(tc.Error, rules)
+		for j := range tc.FlakyFailures {
+			rewriteRerunFailurePaths(&tc.FlakyFailures[j], rules)
+		}
+		for j := range tc.RerunFailures {
+			rewriteRerunFailurePaths(&tc.RerunFailures[j], rules)
+		}
+	}
+	for i := range suite.Suites {
+		rewriteSuitePaths(&suite.Suites[i], rules)
+	}
+}
+
+func rewriteFailurePaths(failure *JUnitFailure, rules testjson.RewriteRules) {
+	if failure == nil {
+		return
+	}
+	failure.Message = rules.Apply(failure.Message)
+	failure.File = rules.Apply(failure.File)
+	failure.Contents = rules.Apply(failure.Contents)
+}
+
+func rewriteRerunFailurePaths(failure *JUnitRerunFailure, rules testjson.RewriteRules) {
+	failure.Message = rules.Apply(failure.Message)
+	failure.StackTrace = rules.Apply(failure.StackTrace)
+	failure.SystemOut = rules.Apply(failure.SystemOut)
+}
@@ -0,0 +1,34 @@
+package junitxml
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestWriteWithConfig_PathRewrite(t *testing.T) {
+	builder := testjson.NewBuilder()
+	builder.Package("example.com/org/repo/pkg").Fail("TestOpen", 0,
+		"    open_test.go:12: open /home/runner/work/repo/repo/testdata/a.txt: no such file")
+	exec := builder.Execution()
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	config := Config{PathRewrite: testjson.RewriteRules{
+		{Pattern: regexp.MustCompile(`/home/runner/work/[^/]+/[^/]+/`), Replacement: "./"},
+		{Pattern: regexp.MustCompile(`^example\.com/org/repo/`), Replacement: ""},
+	}}
+	assert.NilError(t, WriteWithConfig(out, exec, config))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Name, "example.com/org/repo/pkg")
+	tc := suite.TestCases[0]
+	assert.Equal(t, tc.Classname, "pkg")
+	assert.Equal(t, tc.Failure.Contents, "    open_test.go:12: open ./testdata/a.txt: no such file\n")
+}
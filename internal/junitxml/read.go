@@ -0,0 +1,238 @@
+package junitxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// DefaultMaxDocumentBytes is the default value of MaxDocumentBytes.
+const DefaultMaxDocumentBytes int64 = 1000 * 1000 * 1000
+
+// MaxDocumentBytes and maxDepth limit the size of a JUnit XML document which
+// is read or validated, and how deep its elements are nested, so that a report
+// from an untrusted job can not use all the memory of the process which reads
+// it. MaxDocumentBytes may be changed before any document is read. A value of
+// zero or less removes the limit.
+var (
+	MaxDocumentBytes = DefaultMaxDocumentBytes
+	maxDepth         = 64
+)
+
+// Read a JUnit XML document. The root element of the document may be a
+// <testsuites> element, or a single <testsuite>.
+//
+// The document is read as a stream, and is an error if it is larger than
+// MaxDocumentBytes, or has elements nested more than 64 deep. A document with
+// a DOCTYPE is an error, so entities, including external entities, are never
+// declared.
+func Read(in io.Reader) (JUnitTestSuites, error) {
+	decoder := newDecoder(in)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return JUnitTestSuites{}, errors.Wrap(err, "failed to read JUnit XML")
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		return decodeRoot(decoder, start)
+	}
+}
+
+func newDecoder(in io.Reader) *xml.Decoder {
+	if MaxDocumentBytes > 0 {
+		in = &limitedReader{reader: in, remaining: MaxDocumentBytes}
+	}
+	return xml.NewTokenDecoder(&limitedTokenReader{decoder: xml.NewDecoder(in)})
+}
+
+// limitedReader is an io.Reader which returns an error, instead of io.EOF
+// like io.LimitedReader, when more than remaining bytes are read.
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, errDocumentTooLarge()
+	}
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return 0, errDocumentTooLarge()
+	}
+	return n, err
+}
+
+func errDocumentTooLarge() error {
+	return errors.Errorf("document is larger than %s", formatBytes(int(MaxDocumentBytes)))
+}
+
+// limitedTokenReader is an xml.TokenReader which returns an error for a
+// DOCTYPE, or an element which is nested more than maxDepth deep.
+type limitedTokenReader struct {
+	decoder *xml.Decoder
+	depth   int
+}
+
+func (r *limitedTokenReader) Token() (xml.Token, error) {
+	token, err := r.decoder.Token()
+	if err != nil {
+		return token, err
+	}
+	switch t := token.(type) {
+	case xml.StartElement:
+		r.depth++
+		if r.depth > maxDepth {
+			return nil, errors.Errorf("element <%s> is nested more than %d deep", t.Name.Local, maxDepth)
+		}
+	case xml.EndElement:
+		r.depth--
+	case xml.Directive:
+		if isDoctype(t) {
+			return nil, errors.New("DOCTYPE is not allowed")
+		}
+	}
+	return token, nil
+}
+
+func isDoctype(directive xml.Directive) bool {
+	return bytes.HasPrefix(bytes.ToUpper(bytes.TrimSpace(directive)), []byte("DOCTYPE"))
+}
+
+func decodeRoot(decoder *xml.Decoder, start xml.StartElement) (JUnitTestSuites, error) {
+	var suites JUnitTestSuites
+	switch start.Name.Local {
+	case "testsuites":
+		err := decoder.DecodeElement(&suites, &start)
+		return suites, errors.Wrap(err, "failed to read JUnit XML")
+	case "testsuite":
+		var suite JUnitTestSuite
+		if err := decoder.DecodeElement(&suite, &start); err != nil {
+			return suites, errors.Wrap(err, "failed to read JUnit XML")
+		}
+		suites.Suites = []JUnitTestSuite{suite}
+		return suites, nil
+	default:
+		return suites, errors.Errorf("unexpected root element <%s> in JUnit XML", start.Name.Local)
+	}
+}
+
+// Events returns the test2json events which would produce the test suites.
+// The events can be written to a file and used anywhere that accepts a
+// --jsonfile, which allows reports from other tools to be used with gotestsum.
+func Events(suites JUnitTestSuites) []testjson.TestEvent {
+	var events []testjson.TestEvent
+	for _, suite := range suites.Suites {
+		pkgAction := testjson.ActionPass
+		for _, tc := range allTestCases(suite) {
+			events = append(events, testCaseEvents(suite.Name, tc)...)
+			if tc.Failure != nil || tc.Error != nil {
+				pkgAction = testjson.ActionFail
+			}
+		}
+		if len(allTestCases(suite)) == 0 {
+			pkgAction = testjson.ActionSkip
+		}
+		events = append(events, testjson.TestEvent{
+			Action:  pkgAction,
+			Package: suite.Name,
+			Elapsed: parseSeconds(suite.Time),
+		})
+	}
+	return events
+}
+
+func testCaseEvents(pkg string, tc JUnitTestCase) []testjson.TestEvent {
+	event := func(action testjson.Action, output string) testjson.TestEvent {
+		return testjson.TestEvent{Action: action, Package: pkg, Test: tc.Name, Output: output}
+	}
+	events := []testjson.TestEvent{event(testjson.ActionRun, "")}
+	end := event(testjson.ActionPass, "")
+	switch {
+	case tc.Failure != nil || tc.Error != nil:
+		failure := tc.Failure
+		if failure == nil {
+			failure = tc.Error
+		}
+		if failure.Contents != "" {
+			events = append(events, event(testjson.ActionOutput, failure.Contents))
+		}
+		end.Action = testjson.ActionFail
+	case tc.SkipMessage != nil:
+		// the message is only the reason the test was skipped, the output is
+		// in system-out, except in reports written by earlier versions
+		output := tc.SystemOut
+		if output == "" {
+			output = tc.SkipMessage.Message
+		}
+		if output != "" {
+			events = append(events, event(testjson.ActionOutput, output))
+		}
+		end.Action = testjson.ActionSkip
+	}
+	end.Elapsed = parseSeconds(tc.Time)
+	return append(events, end)
+}
+
+func parseSeconds(value string) float64 {
+	seconds, _ := strconv.ParseFloat(value, 64)
+	return seconds
+}
+
+// Validate checks that a JUnit XML document is well formed, matches the
+// schema used by ValidateSchema, and that every testsuite has a name, and
+// every testcase has a classname and a name. The document is read as a stream,
+// with the same limits as Read.
+func Validate(in io.Reader) error {
+	return validateTokens(in, &schemaValidator{}, &nameValidator{})
+}
+
+// nameValidator is the tokenValidator which checks that every testsuite has a
+// name, and every testcase has a classname and a name.
+type nameValidator struct {
+	// path is the names of the elements which are open.
+	path []string
+	// suites is the number of testsuites which are not nested in another
+	// testsuite.
+	suites int
+	// suite is the name of the last testsuite which is not nested in another
+	// testsuite.
+	suite string
+}
+
+func (v *nameValidator) token(token xml.Token) error {
+	switch t := token.(type) {
+	case xml.StartElement:
+		switch {
+		case t.Name.Local == "testsuite" && !contains(v.path, "testsuite"):
+			v.suites++
+			v.suite = attrValue(t.Attr, "name")
+			if v.suite == "" {
+				return errors.Errorf("testsuite %d has no name", v.suites)
+			}
+		case t.Name.Local == "testcase":
+			if attrValue(t.Attr, "classname") == "" || attrValue(t.Attr, "name") == "" {
+				return errors.Errorf("testsuite %s has a testcase without a classname or name", v.suite)
+			}
+		}
+		v.path = append(v.path, t.Name.Local)
+	case xml.EndElement:
+		v.path = v.path[:len(v.path)-1]
+	}
+	return nil
+}
+
+func (v *nameValidator) end() error {
+	return nil
+}
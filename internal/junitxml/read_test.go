@@ -0,0 +1,160 @@
+package junitxml
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/golden"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestRead(t *testing.T) {
+	suites, err := Read(bytes.NewReader(golden.Get(t, "junitxml-report.golden")))
+	assert.NilError(t, err)
+	assert.Equal(t, len(suites.Suites), 3)
+
+	stub := suites.Suites[2]
+	assert.Equal(t, stub.Name, "github.com/gotestyourself/gotestyourself/testjson/internal/stub")
+	assert.Equal(t, stub.Failures, 4)
+	assert.Equal(t, stub.Properties[0].Value, "go7.7.7")
+	assert.Equal(t, len(stub.TestCases), 28)
+	assert.Assert(t, stub.TestCases[0].Failure != nil)
+}
+
+func TestRead_SingleTestSuite(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<testsuite name="frontend" tests="2" failures="1" time="1.5">
+  <testcase classname="frontend" name="renders" time="0.5"/>
+  <testcase classname="frontend" name="submits" time="1.0">
+    <failure message="expected 200">stack trace</failure>
+  </testcase>
+</testsuite>`
+	suites, err := Read(strings.NewReader(doc))
+	assert.NilError(t, err)
+	assert.Equal(t, len(suites.Suites), 1)
+	assert.Equal(t, len(suites.Suites[0].TestCases), 2)
+
+	_, err = Read(strings.NewReader("<html></html>"))
+	assert.ErrorContains(t, err, "unexpected root element <html>")
+}
+
+func TestRead_Limits(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<testsuites>
+  <testsuite name="frontend" tests="1">
+    <testcase classname="frontend" name="renders"/>
+  </testsuite>
+</testsuites>`
+
+	t.Run("document too large", func(t *testing.T) {
+		defer patchMaxDocumentBytes(int64(len(doc) - 1))()
+		_, err := Read(strings.NewReader(doc))
+		assert.ErrorContains(t, err, "document is larger than 154B")
+		assert.ErrorContains(t, Validate(strings.NewReader(doc)), "document is larger than 154B")
+	})
+	t.Run("no size limit", func(t *testing.T) {
+		defer patchMaxDocumentBytes(0)()
+		suites, err := Read(strings.NewReader(doc))
+		assert.NilError(t, err)
+		assert.Equal(t, len(suites.Suites), 1)
+		assert.NilError(t, Validate(strings.NewReader(doc)))
+	})
+	t.Run("document at the size limit", func(t *testing.T) {
+		defer patchMaxDocumentBytes(int64(len(doc)))()
+		suites, err := Read(strings.NewReader(doc))
+		assert.NilError(t, err)
+		assert.Equal(t, len(suites.Suites), 1)
+	})
+	t.Run("nested too deep", func(t *testing.T) {
+		defer patchMaxDepth(2)()
+		_, err := Read(strings.NewReader(doc))
+		assert.ErrorContains(t, err, "element <testcase> is nested more than 2 deep")
+	})
+	t.Run("doctype", func(t *testing.T) {
+		xxe := `<?xml version="1.0"?>
+<!DOCTYPE testsuite [<!ENTITY xxe SYSTEM "file:///etc/passwd">]>
+<testsuite name="&xxe;"></testsuite>`
+		_, err := Read(strings.NewReader(xxe))
+		assert.ErrorContains(t, err, "DOCTYPE is not allowed")
+		assert.ErrorContains(t, Validate(strings.NewReader(xxe)), "DOCTYPE is not allowed")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	assert.NilError(t, Validate(bytes.NewReader(golden.Get(t, "junitxml-report.golden"))))
+
+	var testCases = []struct {
+		name     string
+		doc      string
+		expected string
+	}{
+		{
+			name:     "testsuite without a name",
+			doc:      `<testsuites><testsuite name="a" tests="0"></testsuite><testsuite name="" tests="0"></testsuite></testsuites>`,
+			expected: "testsuite 2 has no name",
+		},
+		{
+			name:     "testcase without a name",
+			doc:      `<testsuite name="a" tests="1"><testcase classname="a" name=""></testcase></testsuite>`,
+			expected: "testsuite a has a testcase without a classname or name",
+		},
+		{
+			name: "nested testcase without a classname",
+			doc: `<testsuite name="a" tests="1"><testsuite name="" tests="1">` +
+				`<testcase classname="" name="TestA"></testcase></testsuite></testsuite>`,
+			expected: "testsuite a has a testcase without a classname or name",
+		},
+		{
+			name:     "not in the schema",
+			doc:      `<testsuite name="a" tests="0" color="red"></testsuite>`,
+			expected: `unknown attribute "color"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorContains(t, Validate(strings.NewReader(tc.doc)), tc.expected)
+		})
+	}
+}
+
+func patchMaxDocumentBytes(n int64) func() {
+	orig := MaxDocumentBytes
+	MaxDocumentBytes = n
+	return func() { MaxDocumentBytes = orig }
+}
+
+func patchMaxDepth(n int) func() {
+	orig := maxDepth
+	maxDepth = n
+	return func() { maxDepth = orig }
+}
+
+func TestEvents(t *testing.T) {
+	suites, err := Read(bytes.NewReader(golden.Get(t, "junitxml-report.golden")))
+	assert.NilError(t, err)
+
+	stdout := new(bytes.Buffer)
+	encoder := json.NewEncoder(stdout)
+	for _, event := range Events(suites) {
+		assert.NilError(t, encoder.Encode(event))
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  stdout,
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+
+	pkg := exec.Package("github.com/gotestyourself/gotestyourself/testjson/internal/stub")
+	assert.Equal(t, pkg.Total, 28)
+	assert.Equal(t, len(pkg.Failed), 4)
+	assert.Equal(t, len(pkg.Skipped), 2)
+	assert.Equal(t, pkg.Result(), testjson.ActionFail)
+	assert.Assert(t, strings.Contains(pkg.Output("TestFailed"), "this failed"))
+
+	badmain := exec.Package("github.com/gotestyourself/gotestyourself/testjson/internal/badmain")
+	assert.Equal(t, badmain.Result(), testjson.ActionFail)
+}
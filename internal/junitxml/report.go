@@ -8,8 +8,11 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -19,7 +22,14 @@ import (
 // JUnitTestSuites is a collection of JUnit test suites.
 type JUnitTestSuites struct {
 	XMLName xml.Name `xml:"testsuites"`
-	Suites  []JUnitTestSuite
+	// Tests, Failures, Errors, Skipped, and Time are the totals of all the
+	// testsuites.
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr,omitempty"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
 }
 
 // JUnitTestSuite is a single JUnit test suite which may contain many
@@ -28,20 +38,46 @@ type JUnitTestSuite struct {
 	XMLName    xml.Name        `xml:"testsuite"`
 	Tests      int             `xml:"tests,attr"`
 	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr,omitempty"`
+	Skipped    int             `xml:"skipped,attr"`
+	Assertions int             `xml:"assertions,attr,omitempty"`
 	Time       string          `xml:"time,attr"`
 	Name       string          `xml:"name,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Hostname   string          `xml:"hostname,attr,omitempty"`
 	Properties []JUnitProperty `xml:"properties>property,omitempty"`
-	TestCases  []JUnitTestCase
+	TestCases  []JUnitTestCase `xml:"testcase"`
+	// Suites are the nested testsuites of tests with subtests, written with
+	// SubtestsNested.
+	Suites    []JUnitTestSuite `xml:"testsuite,omitempty"`
+	SystemOut string           `xml:"system-out,omitempty"`
+	// pkgname is the import path of the package of a testsuite created by
+	// generate. It is empty for a testsuite which was read from a file.
+	pkgname string
 }
 
 // JUnitTestCase is a single test case with its result.
 type JUnitTestCase struct {
-	XMLName     xml.Name          `xml:"testcase"`
-	Classname   string            `xml:"classname,attr"`
-	Name        string            `xml:"name,attr"`
-	Time        string            `xml:"time,attr"`
+	XMLName    xml.Name `xml:"testcase"`
+	Classname  string   `xml:"classname,attr"`
+	Name       string   `xml:"name,attr"`
+	Time       string   `xml:"time,attr"`
+	Assertions int      `xml:"assertions,attr,omitempty"`
+	// File and Line are the location of the first failure of the test.
+	File        string            `xml:"file,attr,omitempty"`
+	Line        int               `xml:"line,attr,omitempty"`
+	Properties  *JUnitProperties  `xml:"properties,omitempty"`
 	SkipMessage *JUnitSkipMessage `xml:"skipped,omitempty"`
 	Failure     *JUnitFailure     `xml:"failure,omitempty"`
+	Error       *JUnitFailure     `xml:"error,omitempty"`
+	// FlakyFailures and RerunFailures are the failed runs of the test,
+	// written with RerunsSurefire.
+	FlakyFailures []JUnitRerunFailure `xml:"flakyFailure,omitempty"`
+	RerunFailures []JUnitRerunFailure `xml:"rerunFailure,omitempty"`
+	SystemOut     string              `xml:"system-out,omitempty"`
+	// Attrs are the custom attributes of the testcase, from
+	// Config.TestCaseAttributes.
+	Attrs []xml.Attr `xml:",any,attr"`
 }
 
 // JUnitSkipMessage contains the reason why a testcase was skipped.
@@ -49,6 +85,11 @@ type JUnitSkipMessage struct {
 	Message string `xml:"message,attr"`
 }
 
+// JUnitProperties is a list of properties of a testcase.
+type JUnitProperties struct {
+	Property []JUnitProperty `xml:"property"`
+}
+
 // JUnitProperty represents a key/value pair used to define properties.
 type JUnitProperty struct {
 	Name  string `xml:"name,attr"`
@@ -57,42 +98,581 @@ type JUnitProperty struct {
 
 // JUnitFailure contains data related to a failed test.
 type JUnitFailure struct {
-	Message  string `xml:"message,attr"`
-	Type     string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	// File and Line are the location of the first line written by t.Error
+	// or t.Fatal, when it is known.
+	File     string `xml:"file,attr,omitempty"`
+	Line     int    `xml:"line,attr,omitempty"`
 	Contents string `xml:",chardata"`
 }
 
+// DuplicatePolicy selects how testcases with the same classname and name are
+// written. Many JUnit consumers use the classname and name as a key, and
+// silently merge testcases with the same key.
+type DuplicatePolicy string
+
+const (
+	// DuplicatesWarn writes the duplicate testcases and logs a warning.
+	DuplicatesWarn DuplicatePolicy = "warn"
+	// DuplicatesSuffix adds a suffix with a count to the name of each
+	// duplicate testcase, starting at (2).
+	DuplicatesSuffix DuplicatePolicy = "suffix"
+)
+
+// SystemOut selects the elements which include the output of the tests in a
+// <system-out> element.
+type SystemOut string
+
+const (
+	// SystemOutNone writes only the output of failed and skipped tests. The
+	// output of a failed test is written to its failure element, and the
+	// output of a skipped test to its <system-out>, because the message of
+	// the skipped element is only the reason the test was skipped.
+	SystemOutNone SystemOut = "none"
+	// SystemOutTestCase writes the output of each test to its testcase.
+	SystemOutTestCase SystemOut = "testcase"
+	// SystemOutAll writes the output of each test to its testcase, and the
+	// output of each package, which is not from a test, to its testsuite.
+	SystemOutAll SystemOut = "all"
+)
+
+// PathMode selects how a package path is written as the name of a testsuite
+// and the classname of a testcase.
+type PathMode string
+
+const (
+	// PathModeRaw uses the full import path of the package.
+	PathModeRaw PathMode = "raw"
+	// PathModeRelative uses the package path relative to the module, or
+	// GOPATH, of the working directory.
+	PathModeRelative PathMode = "relative"
+	// PathModeMunged uses the full import path with each '/' replaced by a
+	// '.', for consumers which expect a Java style classname.
+	PathModeMunged PathMode = "munged"
+)
+
+// PackageNamer returns the name used for a package in the report. The same
+// name is used for the testsuite name and the classname of each testcase.
+type PackageNamer struct {
+	Mode PathMode
+}
+
+// Name returns the name of the package pkg.
+func (n PackageNamer) Name(pkg string) string {
+	switch n.Mode {
+	case PathModeRelative:
+		return testjson.RelativePackagePath(pkg)
+	case PathModeMunged:
+		return strings.Replace(pkg, "/", ".", -1)
+	default:
+		return pkg
+	}
+}
+
+// Config used by WriteWithConfig.
+type Config struct {
+	// PathMode selects the names of packages. Defaults to PathModeRaw.
+	PathMode PathMode
+	// Properties are added to the properties of every testsuite.
+	Properties []JUnitProperty
+	// Duplicates selects how duplicate testcases are written. Defaults to
+	// DuplicatesWarn.
+	Duplicates DuplicatePolicy
+	// SystemOut selects the elements which include the output of the tests.
+	// Defaults to SystemOutNone.
+	SystemOut SystemOut
+	// Hostname is the hostname attribute of every testsuite. Defaults to the
+	// hostname reported by the kernel.
+	Hostname string
+	// Timestamp, when not zero, is the timestamp attribute of every testsuite.
+	// Defaults to the time of the first event of each package.
+	Timestamp time.Time
+	// Naming replaces the classname and name of each testcase.
+	Naming Naming
+	// FailureCategories are the categories of failed tests, by
+	// testjson.TestCase.ID. The category is the type of the failure.
+	FailureCategories map[string]string
+	// InfraErrors are the packages which failed because of an error in the
+	// infrastructure, not in the tests, with the line of output which matched
+	// the error. The failures of the testcases of these packages are written
+	// as errors.
+	InfraErrors map[string]string
+	// Subtests selects how subtests are written. Defaults to SubtestsFlat.
+	Subtests SubtestMode
+	// Reruns selects how a test which was run more than once is written.
+	// Defaults to RerunsSeparate.
+	Reruns RerunMode
+	// Flaky writes the failed runs of a test which passed the last time it
+	// was run as skipped testcases, with a gotestsum.outcome property of
+	// flaky, so that the report does not fail when the run passed. It is used
+	// when the failed tests were rerun, for example with --rerun-fails. It has
+	// no effect with RerunsSurefire, which writes the failed runs as
+	// flakyFailure elements of the testcase which passed.
+	Flaky bool
+	// PathRewrite is applied to the classname, the file, and the output of
+	// each testcase, for example to replace the absolute path of the CI
+	// workspace with a path relative to the repository.
+	PathRewrite testjson.RewriteRules
+	// Sort selects the order of the testsuites and testcases. Defaults to
+	// SortNone.
+	Sort SortMode
+	// Format selects the format of the XML document. Defaults to FormatJUnit.
+	Format Format
+	// TestCaseAttributes, when set, adds custom attributes to the testcase of
+	// each test case.
+	TestCaseAttributes TestCaseAttributes
+	// Labels are the labels of each test, by testjson.TestCase.ID, which are
+	// added to the properties of the testcase. A package which failed without
+	// a failed test has the ID of the package.
+	Labels map[string]map[string]string
+	// ANSI selects how ANSI escape sequences in the output of tests are
+	// written. Defaults to ANSIStrip.
+	ANSI ANSIMode
+	// FailuresOnly writes only the testcases with a failure or an error, and
+	// the testsuites which contain them.
+	FailuresOnly bool
+	// HidePassed writes only the testcases which failed, had an error, or
+	// were skipped. Unlike FailuresOnly the counts of each testsuite include
+	// the testcases which passed.
+	HidePassed bool
+	// MaxOutputBytes, when greater than zero, is the maximum size of the
+	// output of each testcase, and of the system-out of each testsuite, after
+	// it is sanitized. Larger output keeps the start and the end.
+	MaxOutputBytes int
+}
+
 // Write creates an XML document and writes it to out.
 func Write(out io.Writer, exec *testjson.Execution) error {
-	return errors.Wrap(write(out, generate(exec)), "failed to write JUnit XML")
+	return WriteWithConfig(out, exec, Config{})
+}
+
+// WriteWithConfig creates an XML document using config, and writes it to out.
+func WriteWithConfig(out io.Writer, exec *testjson.Execution, config Config) error {
+	if config.Hostname == "" {
+		config.Hostname = hostname()
+	}
+	suites, err := buildSuites(exec, config)
+	if err != nil {
+		return err
+	}
+	if config.Format == FormatOpenTestReporting {
+		report := openTestReport(suites, config.Hostname, exec.Started())
+		return errors.Wrap(writeOpenTestReport(out, report), "failed to write Open Test Reporting XML")
+	}
+	return errors.Wrap(write(out, suites), "failed to write JUnit XML")
+}
+
+// buildSuites returns the testsuites of the packages of exec, with all the
+// options from config applied.
+func buildSuites(exec *testjson.Execution, config Config) (JUnitTestSuites, error) {
+	suites := generate(exec, PackageNamer{Mode: config.PathMode}, config.TestCaseAttributes)
+	if err := validateTestCaseAttrs(suites); err != nil {
+		return suites, err
+	}
+	for i := range suites.Suites {
+		suites.Suites[i].Properties = append(suites.Suites[i].Properties, config.Properties...)
+		suites.Suites[i].Hostname = config.Hostname
+		if !config.Timestamp.IsZero() {
+			suites.Suites[i].Timestamp = formatTimestamp(config.Timestamp)
+		}
+	}
+	addSystemOut(suites, exec, config.SystemOut)
+	addFailureCategories(suites, exec, config.FailureCategories)
+	addInfraErrors(suites, exec, config.InfraErrors)
+	addBuildFailures(suites, exec)
+	addLabels(suites, exec, config.Labels)
+	switch {
+	case config.Reruns == RerunsSurefire:
+		mergeReruns(suites)
+	case config.Flaky:
+		markFlaky(suites, exec)
+	}
+	addRetryHistory(suites, exec)
+	names := testCaseNames(suites)
+	if err := applyNaming(suites, exec, config.Naming); err != nil {
+		return suites, err
+	}
+	rewritePaths(suites, config.PathRewrite)
+	handleDuplicates(suites, config.Duplicates)
+	if config.Subtests == SubtestsNested {
+		nestSubtests(suites, names)
+	}
+	if config.FailuresOnly {
+		suites = onlyFailures(suites)
+	}
+	if config.HidePassed {
+		hidePassed(suites.Suites)
+	}
+	addTotals(&suites)
+	sortSuites(suites.Suites, config.Sort)
+	sanitize(suites, config.ANSI)
+	truncateOutput(suites, config.MaxOutputBytes)
+	return suites, nil
+}
+
+// addSystemOut adds the output of each test to its testcase, and with
+// SystemOutAll the output of each package to its testsuite. It must be called
+// before handleDuplicates changes the names of the testcases.
+func addSystemOut(suites JUnitTestSuites, exec *testjson.Execution, mode SystemOut) {
+	if mode != SystemOutTestCase && mode != SystemOutAll {
+		return
+	}
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		pkg := exec.Package(suite.pkgname)
+		for j := range suite.TestCases {
+			tc := &suite.TestCases[j]
+			tc.SystemOut = pkg.Output(tc.Name)
+		}
+		if mode == SystemOutAll {
+			suite.SystemOut = pkg.Output("")
+		}
+	}
 }
 
-func generate(exec *testjson.Execution) JUnitTestSuites {
+// infraErrorType is the type of the error of a testcase of a package which
+// failed because of an infrastructure error.
+const infraErrorType = "infrastructure"
+
+// addFailureCategories sets the type of the failure, or the error, of each
+// testcase to the category of the failed test. The category of a package
+// which failed in init or TestMain is set on its TestMain testcase. It must be
+// called before the names of the testcases are changed.
+func addFailureCategories(suites JUnitTestSuites, exec *testjson.Execution, categories map[string]string) {
+	if len(categories) == 0 {
+		return
+	}
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		for j := range suite.TestCases {
+			tc := &suite.TestCases[j]
+			failure := tc.Failure
+			if failure == nil {
+				failure = tc.Error
+			}
+			if failure == nil {
+				continue
+			}
+			test := tc.Name
+			if test == "TestMain" {
+				test = ""
+			}
+			id := testjson.TestCase{Package: suite.pkgname, Test: test}.ID()
+			if category := categories[id]; category != "" {
+				failure.Type = category
+			}
+		}
+	}
+}
+
+// addInfraErrors writes the failures of the testcases of the packages in
+// infraErrors as errors, with the line of output which matched the
+// infrastructure error as the message. It must be called before the testcases
+// are merged or nested.
+func addInfraErrors(suites JUnitTestSuites, exec *testjson.Execution, infraErrors map[string]string) {
+	if len(infraErrors) == 0 {
+		return
+	}
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		line, ok := infraErrors[suite.pkgname]
+		if !ok {
+			continue
+		}
+		for j := range suite.TestCases {
+			tc := &suite.TestCases[j]
+			if tc.Failure != nil {
+				tc.Error, tc.Failure = tc.Failure, nil
+			}
+			if tc.Error == nil {
+				continue
+			}
+			tc.Error.Type = infraErrorType
+			tc.Error.Message = "infrastructure error: " + line
+		}
+	}
+}
+
+// addBuildFailures writes the output of a build failure shared by more than
+// one package only once, to the error of the first package, followed by the
+// names of the other packages. The error of each other package refers to the
+// first package, and the system-out of its testsuite is removed, because it is
+// the same output. Every package still has an error, so that it is reported as
+// failed. It must be called before the testcases are merged or nested.
+func addBuildFailures(suites JUnitTestSuites, exec *testjson.Execution) {
+	byPackage := make(map[string]*JUnitTestSuite)
+	for i := range suites.Suites {
+		byPackage[suites.Suites[i].pkgname] = &suites.Suites[i]
+	}
+	for _, failure := range exec.BuildFailures() {
+		if len(failure.Packages) < 2 {
+			continue
+		}
+		first, others := failure.Packages[0], failure.Packages[1:]
+		if tc := buildErrorTestCase(byPackage[first]); tc != nil {
+			tc.Error.Contents += fmt.Sprintf("\nThe same build failure in %d more packages: %s\n",
+				len(others), strings.Join(others, ", "))
+		}
+		for _, pkgname := range others {
+			suite := byPackage[pkgname]
+			tc := buildErrorTestCase(suite)
+			if tc == nil {
+				continue
+			}
+			tc.Error.Message = "Build failed: the same build failure as " + first
+			tc.Error.Contents = tc.Error.Message + "\n"
+			tc.SystemOut = ""
+			suite.SystemOut = ""
+		}
+	}
+}
+
+// buildErrorTestCase returns the testcase of the suite with the error of a
+// package which could not be built, or nil if there is no such testcase, or
+// no suite.
+func buildErrorTestCase(suite *JUnitTestSuite) *JUnitTestCase {
+	if suite == nil {
+		return nil
+	}
+	for i := range suite.TestCases {
+		tc := &suite.TestCases[i]
+		if tc.Error != nil && tc.Error.Type == "build" {
+			return tc
+		}
+	}
+	return nil
+}
+
+// addLabels adds the labels of each test to the properties of its testcases,
+// sorted by name. It must be called before the names of the testcases are
+// changed.
+func addLabels(suites JUnitTestSuites, exec *testjson.Execution, labels map[string]map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		for j := range suite.TestCases {
+			tc := &suite.TestCases[j]
+			test := tc.Name
+			if test == "TestMain" {
+				test = ""
+			}
+			testLabels := labels[testjson.TestCase{Package: suite.pkgname, Test: test}.ID()]
+			if len(testLabels) == 0 {
+				continue
+			}
+			names := make([]string, 0, len(testLabels))
+			for name := range testLabels {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if tc.Properties == nil {
+				tc.Properties = &JUnitProperties{}
+			}
+			for _, name := range names {
+				tc.Properties.Property = append(tc.Properties.Property,
+					JUnitProperty{Name: name, Value: testLabels[name]})
+			}
+		}
+	}
+}
+
+// addTotals counts the testcases of each testsuite, and sets the totals of all
+// the testsuites.
+func addTotals(suites *JUnitTestSuites) {
+	suites.Tests, suites.Failures, suites.Errors, suites.Skipped = 0, 0, 0, 0
+	var elapsed float64
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		countTestCases(suite)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+		if seconds, err := strconv.ParseFloat(suite.Time, 64); err == nil {
+			elapsed += seconds
+		}
+	}
+	suites.Time = fmt.Sprintf("%f", elapsed)
+}
+
+// countTestCases sets the failures, errors, skipped, and assertions of suite,
+// and of its nested testsuites, from their testcases. Tests is not changed,
+// because it also counts tests which do not have a testcase, like the passed
+// tests removed by Config.HidePassed.
+func countTestCases(suite *JUnitTestSuite) {
+	suite.Failures, suite.Errors, suite.Skipped, suite.Assertions = 0, 0, 0, 0
+	for _, tc := range suite.TestCases {
+		suite.Assertions += tc.Assertions
+		switch {
+		case tc.Error != nil:
+			suite.Errors++
+		case tc.Failure != nil:
+			suite.Failures++
+		case tc.SkipMessage != nil:
+			suite.Skipped++
+		}
+	}
+	for i := range suite.Suites {
+		nested := &suite.Suites[i]
+		countTestCases(nested)
+		suite.Failures += nested.Failures
+		suite.Errors += nested.Errors
+		suite.Skipped += nested.Skipped
+		suite.Assertions += nested.Assertions
+	}
+}
+
+// hidePassed removes the testcases which passed from each testsuite, and the
+// nested testsuites which are left empty. A testcase which passed after it
+// failed, with a flakyFailure from RerunsSurefire, is kept. The counts are not
+// changed.
+func hidePassed(suites []JUnitTestSuite) {
+	for i := range suites {
+		suite := &suites[i]
+		var cases []JUnitTestCase
+		for _, tc := range suite.TestCases {
+			if tc.Failure != nil || tc.Error != nil || tc.SkipMessage != nil || len(tc.FlakyFailures) > 0 {
+				cases = append(cases, tc)
+			}
+		}
+		suite.TestCases = cases
+
+		hidePassed(suite.Suites)
+		var nested []JUnitTestSuite
+		for _, n := range suite.Suites {
+			if len(n.TestCases) > 0 || len(n.Suites) > 0 {
+				nested = append(nested, n)
+			}
+		}
+		suite.Suites = nested
+	}
+}
+
+// onlyFailures returns the testsuites with only the testcases which have a
+// failure or an error. The tests of each testsuite are the testcases which
+// remain.
+func onlyFailures(suites JUnitTestSuites) JUnitTestSuites {
+	suites.Suites = failedSuites(suites.Suites)
+	return suites
+}
+
+func failedSuites(suites []JUnitTestSuite) []JUnitTestSuite {
+	var result []JUnitTestSuite
+	for _, suite := range suites {
+		var cases []JUnitTestCase
+		for _, tc := range suite.TestCases {
+			if tc.Failure != nil || tc.Error != nil {
+				cases = append(cases, tc)
+			}
+		}
+		suite.TestCases = cases
+		suite.Suites = failedSuites(suite.Suites)
+		if len(suite.TestCases) == 0 && len(suite.Suites) == 0 {
+			continue
+		}
+
+		suite.Tests = len(suite.TestCases)
+		for _, nested := range suite.Suites {
+			suite.Tests += nested.Tests
+		}
+		result = append(result, suite)
+	}
+	return result
+}
+
+// handleDuplicates finds testcases with the same classname and name. A test
+// may be run more than once in a package, for example with -count or
+// --rerun-fails.
+func handleDuplicates(suites JUnitTestSuites, policy DuplicatePolicy) {
+	seen := make(map[[2]string]int)
+	for i := range suites.Suites {
+		cases := suites.Suites[i].TestCases
+		for j := range cases {
+			key := [2]string{cases[j].Classname, cases[j].Name}
+			seen[key]++
+			count := seen[key]
+			switch {
+			case count == 1:
+			case policy == DuplicatesSuffix:
+				cases[j].Name = fmt.Sprintf("%s (%d)", cases[j].Name, count)
+			case count == 2:
+				logrus.Warnf("duplicate JUnit testcase %s %s", key[0], key[1])
+			}
+		}
+	}
+}
+
+func generate(exec *testjson.Execution, namer PackageNamer, attributes TestCaseAttributes) JUnitTestSuites {
 	version := goVersion()
 	suites := JUnitTestSuites{}
+	notRun := make(map[string][]testjson.TestCase)
+	for _, tc := range exec.NotRun() {
+		notRun[tc.Package] = append(notRun[tc.Package], tc)
+	}
 	for _, pkgname := range exec.Packages() {
 		pkg := exec.Package(pkgname)
+		name := namer.Name(pkgname)
 		junitpkg := JUnitTestSuite{
-			Name:       pkgname,
+			pkgname:    pkgname,
+			Name:       name,
 			Tests:      pkg.Total,
 			Time:       formatDurationAsSeconds(pkg.Elapsed()),
-			Properties: packageProperties(version),
-			TestCases:  packageTestCases(pkg),
-			Failures:   len(pkg.Failed),
+			Timestamp:  formatTimestamp(pkg.Started()),
+			Properties: packageProperties(version, pkg),
+			TestCases: append(packageTestCases(pkgname, pkg, name, attributes),
+				notRunTestCases(notRun[pkgname], name, attributes)...),
 		}
+		junitpkg.Tests += testMainCases(pkg, notRun[pkgname])
+		countTestCases(&junitpkg)
 		suites.Suites = append(suites.Suites, junitpkg)
 	}
 	return suites
 }
 
+// testMainCases returns the number of TestMain testcases added for a package
+// which failed without a failed test, or which did not run. They are not
+// counted in pkg.Total, because no test ran.
+func testMainCases(pkg *testjson.Package, notRun []testjson.TestCase) int {
+	count := 0
+	if pkg.TestMainFailed() {
+		count++
+	}
+	for _, tc := range notRun {
+		// tests which started are already counted in pkg.Total
+		if tc.Test == "" {
+			count++
+		}
+	}
+	return count
+}
+
 func formatDurationAsSeconds(d time.Duration) string {
 	return fmt.Sprintf("%f", d.Seconds())
 }
 
-func packageProperties(goVersion string) []JUnitProperty {
-	return []JUnitProperty{
+// formatTimestamp returns t in UTC, in the ISO 8601 format without a time zone
+// used by the JUnit XML schema. Returns an empty string for the zero time.
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02T15:04:05")
+}
+
+func packageProperties(goVersion string, pkg *testjson.Package) []JUnitProperty {
+	properties := []JUnitProperty{
 		{Name: "go.version", Value: goVersion},
 	}
+	if pct, ok := pkg.Coverage(); ok {
+		properties = append(properties, JUnitProperty{
+			Name:  "coverage.statements.pct",
+			Value: strconv.FormatFloat(pct, 'f', 1, 64),
+		})
+	}
+	return properties
 }
 
 // goVersion returns the version as reported by the go binary in PATH. This
@@ -115,48 +695,210 @@ func goVersion() string {
 	return strings.TrimPrefix(strings.TrimSpace(string(out)), "go version ")
 }
 
-func packageTestCases(pkg *testjson.Package) []JUnitTestCase {
+// hostname returns the hostname reported by the kernel, or an empty string
+// if it can not be found.
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		logrus.WithError(err).Warn("failed to lookup hostname for junit xml")
+		return ""
+	}
+	return name
+}
+
+func packageTestCases(pkgname string, pkg *testjson.Package, classname string, attributes TestCaseAttributes) []JUnitTestCase {
 	cases := []JUnitTestCase{}
 
 	if pkg.TestMainFailed() {
-		jtc := newJUnitTestCase(testjson.TestCase{
-			Test: "TestMain",
-		})
-		jtc.Failure = &JUnitFailure{
-			Message:  "Failed",
-			Contents: pkg.Output(""),
-		}
+		jtc := newJUnitTestCase(testjson.TestCase{Package: pkgname, Test: "TestMain"}, classname, attributes)
+		jtc.Error = packageError(pkg)
 		cases = append(cases, jtc)
 	}
 
+	crashed := make(map[string]bool)
+	if crash := pkg.Crash(); crash != nil {
+		for _, test := range crash.Tests {
+			crashed[test] = true
+		}
+	}
 	for _, tc := range pkg.Failed {
-		jtc := newJUnitTestCase(tc)
+		jtc := newJUnitTestCase(tc, classname, attributes)
+		jtc.Assertions, _ = pkg.Assertions(tc.Test)
+		if crashed[tc.Test] {
+			jtc.Error = crashError(pkg.Output(tc.Test))
+			jtc.Properties = crashProperties(pkg.Crash())
+			cases = append(cases, jtc)
+			continue
+		}
 		jtc.Failure = &JUnitFailure{
 			Message:  "Failed",
 			Contents: pkg.Output(tc.Test),
 		}
+		jtc.Failure.File, jtc.Failure.Line = failureLocation(pkgname, jtc.Failure.Contents)
+		jtc.File, jtc.Line = jtc.Failure.File, jtc.Failure.Line
 		cases = append(cases, jtc)
 	}
 
 	for _, tc := range pkg.Skipped {
-		jtc := newJUnitTestCase(tc)
-		jtc.SkipMessage = &JUnitSkipMessage{Message: pkg.Output(tc.Test)}
+		jtc := newJUnitTestCase(tc, classname, attributes)
+		jtc.Assertions, _ = pkg.Assertions(tc.Test)
+		output := pkg.Output(tc.Test)
+		jtc.SkipMessage = &JUnitSkipMessage{Message: testjson.SkipReason(output)}
+		jtc.SystemOut = output
 		cases = append(cases, jtc)
 	}
 
 	for _, tc := range pkg.Passed {
-		jtc := newJUnitTestCase(tc)
+		jtc := newJUnitTestCase(tc, classname, attributes)
+		jtc.Assertions, _ = pkg.Assertions(tc.Test)
+		if result, ok := pkg.Benchmark(tc.Test); ok {
+			addBenchmarkResult(&jtc, result)
+		}
+		cases = append(cases, jtc)
+	}
+	return cases
+}
+
+// packageError returns the error of a package which failed without a failed
+// test. A package which could not be built has an error with a type of build,
+// and a package where init or TestMain failed has an error with a type of
+// setup, so that they can be reported separately from failed tests.
+func packageError(pkg *testjson.Package) *JUnitFailure {
+	if pkg.BuildFailed() {
+		return &JUnitFailure{
+			Message:  "Build failed",
+			Type:     "build",
+			Contents: pkg.Output(""),
+		}
+	}
+	return &JUnitFailure{
+		Message:  "Failed in init or TestMain",
+		Type:     "setup",
+		Contents: pkg.Output(""),
+	}
+}
+
+func crashError(output string) *JUnitFailure {
+	return &JUnitFailure{
+		Message:  "Crashed: the test binary exited while the test was running",
+		Type:     "crash",
+		Contents: output,
+	}
+}
+
+// crashProperties returns the gotestsum.core-file property when the core
+// file of the crash is known.
+func crashProperties(crash *testjson.Crash) *JUnitProperties {
+	if crash.CoreFile == "" {
+		return nil
+	}
+	return &JUnitProperties{Property: []JUnitProperty{
+		{Name: "gotestsum.core-file", Value: crash.CoreFile},
+	}}
+}
+
+// notRunTestCases returns a skipped testcase, with a gotestsum.outcome
+// property of notrun, for each test case which did not finish. A package which
+// did not run is reported as a TestMain testcase.
+func notRunTestCases(notRun []testjson.TestCase, classname string, attributes TestCaseAttributes) []JUnitTestCase {
+	cases := make([]JUnitTestCase, 0, len(notRun))
+	for _, tc := range notRun {
+		if tc.Test == "" {
+			tc.Test = "TestMain"
+		}
+		jtc := newJUnitTestCase(tc, classname, attributes)
+		jtc.SkipMessage = &JUnitSkipMessage{Message: "not run: the test did not finish"}
+		jtc.Properties = &JUnitProperties{Property: []JUnitProperty{
+			{Name: "gotestsum.outcome", Value: string(testjson.ActionNotRun)},
+		}}
 		cases = append(cases, jtc)
 	}
 	return cases
 }
 
-func newJUnitTestCase(tc testjson.TestCase) JUnitTestCase {
+func newJUnitTestCase(tc testjson.TestCase, classname string, attributes TestCaseAttributes) JUnitTestCase {
 	return JUnitTestCase{
-		Classname: tc.Package,
+		Classname: classname,
 		Name:      tc.Test,
 		Time:      formatDurationAsSeconds(tc.Elapsed),
+		Attrs:     attributes.attrs(tc),
+	}
+}
+
+// TestCaseAttributes returns the custom attributes of the testcase of tc, for
+// example the owner, shard, or tags of the test, so that a program which
+// embeds gotestsum can add them without changing how the XML is written. The
+// attributes are written in the order of their names. An attribute with the
+// name of an attribute written by gotestsum, like name or time, is ignored.
+// The name of every other attribute must be a valid XML name without a
+// namespace, otherwise the report is not written.
+//
+// Custom attributes are not part of the JUnit schema used by ValidateSchema.
+type TestCaseAttributes func(tc testjson.TestCase) map[string]string
+
+// testCaseAttrNames are the attributes of a testcase written by gotestsum.
+var testCaseAttrNames = map[string]bool{
+	"classname": true, "name": true, "time": true, "assertions": true, "file": true, "line": true,
+}
+
+func (f TestCaseAttributes) attrs(tc testjson.TestCase) []xml.Attr {
+	if f == nil {
+		return nil
+	}
+	values := f(tc)
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if testCaseAttrNames[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	attrs := make([]xml.Attr, 0, len(names))
+	for _, name := range names {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: name}, Value: values[name]})
+	}
+	return attrs
+}
+
+// validateTestCaseAttrs returns an error if the name of a custom attribute of
+// a testcase is not a valid XML name, so that a name with a space or a quote
+// can not produce a document which can not be read. It must be called before
+// the testcases are nested.
+func validateTestCaseAttrs(suites JUnitTestSuites) error {
+	for _, suite := range suites.Suites {
+		for _, tc := range suite.TestCases {
+			for _, attr := range tc.Attrs {
+				if !isXMLName(attr.Name.Local) {
+					return errors.Errorf("testcase %s %s: invalid attribute name %q from TestCaseAttributes",
+						tc.Classname, tc.Name, attr.Name.Local)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isXMLName returns true if name is an XML name without a namespace: a letter
+// or underscore, followed by letters, digits, underscores, hyphens, or
+// periods. A name which starts with xmlns declares a namespace, so it is not
+// an attribute of the testcase.
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xmlns") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
 	}
+	return true
 }
 
 func write(out io.Writer, suites JUnitTestSuites) error {
@@ -6,8 +6,11 @@ import (
 	"io"
 	"io/ioutil"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"gotest.tools/assert"
 	"gotest.tools/env"
 	"gotest.tools/golden"
@@ -19,11 +22,489 @@ func TestWrite(t *testing.T) {
 	exec := createExecution(t)
 
 	defer env.Patch(t, "GOVERSION", "go7.7.7")()
-	err := Write(out, exec)
+	err := WriteWithConfig(out, exec, Config{Hostname: "ci-runner-1"})
 	assert.NilError(t, err)
 	golden.Assert(t, out.String(), "junitxml-report.golden")
 }
 
+func TestWriteWithConfig_Timestamp(t *testing.T) {
+	exec := createExecution(t)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	timestamp := time.Date(2021, 2, 3, 4, 5, 6, 0, time.FixedZone("EST", -5*3600))
+	assert.NilError(t, WriteWithConfig(out, exec, Config{Timestamp: timestamp}))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	for _, suite := range suites.Suites {
+		assert.Equal(t, suite.Timestamp, "2021-02-03T09:05:06")
+		assert.Assert(t, suite.Hostname != "")
+	}
+}
+
+func TestWrite_NotRun(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestHangs"}
+`),
+		Stderr:          strings.NewReader(""),
+		Handler:         &noopHandler{},
+		PlannedPackages: []string{"example.com/pkg", "example.com/other"},
+	})
+	assert.NilError(t, err)
+
+	suites := generate(exec, PackageNamer{}, nil)
+	assert.Equal(t, len(suites.Suites), 2)
+	for _, suite := range suites.Suites {
+		assert.Equal(t, len(suite.TestCases), 1)
+		assert.Equal(t, suite.Tests, 1)
+		tc := suite.TestCases[0]
+		assert.Assert(t, tc.SkipMessage != nil)
+		assert.DeepEqual(t, tc.Properties.Property, []JUnitProperty{
+			{Name: "gotestsum.outcome", Value: "notrun"},
+		})
+	}
+	assert.Equal(t, suites.Suites[0].TestCases[0].Name, "TestMain")
+	assert.Equal(t, suites.Suites[1].TestCases[0].Name, "TestHangs")
+}
+
+func TestWrite_BuildFailed(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"./broken_test.go:5:2: undefined: missing\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-fail"}
+{"Action":"output","Package":"example.com/broken","Output":"FAIL\texample.com/broken [build failed]\n"}
+{"Action":"fail","Package":"example.com/broken","Elapsed":0,"FailedBuild":"example.com/broken [example.com/broken.test]"}
+{"Action":"run","Package":"example.com/good","Test":"TestFails"}
+{"Action":"fail","Package":"example.com/good","Test":"TestFails"}
+{"Action":"fail","Package":"example.com/good"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+
+	suites := generate(exec, PackageNamer{}, nil)
+	assert.Equal(t, len(suites.Suites), 2)
+	broken := suites.Suites[0]
+	assert.Equal(t, broken.Tests, 1)
+	assert.Equal(t, broken.Errors, 1)
+	assert.Equal(t, broken.Failures, 0)
+	assert.Equal(t, broken.TestCases[0].Error.Type, "build")
+	assert.Assert(t, strings.Contains(broken.TestCases[0].Error.Contents, "undefined: missing"))
+
+	good := suites.Suites[1]
+	assert.Equal(t, good.Errors, 0)
+	assert.Equal(t, good.Failures, 1)
+}
+
+func TestWriteWithConfig_SameBuildFailure(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"ImportPath":"example.com/core","Action":"build-output","Output":"# example.com/core\n"}
+{"ImportPath":"example.com/core","Action":"build-output","Output":"core/core.go:5:2: undefined: missing\n"}
+{"ImportPath":"example.com/core","Action":"build-fail"}
+{"Action":"output","Package":"example.com/api","Output":"FAIL\texample.com/api [build failed]\n"}
+{"Action":"fail","Package":"example.com/api","FailedBuild":"example.com/core"}
+{"Action":"output","Package":"example.com/db","Output":"FAIL\texample.com/db [build failed]\n"}
+{"Action":"fail","Package":"example.com/db","FailedBuild":"example.com/core"}
+{"Action":"output","Package":"example.com/web","Output":"FAIL\texample.com/web [build failed]\n"}
+{"Action":"fail","Package":"example.com/web","FailedBuild":"example.com/core"}
+`))
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{SystemOut: SystemOutAll}))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	assert.Equal(t, len(suites.Suites), 3)
+
+	api := suites.Suites[0]
+	assert.Equal(t, api.Errors, 1)
+	assert.Equal(t, api.TestCases[0].Error.Contents, `# example.com/core
+core/core.go:5:2: undefined: missing
+FAIL	example.com/api [build failed]
+
+The same build failure in 2 more packages: example.com/db, example.com/web
+`)
+	assert.Assert(t, api.SystemOut != "")
+
+	for _, suite := range suites.Suites[1:] {
+		assert.Equal(t, suite.Errors, 1)
+		assert.Equal(t, suite.TestCases[0].Error.Type, "build")
+		assert.Equal(t, suite.TestCases[0].Error.Message,
+			"Build failed: the same build failure as example.com/api")
+		assert.Equal(t, suite.SystemOut, "")
+	}
+}
+
+func TestWriteWithConfig_FailureCategories(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestDB"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestDB"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestMath"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestMath"}
+{"Action":"fail","Package":"example.com/pkg"}
+{"Action":"output","Package":"example.com/setup","Output":"panic: no database\n"}
+{"Action":"fail","Package":"example.com/setup"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	config := Config{FailureCategories: map[string]string{
+		"example.com/pkg#TestDB": "infra",
+		"example.com/setup#":     "infra",
+	}}
+	assert.NilError(t, WriteWithConfig(out, exec, config))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	cases := suites.Suites[0].TestCases
+	assert.Equal(t, cases[0].Name, "TestDB")
+	assert.Equal(t, cases[0].Failure.Type, "infra")
+	assert.Equal(t, cases[1].Failure.Type, "")
+	setup := suites.Suites[1].TestCases
+	assert.Equal(t, setup[0].Name, "TestMain")
+	assert.Equal(t, setup[0].Error.Type, "infra")
+}
+
+func TestWriteWithConfig_InfraErrors(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/db","Test":"TestQuery"}
+{"Action":"output","Package":"example.com/db","Test":"TestQuery","Output":"connection refused\n"}
+{"Action":"fail","Package":"example.com/db","Test":"TestQuery"}
+{"Action":"run","Package":"example.com/db","Test":"TestSchema"}
+{"Action":"pass","Package":"example.com/db","Test":"TestSchema"}
+{"Action":"fail","Package":"example.com/db"}
+{"Action":"run","Package":"example.com/math","Test":"TestAdd"}
+{"Action":"fail","Package":"example.com/math","Test":"TestAdd"}
+{"Action":"fail","Package":"example.com/math"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	config := Config{InfraErrors: map[string]string{"example.com/db": "connection refused"}}
+	assert.NilError(t, WriteWithConfig(out, exec, config))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	assert.Equal(t, suites.Failures, 1)
+	assert.Equal(t, suites.Errors, 1)
+
+	db := suites.Suites[0]
+	assert.Equal(t, db.Failures, 0)
+	assert.Equal(t, db.Errors, 1)
+	assert.Assert(t, db.TestCases[0].Failure == nil)
+	assert.Equal(t, db.TestCases[0].Error.Type, "infrastructure")
+	assert.Equal(t, db.TestCases[0].Error.Message, "infrastructure error: connection refused")
+	assert.Assert(t, db.TestCases[1].Error == nil)
+
+	math := suites.Suites[1]
+	assert.Equal(t, math.Failures, 1)
+	assert.Assert(t, math.TestCases[0].Error == nil)
+
+	// the package of each testsuite does not depend on the order of the
+	// testsuites
+	generated := generate(exec, PackageNamer{}, nil)
+	generated.Suites[0], generated.Suites[1] = generated.Suites[1], generated.Suites[0]
+	addInfraErrors(generated, exec, config.InfraErrors)
+	assert.Equal(t, generated.Suites[0].Name, "example.com/math")
+	assert.Assert(t, generated.Suites[0].TestCases[0].Error == nil)
+	assert.Equal(t, generated.Suites[1].TestCases[0].Error.Type, "infrastructure")
+}
+
+func TestWriteWithConfig_Labels(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestDB"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestDB"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestMath"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestMath"}
+{"Action":"fail","Package":"example.com/pkg"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	config := Config{Labels: map[string]map[string]string{
+		"example.com/pkg#TestDB": {
+			"gotestsum.label.priority":         "critical",
+			"gotestsum.label.failure-category": "infra",
+		},
+	}}
+	assert.NilError(t, WriteWithConfig(out, exec, config))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	cases := suites.Suites[0].TestCases
+	assert.Equal(t, cases[0].Name, "TestDB")
+	assert.DeepEqual(t, cases[0].Properties, &JUnitProperties{Property: []JUnitProperty{
+		{Name: "gotestsum.label.failure-category", Value: "infra"},
+		{Name: "gotestsum.label.priority", Value: "critical"},
+	}})
+	assert.Assert(t, cases[1].Properties == nil)
+}
+
+func TestWriteWithConfig_FailuresOnly(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestDB"}
+{"Action":"output","Package":"example.com/a","Test":"TestDB","Output":"    db_test.go:9: gotestsum: assertions=3\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestDB"}
+{"Action":"run","Package":"example.com/a","Test":"TestMath"}
+{"Action":"pass","Package":"example.com/a","Test":"TestMath"}
+{"Action":"run","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"fail","Package":"example.com/a"}
+{"Action":"run","Package":"example.com/b","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/b","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/b"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{FailuresOnly: true}))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	assert.Equal(t, len(suites.Suites), 1)
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Name, "example.com/a")
+	assert.Equal(t, suite.Tests, 1)
+	assert.Equal(t, suite.Failures, 1)
+	assert.Equal(t, suite.Assertions, 3)
+	assert.Equal(t, len(suite.TestCases), 1)
+	assert.Equal(t, suite.TestCases[0].Name, "TestDB")
+}
+
+func TestWriteWithConfig_HidePassed(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestDB"}
+{"Action":"fail","Package":"example.com/a","Test":"TestDB"}
+{"Action":"run","Package":"example.com/a","Test":"TestMath"}
+{"Action":"pass","Package":"example.com/a","Test":"TestMath"}
+{"Action":"run","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"fail","Package":"example.com/a"}
+{"Action":"run","Package":"example.com/b","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/b","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/b"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{HidePassed: true}))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	assert.Equal(t, suites.Tests, 4)
+	assert.Equal(t, len(suites.Suites), 2)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 3)
+	assert.Equal(t, suite.Failures, 1)
+	assert.Equal(t, suite.Skipped, 1)
+	assert.Equal(t, len(suite.TestCases), 2)
+	assert.Equal(t, suite.TestCases[0].Name, "TestDB")
+	assert.Equal(t, suite.TestCases[1].Name, "TestSkip")
+
+	suite = suites.Suites[1]
+	assert.Equal(t, suite.Tests, 1)
+	assert.Equal(t, len(suite.TestCases), 0)
+}
+
+func TestWriteWithConfig_HidePassedKeepsFlaky(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestFlaky"}
+{"Action":"fail","Package":"example.com/a","Test":"TestFlaky"}
+{"Action":"run","Package":"example.com/a","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOK"}
+{"Action":"fail","Package":"example.com/a"}
+{"Action":"run","Package":"example.com/a","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/a","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/a"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{HidePassed: true, Reruns: RerunsSurefire}))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Failures, 0)
+	assert.Equal(t, len(suite.TestCases), 1)
+	assert.Equal(t, suite.TestCases[0].Name, "TestFlaky")
+	assert.Equal(t, len(suite.TestCases[0].FlakyFailures), 1)
+}
+
+func TestWrite_Coverage(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOne"}
+{"Action":"output","Package":"example.com/a","Output":"coverage: 71.42% of statements\n"}
+{"Action":"pass","Package":"example.com/a"}
+{"Action":"run","Package":"example.com/b","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/b","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/b"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, Write(out, exec))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, suites.Suites[0].Properties, []JUnitProperty{
+		{Name: "go.version", Value: "go7.7.7"},
+		{Name: "coverage.statements.pct", Value: "71.4"},
+	})
+	assert.DeepEqual(t, suites.Suites[1].Properties, []JUnitProperty{
+		{Name: "go.version", Value: "go7.7.7"},
+	})
+}
+
+func TestWriteWithConfig_Totals(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestDB"}
+{"Action":"fail","Package":"example.com/a","Test":"TestDB","Elapsed":0.5}
+{"Action":"run","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"fail","Package":"example.com/a","Elapsed":0.6}
+{"Action":"run","Package":"example.com/b","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/b","Test":"TestOK"}
+{"Action":"run","Package":"example.com/b","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/b","Test":"TestSkip"}
+{"Action":"pass","Package":"example.com/b","Elapsed":0.2}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{}))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	assert.Equal(t, suites.Tests, 4)
+	assert.Equal(t, suites.Failures, 1)
+	assert.Equal(t, suites.Errors, 0)
+	assert.Equal(t, suites.Skipped, 2)
+	assert.Equal(t, suites.Time, "0.500000")
+	assert.Equal(t, len(suites.Suites), 2)
+	assert.Equal(t, suites.Suites[0].Skipped, 1)
+	assert.Equal(t, suites.Suites[1].Skipped, 1)
+}
+
+func TestWriteWithConfig_SystemOut(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"    one_test.go:9: warning: slow <response>\n"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestSkip"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestSkip","Output":"    skip_test.go:5: requires docker\n"}
+{"Action":"skip","Package":"example.com/pkg","Test":"TestSkip"}
+{"Action":"output","Package":"example.com/pkg","Output":"PASS\n"}
+{"Action":"pass","Package":"example.com/pkg"}
+`),
+		Stderr:           strings.NewReader(""),
+		Handler:          &noopHandler{},
+		KeepPassedOutput: true,
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	read := func(mode SystemOut) JUnitTestSuite {
+		out := new(bytes.Buffer)
+		assert.NilError(t, WriteWithConfig(out, exec, Config{SystemOut: mode}))
+		suites, err := Read(out)
+		assert.NilError(t, err)
+		assert.Equal(t, len(suites.Suites), 1)
+		return suites.Suites[0]
+	}
+
+	// the skipped testcase is before the passed testcase
+	suite := read(SystemOutNone)
+	assert.Equal(t, suite.TestCases[1].SystemOut, "")
+	assert.Equal(t, suite.TestCases[0].Name, "TestSkip")
+	assert.Equal(t, suite.TestCases[0].SystemOut, "    skip_test.go:5: requires docker\n")
+
+	suite = read(SystemOutTestCase)
+	assert.Equal(t, suite.TestCases[1].SystemOut,
+		"=== RUN   TestOne\n    one_test.go:9: warning: slow <response>\n")
+	assert.Equal(t, suite.SystemOut, "")
+
+	suite = read(SystemOutAll)
+	assert.Equal(t, suite.SystemOut, "PASS\n")
+}
+
+func TestPackageNamer(t *testing.T) {
+	pkg := "gotest.tools/gotestsum/internal/junitxml"
+	assert.Equal(t, PackageNamer{}.Name(pkg), pkg)
+	assert.Equal(t, PackageNamer{Mode: PathModeRaw}.Name(pkg), pkg)
+	assert.Equal(t, PackageNamer{Mode: PathModeRelative}.Name(pkg), "internal/junitxml")
+	assert.Equal(t, PackageNamer{Mode: PathModeMunged}.Name(pkg),
+		"gotest.tools.gotestsum.internal.junitxml")
+}
+
+func TestHandleDuplicates(t *testing.T) {
+	newSuites := func() JUnitTestSuites {
+		return JUnitTestSuites{Suites: []JUnitTestSuite{
+			{TestCases: []JUnitTestCase{
+				{Classname: "pkg/a", Name: "TestFlaky"},
+				{Classname: "pkg/a", Name: "TestOther"},
+				{Classname: "pkg/a", Name: "TestFlaky"},
+				{Classname: "pkg/a", Name: "TestFlaky"},
+			}},
+			{TestCases: []JUnitTestCase{
+				{Classname: "pkg/b", Name: "TestFlaky"},
+			}},
+		}}
+	}
+	names := func(suites JUnitTestSuites) []string {
+		var names []string
+		for _, suite := range suites.Suites {
+			for _, tc := range suite.TestCases {
+				names = append(names, tc.Classname+" "+tc.Name)
+			}
+		}
+		return names
+	}
+
+	suites := newSuites()
+	handleDuplicates(suites, DuplicatesWarn)
+	assert.DeepEqual(t, suites, newSuites(), cmpopts.IgnoreUnexported(JUnitTestSuite{}))
+
+	suites = newSuites()
+	handleDuplicates(suites, DuplicatesSuffix)
+	expected := []string{
+		"pkg/a TestFlaky",
+		"pkg/a TestOther",
+		"pkg/a TestFlaky (2)",
+		"pkg/a TestFlaky (3)",
+		"pkg/b TestFlaky",
+	}
+	assert.DeepEqual(t, names(suites), expected)
+}
+
 func createExecution(t *testing.T) *testjson.Execution {
 	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
 		Stdout:  readTestData(t, "out"),
@@ -61,3 +542,54 @@ func TestGoVersion(t *testing.T) {
 		assert.Equal(t, goVersion(), expected)
 	})
 }
+
+func TestWriteWithConfig_TestCaseAttributes(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestTwo"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestTwo"}
+{"Action":"pass","Package":"example.com/pkg"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	var calls []testjson.TestCase
+	config := Config{
+		TestCaseAttributes: func(tc testjson.TestCase) map[string]string {
+			calls = append(calls, tc)
+			if tc.Test != "TestOne" {
+				return nil
+			}
+			return map[string]string{"shard": "2", "owner": "team-a", "name": "ignored"}
+		},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, config))
+	assert.Equal(t, len(calls), 2)
+	assert.Equal(t, calls[0].Package, "example.com/pkg")
+	assert.Assert(t, strings.Contains(out.String(),
+		`<testcase classname="example.com/pkg" name="TestOne" time="0.000000" owner="team-a" shard="2">`), out.String())
+	assert.Assert(t, strings.Contains(out.String(),
+		`<testcase classname="example.com/pkg" name="TestTwo" time="0.000000"></testcase>`), out.String())
+
+	for _, name := range []string{"", "two words", `owner"`, "1st", "xmlns", "xmlns:a", "ns:owner"} {
+		config.TestCaseAttributes = func(tc testjson.TestCase) map[string]string {
+			return map[string]string{name: "value"}
+		}
+		err := WriteWithConfig(new(bytes.Buffer), exec, config)
+		assert.ErrorContains(t, err, fmt.Sprintf("invalid attribute name %q", name))
+	}
+}
+
+func TestIsXMLName(t *testing.T) {
+	for _, name := range []string{"owner", "_shard", "test.tags", "team-a", "zone2", "größe"} {
+		assert.Assert(t, isXMLName(name), name)
+	}
+	for _, name := range []string{"", "two words", "a'b", "-a", ".a", "2a", "xmlns", "XMLNS:a", "a:b", "a>b"} {
+		assert.Assert(t, !isXMLName(name), name)
+	}
+}
@@ -0,0 +1,210 @@
+package junitxml
+
+import (
+	"strconv"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// RerunMode selects how the results of a test which was run more than once in
+// a package are written, for example with --rerun-fails.
+type RerunMode string
+
+const (
+	// RerunsSeparate writes each run of a test as a separate testcase.
+	RerunsSeparate RerunMode = "separate"
+	// RerunsSurefire writes each test as a single testcase, using the
+	// elements of the Maven Surefire report, which are read by the Jenkins
+	// Flaky Test Handler. A test which failed and then passed is a passed
+	// testcase with a flakyFailure element for each failed run. A test which
+	// failed every run is a failed testcase with a rerunFailure element for
+	// each run after the first.
+	RerunsSurefire RerunMode = "surefire"
+)
+
+// JUnitRerunFailure is the result of a failed run of a test, written with
+// RerunsSurefire.
+type JUnitRerunFailure struct {
+	Message    string `xml:"message,attr"`
+	Type       string `xml:"type,attr"`
+	StackTrace string `xml:"stackTrace,omitempty"`
+	SystemOut  string `xml:"system-out,omitempty"`
+}
+
+func newRerunFailure(tc JUnitTestCase) JUnitRerunFailure {
+	failure := tc.Failure
+	if failure == nil {
+		failure = tc.Error
+	}
+	return JUnitRerunFailure{
+		Message:    failure.Message,
+		Type:       failure.Type,
+		StackTrace: failure.Contents,
+		SystemOut:  tc.SystemOut,
+	}
+}
+
+// mergeReruns combines the testcases with the same name in each testsuite
+// into a single testcase, using RerunsSurefire. It must be called before the
+// names of the testcases are changed.
+func mergeReruns(suites JUnitTestSuites) {
+	for i := range suites.Suites {
+		mergeSuiteReruns(&suites.Suites[i])
+	}
+}
+
+func mergeSuiteReruns(suite *JUnitTestSuite) {
+	runs := make(map[string][]int)
+	for i, tc := range suite.TestCases {
+		runs[tc.Name] = append(runs[tc.Name], i)
+	}
+
+	removed := make(map[int]bool)
+	for _, indexes := range runs {
+		if len(indexes) < 2 {
+			continue
+		}
+		keep, failed, ok := classifyRuns(suite.TestCases, indexes)
+		if !ok {
+			continue
+		}
+		tc := &suite.TestCases[keep]
+		for _, index := range failed {
+			if index == keep {
+				continue
+			}
+			removed[index] = true
+			if tc.Failure == nil && tc.Error == nil {
+				tc.FlakyFailures = append(tc.FlakyFailures, newRerunFailure(suite.TestCases[index]))
+				continue
+			}
+			tc.RerunFailures = append(tc.RerunFailures, newRerunFailure(suite.TestCases[index]))
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+
+	cases := make([]JUnitTestCase, 0, len(suite.TestCases)-len(removed))
+	for i, tc := range suite.TestCases {
+		if !removed[i] {
+			cases = append(cases, tc)
+		}
+	}
+	suite.TestCases = cases
+	suite.Tests -= len(removed)
+}
+
+// markFlaky changes each failed testcase of a test which passed the last time
+// it was run to a skipped testcase, with a gotestsum.outcome property of
+// flaky. The output of the failure is kept as the system-out of the testcase.
+// It must be called before the names of the testcases are changed.
+func markFlaky(suites JUnitTestSuites, exec *testjson.Execution) {
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		pkg := exec.Package(suite.pkgname)
+		for j := range suite.TestCases {
+			tc := &suite.TestCases[j]
+			results := pkg.Results(tc.Name)
+			if tc.Failure == nil || len(results) == 0 || results[len(results)-1] != testjson.ActionPass {
+				continue
+			}
+			if tc.SystemOut == "" {
+				tc.SystemOut = tc.Failure.Contents
+			}
+			tc.Failure = nil
+			tc.SkipMessage = &JUnitSkipMessage{Message: "flaky: the test passed when it was run again"}
+			if tc.Properties == nil {
+				tc.Properties = &JUnitProperties{}
+			}
+			tc.Properties.Property = append(tc.Properties.Property,
+				JUnitProperty{Name: "gotestsum.outcome", Value: "flaky"})
+		}
+	}
+}
+
+// classifyRuns returns the index of the testcase to keep, and the indexes of
+// the failed runs. Returns false if the runs should not be merged, because
+// none of the runs failed, or one of the runs was skipped.
+func classifyRuns(cases []JUnitTestCase, indexes []int) (int, []int, bool) {
+	keep := -1
+	var failed []int
+	for _, index := range indexes {
+		tc := cases[index]
+		switch {
+		case tc.SkipMessage != nil:
+			return 0, nil, false
+		case tc.Failure != nil || tc.Error != nil:
+			failed = append(failed, index)
+		case keep == -1:
+			keep = index
+		}
+	}
+	if len(failed) == 0 {
+		return 0, nil, false
+	}
+	if keep == -1 {
+		keep = failed[0]
+	}
+	return keep, failed, true
+}
+
+// addRetryHistory adds the number of retries, and the result of each run, to
+// the properties of the testcase of the final run of each test which was run
+// more than once, so that the retry rate of a test can be calculated from the
+// report. It must be called after the reruns are merged, so that the history
+// is kept when the first run is the testcase which is kept, and before the
+// names of the testcases are changed.
+func addRetryHistory(suites JUnitTestSuites, exec *testjson.Execution) {
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		pkg := exec.Package(suite.pkgname)
+		// final is the index of the testcase of the final run of each test.
+		// The testcases of the runs with the same result are in the order
+		// they ran.
+		final := make(map[string]int)
+		for j, tc := range suite.TestCases {
+			results := pkg.Results(tc.Name)
+			if len(results) < 2 {
+				continue
+			}
+			if _, ok := final[tc.Name]; !ok || testCaseAction(tc) == results[len(results)-1] {
+				final[tc.Name] = j
+			}
+		}
+		for name, j := range final {
+			tc := &suite.TestCases[j]
+			if tc.Properties == nil {
+				tc.Properties = &JUnitProperties{}
+			}
+			tc.Properties.Property = append(tc.Properties.Property, retryProperties(pkg.Results(name))...)
+		}
+	}
+}
+
+// testCaseAction returns the result of the run of a test from its testcase.
+func testCaseAction(tc JUnitTestCase) testjson.Action {
+	switch {
+	case tc.Failure != nil || tc.Error != nil:
+		return testjson.ActionFail
+	case tc.SkipMessage != nil:
+		return testjson.ActionSkip
+	default:
+		return testjson.ActionPass
+	}
+}
+
+// retryProperties returns the gotestsum.retries property, and a
+// gotestsum.attempt-N-result property with the result of each run.
+func retryProperties(results []testjson.Action) []JUnitProperty {
+	properties := []JUnitProperty{
+		{Name: "gotestsum.retries", Value: strconv.Itoa(len(results) - 1)},
+	}
+	for i, result := range results {
+		properties = append(properties, JUnitProperty{
+			Name:  "gotestsum.attempt-" + strconv.Itoa(i+1) + "-result",
+			Value: string(result),
+		})
+	}
+	return properties
+}
@@ -0,0 +1,192 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestWriteWithConfig_RerunsSurefire(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFlaky","Output":"    flaky_test.go:9: timeout\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOK"}
+{"Action":"fail","Package":"example.com/pkg"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/pkg"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{Reruns: RerunsSurefire}))
+	assert.Assert(t, strings.Contains(out.String(), "<flakyFailure"))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 3)
+	assert.Equal(t, suite.Failures, 1)
+	assert.Equal(t, len(suite.TestCases), 3)
+
+	byName := make(map[string]JUnitTestCase)
+	for _, tc := range suite.TestCases {
+		byName[tc.Name] = tc
+	}
+	flaky := byName["TestFlaky"]
+	assert.Assert(t, flaky.Failure == nil)
+	assert.Equal(t, len(flaky.FlakyFailures), 1)
+	assert.Equal(t, flaky.FlakyFailures[0].Message, "Failed")
+	assert.Assert(t, strings.Contains(flaky.FlakyFailures[0].StackTrace, "flaky_test.go:9: timeout"))
+
+	broken := byName["TestBroken"]
+	assert.Assert(t, broken.Failure != nil)
+	assert.Equal(t, len(broken.RerunFailures), 1)
+	assert.Equal(t, len(broken.FlakyFailures), 0)
+
+	assert.Equal(t, len(byName["TestOK"].FlakyFailures), 0)
+}
+
+func TestWriteWithConfig_Flaky(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFlaky","Output":"    flaky_test.go:9: timeout\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/pkg"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{Flaky: true}))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 4)
+	assert.Equal(t, suite.Failures, 2)
+	assert.Equal(t, suite.Skipped, 1)
+
+	flaky := suite.TestCases[0]
+	assert.Equal(t, flaky.Name, "TestFlaky")
+	assert.Assert(t, flaky.Failure == nil)
+	assert.Equal(t, flaky.SkipMessage.Message, "flaky: the test passed when it was run again")
+	assert.Assert(t, strings.Contains(flaky.SystemOut, "flaky_test.go:9: timeout"))
+	assert.DeepEqual(t, flaky.Properties.Property[len(flaky.Properties.Property)-1],
+		JUnitProperty{Name: "gotestsum.outcome", Value: "flaky"})
+
+	for _, tc := range suite.TestCases[1:] {
+		if tc.Name == "TestBroken" {
+			assert.Assert(t, tc.Failure != nil)
+		}
+	}
+}
+
+func TestWriteWithConfig_RetryHistory(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestBroken","Output":"first\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOK"}
+{"Action":"fail","Package":"example.com/pkg"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestBroken","Output":"second\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/pkg"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	read := func(mode RerunMode) []JUnitTestCase {
+		out := new(bytes.Buffer)
+		assert.NilError(t, WriteWithConfig(out, exec, Config{Reruns: mode}))
+		suites, err := Read(out)
+		assert.NilError(t, err)
+		return suites.Suites[0].TestCases
+	}
+	flakyHistory := []JUnitProperty{
+		{Name: "gotestsum.retries", Value: "2"},
+		{Name: "gotestsum.attempt-1-result", Value: "fail"},
+		{Name: "gotestsum.attempt-2-result", Value: "fail"},
+		{Name: "gotestsum.attempt-3-result", Value: "pass"},
+	}
+	brokenHistory := []JUnitProperty{
+		{Name: "gotestsum.retries", Value: "1"},
+		{Name: "gotestsum.attempt-1-result", Value: "fail"},
+		{Name: "gotestsum.attempt-2-result", Value: "fail"},
+	}
+
+	t.Run("surefire", func(t *testing.T) {
+		byName := make(map[string]JUnitTestCase)
+		for _, tc := range read(RerunsSurefire) {
+			byName[tc.Name] = tc
+		}
+		flaky := byName["TestFlaky"]
+		assert.Assert(t, flaky.Properties != nil)
+		assert.DeepEqual(t, flaky.Properties.Property, flakyHistory)
+		broken := byName["TestBroken"]
+		assert.Assert(t, broken.Properties != nil)
+		assert.DeepEqual(t, broken.Properties.Property, brokenHistory)
+		assert.Assert(t, byName["TestOK"].Properties == nil)
+	})
+
+	t.Run("separate", func(t *testing.T) {
+		cases := read(RerunsSeparate)
+		// failed testcases are written before passed testcases, in the
+		// order they ran
+		var names []string
+		for _, tc := range cases {
+			names = append(names, tc.Name)
+		}
+		assert.DeepEqual(t, names, []string{
+			"TestFlaky", "TestBroken", "TestFlaky", "TestBroken", "TestOK", "TestFlaky",
+		})
+		for i, tc := range cases {
+			switch i {
+			case 3:
+				assert.DeepEqual(t, tc.Properties.Property, brokenHistory)
+			case 5:
+				assert.DeepEqual(t, tc.Properties.Property, flakyHistory)
+			default:
+				assert.Assert(t, tc.Properties == nil, "testcase %d %s", i, tc.Name)
+			}
+		}
+	})
+}
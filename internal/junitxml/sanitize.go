@@ -0,0 +1,148 @@
+package junitxml
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSIMode selects how ANSI escape sequences in the output of tests are
+// written.
+type ANSIMode string
+
+const (
+	// ANSIStrip removes ANSI escape sequences, like the color codes written
+	// by many test helpers.
+	ANSIStrip ANSIMode = "strip"
+	// ANSIText keeps ANSI escape sequences as readable text, with the escape
+	// character written as \x1b.
+	ANSIText ANSIMode = "text"
+)
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;?]*[ -/]*[@-~]")
+
+// sanitize removes the characters which are not valid in an XML 1.0 document
+// from the text and the attributes of every testsuite and testcase, including
+// their properties. Go would replace each of
+// them with U+FFFD, which hides bytes like NUL in the output of a fuzz test,
+// so they are written as an escape sequence instead.
+func sanitize(suites JUnitTestSuites, mode ANSIMode) {
+	for i := range suites.Suites {
+		sanitizeSuite(&suites.Suites[i], mode)
+	}
+}
+
+func sanitizeSuite(suite *JUnitTestSuite, mode ANSIMode) {
+	suite.Name = sanitizeText(suite.Name, mode)
+	suite.Hostname = sanitizeText(suite.Hostname, mode)
+	sanitizeProperties(suite.Properties, mode)
+	suite.SystemOut = sanitizeText(suite.SystemOut, mode)
+	for i := range suite.TestCases {
+		tc := &suite.TestCases[i]
+		tc.Classname = sanitizeText(tc.Classname, mode)
+		tc.Name = sanitizeText(tc.Name, mode)
+		tc.File = sanitizeText(tc.File, mode)
+		if tc.Properties != nil {
+			sanitizeProperties(tc.Properties.Property, mode)
+		}
+		tc.SystemOut = sanitizeText(tc.SystemOut, mode)
+		if tc.SkipMessage != nil {
+			tc.SkipMessage.Message = sanitizeText(tc.SkipMessage.Message, mode)
+		}
+		sanitizeFailure(tc.Failure, mode)
+		sanitizeFailure(tc.Error, mode)
+		for j := range tc.FlakyFailures {
+			sanitizeRerunFailure(&tc.FlakyFailures[j], mode)
+		}
+		for j := range tc.RerunFailures {
+			sanitizeRerunFailure(&tc.RerunFailures[j], mode)
+		}
+		for j := range tc.Attrs {
+			tc.Attrs[j].Value = sanitizeText(tc.Attrs[j].Value, mode)
+		}
+	}
+	for i := range suite.Suites {
+		sanitizeSuite(&suite.Suites[i], mode)
+	}
+}
+
+func sanitizeProperties(properties []JUnitProperty, mode ANSIMode) {
+	for i := range properties {
+		properties[i].Name = sanitizeText(properties[i].Name, mode)
+		properties[i].Value = sanitizeText(properties[i].Value, mode)
+	}
+}
+
+func sanitizeFailure(failure *JUnitFailure, mode ANSIMode) {
+	if failure == nil {
+		return
+	}
+	failure.Message = sanitizeText(failure.Message, mode)
+	failure.Type = sanitizeText(failure.Type, mode)
+	failure.File = sanitizeText(failure.File, mode)
+	failure.Contents = sanitizeText(failure.Contents, mode)
+}
+
+func sanitizeRerunFailure(failure *JUnitRerunFailure, mode ANSIMode) {
+	failure.Message = sanitizeText(failure.Message, mode)
+	failure.Type = sanitizeText(failure.Type, mode)
+	failure.StackTrace = sanitizeText(failure.StackTrace, mode)
+	failure.SystemOut = sanitizeText(failure.SystemOut, mode)
+}
+
+// sanitizeText returns text with the ANSI escape sequences removed, unless
+// mode is ANSIText, and any other character which is not valid in XML 1.0,
+// or byte which is not valid UTF-8, replaced by a Go escape sequence.
+func sanitizeText(text string, mode ANSIMode) string {
+	if mode != ANSIText {
+		text = ansiEscape.ReplaceAllString(text, "")
+	}
+	if isValidXMLText(text) {
+		return text
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(text); {
+		r, width := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == utf8.RuneError && width == 1:
+			fmt.Fprintf(&buf, `\x%02x`, text[i])
+		case !isXMLChar(r) && r < utf8.RuneSelf:
+			fmt.Fprintf(&buf, `\x%02x`, r)
+		case !isXMLChar(r):
+			fmt.Fprintf(&buf, `\u%04x`, r)
+		default:
+			buf.WriteString(text[i : i+width])
+		}
+		i += width
+	}
+	return buf.String()
+}
+
+func isValidXMLText(text string) bool {
+	for i := 0; i < len(text); {
+		r, width := utf8.DecodeRuneInString(text[i:])
+		if r == utf8.RuneError && width == 1 || !isXMLChar(r) {
+			return false
+		}
+		i += width
+	}
+	return true
+}
+
+// isXMLChar returns true if r is in the Char production of the XML 1.0
+// specification.
+func isXMLChar(r rune) bool {
+	switch {
+	case r == '\t', r == '\n', r == '\r':
+		return true
+	case r >= 0x20 && r <= 0xD7FF:
+		return true
+	case r >= 0xE000 && r <= 0xFFFD:
+		return true
+	case r >= 0x10000 && r <= 0x10FFFF:
+		return true
+	}
+	return false
+}
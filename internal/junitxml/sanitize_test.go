@@ -0,0 +1,105 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestSanitizeText(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		text     string
+		mode     ANSIMode
+		expected string
+	}{
+		{
+			name:     "valid text",
+			text:     "\tfoo_test.go:12: got ☃\r\n",
+			expected: "\tfoo_test.go:12: got ☃\r\n",
+		},
+		{
+			name:     "ANSI color stripped",
+			text:     "\x1b[31mFAIL\x1b[0m TestOne",
+			expected: "FAIL TestOne",
+		},
+		{
+			name:     "ANSI color as text",
+			text:     "\x1b[31mFAIL\x1b[0m TestOne",
+			mode:     ANSIText,
+			expected: `\x1b[31mFAIL\x1b[0m TestOne`,
+		},
+		{
+			name:     "NUL and control characters",
+			text:     "input: \x00\x07\x1b",
+			expected: `input: \x00\x07\x1b`,
+		},
+		{
+			name:     "invalid UTF-8",
+			text:     "input: \xff\xfe",
+			expected: `input: \xff\xfe`,
+		},
+		{
+			name:     "noncharacter",
+			text:     "input: \ufffe \ufffd",
+			expected: `input: \ufffe ` + "\ufffd",
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, sanitizeText(tc.text, tc.mode), tc.expected)
+		})
+	}
+}
+
+func TestWriteWithConfig_SanitizesOutput(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"FuzzParse"}
+{"Action":"output","Package":"example.com/pkg","Test":"FuzzParse","Output":"    parse_test.go:9: bad input \"\u0000\u0001\"\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"FuzzParse","Output":"\u001b[31mmismatch\u001b[0m\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"FuzzParse"}
+{"Action":"fail","Package":"example.com/pkg"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{}))
+	assert.Assert(t, !strings.Contains(out.String(), "�"))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	contents := suites.Suites[0].TestCases[0].Failure.Contents
+	assert.Assert(t, strings.Contains(contents, `bad input "\x00\x01"`), contents)
+	assert.Assert(t, strings.Contains(contents, "\nmismatch\n"), contents)
+}
+
+func TestWriteWithConfig_SanitizesAttributes(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/p\u0001kg","Test":"TestOne"}
+{"Action":"fail","Package":"example.com/p\u0001kg","Test":"TestOne"}
+{"Action":"fail","Package":"example.com/p\u0001kg"}
+`))
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	config := Config{
+		Hostname:   "host",
+		Properties: []JUnitProperty{{Name: "ci.job", Value: "build\x00\x1b[1m7\x1b[0m"}},
+	}
+	assert.NilError(t, WriteWithConfig(out, exec, config))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Name, `example.com/p\x01kg`)
+	assert.Equal(t, suite.TestCases[0].Classname, `example.com/p\x01kg`)
+	property := suite.Properties[len(suite.Properties)-1]
+	assert.Equal(t, property.Value, `build\x007`)
+}
@@ -0,0 +1,242 @@
+package junitxml
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// attrType is the type of the value of an attribute in the schema.
+type attrType int
+
+const (
+	attrString attrType = iota
+	attrInt
+	attrDecimal
+	attrTimestamp
+)
+
+// elementSchema is the schema of an element in a JUnit XML document.
+type elementSchema struct {
+	attrs    map[string]attrType
+	required []string
+	children []string
+	// text is true if the element may contain text, otherwise only
+	// whitespace is allowed between the child elements.
+	text bool
+}
+
+// schema is the de-facto schema of a JUnit XML document, the union of the
+// Jenkins JUnit XSD, and the Maven Surefire XSD, which most CI systems read.
+// The file and line attributes are not in either XSD, but are read by many
+// CI systems.
+var schema = map[string]elementSchema{
+	"testsuites": {
+		attrs: map[string]attrType{
+			"name": attrString, "tests": attrInt, "failures": attrInt, "errors": attrInt,
+			"skipped": attrInt, "disabled": attrInt, "time": attrDecimal,
+		},
+		children: []string{"testsuite"},
+	},
+	"testsuite": {
+		attrs: map[string]attrType{
+			"name": attrString, "tests": attrInt, "failures": attrInt, "errors": attrInt,
+			"skipped": attrInt, "disabled": attrInt, "assertions": attrInt,
+			"time": attrDecimal, "timestamp": attrTimestamp, "hostname": attrString,
+			"id": attrString, "package": attrString, "group": attrString,
+			"file": attrString, "log": attrString, "url": attrString, "version": attrString,
+		},
+		required: []string{"name", "tests"},
+		children: []string{"properties", "testcase", "testsuite", "system-out", "system-err"},
+	},
+	"properties": {
+		children: []string{"property"},
+	},
+	"property": {
+		attrs:    map[string]attrType{"name": attrString, "value": attrString},
+		required: []string{"name"},
+		text:     true,
+	},
+	"testcase": {
+		attrs: map[string]attrType{
+			"name": attrString, "classname": attrString, "time": attrDecimal,
+			"assertions": attrInt, "status": attrString, "group": attrString,
+			"file": attrString, "line": attrInt,
+		},
+		required: []string{"name"},
+		children: []string{
+			"properties", "skipped", "error", "failure", "system-out", "system-err",
+			"flakyFailure", "flakyError", "rerunFailure", "rerunError",
+		},
+	},
+	"skipped": {
+		attrs: map[string]attrType{"message": attrString},
+		text:  true,
+	},
+	"failure":      failureSchema,
+	"error":        failureSchema,
+	"flakyFailure": rerunSchema,
+	"flakyError":   rerunSchema,
+	"rerunFailure": rerunSchema,
+	"rerunError":   rerunSchema,
+	"stackTrace":   {text: true},
+	"system-out":   {text: true},
+	"system-err":   {text: true},
+}
+
+var failureSchema = elementSchema{
+	attrs: map[string]attrType{
+		"message": attrString, "type": attrString, "file": attrString, "line": attrInt,
+	},
+	text: true,
+}
+
+var rerunSchema = elementSchema{
+	attrs:    map[string]attrType{"message": attrString, "type": attrString},
+	required: []string{"type"},
+	children: []string{"stackTrace", "system-out", "system-err"},
+	text:     true,
+}
+
+// ValidateSchema checks that a JUnit XML document matches the de-facto JUnit
+// schema. Every element and attribute must be in the schema, the required
+// attributes must be set, and the value of a number or timestamp attribute
+// must be valid.
+func ValidateSchema(in io.Reader) error {
+	return validateTokens(in, &schemaValidator{})
+}
+
+// tokenValidator checks each token of a document as it is read.
+type tokenValidator interface {
+	token(token xml.Token) error
+	// end is called after the last token of the document.
+	end() error
+}
+
+// validateTokens reads the document from in as a stream, and checks each
+// token with every validator, so that a document is never read into memory.
+func validateTokens(in io.Reader, validators ...tokenValidator) error {
+	decoder := newDecoder(in)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "invalid XML")
+		}
+		for _, v := range validators {
+			if err := v.token(token); err != nil {
+				return err
+			}
+		}
+	}
+	for _, v := range validators {
+		if err := v.end(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schemaValidator is the tokenValidator of ValidateSchema.
+type schemaValidator struct {
+	path    []string
+	hasRoot bool
+}
+
+func (v *schemaValidator) token(token xml.Token) error {
+	switch t := token.(type) {
+	case xml.StartElement:
+		name := t.Name.Local
+		if len(v.path) == 0 {
+			if v.hasRoot {
+				return errors.Errorf("more than one root element")
+			}
+			v.hasRoot = true
+			if name != "testsuites" && name != "testsuite" {
+				return errors.Errorf("unexpected root element <%s>", name)
+			}
+		} else if parent := v.path[len(v.path)-1]; !contains(schema[parent].children, name) {
+			return errors.Errorf("%s: unexpected element <%s>", strings.Join(v.path, ">"), name)
+		}
+		v.path = append(v.path, name)
+		if err := validateAttrs(schema[name], t.Attr); err != nil {
+			return errors.Wrap(err, strings.Join(v.path, ">"))
+		}
+	case xml.EndElement:
+		v.path = v.path[:len(v.path)-1]
+	case xml.CharData:
+		if len(v.path) > 0 && !schema[v.path[len(v.path)-1]].text && len(strings.TrimSpace(string(t))) > 0 {
+			return errors.Errorf("%s: unexpected text", strings.Join(v.path, ">"))
+		}
+	}
+	return nil
+}
+
+func (v *schemaValidator) end() error {
+	if !v.hasRoot {
+		return errors.New("no root element")
+	}
+	return nil
+}
+
+func validateAttrs(element elementSchema, attrs []xml.Attr) error {
+	values := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		if attr.Name.Space != "" || attr.Name.Local == "xmlns" {
+			// namespaces, and namespaced attributes like
+			// xsi:noNamespaceSchemaLocation, are not part of the schema
+			continue
+		}
+		name := attr.Name.Local
+		typ, ok := element.attrs[name]
+		if !ok {
+			return errors.Errorf("unknown attribute %q", name)
+		}
+		if err := validateAttrValue(typ, attr.Value); err != nil {
+			return errors.Wrapf(err, "invalid attribute %q", name)
+		}
+		values[name] = attr.Value
+	}
+	for _, name := range element.required {
+		if _, ok := values[name]; !ok {
+			return errors.Errorf("missing attribute %q", name)
+		}
+	}
+	return nil
+}
+
+func validateAttrValue(typ attrType, value string) error {
+	switch typ {
+	case attrInt:
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return errors.Errorf("%q is not a number", value)
+		}
+	case attrDecimal:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return errors.Errorf("%q is not a decimal", value)
+		}
+	case attrTimestamp:
+		if _, err := time.Parse("2006-01-02T15:04:05", value); err == nil {
+			return nil
+		}
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return errors.Errorf("%q is not an ISO 8601 timestamp", value)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/golden"
+)
+
+func TestValidateSchema_WrittenReports(t *testing.T) {
+	assert.NilError(t, ValidateSchema(bytes.NewReader(golden.Get(t, "junitxml-report.golden"))))
+
+	exec := createExecution(t)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	configs := map[string]Config{
+		"surefire reruns": {Reruns: RerunsSurefire},
+		"nested subtests": {Subtests: SubtestsNested},
+		"system-out":      {SystemOut: SystemOutAll},
+		"labels": {Labels: map[string]map[string]string{
+			"github.com/gotestyourself/gotestyourself/testjson/internal/good.TestSkipped": {"team": "a"},
+		}},
+		"properties": {Properties: []JUnitProperty{{Name: "ci.build", Value: "42"}}},
+	}
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			assert.NilError(t, WriteWithConfig(out, exec, config))
+			assert.NilError(t, ValidateSchema(out))
+		})
+	}
+}
+
+func TestValidateSchema_Invalid(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		doc      string
+		expected string
+	}{
+		{
+			name:     "unexpected root",
+			doc:      `<report></report>`,
+			expected: "unexpected root element <report>",
+		},
+		{
+			name:     "unexpected element",
+			doc:      `<testsuites><testcase name="TestOne"></testcase></testsuites>`,
+			expected: "testsuites: unexpected element <testcase>",
+		},
+		{
+			name:     "unknown attribute",
+			doc:      `<testsuite name="a" tests="1"><testcase name="TestOne" result="pass"></testcase></testsuite>`,
+			expected: `testsuite>testcase: unknown attribute "result"`,
+		},
+		{
+			name:     "missing attribute",
+			doc:      `<testsuites><testsuite name="a"></testsuite></testsuites>`,
+			expected: `testsuites>testsuite: missing attribute "tests"`,
+		},
+		{
+			name:     "invalid number",
+			doc:      `<testsuite name="a" tests="one"></testsuite>`,
+			expected: `testsuite: invalid attribute "tests": "one" is not a number`,
+		},
+		{
+			name:     "invalid time",
+			doc:      `<testsuite name="a" tests="1" time="1s"></testsuite>`,
+			expected: `testsuite: invalid attribute "time": "1s" is not a decimal`,
+		},
+		{
+			name:     "invalid timestamp",
+			doc:      `<testsuite name="a" tests="1" timestamp="yesterday"></testsuite>`,
+			expected: `testsuite: invalid attribute "timestamp": "yesterday" is not an ISO 8601 timestamp`,
+		},
+		{
+			name:     "unexpected text",
+			doc:      `<testsuite name="a" tests="1">output</testsuite>`,
+			expected: "testsuite: unexpected text",
+		},
+		{
+			name:     "truncated",
+			doc:      `<testsuites><testsuite name="a" tests="1">`,
+			expected: "invalid XML",
+		},
+		{
+			name:     "empty",
+			doc:      ``,
+			expected: "no root element",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorContains(t, ValidateSchema(strings.NewReader(tc.doc)), tc.expected)
+		})
+	}
+}
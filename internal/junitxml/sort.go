@@ -0,0 +1,113 @@
+package junitxml
+
+import (
+	"sort"
+)
+
+// SortMode selects the order of the testsuites, and of the testcases of each
+// testsuite.
+type SortMode string
+
+const (
+	// SortNone writes a testsuite for each package in the order of the
+	// package names, and the testcases of each package grouped by outcome, in
+	// the order the tests ended, which may change between runs.
+	SortNone SortMode = "none"
+	// SortName sorts the testsuites by name, and the testcases by classname
+	// and name.
+	SortName SortMode = "name"
+	// SortDuration sorts the testsuites and testcases by time, the slowest
+	// first, and then by name.
+	SortDuration SortMode = "duration"
+	// SortOutcome sorts the testsuites and testcases by outcome: errors,
+	// failures, skipped, and then passed, and then by name.
+	SortOutcome SortMode = "outcome"
+)
+
+// sortSuites sorts the testsuites, any nested testsuites, and the testcases of
+// each testsuite. The sort is stable, so that testcases with the same sort key
+// keep their order.
+func sortSuites(suites []JUnitTestSuite, mode SortMode) {
+	var less func(a, b JUnitTestSuite) bool
+	var lessCase func(a, b JUnitTestCase) bool
+	switch mode {
+	case SortName:
+		less = func(a, b JUnitTestSuite) bool {
+			return a.Name < b.Name
+		}
+		lessCase = testCaseNameLess
+	case SortDuration:
+		less = func(a, b JUnitTestSuite) bool {
+			if ta, tb := parseSeconds(a.Time), parseSeconds(b.Time); ta != tb {
+				return ta > tb
+			}
+			return a.Name < b.Name
+		}
+		lessCase = func(a, b JUnitTestCase) bool {
+			if ta, tb := parseSeconds(a.Time), parseSeconds(b.Time); ta != tb {
+				return ta > tb
+			}
+			return testCaseNameLess(a, b)
+		}
+	case SortOutcome:
+		less = func(a, b JUnitTestSuite) bool {
+			if ra, rb := suiteOutcomeRank(a), suiteOutcomeRank(b); ra != rb {
+				return ra < rb
+			}
+			return a.Name < b.Name
+		}
+		lessCase = func(a, b JUnitTestCase) bool {
+			if ra, rb := testCaseOutcomeRank(a), testCaseOutcomeRank(b); ra != rb {
+				return ra < rb
+			}
+			return testCaseNameLess(a, b)
+		}
+	default:
+		return
+	}
+	var sortAll func(suites []JUnitTestSuite)
+	sortAll = func(suites []JUnitTestSuite) {
+		sort.SliceStable(suites, func(i, j int) bool {
+			return less(suites[i], suites[j])
+		})
+		for i := range suites {
+			cases := suites[i].TestCases
+			sort.SliceStable(cases, func(i, j int) bool {
+				return lessCase(cases[i], cases[j])
+			})
+			sortAll(suites[i].Suites)
+		}
+	}
+	sortAll(suites)
+}
+
+func testCaseNameLess(a, b JUnitTestCase) bool {
+	if a.Classname != b.Classname {
+		return a.Classname < b.Classname
+	}
+	return a.Name < b.Name
+}
+
+func testCaseOutcomeRank(tc JUnitTestCase) int {
+	switch {
+	case tc.Error != nil:
+		return 0
+	case tc.Failure != nil:
+		return 1
+	case tc.SkipMessage != nil:
+		return 2
+	}
+	return 3
+}
+
+func suiteOutcomeRank(suite JUnitTestSuite) int {
+	switch {
+	case suite.Errors > 0:
+		return 0
+	case suite.Failures > 0:
+		return 1
+	case suite.Tests > 0 && suite.Skipped == suite.Tests:
+		return 2
+	}
+	return 3
+}
@@ -0,0 +1,65 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestWriteWithConfig_Sort(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/b","Test":"TestPass"}
+{"Action":"pass","Package":"example.com/b","Test":"TestPass","Elapsed":0.2}
+{"Action":"run","Package":"example.com/b","Test":"TestFail"}
+{"Action":"fail","Package":"example.com/b","Test":"TestFail","Elapsed":0.1}
+{"Action":"run","Package":"example.com/b","Test":"TestAlsoPass"}
+{"Action":"pass","Package":"example.com/b","Test":"TestAlsoPass","Elapsed":0.3}
+{"Action":"run","Package":"example.com/b","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/b","Test":"TestSkip"}
+{"Action":"fail","Package":"example.com/b","Elapsed":0.6}
+{"Action":"run","Package":"example.com/a","Test":"TestSlow"}
+{"Action":"pass","Package":"example.com/a","Test":"TestSlow","Elapsed":2}
+{"Action":"pass","Package":"example.com/a","Elapsed":2}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	order := func(mode SortMode) []string {
+		out := new(bytes.Buffer)
+		assert.NilError(t, WriteWithConfig(out, exec, Config{Sort: mode}))
+		suites, err := Read(out)
+		assert.NilError(t, err)
+		var names []string
+		for _, suite := range suites.Suites {
+			names = append(names, suite.Name)
+			for _, tc := range suite.TestCases {
+				names = append(names, "  "+tc.Name)
+			}
+		}
+		return names
+	}
+
+	assert.DeepEqual(t, order(SortNone), []string{
+		"example.com/a", "  TestSlow",
+		"example.com/b", "  TestFail", "  TestSkip", "  TestPass", "  TestAlsoPass",
+	})
+	assert.DeepEqual(t, order(SortName), []string{
+		"example.com/a", "  TestSlow",
+		"example.com/b", "  TestAlsoPass", "  TestFail", "  TestPass", "  TestSkip",
+	})
+	assert.DeepEqual(t, order(SortDuration), []string{
+		"example.com/a", "  TestSlow",
+		"example.com/b", "  TestAlsoPass", "  TestPass", "  TestFail", "  TestSkip",
+	})
+	assert.DeepEqual(t, order(SortOutcome), []string{
+		"example.com/b", "  TestFail", "  TestSkip", "  TestAlsoPass", "  TestPass",
+		"example.com/a", "  TestSlow",
+	})
+}
@@ -0,0 +1,78 @@
+package junitxml
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// StreamWriter writes a JUnit XML file one testsuite at a time, as each
+// package ends, instead of the whole document after the run. The document of
+// every testsuite is not held in memory at the same time, and the testsuites
+// of the packages which ended are in the file if the run is interrupted.
+//
+// The testsuites element does not have the totals of the run, because they
+// are not known when it is written. The testsuites are written in the order
+// the packages ended, so Config.Sort only sorts the testcases of each
+// testsuite. Duplicate testcases are only found within the testsuite of a
+// package, so Config.Duplicates should be DuplicatesWarn. Config.Format must
+// be FormatJUnit.
+type StreamWriter struct {
+	out     io.Writer
+	config  Config
+	written map[string]bool
+}
+
+// NewStreamWriter writes the start of the document to out, and returns a
+// StreamWriter which writes the testsuites to out.
+func NewStreamWriter(out io.Writer, config Config) (*StreamWriter, error) {
+	if config.Format != "" && config.Format != FormatJUnit {
+		return nil, errors.Errorf("format %s can not be streamed", config.Format)
+	}
+	if config.Hostname == "" {
+		config.Hostname = hostname()
+	}
+	if _, err := io.WriteString(out, xml.Header+"<testsuites>\n"); err != nil {
+		return nil, errors.Wrap(err, "failed to write JUnit XML")
+	}
+	return &StreamWriter{out: out, config: config, written: make(map[string]bool)}, nil
+}
+
+// WritePackage writes the testsuite of the package pkgname. A package is only
+// written once, so the results of the package must not change after it is
+// written.
+func (w *StreamWriter) WritePackage(exec *testjson.Execution, pkgname string) error {
+	if w.written[pkgname] {
+		return nil
+	}
+	w.written[pkgname] = true
+	suites, err := buildSuites(exec.Subset(pkgname), w.config)
+	if err != nil {
+		return err
+	}
+	for _, suite := range suites.Suites {
+		doc, err := xml.MarshalIndent(suite, "\t", "\t")
+		if err != nil {
+			return errors.Wrap(err, "failed to write JUnit XML")
+		}
+		if _, err := w.out.Write(append(doc, '\n')); err != nil {
+			return errors.Wrap(err, "failed to write JUnit XML")
+		}
+	}
+	return nil
+}
+
+// Close writes the testsuites of the packages which were not written, for
+// example the packages which did not run, and the end of the document. It
+// does not close the writer.
+func (w *StreamWriter) Close(exec *testjson.Execution) error {
+	for _, pkgname := range exec.Packages() {
+		if err := w.WritePackage(exec, pkgname); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w.out, "</testsuites>\n")
+	return errors.Wrap(err, "failed to write JUnit XML")
+}
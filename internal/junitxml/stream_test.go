@@ -0,0 +1,59 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestStreamWriter(t *testing.T) {
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/b","Test":"TestB"}
+{"Action":"pass","Package":"example.com/b","Test":"TestB","Elapsed":1}
+{"Action":"run","Package":"example.com/b","Test":"TestC"}
+{"Action":"output","Package":"example.com/b","Test":"TestC","Output":"    c_test.go:3: broken\n"}
+{"Action":"fail","Package":"example.com/b","Test":"TestC"}
+{"Action":"fail","Package":"example.com/b"}
+{"Action":"run","Package":"example.com/a","Test":"TestA"}
+{"Action":"pass","Package":"example.com/a","Test":"TestA"}
+{"Action":"pass","Package":"example.com/a"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	w, err := NewStreamWriter(out, Config{})
+	assert.NilError(t, err)
+	assert.NilError(t, w.WritePackage(exec, "example.com/b"))
+	// the testsuite of a package which ended is written before the run ends
+	partial := out.String()
+	assert.Assert(t, strings.Contains(partial, `name="example.com/b"`), partial)
+	assert.Assert(t, !strings.Contains(partial, "</testsuites>"), partial)
+
+	assert.NilError(t, w.WritePackage(exec, "example.com/b"))
+	assert.NilError(t, w.Close(exec))
+
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	var names []string
+	for _, suite := range suites.Suites {
+		names = append(names, suite.Name)
+	}
+	// packages are written in the order they ended
+	assert.DeepEqual(t, names, []string{"example.com/b", "example.com/a"})
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 2)
+	assert.Equal(t, suite.Failures, 1)
+	assert.Equal(t, suite.TestCases[0].Name, "TestC")
+	assert.Equal(t, suite.TestCases[0].Failure.Contents, "    c_test.go:3: broken\n")
+
+	_, err = NewStreamWriter(new(bytes.Buffer), Config{Format: FormatOpenTestReporting})
+	assert.ErrorContains(t, err, "can not be streamed")
+}
@@ -0,0 +1,124 @@
+package junitxml
+
+import (
+	"strings"
+)
+
+// SubtestMode selects how subtests are written.
+type SubtestMode string
+
+const (
+	// SubtestsFlat writes every test and subtest as a testcase of the
+	// testsuite of the package.
+	SubtestsFlat SubtestMode = "flat"
+	// SubtestsNested writes a test with subtests as a nested testsuite, which
+	// contains the testcase of the test and the testcases of its subtests, so
+	// that the tree is the same as the output of go test -v.
+	SubtestsNested SubtestMode = "nested"
+)
+
+// testCaseNames returns the names of the testcases of each testsuite, before
+// the names are changed by Naming or DuplicatesSuffix.
+func testCaseNames(suites JUnitTestSuites) [][]string {
+	names := make([][]string, len(suites.Suites))
+	for i, suite := range suites.Suites {
+		for _, tc := range suite.TestCases {
+			names[i] = append(names[i], tc.Name)
+		}
+	}
+	return names
+}
+
+// nestSubtests moves the testcases of tests with subtests to a nested
+// testsuite. names are the test names of the testcases of each suite, from
+// testCaseNames.
+func nestSubtests(suites JUnitTestSuites, names [][]string) {
+	for i := range suites.Suites {
+		nestSuite(&suites.Suites[i], names[i])
+	}
+}
+
+type subtestNode struct {
+	name     string
+	time     string
+	cases    []JUnitTestCase
+	children []*subtestNode
+}
+
+func nestSuite(suite *JUnitTestSuite, names []string) {
+	parents := make(map[string]bool)
+	for _, name := range names {
+		for i := strings.LastIndex(name, "/"); i > 0; i = strings.LastIndex(name[:i], "/") {
+			parents[name[:i]] = true
+		}
+	}
+	if len(parents) == 0 {
+		return
+	}
+
+	root := &subtestNode{}
+	nodes := map[string]*subtestNode{"": root}
+	var nodeFor func(name string) *subtestNode
+	nodeFor = func(name string) *subtestNode {
+		if node, ok := nodes[name]; ok {
+			return node
+		}
+		parent := root
+		if i := strings.LastIndex(name, "/"); i > 0 {
+			parent = nodeFor(name[:i])
+		}
+		node := &subtestNode{name: name}
+		parent.children = append(parent.children, node)
+		nodes[name] = node
+		return node
+	}
+
+	for i, tc := range suite.TestCases {
+		name := names[i]
+		var node *subtestNode
+		switch {
+		case parents[name]:
+			node = nodeFor(name)
+			if node.time == "" {
+				node.time = tc.Time
+			}
+		case strings.Contains(name, "/"):
+			node = nodeFor(name[:strings.LastIndex(name, "/")])
+		default:
+			node = root
+		}
+		node.cases = append(node.cases, tc)
+	}
+	suite.TestCases = root.cases
+	suite.Suites = buildSubtestSuites(root.children)
+}
+
+func buildSubtestSuites(nodes []*subtestNode) []JUnitTestSuite {
+	suites := make([]JUnitTestSuite, 0, len(nodes))
+	for _, node := range nodes {
+		suite := JUnitTestSuite{
+			Name:      node.name,
+			Time:      node.time,
+			TestCases: node.cases,
+			Suites:    buildSubtestSuites(node.children),
+		}
+		if suite.Time == "" {
+			suite.Time = formatDurationAsSeconds(0)
+		}
+		suite.Tests = len(suite.TestCases)
+		for _, nested := range suite.Suites {
+			suite.Tests += nested.Tests
+		}
+		suites = append(suites, suite)
+	}
+	return suites
+}
+
+// allTestCases returns the testcases of the suite, and of any nested suites.
+func allTestCases(suite JUnitTestSuite) []JUnitTestCase {
+	cases := append([]JUnitTestCase{}, suite.TestCases...)
+	for _, nested := range suite.Suites {
+		cases = append(cases, allTestCases(nested)...)
+	}
+	return cases
+}
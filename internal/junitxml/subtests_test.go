@@ -0,0 +1,68 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestWriteWithConfig_SubtestsNested(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFoo"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFoo/case_1"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFoo/case_1"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFoo/case_2"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFoo/case_2/deep"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFoo/case_2/deep"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFoo/case_2"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFoo","Elapsed":1.5}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBar"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestBar"}
+{"Action":"fail","Package":"example.com/pkg"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{Subtests: SubtestsNested}))
+	suites, err := Read(bytes.NewReader(out.Bytes()))
+	assert.NilError(t, err)
+
+	pkg := suites.Suites[0]
+	assert.Equal(t, pkg.Tests, 5)
+	assert.Equal(t, len(pkg.TestCases), 1)
+	assert.Equal(t, pkg.TestCases[0].Name, "TestBar")
+	assert.Equal(t, len(pkg.Suites), 1)
+
+	foo := pkg.Suites[0]
+	assert.Equal(t, foo.Name, "TestFoo")
+	assert.Equal(t, foo.Time, "1.500000")
+	assert.Equal(t, foo.Tests, 4)
+	assert.Equal(t, foo.Failures, 3)
+	assert.Equal(t, len(foo.TestCases), 2)
+	assert.Equal(t, len(foo.Suites), 1)
+
+	case2 := foo.Suites[0]
+	assert.Equal(t, case2.Name, "TestFoo/case_2")
+	assert.Equal(t, len(case2.TestCases), 2)
+	assert.Equal(t, case2.TestCases[0].Name, "TestFoo/case_2/deep")
+	assert.Equal(t, case2.TestCases[1].Name, "TestFoo/case_2")
+
+	assert.Equal(t, len(Events(suites)), len(Events(flatSuites(t, exec))))
+	assert.NilError(t, Validate(bytes.NewReader(out.Bytes())))
+}
+
+func flatSuites(t *testing.T, exec *testjson.Execution) JUnitTestSuites {
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{}))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	return suites
+}
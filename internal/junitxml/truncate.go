@@ -0,0 +1,82 @@
+package junitxml
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// truncateOutput limits the size of the output of each testcase, and the
+// system-out of each testsuite, to limit bytes. It runs after sanitize, so that
+// the limit applies to the text which is written. The output of a failure,
+// error, skip message, or system-out which is larger keeps the start and the
+// end, which usually have the most useful lines, and replaces the middle with a
+// marker.
+func truncateOutput(suites JUnitTestSuites, limit int) {
+	if limit <= 0 {
+		return
+	}
+	for i := range suites.Suites {
+		truncateSuite(&suites.Suites[i], limit)
+	}
+}
+
+func truncateSuite(suite *JUnitTestSuite, limit int) {
+	suite.SystemOut = truncateText(suite.SystemOut, limit)
+	for i := range suite.TestCases {
+		tc := &suite.TestCases[i]
+		tc.SystemOut = truncateText(tc.SystemOut, limit)
+		if tc.SkipMessage != nil {
+			tc.SkipMessage.Message = truncateText(tc.SkipMessage.Message, limit)
+		}
+		if tc.Failure != nil {
+			tc.Failure.Contents = truncateText(tc.Failure.Contents, limit)
+		}
+		if tc.Error != nil {
+			tc.Error.Contents = truncateText(tc.Error.Contents, limit)
+		}
+		for j := range tc.FlakyFailures {
+			tc.FlakyFailures[j].StackTrace = truncateText(tc.FlakyFailures[j].StackTrace, limit)
+			tc.FlakyFailures[j].SystemOut = truncateText(tc.FlakyFailures[j].SystemOut, limit)
+		}
+		for j := range tc.RerunFailures {
+			tc.RerunFailures[j].StackTrace = truncateText(tc.RerunFailures[j].StackTrace, limit)
+			tc.RerunFailures[j].SystemOut = truncateText(tc.RerunFailures[j].SystemOut, limit)
+		}
+	}
+	for i := range suite.Suites {
+		truncateSuite(&suite.Suites[i], limit)
+	}
+}
+
+// truncateText returns the first and last half of limit bytes of text, with a
+// marker with the size of the text which was removed in between.
+// Text is never split in the middle of a UTF-8 encoded character.
+func truncateText(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	head := limit / 2
+	for head > 0 && !utf8.RuneStart(text[head]) {
+		head--
+	}
+	tail := len(text) - (limit - limit/2)
+	for tail < len(text) && !utf8.RuneStart(text[tail]) {
+		tail++
+	}
+	return fmt.Sprintf("%s\n… %s truncated …\n%s", text[:head], formatBytes(tail-head), text[tail:])
+}
+
+// formatBytes returns n as a number of bytes, with a unit for large sizes, for
+// example 1.2MB.
+func formatBytes(n int) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
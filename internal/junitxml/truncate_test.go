@@ -0,0 +1,80 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestTruncateText(t *testing.T) {
+	assert.Equal(t, truncateText("short", 10), "short")
+	assert.Equal(t, truncateText("0123456789abcdefghij", 10), "01234\n… 10B truncated …\nfghij")
+	// a character is not split
+	assert.Equal(t, truncateText("aé_____éb", 4), "a\n… 9B truncated …\nb")
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, formatBytes(999), "999B")
+	assert.Equal(t, formatBytes(1200), "1.2kB")
+	assert.Equal(t, formatBytes(1234567), "1.2MB")
+	assert.Equal(t, formatBytes(5000000000), "5.0GB")
+}
+
+func TestWriteWithConfig_MaxOutputBytes(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestDump"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestDump","Output":"` + strings.Repeat(`goroutine 1 [running]:\n`, 1000) + `"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestDump","Output":"--- FAIL: TestDump (0.00s)\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestDump"}
+{"Action":"fail","Package":"example.com/pkg"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, WriteWithConfig(out, exec, Config{MaxOutputBytes: 100}))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+	contents := suites.Suites[0].TestCases[0].Failure.Contents
+	assert.Assert(t, strings.Contains(contents, "kB truncated …\n"), contents)
+	assert.Assert(t, strings.HasSuffix(contents, "--- FAIL: TestDump (0.00s)\n"), contents)
+	assert.Assert(t, len(contents) < 150, len(contents))
+}
+
+func TestWriteWithConfig_MaxOutputBytesAfterSanitize(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"output","Package":"example.com/pkg","Output":"` + strings.Repeat(`init\n`, 100) + `"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestColor"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestColor","Output":"` + strings.Repeat(`\u001b[31mred\u001b[0m\n`, 100) + `"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestColor","Output":"--- FAIL: TestColor (0.00s)\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestColor"}
+{"Action":"fail","Package":"example.com/pkg"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+
+	out := new(bytes.Buffer)
+	config := Config{MaxOutputBytes: 100, SystemOut: SystemOutAll, ANSI: ANSIText}
+	assert.NilError(t, WriteWithConfig(out, exec, config))
+	suites, err := Read(out)
+	assert.NilError(t, err)
+
+	systemOut := suites.Suites[0].SystemOut
+	assert.Assert(t, strings.Contains(systemOut, "truncated …\n"), systemOut)
+	assert.Assert(t, len(systemOut) < 150, len(systemOut))
+
+	contents := suites.Suites[0].TestCases[0].Failure.Contents
+	assert.Assert(t, strings.Contains(contents, `\x1b[31mred`), contents)
+	assert.Assert(t, strings.HasSuffix(contents, "--- FAIL: TestColor (0.00s)\n"), contents)
+	assert.Assert(t, len(contents) < 150, len(contents))
+}
@@ -0,0 +1,83 @@
+package liveui
+
+const indexHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gotestsum</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+.counts span { margin-right: 1.5em; font-size: 1.2em; }
+.passed { color: #2a7d2a; }
+.failed { color: #c0392b; }
+.skipped { color: #b7950b; }
+ul { list-style: none; padding-left: 0; }
+li { cursor: pointer; font-family: monospace; padding: 2px 0; }
+li:hover { text-decoration: underline; }
+.columns { display: flex; gap: 2em; }
+.columns > div { flex: 1; min-width: 0; }
+pre { background: #f4f4f4; padding: 1em; overflow: auto; max-height: 60vh; }
+</style>
+</head>
+<body>
+<h1>gotestsum <small id="state">running</small></h1>
+<div class="counts">
+  <span id="elapsed"></span>
+  <span class="passed" id="passed"></span>
+  <span class="failed" id="failed"></span>
+  <span class="skipped" id="skipped"></span>
+</div>
+<div class="columns">
+  <div><h2>Failures</h2><ul id="failures"></ul></div>
+  <div><h2>Running</h2><ul id="running"></ul></div>
+</div>
+<h2>Output <small id="selected"></small></h2>
+<pre id="output">Select a test to show its output.</pre>
+<script>
+var source = null;
+
+function select(id) {
+  if (source) { source.close(); }
+  var output = document.getElementById("output");
+  output.textContent = "";
+  document.getElementById("selected").textContent = id;
+  source = new EventSource("/output?test=" + encodeURIComponent(id));
+  source.onmessage = function(event) { output.insertAdjacentHTML("beforeend", event.data + "\n"); };
+  source.addEventListener("done", function() { source.close(); });
+}
+
+function list(element, items, label) {
+  element.innerHTML = "";
+  items.forEach(function(item) {
+    var li = document.createElement("li");
+    li.textContent = label(item);
+    li.onclick = function() { select(item.id || item); };
+    element.appendChild(li);
+  });
+}
+
+function refresh() {
+  fetch("/status").then(function(response) { return response.json(); }).then(function(status) {
+    document.getElementById("elapsed").textContent = status.elapsed_seconds.toFixed(0) + "s";
+    document.getElementById("passed").textContent = status.passed + " passed";
+    document.getElementById("failed").textContent = status.failed + " failed";
+    document.getElementById("skipped").textContent = status.skipped + " skipped";
+    list(document.getElementById("failures"), status.failures, function(id) { return id; });
+    list(document.getElementById("running"), status.running, function(test) {
+      return test.id + " (" + test.elapsed_seconds.toFixed(0) + "s)";
+    });
+    if (status.done) {
+      document.getElementById("state").textContent = "done";
+      return;
+    }
+    setTimeout(refresh, 1000);
+  }).catch(function() {
+    document.getElementById("state").textContent = "disconnected";
+  });
+}
+
+refresh();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,295 @@
+/*
+Package liveui serves a web page which shows the progress of a run while the
+tests run: the number of tests which passed, failed, and were skipped, the
+tests which are running, the failures so far, and the output of a selected
+test as it is printed.
+*/
+package liveui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Server serves the web page, and records the events of the run.
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+	started  time.Time
+	// links, when set, links the file and line references in the output to
+	// the source.
+	links *testjson.SourceLinks
+	// maxOutputLines is the number of lines of output kept for each test.
+	maxOutputLines int
+
+	mu       sync.Mutex
+	status   Status
+	running  map[string]time.Time
+	failed   map[string]bool
+	output   map[string]*testOutput
+	finished bool
+	// changed is closed, and replaced, when an event is recorded, to wake the
+	// requests which stream the output of a test.
+	changed chan struct{}
+}
+
+// Status is the JSON response of /status.
+type Status struct {
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+	Passed         int       `json:"passed"`
+	Failed         int       `json:"failed"`
+	Skipped        int       `json:"skipped"`
+	Running        []Running `json:"running"`
+	// Failures are the IDs of the failed tests, in the order they failed.
+	Failures []string `json:"failures"`
+	Done     bool     `json:"done"`
+}
+
+// Running is a test which has started, but has not finished.
+type Running struct {
+	ID             string  `json:"id"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+}
+
+// outputLine is a line of output from a test.
+type outputLine struct {
+	Package string
+	Text    string
+}
+
+// defaultMaxOutputLines is the number of lines of output kept for each test.
+// The oldest lines of a test which prints more are removed, so that a test
+// with a lot of output does not use all the memory of a long run.
+const defaultMaxOutputLines = 2000
+
+// testOutput is the output of a test which is kept to be sent to the page.
+type testOutput struct {
+	lines []outputLine
+	// removed is the number of lines which were removed from the start of
+	// lines to keep it under the limit.
+	removed int
+}
+
+// add appends line, and removes the oldest half of the lines when there are
+// more than max lines.
+func (o *testOutput) add(line outputLine, max int) {
+	o.lines = append(o.lines, line)
+	if len(o.lines) <= max {
+		return
+	}
+	keep := max / 2
+	n := len(o.lines) - keep
+	o.lines = append([]outputLine{}, o.lines[n:]...)
+	o.removed += n
+}
+
+// since returns the lines after the first sent lines, and the number of lines
+// which were removed before they could be sent.
+func (o *testOutput) since(sent int) ([]outputLine, int) {
+	start := sent - o.removed
+	if start < 0 {
+		return o.lines, -start
+	}
+	return o.lines[start:], 0
+}
+
+// end is the number of lines of output, including the lines which were
+// removed.
+func (o *testOutput) end() int {
+	return o.removed + len(o.lines)
+}
+
+// Start listens on addr, and serves the web page from a goroutine. When links
+// is not nil the file and line references in the output of tests are links to
+// the source.
+func Start(addr string, links *testjson.SourceLinks) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start the web UI")
+	}
+	s := &Server{
+		listener: listener,
+		started:  time.Now(),
+		links:    links,
+		running:  make(map[string]time.Time),
+		failed:   make(map[string]bool),
+		output:   make(map[string]*testOutput),
+		changed:  make(chan struct{}),
+
+		maxOutputLines: defaultMaxOutputLines,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveIndex)
+	mux.HandleFunc("/status", s.serveStatus)
+	mux.HandleFunc("/output", s.serveOutput)
+	s.server = &http.Server{Handler: mux}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Warn("web UI stopped")
+		}
+	}()
+	return s, nil
+}
+
+// Addr returns the address the server is listening on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Event records the event. The output of a test which passed is removed, only
+// the output of tests which are running, failed, or were skipped is kept.
+func (s *Server) Event(event testjson.TestEvent) {
+	if event.Package == "" {
+		return
+	}
+	id := testjson.TestID(event.Package, event.Test)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case event.Action == testjson.ActionOutput:
+		out, ok := s.output[id]
+		if !ok {
+			out = &testOutput{}
+			s.output[id] = out
+		}
+		out.add(outputLine{Package: event.Package, Text: event.Output}, s.maxOutputLines)
+	case event.PackageEvent():
+	case event.Action == testjson.ActionRun:
+		s.running[id] = time.Now()
+	case event.Action == testjson.ActionPass:
+		delete(s.running, id)
+		// keep the output of a test which failed in an earlier run
+		if !s.failed[id] {
+			delete(s.output, id)
+		}
+		s.status.Passed++
+	case event.Action == testjson.ActionSkip:
+		delete(s.running, id)
+		s.status.Skipped++
+	case event.Action == testjson.ActionFail:
+		delete(s.running, id)
+		s.status.Failed++
+		if !s.failed[id] {
+			s.failed[id] = true
+			s.status.Failures = append(s.status.Failures, id)
+		}
+	}
+	s.notify()
+}
+
+// notify wakes the requests waiting for a change. Must be called with mu
+// locked.
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Done marks the run as finished. Requests which stream output end once they
+// have sent all the output.
+func (s *Server) Done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished = true
+	s.notify()
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	s.Done()
+	return s.server.Close()
+}
+
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, indexHTML)
+}
+
+func (s *Server) serveStatus(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	now := time.Now()
+	status := s.status
+	status.ElapsedSeconds = now.Sub(s.started).Seconds()
+	status.Done = s.finished
+	status.Failures = append([]string{}, s.status.Failures...)
+	status.Running = make([]Running, 0, len(s.running))
+	for id, started := range s.running {
+		status.Running = append(status.Running, Running{ID: id, ElapsedSeconds: now.Sub(started).Seconds()})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(status.Running, func(i, j int) bool {
+		return status.Running[i].ID < status.Running[j].ID
+	})
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.WithError(err).Debug("failed to write web UI status")
+	}
+}
+
+// serveOutput streams the output of the test with the id from the test query
+// parameter, as server-sent events with one line of output, as HTML, in each
+// event.
+func (s *Server) serveOutput(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	id := r.URL.Query().Get("test")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	var sent int
+	for {
+		s.mu.Lock()
+		var lines []outputLine
+		var removed int
+		if out, ok := s.output[id]; ok {
+			if sent > out.end() {
+				// the output was removed when the test passed, and the
+				// test is running again
+				sent = 0
+			}
+			lines, removed = out.since(sent)
+			sent = out.end()
+		}
+		finished := s.finished
+		changed := s.changed
+		s.mu.Unlock()
+
+		if removed > 0 {
+			fmt.Fprintf(w, "data: ... %d lines of output removed ...\n\n", removed)
+		}
+		for _, line := range lines {
+			text := s.links.HTML(line.Package, strings.TrimSuffix(line.Text, "\n"))
+			fmt.Fprintf(w, "data: %s\n\n", strings.Replace(text, "\n", "\ndata: ", -1))
+		}
+		if finished {
+			fmt.Fprint(w, "event: done\ndata:\n\n")
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package liveui
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestServer(t *testing.T) {
+	s, err := Start("127.0.0.1:0", nil)
+	assert.NilError(t, err)
+	defer s.Close() // nolint: errcheck
+	url := "http://" + s.Addr()
+
+	for _, event := range []testjson.TestEvent{
+		{Action: testjson.ActionRun, Package: "example.com/a", Test: "TestOne"},
+		{Action: testjson.ActionOutput, Package: "example.com/a", Test: "TestOne", Output: "=== RUN   TestOne\n"},
+		{Action: testjson.ActionOutput, Package: "example.com/a", Test: "TestOne", Output: "    one_test.go:9: broken\n"},
+		{Action: testjson.ActionFail, Package: "example.com/a", Test: "TestOne"},
+		{Action: testjson.ActionRun, Package: "example.com/a", Test: "TestTwo"},
+		{Action: testjson.ActionRun, Package: "example.com/a", Test: "TestThree"},
+		{Action: testjson.ActionPass, Package: "example.com/a", Test: "TestThree"},
+	} {
+		s.Event(event)
+	}
+
+	resp, err := http.Get(url + "/status")
+	assert.NilError(t, err)
+	defer resp.Body.Close() // nolint: errcheck
+	var status Status
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&status))
+	assert.Equal(t, status.Passed, 1)
+	assert.Equal(t, status.Failed, 1)
+	assert.DeepEqual(t, status.Failures, []string{"example.com/a#TestOne"})
+	assert.Equal(t, len(status.Running), 1)
+	assert.Equal(t, status.Running[0].ID, "example.com/a#TestTwo")
+	assert.Assert(t, !status.Done)
+
+	s.Done()
+	resp, err = http.Get(url + "/output?test=example.com/a%23TestOne")
+	assert.NilError(t, err)
+	defer resp.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NilError(t, err)
+	expected := "data: === RUN   TestOne\n\ndata:     one_test.go:9: broken\n\nevent: done\ndata:\n\n"
+	assert.Equal(t, string(body), expected)
+}
+
+func TestServer_Output(t *testing.T) {
+	s, err := Start("127.0.0.1:0", nil)
+	assert.NilError(t, err)
+	defer s.Close() // nolint: errcheck
+	s.maxOutputLines = 4
+
+	s.Event(testjson.TestEvent{Action: testjson.ActionRun, Package: "example.com/a", Test: "TestLong"})
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n", "six\n"} {
+		s.Event(testjson.TestEvent{Action: testjson.ActionOutput, Package: "example.com/a", Test: "TestLong", Output: line})
+	}
+	s.Event(testjson.TestEvent{Action: testjson.ActionRun, Package: "example.com/a", Test: "TestPass"})
+	s.Event(testjson.TestEvent{Action: testjson.ActionOutput, Package: "example.com/a", Test: "TestPass", Output: "ok\n"})
+	s.Event(testjson.TestEvent{Action: testjson.ActionPass, Package: "example.com/a", Test: "TestPass"})
+	s.Done()
+
+	get := func(id string) string {
+		resp, err := http.Get("http://" + s.Addr() + "/output?test=" + url.QueryEscape(id))
+		assert.NilError(t, err)
+		defer resp.Body.Close() // nolint: errcheck
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.NilError(t, err)
+		return string(body)
+	}
+	expected := "data: ... 3 lines of output removed ...\n\ndata: four\n\ndata: five\n\ndata: six\n\nevent: done\ndata:\n\n"
+	assert.Equal(t, get("example.com/a#TestLong"), expected)
+	assert.Equal(t, get("example.com/a#TestPass"), "event: done\ndata:\n\n")
+}
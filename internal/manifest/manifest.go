@@ -0,0 +1,176 @@
+/*
+Package manifest writes a run manifest, a JSON file which describes a run and
+lists the SHA256 digest of each report file, and signs files with a private
+key.
+
+A signature is a detached signature, written to a file with a .sig suffix, with
+the same format used by cosign sign-blob: the base64 encoded signature of the
+SHA256 digest of the file. A signature from an ECDSA key can be verified with
+cosign verify-blob --key.
+*/
+package manifest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Manifest describes a run, and the reports written by the run.
+type Manifest struct {
+	RunID          string    `json:"run_id"`
+	Started        time.Time `json:"started"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+	Command        string    `json:"command,omitempty"`
+	// Setup are the steps run before go test.
+	Setup []Step `json:"setup,omitempty"`
+	// Teardown is the command run after go test.
+	Teardown *Step `json:"teardown,omitempty"`
+	// Artifacts are the paths of the files collected because the run failed.
+	Artifacts []string `json:"artifacts,omitempty"`
+	Reports   []Report `json:"reports,omitempty"`
+}
+
+// Step is a command run before or after go test, or a wait for a service to
+// be ready.
+type Step struct {
+	Command string `json:"command,omitempty"`
+	WaitFor string `json:"wait_for,omitempty"`
+	// Output is the stdout and stderr of the command.
+	Output         string  `json:"output,omitempty"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	// Error is set when the command failed, or the service was not ready.
+	Error string `json:"error,omitempty"`
+}
+
+// Report is a file written by the run.
+type Report struct {
+	Path string `json:"path"`
+	// SHA256 is the hex encoded digest of the file.
+	SHA256 string `json:"sha256"`
+	// Signature is the path of the detached signature of the file, when the
+	// file was signed.
+	Signature string `json:"signature,omitempty"`
+}
+
+// NewReport returns the Report for the file at path. If signer is not nil,
+// the file is signed, and the signature is written to path.sig.
+func NewReport(path string, signer crypto.Signer) (Report, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Report{}, errors.Wrap(err, "failed to read report")
+	}
+	digest := sha256.Sum256(content)
+	report := Report{Path: path, SHA256: hex.EncodeToString(digest[:])}
+	if signer == nil {
+		return report, nil
+	}
+	report.Signature, err = writeSignature(path, content, signer)
+	return report, err
+}
+
+// Write the manifest to path. If signer is not nil, the manifest is signed,
+// and the signature is written to path.sig.
+func Write(path string, manifest Manifest, signer crypto.Signer) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode manifest")
+	}
+	content = append(content, '\n')
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return errors.Wrap(err, "failed to write manifest")
+	}
+	if signer == nil {
+		return nil
+	}
+	_, err = writeSignature(path, content, signer)
+	return err
+}
+
+func writeSignature(path string, content []byte, signer crypto.Signer) (string, error) {
+	signature, err := Sign(content, signer)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to sign %s", path)
+	}
+	sigPath := path + ".sig"
+	encoded := base64.StdEncoding.EncodeToString(signature)
+	if err := ioutil.WriteFile(sigPath, []byte(encoded), 0644); err != nil {
+		return "", errors.Wrap(err, "failed to write signature")
+	}
+	return sigPath, nil
+}
+
+// Sign returns the signature of content. An ECDSA key signs the SHA256 digest
+// of content, and an Ed25519 key signs content.
+func Sign(content []byte, signer crypto.Signer) ([]byte, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand.Reader, content, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(content)
+	return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// Verify returns an error if signature is not a valid signature of content
+// from the private key of the public key.
+func Verify(content, signature []byte, public crypto.PublicKey) error {
+	switch key := public.(type) {
+	case ed25519.PublicKey:
+		if ed25519.Verify(key, content, signature) {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(content)
+		if ecdsa.VerifyASN1(key, digest[:], signature) {
+			return nil
+		}
+	default:
+		return errors.Errorf("unsupported public key type %T", public)
+	}
+	return errors.New("invalid signature")
+}
+
+// ReadKeyFile reads an unencrypted PEM encoded ECDSA or Ed25519 private key,
+// in PKCS #8, or for ECDSA in SEC 1, form.
+func ReadKeyFile(path string) (crypto.Signer, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read signing key")
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.Errorf("signing key %s is not PEM encoded", path)
+	}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse signing key")
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse signing key")
+		}
+		switch key := key.(type) {
+		case *ecdsa.PrivateKey:
+			return key, nil
+		case ed25519.PrivateKey:
+			return key, nil
+		}
+		return nil, errors.Errorf("signing key must be an ECDSA or Ed25519 key, not %T", key)
+	default:
+		return nil, errors.Errorf("unsupported signing key type %s, the key must not be encrypted", block.Type)
+	}
+}
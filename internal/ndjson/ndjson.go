@@ -0,0 +1,407 @@
+/*
+Package ndjson writes test results as newline delimited JSON, with one row
+for each test case. Each row includes the metadata of the run, so that the
+rows can be bulk loaded into a data warehouse without any joins.
+*/
+package ndjson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Outcome of a test case.
+const (
+	OutcomePass = "pass"
+	OutcomeFail = "fail"
+	OutcomeSkip = "skip"
+	// OutcomeNotRun is the outcome of a test which started but never
+	// finished, or a package which never finished.
+	OutcomeNotRun = "notrun"
+	// OutcomeError is the outcome of a test which was running when the test
+	// binary crashed.
+	OutcomeError = "error"
+)
+
+// RunMetadata is the metadata of a run which is added to every Row.
+type RunMetadata struct {
+	RunID    string
+	Hostname string
+	// Env is the environment variables captured for the run.
+	Env map[string]string
+	// Command is the go test command line of the run.
+	Command string
+	// Branch is the version control branch of the run.
+	Branch string
+	// SkipCategories are used to set the SkipCategory of skipped tests.
+	SkipCategories testjson.SkipCategories
+	// FailureCategories are the categories of failed tests, by
+	// testjson.TestCase.ID, used to set the FailureCategory of each row.
+	FailureCategories map[string]string
+	// Timeout is the go test -timeout of the run, used to set the
+	// TimeoutRatio of each test. Zero when the timeout is not known.
+	Timeout time.Duration
+	// Labels are the labels of each test, by testjson.TestCase.ID, used to
+	// set the Labels of each row.
+	Labels map[string]map[string]string
+	// RunLabels are the labels of the run, from --label.
+	RunLabels map[string]string
+}
+
+// Row is a single test case result. The fields with a run_ prefix are the
+// same for every row from a run.
+type Row struct {
+	RunID             string            `json:"run_id"`
+	RunStarted        time.Time         `json:"run_started"`
+	RunElapsedSeconds float64           `json:"run_elapsed_seconds"`
+	RunHostname       string            `json:"run_hostname"`
+	RunTotal          int               `json:"run_total"`
+	RunFailed         int               `json:"run_failed"`
+	RunAssertions     int               `json:"run_assertions,omitempty"`
+	RunWarnings       int               `json:"run_warnings,omitempty"`
+	RunEnv            map[string]string `json:"run_env,omitempty"`
+	RunCommand        string            `json:"run_command,omitempty"`
+	RunBranch         string            `json:"run_branch,omitempty"`
+	RunLabels         map[string]string `json:"run_labels,omitempty"`
+	Package           string            `json:"package"`
+	Test              string            `json:"test"`
+	TestID            string            `json:"test_id"`
+	Outcome           string            `json:"outcome"`
+	ElapsedSeconds    float64           `json:"elapsed_seconds"`
+	// Assertions is the number of assertions made by the test, when it is
+	// known. See testjson.Package.Assertions.
+	Assertions int `json:"assertions,omitempty"`
+	// SkipCategory is the name of the category of a skipped test. See
+	// testjson.SkipCategories.
+	SkipCategory string `json:"skip_category,omitempty"`
+	// FailureCategory is the category of a failed test, from the
+	// --failure-classifier.
+	FailureCategory string `json:"failure_category,omitempty"`
+	// TimeoutRatio is the elapsed time of the test divided by the go test
+	// -timeout of the run.
+	TimeoutRatio float64 `json:"timeout_ratio,omitempty"`
+	// Labels are the gotestsum.label.* labels of the test, the same labels
+	// written as the properties of a JUnit testcase.
+	Labels map[string]string `json:"labels,omitempty"`
+	// SameBuildFailureAs is the first package which failed to build with the
+	// same output as the package of a row which failed to build. See
+	// testjson.Execution.BuildFailures.
+	SameBuildFailureAs string `json:"same_build_failure_as,omitempty"`
+}
+
+// Rows returns a Row for each test case in the execution. A package which
+// failed without any failed tests is included as a row with an empty Test.
+func Rows(exec *testjson.Execution, meta RunMetadata) []Row {
+	run := Row{
+		RunID:             meta.RunID,
+		RunStarted:        exec.Started().UTC(),
+		RunElapsedSeconds: exec.Elapsed().Seconds(),
+		RunHostname:       meta.Hostname,
+		RunTotal:          exec.Total(),
+		RunFailed:         len(exec.Failed()),
+		RunWarnings:       len(exec.GoTestWarnings()),
+		RunEnv:            meta.Env,
+		RunCommand:        meta.Command,
+		RunBranch:         meta.Branch,
+		RunLabels:         meta.RunLabels,
+	}
+	newRow := func(tc testjson.TestCase, outcome string) Row {
+		row := run
+		row.Package = tc.Package
+		row.Test = tc.Test
+		row.TestID = tc.ID()
+		row.Outcome = outcome
+		row.ElapsedSeconds = tc.Elapsed.Seconds()
+		row.Labels = meta.Labels[row.TestID]
+		if meta.Timeout > 0 && tc.Test != "" {
+			row.TimeoutRatio = tc.Elapsed.Seconds() / meta.Timeout.Seconds()
+		}
+		return row
+	}
+
+	sameBuildFailure := make(map[string]string)
+	for _, failure := range exec.BuildFailures() {
+		for _, pkgname := range failure.Packages[1:] {
+			sameBuildFailure[pkgname] = failure.Packages[0]
+		}
+	}
+
+	var rows []Row
+	for _, pkgname := range exec.Packages() {
+		pkg := exec.Package(pkgname)
+		if pkg.TestMainFailed() {
+			tc := testjson.TestCase{Package: pkgname}
+			row := newRow(tc, OutcomeFail)
+			row.FailureCategory = meta.FailureCategories[tc.ID()]
+			row.SameBuildFailureAs = sameBuildFailure[pkgname]
+			rows = append(rows, row)
+		}
+		newTestRow := func(tc testjson.TestCase, outcome string) Row {
+			row := newRow(tc, outcome)
+			row.Assertions, _ = pkg.Assertions(tc.Test)
+			return row
+		}
+		crashed := make(map[string]bool)
+		if crash := pkg.Crash(); crash != nil {
+			for _, test := range crash.Tests {
+				crashed[test] = true
+			}
+		}
+		for _, tc := range pkg.Failed {
+			if crashed[tc.Test] {
+				rows = append(rows, newTestRow(tc, OutcomeError))
+				continue
+			}
+			row := newTestRow(tc, OutcomeFail)
+			row.FailureCategory = meta.FailureCategories[tc.ID()]
+			rows = append(rows, row)
+		}
+		for _, tc := range pkg.Skipped {
+			row := newTestRow(tc, OutcomeSkip)
+			row.SkipCategory = meta.SkipCategories.Category(pkg, tc.Test)
+			rows = append(rows, row)
+		}
+		for _, tc := range pkg.Passed {
+			rows = append(rows, newTestRow(tc, OutcomePass))
+		}
+	}
+	for _, tc := range exec.NotRun() {
+		rows = append(rows, newRow(tc, OutcomeNotRun))
+	}
+
+	total := 0
+	for _, row := range rows {
+		total += row.Assertions
+	}
+	for i := range rows {
+		rows[i].RunAssertions = total
+	}
+	return rows
+}
+
+// Write the rows of the execution to out, one JSON object per line.
+func Write(out io.Writer, exec *testjson.Execution, meta RunMetadata) error {
+	encoder := json.NewEncoder(out)
+	for _, row := range Rows(exec, meta) {
+		if err := encoder.Encode(row); err != nil {
+			return errors.Wrap(err, "failed to write NDJSON")
+		}
+	}
+	return nil
+}
+
+// Read rows written by Write.
+func Read(in io.Reader) ([]Row, error) {
+	var rows []Row
+	decoder := json.NewDecoder(bufio.NewReader(in))
+	for {
+		var row Row
+		switch err := decoder.Decode(&row); {
+		case err == io.EOF:
+			return rows, nil
+		case err != nil:
+			return rows, errors.Wrap(err, "failed to read NDJSON")
+		}
+		rows = append(rows, row)
+	}
+}
+
+// Validate checks that every line of in is a Row, with no unknown fields, and
+// that every row has a run_id, a package, a test_id, and a known outcome.
+func Validate(in io.Reader) error {
+	reader := bufio.NewReader(in)
+	for number := 1; ; number++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if err := validateRow(line); err != nil {
+				return errors.Wrapf(err, "line %d", number)
+			}
+		}
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return errors.Wrap(err, "failed to read NDJSON")
+		}
+	}
+}
+
+func validateRow(line []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.DisallowUnknownFields()
+	var row Row
+	if err := decoder.Decode(&row); err != nil {
+		return errors.Wrap(err, "invalid row")
+	}
+	switch {
+	case row.RunID == "":
+		return errors.New("missing run_id")
+	case row.Package == "":
+		return errors.New("missing package")
+	case row.TestID == "":
+		return errors.New("missing test_id")
+	}
+	switch row.Outcome {
+	case OutcomePass, OutcomeFail, OutcomeSkip, OutcomeNotRun, OutcomeError:
+		return nil
+	default:
+		return errors.Errorf("unknown outcome %q", row.Outcome)
+	}
+}
+
+// PackageDurations returns the average time spent running the tests of each
+// package, across all the runs in rows.
+func PackageDurations(rows []Row) map[string]time.Duration {
+	type key struct{ run, pkg string }
+	totals := make(map[key]float64)
+	for _, row := range rows {
+		totals[key{run: row.RunID, pkg: row.Package}] += row.ElapsedSeconds
+	}
+
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for k, seconds := range totals {
+		sums[k.pkg] += seconds
+		counts[k.pkg]++
+	}
+	durations := make(map[string]time.Duration, len(sums))
+	for pkg, seconds := range sums {
+		durations[pkg] = time.Duration(seconds / float64(counts[pkg]) * float64(time.Second))
+	}
+	return durations
+}
+
+// PackageFailures returns the number of runs in rows in which each package
+// had a failed or crashed test, or failed without a failed test.
+func PackageFailures(rows []Row) map[string]int {
+	type key struct{ run, pkg string }
+	seen := make(map[key]bool)
+	failures := make(map[string]int)
+	for _, row := range rows {
+		k := key{run: row.RunID, pkg: row.Package}
+		if (row.Outcome != OutcomeFail && row.Outcome != OutcomeError) || seen[k] {
+			continue
+		}
+		seen[k] = true
+		failures[row.Package]++
+	}
+	return failures
+}
+
+// PackageFlakeRates returns the fraction of the runs in rows in which each
+// package had a failed or crashed test which passed in another run. Packages
+// which never had a flaky failure are not included.
+func PackageFlakeRates(rows []Row) map[string]float64 {
+	passed := make(map[string]bool)
+	for _, row := range rows {
+		if row.Outcome == OutcomePass {
+			passed[row.TestID] = true
+		}
+	}
+
+	type key struct{ run, pkg string }
+	runs := make(map[key]bool)
+	flaky := make(map[key]bool)
+	for _, row := range rows {
+		k := key{run: row.RunID, pkg: row.Package}
+		runs[k] = true
+		if (row.Outcome == OutcomeFail || row.Outcome == OutcomeError) && passed[row.TestID] {
+			flaky[k] = true
+		}
+	}
+
+	counts := make(map[string]int)
+	for k := range runs {
+		counts[k.pkg]++
+	}
+	flakyCounts := make(map[string]int)
+	for k := range flaky {
+		flakyCounts[k.pkg]++
+	}
+	rates := make(map[string]float64, len(flakyCounts))
+	for pkg, count := range flakyCounts {
+		rates[pkg] = float64(count) / float64(counts[pkg])
+	}
+	return rates
+}
+
+// ReadFiles reads the rows from each of the files. A file may be a local path,
+// or an http or https URL.
+func ReadFiles(paths []string) ([]Row, error) {
+	var rows []Row
+	for _, path := range paths {
+		fileRows, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, fileRows...)
+	}
+	return rows, nil
+}
+
+func readFile(path string) ([]Row, error) {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return readURL(path)
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close() // nolint: errcheck
+	rows, err := Read(in)
+	return rows, errors.Wrapf(err, "failed to read %s", path)
+}
+
+// httpClient is used to read the files stored remotely. The timeout includes
+// reading the body, so that an endpoint which stops responding does not block
+// the run.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// readURL reads rows from a file stored remotely, for example in a bucket
+// shared by every CI machine. A file which does not exist has no rows.
+func readURL(url string) ([]Row, error) {
+	resp, err := httpClient.Get(url) // nolint: gosec
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", url)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		// there is no history yet, for example from a new branch
+		return nil, nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, errors.Errorf("failed to read %s: %s", url, resp.Status)
+	}
+	rows, err := Read(resp.Body)
+	return rows, errors.Wrapf(err, "failed to read %s", url)
+}
+
+// LastRun returns the rows of the most recent run of branch. Returns nil if
+// there are no runs of branch.
+func LastRun(rows []Row, branch string) []Row {
+	var last Row
+	for _, row := range rows {
+		if row.RunBranch == branch && row.RunStarted.After(last.RunStarted) {
+			last = row
+		}
+	}
+	if last.RunID == "" {
+		return nil
+	}
+	var run []Row
+	for _, row := range rows {
+		if row.RunID == last.RunID {
+			run = append(run, row)
+		}
+	}
+	return run
+}
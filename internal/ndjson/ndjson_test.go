@@ -0,0 +1,230 @@
+package ndjson
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOne","Elapsed":0.5}
+{"Action":"run","Package":"example.com/a","Test":"TestTwo"}
+{"Action":"fail","Package":"example.com/a","Test":"TestTwo","Elapsed":1.25}
+{"Action":"fail","Package":"example.com/a"}
+{"Action":"output","Package":"example.com/b","Output":"panic in init\n"}
+{"Action":"fail","Package":"example.com/b"}
+`))
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	meta := RunMetadata{RunID: "run-1", Hostname: "ci-7"}
+	assert.NilError(t, Write(out, exec, meta))
+	assert.Equal(t, strings.Count(out.String(), "\n"), 3)
+
+	rows, err := Read(out)
+	assert.NilError(t, err)
+	assert.Equal(t, len(rows), 3)
+
+	row := rows[0]
+	assert.Equal(t, row.RunID, "run-1")
+	assert.Equal(t, row.RunHostname, "ci-7")
+	assert.Equal(t, row.RunTotal, 2)
+	assert.Equal(t, row.RunFailed, 2)
+	assert.Equal(t, row.TestID, "example.com/a#TestTwo")
+	assert.Equal(t, row.Outcome, OutcomeFail)
+	assert.Equal(t, row.ElapsedSeconds, 1.25)
+
+	assert.Equal(t, rows[1].Outcome, OutcomePass)
+	assert.Equal(t, rows[2].Package, "example.com/b")
+	assert.Equal(t, rows[2].Test, "")
+}
+
+func TestRows_SkipCategory(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestCloud"}
+{"Action":"output","Package":"example.com/a","Test":"TestCloud","Output":"    cloud_test.go:10: API_TOKEN is not set\n"}
+{"Action":"skip","Package":"example.com/a","Test":"TestCloud"}
+{"Action":"run","Package":"example.com/a","Test":"TestOther"}
+{"Action":"skip","Package":"example.com/a","Test":"TestOther"}
+{"Action":"skip","Package":"example.com/a"}
+`))
+	assert.NilError(t, err)
+
+	rows := Rows(exec, RunMetadata{SkipCategories: testjson.SkipCategories{
+		{Name: "missing-credential", Pattern: regexp.MustCompile(`_TOKEN is not set`)},
+	}})
+	assert.Equal(t, len(rows), 2)
+	assert.Equal(t, rows[0].SkipCategory, "missing-credential")
+	assert.Equal(t, rows[1].SkipCategory, "")
+}
+
+func TestRows_FailureCategory(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestDB"}
+{"Action":"fail","Package":"example.com/a","Test":"TestDB"}
+{"Action":"run","Package":"example.com/a","Test":"TestMath"}
+{"Action":"fail","Package":"example.com/a","Test":"TestMath"}
+{"Action":"fail","Package":"example.com/a"}
+`))
+	assert.NilError(t, err)
+
+	rows := Rows(exec, RunMetadata{FailureCategories: map[string]string{
+		"example.com/a#TestDB": "infra",
+	}})
+	assert.Equal(t, len(rows), 2)
+	assert.Equal(t, rows[0].FailureCategory, "infra")
+	assert.Equal(t, rows[1].FailureCategory, "")
+}
+
+func TestRows_SameBuildFailure(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"ImportPath":"example.com/core","Action":"build-output","Output":"# example.com/core\n"}
+{"ImportPath":"example.com/core","Action":"build-output","Output":"core/core.go:5:2: undefined: missing\n"}
+{"ImportPath":"example.com/core","Action":"build-fail"}
+{"Action":"output","Package":"example.com/api","Output":"FAIL\texample.com/api [build failed]\n"}
+{"Action":"fail","Package":"example.com/api","FailedBuild":"example.com/core"}
+{"Action":"output","Package":"example.com/db","Output":"FAIL\texample.com/db [build failed]\n"}
+{"Action":"fail","Package":"example.com/db","FailedBuild":"example.com/core"}
+{"Action":"output","Package":"example.com/web","Output":"FAIL\texample.com/web [build failed]\n"}
+{"Action":"fail","Package":"example.com/web","FailedBuild":"example.com/core"}
+`))
+	assert.NilError(t, err)
+
+	rows := Rows(exec, RunMetadata{})
+	assert.Equal(t, len(rows), 3)
+	assert.Equal(t, rows[0].SameBuildFailureAs, "")
+	assert.Equal(t, rows[1].SameBuildFailureAs, "example.com/api")
+	assert.Equal(t, rows[2].SameBuildFailureAs, "example.com/api")
+}
+
+func TestRows_TimeoutRatio(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestSlow"}
+{"Action":"pass","Package":"example.com/a","Test":"TestSlow","Elapsed":150}
+{"Action":"pass","Package":"example.com/a","Elapsed":150}
+`))
+	assert.NilError(t, err)
+
+	rows := Rows(exec, RunMetadata{Timeout: 10 * time.Minute})
+	assert.Equal(t, len(rows), 1)
+	assert.Equal(t, rows[0].TimeoutRatio, 0.25)
+
+	rows = Rows(exec, RunMetadata{})
+	assert.Equal(t, rows[0].TimeoutRatio, 0.0)
+}
+
+func TestPackageDurations(t *testing.T) {
+	rows := []Row{
+		{RunID: "1", Package: "a", ElapsedSeconds: 1},
+		{RunID: "1", Package: "a", ElapsedSeconds: 2},
+		{RunID: "2", Package: "a", ElapsedSeconds: 5},
+		{RunID: "2", Package: "b", ElapsedSeconds: 0.5},
+	}
+	durations := PackageDurations(rows)
+	assert.Equal(t, durations["a"], 4*time.Second)
+	assert.Equal(t, durations["b"], 500*time.Millisecond)
+}
+
+func TestPackageFailures(t *testing.T) {
+	rows := []Row{
+		{RunID: "1", Package: "a", Outcome: OutcomeFail},
+		{RunID: "1", Package: "a", Outcome: OutcomeFail},
+		{RunID: "2", Package: "a", Outcome: OutcomeFail},
+		{RunID: "2", Package: "b", Outcome: OutcomePass},
+		{RunID: "3", Package: "b", Outcome: OutcomeFail},
+	}
+	assert.DeepEqual(t, PackageFailures(rows), map[string]int{"a": 2, "b": 1})
+}
+
+func TestPackageFlakeRates(t *testing.T) {
+	rows := []Row{
+		{RunID: "1", Package: "a", TestID: "a.TestA", Outcome: OutcomeFail},
+		{RunID: "2", Package: "a", TestID: "a.TestA", Outcome: OutcomePass},
+		{RunID: "3", Package: "a", TestID: "a.TestA", Outcome: OutcomePass},
+		{RunID: "4", Package: "a", TestID: "a.TestA", Outcome: OutcomeError},
+		{RunID: "1", Package: "b", TestID: "b.TestB", Outcome: OutcomeFail},
+		{RunID: "2", Package: "b", TestID: "b.TestB", Outcome: OutcomeFail},
+		{RunID: "1", Package: "c", TestID: "c.TestC", Outcome: OutcomePass},
+	}
+	assert.DeepEqual(t, PackageFlakeRates(rows), map[string]float64{"a": 0.5})
+}
+
+func TestLastRun(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2019, 4, d, 0, 0, 0, 0, time.UTC)
+	}
+	rows := []Row{
+		{RunID: "1", RunBranch: "main", RunStarted: day(1), Test: "TestA"},
+		{RunID: "2", RunBranch: "main", RunStarted: day(3), Test: "TestA"},
+		{RunID: "2", RunBranch: "main", RunStarted: day(3), Test: "TestB"},
+		{RunID: "3", RunBranch: "feature", RunStarted: day(4), Test: "TestA"},
+		{RunID: "4", RunBranch: "main", RunStarted: day(2), Test: "TestA"},
+	}
+	run := LastRun(rows, "main")
+	assert.DeepEqual(t, run, rows[1:3])
+	assert.Assert(t, LastRun(rows, "other") == nil)
+}
+
+func TestReadFiles_URL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/main.ndjson" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"run_id":"1","test":"TestA","outcome":"fail"}` + "\n")) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	rows, err := ReadFiles([]string{server.URL + "/main.ndjson"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(rows), 1)
+	assert.Equal(t, rows[0].Outcome, OutcomeFail)
+
+	rows, err = ReadFiles([]string{server.URL + "/missing.ndjson"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(rows), 0)
+}
+
+func TestReadFiles_URLError(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow.ndjson":
+			<-unblock
+		default:
+			http.Error(w, `{"run_id":"1","test":"TestA"}`, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	_, err := ReadFiles([]string{server.URL + "/error.ndjson"})
+	assert.ErrorContains(t, err, "500 Internal Server Error")
+
+	defer func(client *http.Client) { httpClient = client }(httpClient)
+	httpClient = &http.Client{Timeout: 50 * time.Millisecond}
+	_, err = ReadFiles([]string{server.URL + "/slow.ndjson"})
+	assert.ErrorContains(t, err, "Client.Timeout exceeded")
+}
+
+func TestRows_Labels(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestDB"}
+{"Action":"fail","Package":"example.com/a","Test":"TestDB"}
+{"Action":"run","Package":"example.com/a","Test":"TestMath"}
+{"Action":"pass","Package":"example.com/a","Test":"TestMath"}
+{"Action":"fail","Package":"example.com/a"}
+`))
+	assert.NilError(t, err)
+
+	labels := map[string]string{"gotestsum.label.failure-category": "infra"}
+	rows := Rows(exec, RunMetadata{Labels: map[string]map[string]string{
+		"example.com/a#TestDB": labels,
+	}})
+	assert.Equal(t, len(rows), 2)
+	assert.DeepEqual(t, rows[0].Labels, labels)
+	assert.Assert(t, rows[1].Labels == nil)
+}
@@ -0,0 +1,80 @@
+package trend
+
+// Messages is a catalog of the human readable text in the HTML report.
+type Messages struct {
+	Title           string
+	HeadingPassRate string
+	HeadingDuration string
+	HeadingRuns     string
+	HeadingFlaky    string
+	ColumnRun       string
+	ColumnStarted   string
+	ColumnTests     string
+	ColumnTest      string
+	ColumnPassed    string
+	ColumnFailed    string
+	ColumnSkipped   string
+	ColumnPassRate  string
+	ColumnDuration  string
+	// ColumnFlips is the heading of the number of times the result of a test
+	// changed from one run to the next.
+	ColumnFlips string
+	// NoFlakyTests is printed in place of the table of flaky tests when no
+	// test both passed and failed.
+	NoFlakyTests string
+}
+
+// EnglishMessages is the default Messages catalog.
+var EnglishMessages = Messages{
+	Title:           "Test trend",
+	HeadingPassRate: "Pass rate",
+	HeadingDuration: "Duration",
+	HeadingRuns:     "Runs",
+	HeadingFlaky:    "Flakiest tests",
+	ColumnRun:       "Run",
+	ColumnStarted:   "Started",
+	ColumnTests:     "Tests",
+	ColumnTest:      "Test",
+	ColumnPassed:    "Passed",
+	ColumnFailed:    "Failed",
+	ColumnSkipped:   "Skipped",
+	ColumnPassRate:  "Pass rate",
+	ColumnDuration:  "Duration",
+	ColumnFlips:     "Result changes",
+	NoFlakyTests:    "No test both passed and failed.",
+}
+
+// JapaneseMessages is the Japanese Messages catalog.
+var JapaneseMessages = Messages{
+	Title:           "テストの推移",
+	HeadingPassRate: "成功率",
+	HeadingDuration: "実行時間",
+	HeadingRuns:     "実行",
+	HeadingFlaky:    "不安定なテスト",
+	ColumnRun:       "実行",
+	ColumnStarted:   "開始",
+	ColumnTests:     "テスト数",
+	ColumnTest:      "テスト",
+	ColumnPassed:    "成功",
+	ColumnFailed:    "失敗",
+	ColumnSkipped:   "スキップ",
+	ColumnPassRate:  "成功率",
+	ColumnDuration:  "実行時間",
+	ColumnFlips:     "結果の変化",
+	NoFlakyTests:    "成功と失敗の両方があったテストはありません。",
+}
+
+var catalogs = map[string]Messages{
+	"en": EnglishMessages,
+	"ja": JapaneseMessages,
+}
+
+// NewMessages returns the Messages catalog for lang, a language returned by
+// testjson.Language. Returns EnglishMessages if there is no catalog for the
+// language.
+func NewMessages(lang string) Messages {
+	if messages, ok := catalogs[lang]; ok {
+		return messages
+	}
+	return EnglishMessages
+}
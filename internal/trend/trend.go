@@ -0,0 +1,262 @@
+/*
+Package trend renders an HTML report of the trend of pass rate, duration, and
+flaky tests across multiple test runs.
+*/
+package trend
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run is the result of a single test run, read from a file written by
+// gotestsum --jsonfile.
+type Run struct {
+	Name    string
+	Started time.Time
+	Elapsed time.Duration
+	Total   int
+	// Failed is the number of tests which failed. Packages which failed
+	// without a failed test, like a build failure, are not included.
+	Failed  int
+	Skipped int
+	// results maps a test ID to the final action of the test in the run.
+	results map[string]testjson.Action
+}
+
+// PassRate returns the percentage of tests which did not fail.
+func (r Run) PassRate() float64 {
+	if r.Total == 0 {
+		return 100
+	}
+	return 100 * float64(r.Total-r.Failed) / float64(r.Total)
+}
+
+// ReadRun reads the events written by gotestsum --jsonfile and returns a Run.
+func ReadRun(name string, in io.Reader) (Run, error) {
+	timer := &eventTimer{}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  in,
+		Stderr:  strings.NewReader(""),
+		Handler: timer,
+	})
+	if err != nil {
+		return Run{}, errors.Wrapf(err, "failed to read %s", name)
+	}
+	run := Run{
+		Name:    name,
+		Started: timer.first,
+		Elapsed: timer.last.Sub(timer.first),
+		Total:   exec.Total(),
+		Skipped: len(exec.Skipped()),
+		results: make(map[string]testjson.Action),
+	}
+	for _, tc := range exec.Failed() {
+		if tc.Test != "" {
+			run.Failed++
+		}
+	}
+	for _, pkgname := range exec.Packages() {
+		pkg := exec.Package(pkgname)
+		for _, tc := range pkg.Passed {
+			run.results[tc.ID()] = testjson.ActionPass
+		}
+		for _, tc := range pkg.Skipped {
+			run.results[tc.ID()] = testjson.ActionSkip
+		}
+		for _, tc := range pkg.Failed {
+			run.results[tc.ID()] = testjson.ActionFail
+		}
+	}
+	return run, nil
+}
+
+// eventTimer records the time of the first and last event.
+type eventTimer struct {
+	first time.Time
+	last  time.Time
+}
+
+func (t *eventTimer) Event(event testjson.TestEvent, _ *testjson.Execution) error {
+	if event.Time.IsZero() {
+		return nil
+	}
+	if t.first.IsZero() || event.Time.Before(t.first) {
+		t.first = event.Time
+	}
+	if event.Time.After(t.last) {
+		t.last = event.Time
+	}
+	return nil
+}
+
+func (t *eventTimer) Err(string) error {
+	return nil
+}
+
+// FlakyTest is a test which both passed and failed in the runs.
+type FlakyTest struct {
+	ID     string
+	Passed int
+	Failed int
+	// Flips is the number of times the result changed from one run to the
+	// next.
+	Flips int
+}
+
+// FlakyTests returns the tests which both passed and failed, sorted by the
+// number of times the result changed, most first. runs must be sorted by
+// start time.
+func FlakyTests(runs []Run) []FlakyTest {
+	byID := make(map[string]*FlakyTest)
+	previous := make(map[string]testjson.Action)
+	for _, run := range runs {
+		for id, action := range run.results {
+			flaky, ok := byID[id]
+			if !ok {
+				flaky = &FlakyTest{ID: id}
+				byID[id] = flaky
+			}
+			switch action {
+			case testjson.ActionPass:
+				flaky.Passed++
+			case testjson.ActionFail:
+				flaky.Failed++
+			default:
+				continue
+			}
+			if prev, ok := previous[id]; ok && prev != action {
+				flaky.Flips++
+			}
+			previous[id] = action
+		}
+	}
+
+	var result []FlakyTest
+	for _, flaky := range byID {
+		if flaky.Passed > 0 && flaky.Failed > 0 {
+			result = append(result, *flaky)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Flips != result[j].Flips {
+			return result[i].Flips > result[j].Flips
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// SortRuns sorts runs by the time they started.
+func SortRuns(runs []Run) {
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Started.Before(runs[j].Started)
+	})
+}
+
+// maxFlakyTests is the number of flaky tests included in the report.
+const maxFlakyTests = 25
+
+// WriteHTML writes a static HTML page with the trend of the runs, using the
+// text from msgs. runs must be sorted by start time.
+func WriteHTML(out io.Writer, runs []Run, msgs Messages) error {
+	flaky := FlakyTests(runs)
+	if len(flaky) > maxFlakyTests {
+		flaky = flaky[:maxFlakyTests]
+	}
+	var maxElapsed time.Duration
+	for _, run := range runs {
+		if run.Elapsed > maxElapsed {
+			maxElapsed = run.Elapsed
+		}
+	}
+	data := struct {
+		Runs       []Run
+		Flaky      []FlakyTest
+		MaxElapsed time.Duration
+		Msgs       Messages
+	}{Runs: runs, Flaky: flaky, MaxElapsed: maxElapsed, Msgs: msgs}
+	return errors.Wrap(page.Execute(out, data), "failed to write HTML trend report")
+}
+
+const barWidth = 24
+
+var page = template.Must(template.New("trend").Funcs(template.FuncMap{
+	"x": func(i int) int {
+		return i * barWidth
+	},
+	"height": func(value, max float64) float64 {
+		if max == 0 {
+			return 0
+		}
+		return 100 * value / max
+	},
+	"seconds": func(d time.Duration) float64 {
+		return d.Seconds()
+	},
+	"sub100": func(value float64) float64 {
+		return 100 - value
+	},
+	"width": func(runs []Run) int {
+		return len(runs) * barWidth
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Msgs.Title }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 0.8em; text-align: left; border-bottom: 1px solid #ddd; }
+svg { background: #f8f8f8; margin-bottom: 1em; }
+.pass { fill: #3a3; }
+.time { fill: #36c; }
+</style>
+</head>
+<body>
+<h1>{{ .Msgs.Title }}</h1>
+
+<h2>{{ .Msgs.HeadingPassRate }}</h2>
+<svg width="{{ width .Runs }}" height="100">
+{{- range $i, $run := .Runs }}
+<rect class="pass" x="{{ x $i }}" y="{{ printf "%.1f" (sub100 $run.PassRate) }}" width="{{ x 1 }}" height="{{ printf "%.1f" $run.PassRate }}"><title>{{ $run.Name }}: {{ printf "%.1f" $run.PassRate }}%</title></rect>
+{{- end }}
+</svg>
+
+<h2>{{ .Msgs.HeadingDuration }}</h2>
+<svg width="{{ width .Runs }}" height="100">
+{{- range $i, $run := .Runs }}
+{{- $h := height (seconds $run.Elapsed) (seconds $.MaxElapsed) }}
+<rect class="time" x="{{ x $i }}" y="{{ printf "%.1f" (sub100 $h) }}" width="{{ x 1 }}" height="{{ printf "%.1f" $h }}"><title>{{ $run.Name }}: {{ $run.Elapsed }}</title></rect>
+{{- end }}
+</svg>
+
+<h2>{{ .Msgs.HeadingRuns }}</h2>
+<table>
+<tr><th>{{ .Msgs.ColumnRun }}</th><th>{{ .Msgs.ColumnStarted }}</th><th>{{ .Msgs.ColumnTests }}</th><th>{{ .Msgs.ColumnFailed }}</th><th>{{ .Msgs.ColumnSkipped }}</th><th>{{ .Msgs.ColumnPassRate }}</th><th>{{ .Msgs.ColumnDuration }}</th></tr>
+{{- range .Runs }}
+<tr><td>{{ .Name }}</td><td>{{ .Started.Format "2006-01-02 15:04:05" }}</td><td>{{ .Total }}</td><td>{{ .Failed }}</td><td>{{ .Skipped }}</td><td>{{ printf "%.1f" .PassRate }}%</td><td>{{ .Elapsed }}</td></tr>
+{{- end }}
+</table>
+
+<h2>{{ .Msgs.HeadingFlaky }}</h2>
+{{- if .Flaky }}
+<table>
+<tr><th>{{ .Msgs.ColumnTest }}</th><th>{{ .Msgs.ColumnPassed }}</th><th>{{ .Msgs.ColumnFailed }}</th><th>{{ .Msgs.ColumnFlips }}</th></tr>
+{{- range .Flaky }}
+<tr><td>{{ .ID }}</td><td>{{ .Passed }}</td><td>{{ .Failed }}</td><td>{{ .Flips }}</td></tr>
+{{- end }}
+</table>
+{{- else }}
+<p>{{ .Msgs.NoFlakyTests }}</p>
+{{- end }}
+</body>
+</html>
+`))
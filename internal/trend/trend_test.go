@@ -0,0 +1,88 @@
+package trend
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/golden"
+	"gotest.tools/gotestsum/testjson"
+)
+
+const runOne = `{"Time":"2019-04-01T10:00:00Z","Action":"run","Package":"example.com/a","Test":"TestFlaky"}
+{"Time":"2019-04-01T10:00:01Z","Action":"pass","Package":"example.com/a","Test":"TestFlaky"}
+{"Time":"2019-04-01T10:00:01Z","Action":"run","Package":"example.com/a","Test":"TestStable"}
+{"Time":"2019-04-01T10:00:02Z","Action":"pass","Package":"example.com/a","Test":"TestStable"}
+{"Time":"2019-04-01T10:00:02Z","Action":"pass","Package":"example.com/a"}
+`
+
+const runTwo = `{"Time":"2019-04-02T10:00:00Z","Action":"run","Package":"example.com/a","Test":"TestFlaky"}
+{"Time":"2019-04-02T10:00:03Z","Action":"fail","Package":"example.com/a","Test":"TestFlaky"}
+{"Time":"2019-04-02T10:00:03Z","Action":"run","Package":"example.com/a","Test":"TestStable"}
+{"Time":"2019-04-02T10:00:04Z","Action":"pass","Package":"example.com/a","Test":"TestStable"}
+{"Time":"2019-04-02T10:00:04Z","Action":"fail","Package":"example.com/a"}
+`
+
+func readRuns(t *testing.T) []Run {
+	second, err := ReadRun("two", strings.NewReader(runTwo))
+	assert.NilError(t, err)
+	first, err := ReadRun("one", strings.NewReader(runOne))
+	assert.NilError(t, err)
+	runs := []Run{second, first}
+	SortRuns(runs)
+	return runs
+}
+
+func TestReadRun(t *testing.T) {
+	runs := readRuns(t)
+	assert.Equal(t, runs[0].Name, "one")
+	assert.Equal(t, runs[1].Total, 2)
+	assert.Equal(t, runs[1].Failed, 1)
+	assert.Equal(t, runs[1].PassRate(), 50.0)
+	assert.Equal(t, runs[1].Elapsed.String(), "4s")
+}
+
+func TestFlakyTests(t *testing.T) {
+	flaky := FlakyTests(readRuns(t))
+	assert.Equal(t, len(flaky), 1)
+	assert.Equal(t, flaky[0], FlakyTest{
+		ID:     "example.com/a#TestFlaky",
+		Passed: 1,
+		Failed: 1,
+		Flips:  1,
+	})
+}
+
+func TestWriteHTML(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := WriteHTML(out, readRuns(t), EnglishMessages)
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "trend.golden")
+}
+
+func TestReadRun_PackageFailure(t *testing.T) {
+	in := `{"Time":"2019-04-03T10:00:00Z","Action":"run","Package":"example.com/a","Test":"TestOne"}
+{"Time":"2019-04-03T10:00:01Z","Action":"pass","Package":"example.com/a","Test":"TestOne"}
+{"Time":"2019-04-03T10:00:01Z","Action":"output","Package":"example.com/a","Output":"panic: in TestMain\n"}
+{"Time":"2019-04-03T10:00:01Z","Action":"fail","Package":"example.com/a"}
+{"Time":"2019-04-03T10:00:01Z","Action":"output","Package":"example.com/b","Output":"# example.com/b\n"}
+{"Time":"2019-04-03T10:00:01Z","Action":"fail","Package":"example.com/b"}
+`
+	run, err := ReadRun("three", strings.NewReader(in))
+	assert.NilError(t, err)
+	assert.Equal(t, run.Total, 1)
+	assert.Equal(t, run.Failed, 0)
+	assert.Equal(t, run.PassRate(), 100.0)
+}
+
+func TestNewMessages(t *testing.T) {
+	assert.Equal(t, NewMessages("ja").Title, JapaneseMessages.Title)
+	assert.Equal(t, NewMessages("xx").Title, EnglishMessages.Title)
+
+	// every language accepted by --lang has a catalog
+	for _, lang := range testjson.Languages() {
+		_, ok := catalogs[lang]
+		assert.Assert(t, ok, "no catalog for %s", lang)
+	}
+}
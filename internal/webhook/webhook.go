@@ -0,0 +1,172 @@
+/*
+Package webhook posts batches of test events to a URL while the tests run, so
+that an external orchestrator can react to a failure as soon as it happens.
+*/
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Config of a Sender.
+type Config struct {
+	URL string
+	// RunID is included in every batch.
+	RunID string
+	// Labels and Title are included in every batch, so that a receiver of
+	// batches from many runs can route them.
+	Labels map[string]string
+	Title  string
+	// BatchSize is the maximum number of events in a batch. Defaults to 100.
+	BatchSize int
+	// FlushInterval is the maximum time an event waits before it is sent.
+	// Defaults to 1 second.
+	FlushInterval time.Duration
+	// Retries is the number of times a batch is sent again after a network
+	// error, a 429, or a 5xx response.
+	Retries int
+	// Backoff is the wait before the first retry. The wait is doubled for each
+	// retry. Defaults to 500 milliseconds.
+	Backoff time.Duration
+	Client  *http.Client
+}
+
+// Batch is the JSON body of each request.
+type Batch struct {
+	RunID  string               `json:"run_id"`
+	Title  string               `json:"title,omitempty"`
+	Labels map[string]string    `json:"labels,omitempty"`
+	Events []testjson.TestEvent `json:"events"`
+}
+
+// Sender posts batches of events to the URL from a goroutine. Send blocks when
+// the buffer of events is full, which applies backpressure to the run instead
+// of dropping events when the receiver is slow.
+type Sender struct {
+	config Config
+	events chan testjson.TestEvent
+	done   chan struct{}
+	err    error
+}
+
+// New returns a Sender and starts the goroutine which sends the batches.
+func New(config Config) *Sender {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = 500 * time.Millisecond
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	s := &Sender{
+		config: config,
+		events: make(chan testjson.TestEvent, 10*config.BatchSize),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Send queues the event to be sent in the next batch.
+func (s *Sender) Send(event testjson.TestEvent) {
+	s.events <- event
+}
+
+// Close sends any queued events, and returns the first error from sending a
+// batch.
+func (s *Sender) Close() error {
+	close(s.events)
+	<-s.done
+	return s.err
+}
+
+func (s *Sender) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []testjson.TestEvent
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.post(batch); err != nil {
+			log.WithError(err).Warn("failed to send events to webhook")
+			if s.err == nil {
+				s.err = err
+			}
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case event, ok := <-s.events:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= s.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (s *Sender) post(events []testjson.TestEvent) error {
+	body, err := json.Marshal(Batch{
+		RunID:  s.config.RunID,
+		Title:  s.config.Title,
+		Labels: s.config.Labels,
+		Events: events,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode events")
+	}
+	wait := s.config.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.postOnce(body)
+		if err == nil || !retry || attempt >= s.config.Retries {
+			return err
+		}
+		log.Debugf("webhook request failed, retry in %s: %s", wait, err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// postOnce sends the request, and returns true if a failed request should be
+// retried.
+func (s *Sender) postOnce(body []byte) (bool, error) {
+	resp, err := s.config.Client.Post(s.config.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, errors.Wrap(err, "failed to send webhook request")
+	}
+	defer resp.Body.Close()            // nolint: errcheck
+	io.Copy(ioutil.Discard, resp.Body) // nolint: errcheck
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, errors.Errorf("webhook responded with %s", resp.Status)
+	default:
+		return false, errors.Errorf("webhook responded with %s", resp.Status)
+	}
+}
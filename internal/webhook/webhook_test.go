@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	batches  []Batch
+	failures int
+	status   int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+		return
+	}
+	var batch Batch
+	if err := json.NewDecoder(req.Body).Decode(&batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.batches = append(r.batches, batch)
+}
+
+func newEvents(n int) []testjson.TestEvent {
+	events := make([]testjson.TestEvent, n)
+	for i := range events {
+		events[i] = testjson.TestEvent{Action: testjson.ActionPass, Package: "pkg", Test: "TestA"}
+	}
+	return events
+}
+
+func TestSender(t *testing.T) {
+	recv := &receiver{failures: 2}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	sender := New(Config{
+		URL:           srv.URL,
+		RunID:         "run-1",
+		Labels:        map[string]string{"team": "payments"},
+		Title:         "payments tests",
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		Retries:       2,
+		Backoff:       time.Millisecond,
+	})
+	for _, event := range newEvents(5) {
+		sender.Send(event)
+	}
+	assert.NilError(t, sender.Close())
+
+	var sizes []int
+	for _, batch := range recv.batches {
+		assert.Equal(t, batch.RunID, "run-1")
+		assert.Equal(t, batch.Title, "payments tests")
+		assert.DeepEqual(t, batch.Labels, map[string]string{"team": "payments"})
+		sizes = append(sizes, len(batch.Events))
+	}
+	assert.DeepEqual(t, sizes, []int{2, 2, 1})
+	assert.Equal(t, recv.batches[0].Events[0].Package, "pkg")
+}
+
+func TestSender_NoRetryOnClientError(t *testing.T) {
+	recv := &receiver{status: http.StatusUnauthorized}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	sender := New(Config{URL: srv.URL, Retries: 3, Backoff: time.Hour})
+	sender.Send(newEvents(1)[0])
+	assert.ErrorContains(t, sender.Close(), "401 Unauthorized")
+}
@@ -0,0 +1,54 @@
+/*
+Package junit writes a JUnit XML report of a testjson.Execution, for programs
+which embed gotestsum and add their own attributes to each testcase.
+*/
+package junit // import "gotest.tools/gotestsum/junit"
+
+import (
+	"io"
+	"time"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// TestCaseAttributes returns the custom attributes of the testcase of tc, for
+// example the owner, shard, or tags of the test. The attributes are written in
+// the order of their names. An attribute with the name of an attribute written
+// by gotestsum, like classname, name, or time, is ignored. The name of every
+// other attribute must be a valid XML name without a namespace, otherwise
+// Write returns an error.
+type TestCaseAttributes func(tc testjson.TestCase) map[string]string
+
+// Config used by Write.
+type Config struct {
+	// Hostname is the hostname attribute of every testsuite. Defaults to the
+	// hostname reported by the kernel.
+	Hostname string
+	// Timestamp, when not zero, is the timestamp attribute of every testsuite.
+	// Defaults to the time of the first event of each package.
+	Timestamp time.Time
+	// TestCaseAttributes, when set, adds custom attributes to the testcase of
+	// each test case.
+	TestCaseAttributes TestCaseAttributes
+	// HidePassed writes only the testcases which failed, had an error, or
+	// were skipped. The counts of each testsuite include the testcases which
+	// passed.
+	HidePassed bool
+	// MaxOutputBytes, when greater than zero, is the maximum size of the
+	// output of each testcase. Larger output keeps the start and the end.
+	MaxOutputBytes int
+}
+
+// Write creates a JUnit XML document of exec using config, and writes it to
+// out. The document is the same as the --junitfile written by gotestsum with
+// the default flags.
+func Write(out io.Writer, exec *testjson.Execution, config Config) error {
+	return junitxml.WriteWithConfig(out, exec, junitxml.Config{
+		Hostname:           config.Hostname,
+		Timestamp:          config.Timestamp,
+		TestCaseAttributes: junitxml.TestCaseAttributes(config.TestCaseAttributes),
+		HidePassed:         config.HidePassed,
+		MaxOutputBytes:     config.MaxOutputBytes,
+	})
+}
@@ -0,0 +1,35 @@
+package junit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestWrite_TestCaseAttributes(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg"}
+`))
+	assert.NilError(t, err)
+
+	config := Config{
+		Hostname: "ci-runner",
+		TestCaseAttributes: func(tc testjson.TestCase) map[string]string {
+			return map[string]string{"owner": "team-" + strings.ToLower(tc.Test)}
+		},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Write(out, exec, config))
+	assert.Assert(t, strings.Contains(out.String(),
+		`<testcase classname="example.com/pkg" name="TestOne" time="0.000000" owner="team-testone">`), out.String())
+
+	config.TestCaseAttributes = func(tc testjson.TestCase) map[string]string {
+		return map[string]string{"test owner": "team-a"}
+	}
+	err = Write(new(bytes.Buffer), exec, config)
+	assert.ErrorContains(t, err, `invalid attribute name "test owner"`)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// writeJUnitDir writes a JUnit XML file for each package to the
+// --junitfile-dir, with the --junit options used by every --junitfile, and
+// records the paths of the files in opts.
+func writeJUnitDir(opts *options, execution *testjson.Execution) error {
+	if opts.junitFileDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(opts.junitFileDir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create the --junitfile-dir")
+	}
+	config := junitFileConfig(opts, junitFileSpec{})
+	config.Properties = junitFileProperties(opts)
+	pkgs := execution.Packages()
+	opts.junitDirFiles = nil
+	for i, filename := range junitDirFilenames(pkgs) {
+		path := filepath.Join(opts.junitFileDir, filename)
+		if err := writeJUnitFile(path, execution.Subset(pkgs[i]), config); err != nil {
+			return err
+		}
+		opts.junitDirFiles = append(opts.junitDirFiles, path)
+	}
+	return nil
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// junitDirFilenames returns the name of the file of each package, in the same
+// order. The name is the import path of the package, with each sequence of
+// characters which are not safe in a filename replaced by _, ex:
+// example.com/mod/pkg is example.com_mod_pkg.xml. When the names of two
+// packages are the same, -2, -3, and so on is added to the later ones.
+func junitDirFilenames(pkgs []string) []string {
+	filenames := make([]string, 0, len(pkgs))
+	seen := make(map[string]bool)
+	for _, pkg := range pkgs {
+		name := strings.Trim(unsafeFilenameChars.ReplaceAllString(pkg, "_"), ".")
+		if name == "" {
+			name = "package"
+		}
+		unique := name
+		for n := 2; seen[strings.ToLower(unique)]; n++ {
+			unique = name + "-" + strconv.Itoa(n)
+		}
+		// names are compared without case for case-insensitive file systems
+		seen[strings.ToLower(unique)] = true
+		filenames = append(filenames, unique+".xml")
+	}
+	return filenames
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/fs"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestJUnitDirFilenames(t *testing.T) {
+	pkgs := []string{"example.com/mod/a", "example.com/mod_a", "example.com/Mod/A", "command-line-arguments", ""}
+	expected := []string{
+		"example.com_mod_a.xml",
+		"example.com_mod_a-2.xml",
+		"example.com_Mod_A-3.xml",
+		"command-line-arguments.xml",
+		"package.xml",
+	}
+	assert.DeepEqual(t, junitDirFilenames(pkgs), expected)
+}
+
+func TestWriteJUnitDir(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/mod/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/mod/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/mod/a"}
+{"Action":"run","Package":"example.com/mod/b","Test":"TestTwo"}
+{"Action":"output","Package":"example.com/mod/b","Test":"TestTwo","Output":"    b_test.go:4: broken\n"}
+{"Action":"fail","Package":"example.com/mod/b","Test":"TestTwo"}
+{"Action":"fail","Package":"example.com/mod/b"}
+`))
+	assert.NilError(t, err)
+
+	dir := fs.NewDir(t, "junitfile-dir")
+	defer dir.Remove()
+	opts := &options{
+		junitFileDir:    filepath.Join(dir.Path(), "junit"),
+		junitProperties: newJUnitPropertyValue(""),
+		junitHostname:   "ci",
+	}
+	assert.NilError(t, writeJUnitDir(opts, exec))
+	assert.DeepEqual(t, opts.junitDirFiles, []string{
+		filepath.Join(dir.Path(), "junit", "example.com_mod_a.xml"),
+		filepath.Join(dir.Path(), "junit", "example.com_mod_b.xml"),
+	})
+	files, err := ioutil.ReadDir(opts.junitFileDir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 2)
+
+	raw, err := ioutil.ReadFile(opts.junitDirFiles[1])
+	assert.NilError(t, err)
+	assert.NilError(t, junitxml.Validate(strings.NewReader(string(raw))))
+	assert.Assert(t, strings.Contains(string(raw), `name="example.com/mod/b"`))
+	assert.Assert(t, !strings.Contains(string(raw), "example.com/mod/a"))
+}
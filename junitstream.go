@@ -0,0 +1,137 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// junitStream is a --junitfile with stream=true. The testsuite of each
+// package is written when the package ends.
+type junitStream struct {
+	file   *os.File
+	gz     *gzip.Writer
+	writer *junitxml.StreamWriter
+}
+
+// openJUnitStreams creates each --junitfile with stream=true, and writes the
+// start of the document.
+func openJUnitStreams(opts *options) ([]*junitStream, error) {
+	var streams []*junitStream
+	for _, spec := range junitFileSpecs(opts) {
+		if !spec.stream {
+			continue
+		}
+		stream, err := openJUnitStream(opts, spec)
+		if err != nil {
+			closeJUnitStreams(streams)
+			return nil, err
+		}
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
+
+func openJUnitStream(opts *options, spec junitFileSpec) (*junitStream, error) {
+	stream := &junitStream{file: os.Stdout}
+	if !spec.stdout() {
+		var err error
+		if stream.file, err = os.Create(spec.path); err != nil {
+			return nil, errors.Wrap(err, "failed to open JUnit file")
+		}
+	}
+	var out io.Writer = stream.file
+	if strings.HasSuffix(spec.path, ".gz") {
+		stream.gz = gzip.NewWriter(stream.file)
+		out = stream.gz
+	}
+	config := junitFileConfig(opts, spec)
+	config.Properties = junitFileProperties(opts)
+	var err error
+	if stream.writer, err = junitxml.NewStreamWriter(out, config); err != nil {
+		stream.close() // nolint: errcheck
+		return nil, err
+	}
+	return stream, nil
+}
+
+// Event writes the testsuite of the package when the package ends. A
+// compressed file is flushed after each package, so that the testsuites which
+// were written can be read from the file if the run is interrupted.
+func (s *junitStream) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	if !event.PackageEvent() {
+		return nil
+	}
+	switch event.Action {
+	case testjson.ActionPass, testjson.ActionFail, testjson.ActionSkip:
+		if err := s.writer.WritePackage(execution, event.Package); err != nil {
+			return err
+		}
+		if s.gz != nil {
+			return errors.Wrap(s.gz.Flush(), "failed to compress JUnit file")
+		}
+	}
+	return nil
+}
+
+// finish writes the packages which did not end, and the end of the document,
+// and closes the file.
+func (s *junitStream) finish(execution *testjson.Execution) error {
+	if err := s.writer.Close(execution); err != nil {
+		s.close() // nolint: errcheck
+		return err
+	}
+	return s.close()
+}
+
+func (s *junitStream) close() error {
+	if s.gz != nil {
+		if err := s.gz.Close(); err != nil {
+			return errors.Wrap(err, "failed to compress JUnit file")
+		}
+	}
+	if s.file == os.Stdout {
+		return nil
+	}
+	return errors.Wrap(s.file.Close(), "failed to close JUnit file")
+}
+
+func closeJUnitStreams(streams []*junitStream) {
+	for _, stream := range streams {
+		stream.close() // nolint: errcheck
+	}
+}
+
+// validateJUnitStream returns an error if a --junitfile with stream=true is
+// used with an option which changes the results or labels of a package after
+// it ends, or which needs the testcases of every package.
+func validateJUnitStream(opts *options, spec junitFileSpec) error {
+	if !spec.stream {
+		return nil
+	}
+	config := junitFileConfig(opts, spec)
+	switch {
+	case config.Format != junitxml.FormatJUnit:
+		return errors.New("stream=true can only be used with format=junit")
+	case config.Duplicates == junitxml.DuplicatesSuffix:
+		return errors.New("stream=true can not be used with duplicates=suffix")
+	case opts.rerunFails > 0:
+		return errors.New("stream=true can not be used with --rerun-fails")
+	case len(opts.infraErrors.patterns) > 0:
+		return errors.New("stream=true can not be used with --infra-error")
+	case opts.failureClassifier != "":
+		return errors.New("stream=true can not be used with --failure-classifier")
+	case opts.failOnSkip != "":
+		return errors.New("stream=true can not be used with --fail-on-skip")
+	case len(opts.packagePriority.rules) > 0:
+		return errors.New("stream=true can not be used with --package-priority")
+	case len(opts.skipCategories.categories) > 0:
+		return errors.New("stream=true can not be used with --skip-category")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/fs"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestJUnitStream_GzipFlushedAfterEachPackage(t *testing.T) {
+	dir := fs.NewDir(t, "junit-stream")
+	defer dir.Remove()
+	_, opts := setupFlags("gotestsum")
+	opts.stdin = true
+
+	spec := junitFileSpec{path: dir.Join("junit.xml.gz"), stream: true}
+	stream, err := openJUnitStream(opts, spec)
+	assert.NilError(t, err)
+	defer stream.close() // nolint: errcheck
+
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/a"}
+`))
+	assert.NilError(t, err)
+	event := testjson.TestEvent{Action: testjson.ActionPass, Package: "example.com/a"}
+	assert.NilError(t, stream.Event(event, exec))
+
+	// the file is read before the stream is closed, like a file left by an
+	// interrupted run
+	f, err := os.Open(spec.path)
+	assert.NilError(t, err)
+	defer f.Close() // nolint: errcheck
+	gz, err := gzip.NewReader(f)
+	assert.NilError(t, err)
+	content, err := ioutil.ReadAll(gz)
+	assert.Equal(t, err, io.ErrUnexpectedEOF)
+	assert.Assert(t, strings.Contains(string(content), `<testsuite tests="1"`), string(content))
+}
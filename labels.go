@@ -0,0 +1,60 @@
+package main
+
+import (
+	"gotest.tools/gotestsum/testjson"
+)
+
+// labelPrefix is the namespace of the labels assigned to tests by the
+// features of gotestsum. The labels are written with the same names to the
+// properties of each JUnit testcase, and the labels of each NDJSON row.
+const labelPrefix = "gotestsum.label."
+
+// testLabels returns the labels of each test, by test ID. A package which
+// failed without a failed test has the ID of the package.
+//
+//	priority          the --package-priority of the package
+//	skip-category     the --skip-category of a skipped test
+//	failure-category  the category from the --failure-classifier
+//	flaky             true when the test failed, and passed when it was rerun
+//	rerun-isolation   the --rerun-fails-isolation of the rerun which passed
+func testLabels(opts *options, exec *testjson.Execution) map[string]map[string]string {
+	labels := make(map[string]map[string]string)
+	add := func(tc testjson.TestCase, name, value string) {
+		if value == "" {
+			return
+		}
+		id := tc.ID()
+		if labels[id] == nil {
+			labels[id] = make(map[string]string)
+		}
+		labels[id][labelPrefix+name] = value
+	}
+
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		priority := opts.packagePriority.name(name)
+		if pkg.TestMainFailed() {
+			tc := testjson.TestCase{Package: name}
+			add(tc, "priority", priority)
+			add(tc, "failure-category", opts.failureCategories[tc.ID()])
+		}
+		for _, tc := range pkg.TestCases() {
+			add(tc, "priority", priority)
+		}
+		for _, tc := range pkg.Skipped {
+			add(tc, "skip-category", opts.skipCategories.categories.Category(pkg, tc.Test))
+		}
+		failed := make(map[string]bool)
+		for _, tc := range pkg.Failed {
+			failed[tc.Test] = true
+			add(tc, "failure-category", opts.failureCategories[tc.ID()])
+		}
+		for _, tc := range pkg.Passed {
+			if failed[tc.Test] {
+				add(tc, "flaky", "true")
+				add(tc, "rerun-isolation", opts.rerunPassedIsolation[tc.ID()])
+			}
+		}
+	}
+	return labels
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestTestLabels(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"labs/new","Test":"TestFlaky"}
+{"Action":"fail","Package":"labs/new","Test":"TestFlaky"}
+{"Action":"run","Package":"labs/new","Test":"TestQuarantined"}
+{"Action":"output","Package":"labs/new","Test":"TestQuarantined","Output":"    a_test.go:3: quarantined\n"}
+{"Action":"skip","Package":"labs/new","Test":"TestQuarantined"}
+{"Action":"fail","Package":"labs/new"}
+{"Action":"run","Package":"labs/new","Test":"TestFlaky"}
+{"Action":"pass","Package":"labs/new","Test":"TestFlaky"}
+{"Action":"pass","Package":"labs/new"}
+{"Action":"run","Package":"core","Test":"TestDB"}
+{"Action":"fail","Package":"core","Test":"TestDB"}
+{"Action":"fail","Package":"core"}
+`))
+	assert.NilError(t, err)
+
+	opts := &options{
+		packagePriority:      &priorityValue{},
+		skipCategories:       &skipCategoryValue{},
+		failureCategories:    map[string]string{"core#TestDB": "infra"},
+		rerunPassedIsolation: map[string]string{"labs/new#TestFlaky": "isolated"},
+	}
+	assert.NilError(t, opts.packagePriority.Set("./labs/...=experimental"))
+	assert.NilError(t, opts.skipCategories.Set("quarantined=quarantined"))
+
+	labels := testLabels(opts, exec)
+	assert.DeepEqual(t, labels, map[string]map[string]string{
+		"labs/new#TestFlaky": {
+			"gotestsum.label.priority":        "experimental",
+			"gotestsum.label.flaky":           "true",
+			"gotestsum.label.rerun-isolation": "isolated",
+		},
+		"labs/new#TestQuarantined": {
+			"gotestsum.label.priority":      "experimental",
+			"gotestsum.label.skip-category": "quarantined",
+		},
+		"core#TestDB": {
+			"gotestsum.label.priority":         "normal",
+			"gotestsum.label.failure-category": "infra",
+		},
+	})
+}
+
+func TestTestLabels_NoLabels(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"core","Test":"TestDB"}
+{"Action":"fail","Package":"core","Test":"TestDB"}
+{"Action":"fail","Package":"core"}
+`))
+	assert.NilError(t, err)
+
+	opts := &options{packagePriority: &priorityValue{}, skipCategories: &skipCategoryValue{}}
+	assert.Equal(t, len(testLabels(opts, exec)), 0)
+}
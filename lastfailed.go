@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/internal/ndjson"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func validateLastFailedOptions(opts *options) error {
+	switch {
+	case !opts.rerunLastFailed:
+	case opts.rawCommand || opts.stdin:
+		return errors.New("--rerun-last-failed can not be used with --raw-command or --stdin")
+	case len(opts.history) == 0:
+		return errors.New("--rerun-last-failed requires --history")
+	}
+	return nil
+}
+
+// gitBranch returns the name of the current git branch. Returns an empty
+// string if the branch is not known, for example when HEAD is detached.
+func gitBranch() string {
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+	log.Debugf("exec: %s", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		log.WithError(err).Debug("failed to lookup the git branch")
+		return ""
+	}
+	branch := strings.TrimSpace(string(out))
+	if branch == "HEAD" {
+		return ""
+	}
+	return branch
+}
+
+// selectLastFailed returns the top level tests, by package, which failed in
+// the last run of the branch from --history. When there is no run of the
+// branch, the last run of the base branch is used. Returns nil when all
+// tests should be run.
+func selectLastFailed(opts *options) (map[string][]string, error) {
+	rows, err := ndjson.ReadFiles(opts.history)
+	if err != nil {
+		return nil, err
+	}
+	tests, branch := lastFailedTests(rows, opts.branch, opts.baseBranch)
+	if len(tests) == 0 {
+		log.Infof("no failed tests in the last run, running all tests")
+		return nil, nil
+	}
+	log.Debugf("running the failed tests from the last run of %s: %v", branch, tests)
+	return tests, nil
+}
+
+// lastFailedTests returns the failed tests from the last run of the first
+// branch in branches which has a run in rows, and the name of that branch.
+func lastFailedTests(rows []ndjson.Row, branches ...string) (map[string][]string, string) {
+	for _, branch := range branches {
+		if branch == "" {
+			continue
+		}
+		run := ndjson.LastRun(rows, branch)
+		if run == nil {
+			continue
+		}
+		var failed []testjson.TestCase
+		for _, row := range run {
+			switch row.Outcome {
+			case ndjson.OutcomeFail, ndjson.OutcomeError, ndjson.OutcomeNotRun:
+				failed = append(failed, testjson.TestCase{Package: row.Package, Test: row.Test})
+			}
+		}
+		tests, ok := failedRootTests(failed)
+		if !ok {
+			log.Warnf("a package failed in the last run of %s, running all tests", branch)
+			return nil, branch
+		}
+		return tests, branch
+	}
+	return nil, ""
+}
+
+// lastFailedCmdArgs returns the go test command used to run the tests from
+// opts.lastFailed. A test with the same name in more than one of the packages
+// is run in each of them.
+func lastFailedCmdArgs(opts *options) []string {
+	seen := make(map[string]bool)
+	var tests []string
+	for _, pkgTests := range opts.lastFailed {
+		for _, test := range pkgTests {
+			if !seen[test] {
+				seen[test] = true
+				tests = append(tests, test)
+			}
+		}
+	}
+	sort.Strings(tests)
+	return rerunCmdArgs(opts, rerunPackage, tests, sortedPackages(opts.lastFailed)...)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/internal/ndjson"
+)
+
+func TestLastFailedTests(t *testing.T) {
+	started := time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)
+	rows := []ndjson.Row{
+		{RunID: "1", RunBranch: "main", RunStarted: started, Package: "pkg/a", Test: "TestOld", Outcome: ndjson.OutcomeFail},
+		{RunID: "2", RunBranch: "main", RunStarted: started.Add(time.Hour), Package: "pkg/a", Test: "TestOne/sub", Outcome: ndjson.OutcomeFail},
+		{RunID: "2", RunBranch: "main", RunStarted: started.Add(time.Hour), Package: "pkg/a", Test: "TestTwo", Outcome: ndjson.OutcomePass},
+		{RunID: "2", RunBranch: "main", RunStarted: started.Add(time.Hour), Package: "pkg/b", Test: "TestThree", Outcome: ndjson.OutcomeNotRun},
+		{RunID: "3", RunBranch: "feature", RunStarted: started, Package: "pkg/a", Test: "TestTwo", Outcome: ndjson.OutcomeFail},
+	}
+
+	tests, branch := lastFailedTests(rows, "fix", "main")
+	assert.Equal(t, branch, "main")
+	assert.DeepEqual(t, tests, map[string][]string{
+		"pkg/a": {"TestOne"},
+		"pkg/b": {"TestThree"},
+	})
+
+	tests, branch = lastFailedTests(rows, "feature", "main")
+	assert.Equal(t, branch, "feature")
+	assert.DeepEqual(t, tests, map[string][]string{"pkg/a": {"TestTwo"}})
+
+	tests, branch = lastFailedTests(rows, "fix", "")
+	assert.Equal(t, branch, "")
+	assert.Assert(t, tests == nil)
+}
+
+func TestGoTestCmdArgs_LastFailed(t *testing.T) {
+	opts := &options{
+		args: []string{"-race", "-tags", "integration", "./...", "-args", "-update"},
+		lastFailed: map[string][]string{
+			"pkg/b": {"TestThree", "TestOne"},
+			"pkg/a": {"TestOne"},
+		},
+	}
+	assert.DeepEqual(t, goTestCmdArgs(opts), []string{
+		"go", "test", "-json", "-count=1", "-run", "^(TestOne|TestThree)$",
+		"-race", "-tags", "integration", "pkg/a", "pkg/b", "-args", "-update",
+	})
+}
@@ -1,24 +1,46 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/manifest"
 	"gotest.tools/gotestsum/testjson"
 )
 
 var version = "master"
 
+// Values of --strict-events.
+const (
+	strictEventsWarn = "warn"
+	strictEventsFail = "fail"
+)
+
 func main() {
 	name := os.Args[0]
+	if len(os.Args) > 1 && os.Args[1] == "tool" {
+		if err := runTool(name+" tool", os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, name+": Error: "+err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	flags, opts := setupFlags(name)
 	switch err := flags.Parse(os.Args[1:]); {
 	case err == pflag.ErrHelp:
@@ -28,6 +50,10 @@ func main() {
 		flags.Usage()
 		os.Exit(1)
 	}
+	if err := setFlagsFromEnv(flags, os.LookupEnv); err != nil {
+		log.Error(err.Error())
+		os.Exit(1)
+	}
 	opts.args = flags.Args()
 	setupLogging(opts)
 
@@ -36,12 +62,20 @@ func main() {
 		os.Exit(0)
 	}
 
-	switch err := run(opts).(type) {
+	var err error
+	if len(opts.goVersions) > 0 {
+		err = runGoVersions(opts, os.Stdout)
+	} else {
+		err = run(opts)
+	}
+	switch err := err.(type) {
 	case nil:
 	case *exec.ExitError:
 		// go test should already report the error to stderr so just exit with
 		// the same status code
 		os.Exit(ExitCodeWithDefault(err))
+	case *exitCodeError:
+		os.Exit(err.code)
 	default:
 		fmt.Fprintln(os.Stderr, name+": Error: "+err.Error())
 		os.Exit(3)
@@ -49,7 +83,17 @@ func main() {
 }
 
 func setupFlags(name string) (*pflag.FlagSet, *options) {
-	opts := &options{noSummary: newNoSummaryValue()}
+	opts := &options{
+		noSummary:         newNoSummaryValue(),
+		junitFiles:        newJUnitFileValue(lookEnvWithDefault("GOTESTSUM_JUNITFILE", "")),
+		junitFailuresOnly: newJUnitFileValue(""),
+		junitProperties:   newJUnitPropertyValue(lookEnvWithDefault("GOTESTSUM_JUNIT_PROPERTIES", "")),
+		packagePriority:   &priorityValue{},
+		skipCategories:    &skipCategoryValue{},
+		rewriteRules:      &rewriteRuleValue{},
+		skipThresholds:    &skipThresholdValue{},
+		collectOnFailure:  &collectValue{},
+	}
 	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
 	flags.SetInterspersed(false)
 	flags.Usage = func() {
@@ -59,6 +103,10 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 Flags:
 `, name)
 		flags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Tools:
+    %s tool TOOL [flags]   run '%s tool --help' for the list of tools
+`, name, name)
 		fmt.Fprint(os.Stderr, `
 Formats:
     dots              print a character for each test
@@ -66,23 +114,204 @@ Formats:
     short-verbose     print a line for each test and package
     standard-quiet    default go test format
     standard-verbose  default go test -v format
+
+With --accessible the dots format prints a line for each test, and the short
+format prints PASS, FAIL, or EMPTY instead of symbols.
+
+Environment:
+    Every flag, except --version, can be set with a GOTESTSUM_ environment
+    variable. For example --rerun-fails=2 is the same as GOTESTSUM_RERUN_FAILS=2.
+    Flags on the command line take precedence over the environment.
 `)
 	}
 	flags.BoolVar(&opts.debug, "debug", false, "enabled debug")
 	flags.StringVarP(&opts.format, "format", "f",
 		lookEnvWithDefault("GOTESTSUM_FORMAT", "short"),
 		"print format of test input")
+	flags.StringVar(&opts.hyperlinkURL, "hyperlinks", "",
+		"add terminal hyperlinks to file:line references in test output, using this URL template")
+	flags.Lookup("hyperlinks").NoOptDefVal = testjson.DefaultHyperlinkURL
+	flags.Var(opts.rewriteRules, "rewrite-output",
+		"replace the text in test output which matches the regexp, before it is printed or written to a report, repeat to add more rules")
+	flags.StringVar(&opts.timestampFormat, "timestamp-format", string(testjson.TimestampNone),
+		"print a timestamp at the start of each line of output, one of: none, relative, clock, iso")
 	flags.BoolVar(&opts.rawCommand, "raw-command", false,
 		"don't prepend 'go test -json' to the 'go test' command")
+	flags.BoolVar(&opts.stdin, "stdin", false,
+		"read test2json events from stdin instead of running 'go test'")
 	flags.StringVar(&opts.jsonFile, "jsonfile",
 		lookEnvWithDefault("GOTESTSUM_JSONFILE", ""),
 		"write all TestEvents to file")
-	flags.StringVar(&opts.junitFile, "junitfile",
-		lookEnvWithDefault("GOTESTSUM_JUNITFILE", ""),
-		"write a JUnit XML file")
+	flags.Var(opts.junitFiles, "junitfile",
+		"write a JUnit XML file, repeat or use a comma separated list to write more than one file, - writes to stdout (PATH[,path-mode=MODE][,duplicates=POLICY][,system-out=MODE][,subtests=MODE][,reruns=MODE][,sort=MODE][,format=FORMAT][,failures-only=BOOL][,stream=BOOL])")
+	flags.Var(opts.junitFailuresOnly, "junitfile-failures-only",
+		"write a JUnit XML file with only the failed tests, repeat to write more than one file (same options as --junitfile)")
+	flags.StringVar(&opts.junitFileDir, "junitfile-dir", "",
+		"write a JUnit XML file for each package to this directory, named from the import path of the package")
+	flags.StringVar(&opts.junitPathMode, "junit-path-mode", string(junitxml.PathModeRaw),
+		"how to write package paths in the JUnit XML file, one of: raw, relative, munged")
+	flags.StringVar(&opts.junitDuplicates, "junit-duplicates", string(junitxml.DuplicatesWarn),
+		"how to write JUnit testcases with the same classname and name, one of: warn, suffix")
+	flags.StringVar(&opts.junitSystemOut, "junit-system-out", string(junitxml.SystemOutNone),
+		"write test output to <system-out> elements in the JUnit XML file, one of: none, testcase, all")
+	flags.StringVar(&opts.junitSubtests, "junit-subtests", string(junitxml.SubtestsFlat),
+		"how to write subtests in the JUnit XML file, one of: flat, nested")
+	flags.StringVar(&opts.junitReruns, "junit-reruns", string(junitxml.RerunsSeparate),
+		"how to write tests which were run more than once in the JUnit XML file, one of: separate, surefire")
+	flags.Var(&opts.junitRewritePaths, "junit-rewrite-path",
+		"replace text in the classnames, file paths, and output of the JUnit XML file, repeat for more than one rule (REGEXP=>REPLACEMENT)")
+	flags.StringVar(&opts.junitSort, "junit-sort", string(junitxml.SortNone),
+		"order of the testsuites and testcases in the JUnit XML file, one of: none, name, duration, outcome")
+	flags.StringVar(&opts.junitFileFormat, "junitfile-format", string(junitxml.FormatJUnit),
+		"format of the JUnit XML file, one of: junit, open-test-reporting")
+	flags.StringVar(&opts.junitANSI, "junit-ansi", string(junitxml.ANSIStrip),
+		"how to write ANSI escape sequences from test output in the JUnit XML file, one of: strip, text")
+	flags.IntVar(&opts.junitMaxOutputBytes, "junit-max-output-bytes", 0,
+		"truncate the output of each testcase in the JUnit XML file to this many bytes, keeping the start and end (default no limit)")
+	flags.BoolVar(&opts.junitHidePassed, "junit-hide-passed", false,
+		"write only the failed and skipped testcases to the JUnit XML file, the counts of each testsuite include the passed tests")
+	flags.Var(opts.junitProperties, "junit-property",
+		"add a property to every testsuite in the JUnit XML file, repeat to add more than one (NAME=VALUE)")
+	flags.StringVar(&opts.junitHostname, "junit-hostname", "",
+		"hostname attribute of each testsuite in the JUnit XML file (default the hostname of the machine)")
+	flags.Var(&opts.junitNaming, "junit-naming",
+		"Go template used for the classname or name of each JUnit testcase (classname=TEMPLATE or name=TEMPLATE)")
+	flags.Var(&opts.junitTimestamp, "junit-timestamp",
+		"RFC3339 timestamp attribute of every testsuite in the JUnit XML file (default the start time of each package)")
+	flags.StringVar(&opts.ndjsonFile, "ndjson-file",
+		lookEnvWithDefault("GOTESTSUM_NDJSONFILE", ""),
+		"write a newline delimited JSON file with a row for each test case")
+	flags.StringVar(&opts.bepJSONFile, "bep-json-file", "",
+		"experimental: write the results as Bazel Build Event Protocol JSON events")
+	flags.StringVar(&opts.manifestFile, "manifest-file", "",
+		"write a JSON file which describes the run, with the SHA256 digest of each report file")
+	flags.StringVar(&opts.signKey, "sign-key", "",
+		"sign the report files and the --manifest-file with this PEM encoded ECDSA or Ed25519 private key")
+	flags.StringVar(&opts.coverageDiff, "coverage-diff", "",
+		"report the changed lines from git diff against this ref which were not covered, requires -coverprofile=PATH")
+	flags.StringVar(&opts.coverageDiffFile, "coverage-diff-file", "",
+		"with --coverage-diff, write the coverage of the changed lines of each file to this JSON file")
+	flags.StringVar(&opts.statusFile, "status-file", "",
+		"keep a JSON file updated with the progress of the run, for programs which show the progress")
+	flags.BoolVar(&opts.syncReports, "sync-reports", false,
+		"flush the report files, and their directories, to disk after they are written")
+	flags.BoolVar(&opts.validateReports, "validate-reports", false,
+		"read the --junitfile and --ndjson-file reports after they are written, and fail the run if any is invalid")
+	flags.StringVar(&opts.runID, "run-id",
+		lookEnvWithDefault("GOTESTSUM_RUN_ID", ""),
+		"identifier of the run included in reports (default: start time and pid)")
+	flags.IntVar(&opts.maxLinesPerSecond, "max-lines-per-second", 0,
+		"print at most this many lines of test output each second, the files are not limited")
+	flags.StringVar(&opts.tailTest, "tail-test", "",
+		"print the output of the tests which match this regular expression while they run, with any --format")
+	flags.IntVar(&opts.tailTestLinesPerSecond, "tail-test-lines-per-second", defaultTailTestLinesPerSecond,
+		"print at most this many lines of --tail-test output each second")
+	flags.StringSliceVar(&opts.captureEnv, "capture-env", nil,
+		"environment variables (NAME or PREFIX_*) to record in the JUnit XML and NDJSON files")
+	flags.StringVar(&opts.eventWebhookURL, "event-webhook-url",
+		lookEnvWithDefault("GOTESTSUM_EVENT_WEBHOOK_URL", ""),
+		"POST batches of test events as JSON to this URL while the tests run")
+	flags.StringVar(&opts.eventWebhookTitle, "event-webhook-title", "",
+		"Go template used to create the title included in every batch sent to --event-webhook-url")
+	opts.runLabels = make(map[string]string)
+	flags.Var(labelValue(opts.runLabels), "label",
+		"add a label to the notifications and reports of the run, repeat to add more than one (NAME=VALUE)")
 	flags.BoolVar(&opts.noColor, "no-color", false, "disable color output")
+	flags.BoolVar(&opts.accessible, "accessible", false,
+		"print words instead of symbols and color, and one line for each event")
+	flags.BoolVar(&opts.verboseAfterFailure, "verbose-after-failure", false,
+		"switch to the standard-verbose format after the first test fails")
+	flags.BoolVar(&opts.subtestCounts, "subtest-counts", false,
+		"print the number of subtests which passed, failed, and were skipped after each test with subtests")
 	flags.Var(opts.noSummary, "no-summary",
 		fmt.Sprintf("do not print summary of: %s", testjson.SummarizeAll.String()))
+	flags.StringVar(&opts.summaryLineTemplate, "summary-line-template",
+		lookEnvWithDefault("GOTESTSUM_SUMMARY_LINE_TEMPLATE", ""),
+		"Go template used to print the last line of the summary, instead of the DONE line")
+	flags.BoolVar(&opts.summaryTiming, "summary-timing", false,
+		"print the elapsed time, cumulative package time, and parallel speedup")
+	flags.StringVar(&opts.requiredTests, "required-tests",
+		lookEnvWithDefault("GOTESTSUM_REQUIRED_TESTS", ""),
+		"file of test IDs which must run and pass, one on each line")
+	flags.StringVar(&opts.failOnSkip, "fail-on-skip", "",
+		"count skipped tests as failures, or only the tests with an ID which matches the regexp")
+	flags.Lookup("fail-on-skip").NoOptDefVal = "."
+	flags.Var(opts.skipCategories, "skip-category",
+		"classify skipped tests with output matching the regexp, repeat to add more categories")
+	flags.StringVar(&opts.failureClassifier, "failure-classifier", "",
+		"shell command which reads a JSON line for each failed test, and writes a JSON line with its category")
+	flags.DurationVar(&opts.failureClassifierTimeout, "failure-classifier-timeout", time.Minute,
+		"stop the --failure-classifier if it has not finished after this time, 0 for no limit")
+	flags.Var(opts.skipThresholds, "skip-category-threshold",
+		"warn when more than this many tests are skipped in a --skip-category")
+	flags.StringSliceVar(&opts.failOnEmpty, "fail-on-empty", nil,
+		"exit 4 when no tests ran, or no tests ran in the packages matching the patterns")
+	flags.Lookup("fail-on-empty").NoOptDefVal = "all"
+	flags.StringVar(&opts.strictEvents, "strict-events", "",
+		"warn, or fail the run, when events are missing or could not be attributed (warn, fail)")
+	flags.Lookup("strict-events").NoOptDefVal = strictEventsWarn
+	flags.BoolVar(&opts.checkGitStatus, "check-git-status", false,
+		"warn about files in the git working tree modified by the tests")
+	flags.Var(opts.packagePriority, "package-priority",
+		"set the priority (critical, normal, experimental) of packages matching a pattern")
+	flags.BoolVar(&opts.ignoreExperimental, "ignore-experimental-failures", false,
+		"exit 0 when all failures are from experimental packages")
+	flags.StringVar(&opts.lang, "lang",
+		lookEnvWithDefault("GOTESTSUM_LANG", "en"),
+		fmt.Sprintf("language of the summary, one of: %s",
+			strings.Join(testjson.Languages(), ", ")))
+	setupEmailFlags(flags, &opts.email)
+	flags.DurationVar(&opts.deadline, "deadline", 0,
+		"stop the run after this duration, and report the tests which did not finish as not run")
+	flags.StringSliceVar(&opts.history, "history", nil,
+		"NDJSON files, or http(s) URLs, from previous runs, used by --deadline and --rerun-last-failed")
+	flags.StringVar(&opts.packageArgsOrder, "package-args-order",
+		lookEnvWithDefault("GOTESTSUM_PACKAGE_ARGS_ORDER", ""),
+		"list the packages and pass them to go test in this order using --history (failed-first, slowest-last, alpha, random)")
+	flags.BoolVar(&opts.rerunLastFailed, "rerun-last-failed", false,
+		"run only the tests which failed in the last run of the branch from --history")
+	flags.BoolVar(&opts.flakyScore, "flaky-score", false,
+		"show the percent of runs from --history in which each package had a flaky failure")
+	flags.StringVar(&opts.branch, "branch",
+		lookEnvWithDefault("GOTESTSUM_BRANCH", ""),
+		"branch of the run, written to --ndjson-file (default: the current git branch)")
+	flags.StringVar(&opts.baseBranch, "base-branch",
+		lookEnvWithDefault("GOTESTSUM_BASE_BRANCH", ""),
+		"with --rerun-last-failed, use the last run of this branch when there is no run of --branch")
+	flags.IntVar(&opts.rerunFails, "rerun-fails", 0,
+		"rerun failed tests up to this many times, and exit 0 if they pass")
+	flags.IntVar(&opts.timeoutWarning, "timeout-warning", 0,
+		"warn about tests which took at least this percent of the go test -timeout")
+	flags.DurationVar(&opts.perTestTimeout, "per-test-timeout", 0,
+		"with --rerun-fails, run each failed test with its own go test -timeout")
+	flags.DurationVar(&opts.rerunDelay, "rerun-fails-delay", 0,
+		"with --rerun-fails, wait this long before each rerun of the failed tests")
+	flags.Var(&opts.rerunIsolation, "rerun-fails-isolation",
+		"with --rerun-fails, how each rerun runs the failed tests, a comma separated list of package, test, or isolated for each rerun")
+	flags.Var(&opts.infraErrors, "infra-error",
+		"retry a failed package when its output matches this regexp, and report it as an infrastructure error if it persists")
+	flags.IntVar(&opts.infraRetries, "infra-retries", 1,
+		"with --infra-error, retry a package up to this many times")
+	flags.StringVar(&opts.serveUI, "serve-ui", "",
+		"serve a web page with the progress of the run at this address, for example :8080")
+	flags.StringVar(&opts.sourceURLTemplate, "source-url-template", "",
+		"link file:line references in HTML reports to the source at the tested commit, using this URL template")
+	flags.StringVar(&opts.preRunCommand, "pre-run-command", "",
+		"run this shell command before go test, and stop if it fails")
+	flags.StringArrayVar(&opts.waitFor, "wait-for", nil,
+		"before go test, wait for a tcp://host:port to accept connections, or an http(s):// URL to return 200")
+	flags.DurationVar(&opts.waitTimeout, "wait-timeout", time.Minute,
+		"maximum time to wait for each --wait-for")
+	flags.StringVar(&opts.teardownCommand, "teardown-command", "",
+		"run this shell command after go test, even when the run fails or is interrupted")
+	flags.StringVar(&opts.artifactDir, "artifact-dir", "",
+		"directory for the files copied by --collect-on-failure")
+	flags.Var(opts.collectOnFailure, "collect-on-failure",
+		"when the run fails, copy the files matching the glob to DEST in the --artifact-dir, may be repeated")
+	flags.StringSliceVar(&opts.goVersions, "go-versions", nil,
+		"run the tests with each of these versions of Go, ex: 1.22,1.23, and print the result of each package with each version")
+	flags.BoolVar(&opts.internalMetrics, "internal-metrics", false,
+		"print the time and memory used by gotestsum to parse, format, and write reports")
 	flags.BoolVar(&opts.version, "version", false, "show version and exit")
 	return flags, opts
 }
@@ -95,22 +324,134 @@ func lookEnvWithDefault(key, defValue string) string {
 }
 
 type options struct {
-	args       []string
-	format     string
-	debug      bool
-	rawCommand bool
-	jsonFile   string
-	junitFile  string
-	noColor    bool
-	noSummary  *noSummaryValue
-	version    bool
+	args                     []string
+	format                   string
+	timestampFormat          string
+	hyperlinkURL             string
+	hyperlinks               *testjson.Hyperlinks
+	debug                    bool
+	rawCommand               bool
+	stdin                    bool
+	jsonFile                 string
+	junitFiles               *junitFileValue
+	junitFileDir             string
+	junitFailuresOnly        *junitFileValue
+	junitProperties          *junitPropertyValue
+	junitPathMode            string
+	junitDuplicates          string
+	junitSystemOut           string
+	junitHostname            string
+	junitSubtests            string
+	junitReruns              string
+	junitFileFormat          string
+	junitSort                string
+	junitRewritePaths        rewriteRuleValue
+	junitANSI                string
+	junitMaxOutputBytes      int
+	junitHidePassed          bool
+	junitTimestamp           timestampValue
+	junitNaming              junitNamingValue
+	ndjsonFile               string
+	bepJSONFile              string
+	manifestFile             string
+	signKey                  string
+	validateReports          bool
+	syncReports              bool
+	statusFile               string
+	coverageDiff             string
+	coverageDiffFile         string
+	runID                    string
+	runLabels                map[string]string
+	eventWebhookTitle        string
+	webhookTitle             string
+	captureEnv               []string
+	eventWebhookURL          string
+	maxLinesPerSecond        int
+	tailTest                 string
+	tailTestLinesPerSecond   int
+	noColor                  bool
+	accessible               bool
+	verboseAfterFailure      bool
+	subtestCounts            bool
+	noSummary                *noSummaryValue
+	summaryTiming            bool
+	summaryLineTemplate      string
+	requiredTests            string
+	failOnSkip               string
+	failOnEmpty              []string
+	strictEvents             string
+	checkGitStatus           bool
+	packagePriority          *priorityValue
+	skipCategories           *skipCategoryValue
+	rewriteRules             *rewriteRuleValue
+	skipThresholds           *skipThresholdValue
+	ignoreExperimental       bool
+	lang                     string
+	email                    emailOptions
+	deadline                 time.Duration
+	history                  []string
+	packageArgsOrder         string
+	rerunLastFailed          bool
+	flakyScore               bool
+	branch                   string
+	baseBranch               string
+	rerunFails               int
+	perTestTimeout           time.Duration
+	rerunDelay               time.Duration
+	rerunIsolation           rerunIsolationValue
+	infraErrors              infraErrorValue
+	infraRetries             int
+	infraErrorPackages       map[string]string
+	timeoutWarning           int
+	failureClassifier        string
+	failureClassifierTimeout time.Duration
+	failureCategories        map[string]string
+	labels                   map[string]map[string]string
+	internalMetrics          bool
+	serveUI                  string
+	sourceURLTemplate        string
+	sourceLinks              *testjson.SourceLinks
+	preRunCommand            string
+	waitFor                  []string
+	waitTimeout              time.Duration
+	teardownCommand          string
+	artifactDir              string
+	collectOnFailure         *collectValue
+	goVersions               []string
+	// packages to test, when they are listed by gotestsum instead of go test.
+	packages []string
+	// rerunPassedIsolation is the --rerun-fails-isolation of the rerun which
+	// passed, by test ID.
+	rerunPassedIsolation map[string]string
+	// reran is true when the failed tests were run again, so that the failed
+	// runs of the tests which passed are reported as flaky.
+	reran bool
+	// junitDirFiles are the paths of the files written to the --junitfile-dir.
+	junitDirFiles []string
+	// execution is the Execution of the run, used by --go-versions to print
+	// the result of each package.
+	execution *testjson.Execution
+	// lastFailed are the tests to run, by package, with --rerun-last-failed.
+	lastFailed map[string][]string
+	// flakeRates are the flake rates of packages from --history, printed
+	// with --flaky-score.
+	flakeRates map[string]float64
+	// setup are the steps run by --pre-run-command and --wait-for.
+	setup []manifest.Step
+	// artifacts are the paths of the files copied by --collect-on-failure.
+	artifacts []string
+	// teardown runs the --teardown-command.
+	teardown *teardown
+	// signer is the private key read from --sign-key.
+	signer  crypto.Signer
+	version bool
 }
 
 func setupLogging(opts *options) {
 	if opts.debug {
 		log.SetLevel(log.DebugLevel)
 	}
-	if opts.noColor {
+	if opts.noColor || opts.accessible {
 		color.NoColor = true
 	}
 }
@@ -118,35 +459,366 @@ func setupLogging(opts *options) {
 // TODO: add flag --max-failures
 func run(opts *options) error {
 	ctx := context.Background()
-	goTestProc, err := startGoTest(ctx, goTestCmdArgs(opts))
+	msgs, ok := testjson.NewMessages(opts.lang)
+	if !ok {
+		return errors.Errorf("unknown language %s", opts.lang)
+	}
+	if opts.runID == "" {
+		opts.runID = defaultRunID(time.Now())
+	}
+	if err := validateRerunOptions(opts); err != nil {
+		return err
+	}
+	if err := validateJUnitOptions(opts); err != nil {
+		return err
+	}
+	if err := validateLastFailedOptions(opts); err != nil {
+		return err
+	}
+	if err := validateOrderOptions(opts); err != nil {
+		return err
+	}
+	if err := validateFlakyScoreOptions(opts); err != nil {
+		return err
+	}
+	if err := validateSkipCategoryOptions(opts); err != nil {
+		return err
+	}
+	if err := validatePreRunOptions(opts); err != nil {
+		return err
+	}
+	if err := validateCollectOptions(opts); err != nil {
+		return err
+	}
+	if err := validateTimeoutWarningOptions(opts); err != nil {
+		return err
+	}
+	if err := validateCoverageDiffOptions(opts); err != nil {
+		return err
+	}
+	if err := validateEmailOptions(opts); err != nil {
+		return err
+	}
+	switch opts.strictEvents {
+	case "", strictEventsWarn, strictEventsFail:
+	default:
+		return errors.Errorf("unknown --strict-events value %s", opts.strictEvents)
+	}
+	lineTemplate, err := parseSummaryLineTemplate(opts.summaryLineTemplate)
+	if err != nil {
+		return err
+	}
+	if opts.hyperlinkURL != "" {
+		if opts.hyperlinks, err = testjson.NewHyperlinks(opts.hyperlinkURL); err != nil {
+			return errors.Wrap(err, "invalid --hyperlinks template")
+		}
+	}
+	if opts.sourceURLTemplate != "" {
+		if opts.sourceLinks, err = gitSourceLinks(opts.sourceURLTemplate); err != nil {
+			return err
+		}
+	}
+	if opts.webhookTitle, err = webhookTitle(opts); err != nil {
+		return err
+	}
+	var failOnSkip *regexp.Regexp
+	if opts.failOnSkip != "" {
+		if failOnSkip, err = regexp.Compile(opts.failOnSkip); err != nil {
+			return errors.Wrap(err, "invalid --fail-on-skip pattern")
+		}
+	}
+	var requiredTests []string
+	if opts.requiredTests != "" {
+		if requiredTests, err = readRequiredTests(opts.requiredTests); err != nil {
+			return err
+		}
+	}
+	if opts.branch == "" && (opts.rerunLastFailed || opts.ndjsonFile != "") {
+		opts.branch = gitBranch()
+	}
+	if opts.rerunLastFailed {
+		if opts.lastFailed, err = selectLastFailed(opts); err != nil {
+			return err
+		}
+	}
+	if opts.signKey != "" {
+		if opts.signer, err = manifest.ReadKeyFile(opts.signKey); err != nil {
+			return err
+		}
+	}
+	if opts.flakyScore {
+		if opts.flakeRates, err = readFlakeRates(opts); err != nil {
+			return err
+		}
+	}
+	if opts.teardownCommand != "" {
+		opts.teardown = &teardown{command: opts.teardownCommand, runID: opts.runID}
+		ctx, opts.teardown.signals = cancelOnSignal(ctx)
+		defer opts.teardown.run(resultError, nil)
+	}
+	// runCtx is stopped by a signal, but not by the --deadline, for the steps
+	// which run after go test
+	runCtx := ctx
+	if opts.deadline > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, opts.deadline)
+		defer cancel()
+	}
+	if opts.deadline > 0 || opts.packageArgsOrder != "" {
+		if !opts.rawCommand && !opts.stdin && len(opts.args) == 0 && len(opts.lastFailed) == 0 {
+			if opts.packages, err = schedulePackages(opts); err != nil {
+				return err
+			}
+		}
+	}
+	if opts.setup, err = runSetup(ctx, opts); err != nil {
+		// the manifest records the output of the step which failed
+		if err := writeManifest(opts, nil); err != nil {
+			log.WithError(err).Warn("failed to write the manifest")
+		}
+		return err
+	}
+	var gitBefore map[string]gitFileStatus
+	if opts.checkGitStatus {
+		if gitBefore, err = gitStatus(); err != nil {
+			return err
+		}
+	}
+	goTestProc, err := startProc(ctx, opts)
 	if err != nil {
-		return errors.Wrapf(err, "failed to run %s %s",
-			goTestProc.cmd.Path,
-			strings.Join(goTestProc.cmd.Args, " "))
+		return err
 	}
 	defer goTestProc.cancel()
 
 	out := os.Stdout
+	if junitFileToStdout(opts) {
+		out = os.Stderr
+	}
 	handler, err := newEventHandler(opts, out, os.Stderr)
 	if err != nil {
 		return err
 	}
 	defer handler.Close() // nolint: errcheck
-	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
-		Stdout:  goTestProc.stdout,
-		Stderr:  goTestProc.stderr,
-		Handler: handler,
-	})
+	var metrics *overheadMetrics
+	if opts.internalMetrics {
+		metrics = &overheadMetrics{}
+		handler.metrics = metrics
+	}
+	scanConfig := testjson.ScanConfig{
+		Stdout:           goTestProc.stdout,
+		Stderr:           goTestProc.stderr,
+		Handler:          handler,
+		PlannedPackages:  opts.packages,
+		KeepPassedOutput: junitSystemOutEnabled(opts),
+		Rewrite:          opts.rewriteRules.rules,
+	}
+	if metrics != nil {
+		scanConfig.Metrics = &metrics.scan
+	}
+	exec, err := testjson.ScanTestOutput(scanConfig)
+	if err != nil {
+		return err
+	}
+	opts.execution = exec
+	testErr := goTestProc.wait(exec)
+	// the coverage profile is read before any rerun replaces it
+	var coverDiff *coverageDiff
+	if opts.coverageDiff != "" {
+		diff, err := changedLinesCoverage(opts)
+		if err != nil {
+			log.WithError(err).Warn("failed to find the coverage of the changed lines")
+			diff.Error = err.Error()
+		}
+		coverDiff = &diff
+	}
+	if testErr != nil && len(opts.infraErrors.patterns) > 0 && ctx.Err() == nil {
+		var passed bool
+		opts.infraErrorPackages, passed, err = retryInfraErrors(ctx, opts, handler, exec)
+		if err != nil {
+			return err
+		}
+		if passed {
+			testErr = nil
+		}
+	}
+	if testErr != nil && opts.rerunFails > 0 && ctx.Err() == nil {
+		opts.reran = true
+		passed, err := rerunFailed(ctx, opts, handler, exec)
+		if err != nil {
+			return err
+		}
+		if passed && len(opts.infraErrorPackages) == 0 {
+			testErr = nil
+		}
+	}
+	var skippedFailures []testjson.TestCase
+	if failOnSkip != nil {
+		skippedFailures = exec.FailSkipped(func(tc testjson.TestCase) bool {
+			return failOnSkip.MatchString(tc.ID())
+		})
+		if len(skippedFailures) > 0 && testErr == nil {
+			testErr = &exitCodeError{code: 1}
+		}
+	}
+	if err := handler.Flush(); err != nil {
+		return err
+	}
+	opts.failureCategories, err = classifyFailures(runCtx, opts, exec)
+	if err != nil {
+		log.WithError(err).Warn("failed to classify the failed tests")
+	}
+	opts.failureCategories = withInfraErrors(opts.failureCategories, exec, opts.infraErrorPackages)
+	opts.labels = testLabels(opts, exec)
+	summaryOpts := testjson.SummaryOptions{
+		Sections:          opts.noSummary.value,
+		Messages:          &msgs,
+		Timing:            opts.summaryTiming,
+		FlakeRates:        opts.flakeRates,
+		SkipCategories:    opts.skipCategories.categories,
+		FailureCategories: opts.failureCategories,
+		Hyperlinks:        opts.hyperlinks,
+		Flaky:             opts.reran,
+	}
+	if coverDiff != nil {
+		summaryOpts.UncoveredChanges = uncoveredChanges(*coverDiff, msgs)
+	}
+	if lineTemplate != nil {
+		summaryOpts.LineTemplate = lineTemplate
+		summaryOpts.RunID = opts.runID
+	}
+	if len(opts.packagePriority.rules) > 0 {
+		summaryOpts.PackageRank = opts.packagePriority.rank
+	}
+	if opts.checkGitStatus {
+		summaryOpts.Warnings, err = gitStatusWarnings(gitBefore, exec.Packages())
+		if err != nil {
+			return err
+		}
+	}
+	if len(skippedFailures) > 0 {
+		summaryOpts.Warnings = append(summaryOpts.Warnings, fmt.Sprintf(
+			"%d skipped tests were counted as failures by --fail-on-skip", len(skippedFailures)))
+	}
+	empty := emptyWarnings(exec, opts.failOnEmpty)
+	summaryOpts.Warnings = append(summaryOpts.Warnings, empty...)
+	if len(empty) > 0 && testErr == nil {
+		testErr = &exitCodeError{code: exitCodeEmpty}
+	}
+	requiredWarnings := requiredTestWarnings(exec, requiredTests)
+	summaryOpts.Warnings = append(summaryOpts.Warnings, requiredWarnings...)
+	if len(requiredWarnings) > 0 && testErr == nil {
+		testErr = &exitCodeError{code: 1}
+	}
+	if opts.strictEvents != "" {
+		discrepancies := exec.Discrepancies()
+		for _, problem := range discrepancies {
+			summaryOpts.Warnings = append(summaryOpts.Warnings, "strict events: "+problem)
+		}
+		if len(discrepancies) > 0 && opts.strictEvents == strictEventsFail && testErr == nil {
+			testErr = &exitCodeError{code: 1}
+		}
+	}
+	summaryOpts.Warnings = append(summaryOpts.Warnings, crashWarnings(exec)...)
+	summaryOpts.Warnings = append(summaryOpts.Warnings, infraErrorWarnings(opts)...)
+	summaryOpts.Warnings = append(summaryOpts.Warnings, skipCategoryWarnings(exec, opts)...)
+	summaryOpts.Warnings = append(summaryOpts.Warnings, timeoutWarnings(exec, opts)...)
+	summaryOpts.Warnings = append(summaryOpts.Warnings, goTestWarnings(exec)...)
+	deadlineReached := ctx.Err() == context.DeadlineExceeded
+	if deadlineReached {
+		summaryOpts.Warnings = append(summaryOpts.Warnings,
+			deadlineWarnings(opts.deadline, unfinishedPackages(exec, opts.packages))...)
+	}
+	reportsStarted := time.Now()
+	summary := new(bytes.Buffer)
+	err = testjson.PrintSummaryWithOptions(io.MultiWriter(out, summary), exec, summaryOpts)
 	if err != nil {
 		return err
 	}
-	if err := testjson.PrintSummary(out, exec, opts.noSummary.value); err != nil {
+	if err := handler.finishJUnitStreams(exec); err != nil {
+		return err
+	}
+	if err := writeJUnitFiles(opts, exec); err != nil {
+		return err
+	}
+	if err := writeNDJSONFile(opts, exec); err != nil {
+		return err
+	}
+	if err := writeBEPFile(opts, exec); err != nil {
+		return err
+	}
+	if opts.coverageDiffFile != "" {
+		if err := writeCoverageDiffFile(opts.coverageDiffFile, *coverDiff); err != nil {
+			return err
+		}
+	}
+	if opts.validateReports {
+		if err := validateReports(opts); err != nil {
+			return err
+		}
+	}
+	result := resultPass
+	switch {
+	case opts.teardown != nil && opts.teardown.signals.interrupted():
+		result = resultInterrupted
+	case testErr != nil || deadlineReached:
+		result = resultFail
+	}
+	opts.teardown.run(result, exec)
+	if result != resultPass {
+		if opts.artifacts, err = collectArtifacts(opts); err != nil {
+			log.WithError(err).Warn("failed to collect artifacts")
+		}
+	}
+	if err := writeManifest(opts, exec); err != nil {
 		return err
 	}
-	if err := writeJUnitFile(opts.junitFile, exec); err != nil {
+	if err := syncReports(opts); err != nil {
 		return err
 	}
-	return goTestProc.cmd.Wait()
+	if metrics != nil {
+		metrics.reports = time.Since(reportsStarted)
+		metrics.print(out)
+	}
+	err = testErr
+	switch {
+	case deadlineReached:
+		err = &exitCodeError{code: exitCodeDeadline}
+	case opts.teardown != nil && opts.teardown.signals.interrupted():
+		err = &exitCodeError{code: exitCodeInterrupted}
+	}
+	if err := sendEmail(opts, exec, summary.String(), err != nil); err != nil {
+		log.WithError(err).Error("failed to send email")
+	}
+	if err != nil && opts.ignoreExperimental && onlyExperimentalFailures(exec, opts.packagePriority) {
+		log.Warn("ignoring failures from experimental packages")
+		return nil
+	}
+	return err
+}
+
+// goTestWarnings returns the warnings from go test and the testing package,
+// prefixed with the package, or the test ID, which printed the warning.
+func goTestWarnings(exec *testjson.Execution) []string {
+	var warnings []string
+	for _, warning := range exec.GoTestWarnings() {
+		switch {
+		case warning.Test != "":
+			warnings = append(warnings, testjson.TestID(warning.Package, warning.Test)+": "+warning.Text)
+		case warning.Package != "":
+			warnings = append(warnings, testjson.RelativePackagePath(warning.Package)+": "+warning.Text)
+		default:
+			warnings = append(warnings, warning.Text)
+		}
+	}
+	return warnings
+}
+
+func parseSummaryLineTemplate(value string) (*template.Template, error) {
+	if value == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("summary-line").Parse(value)
+	return tmpl, errors.Wrap(err, "invalid --summary-line-template")
 }
 
 func goTestCmdArgs(opts *options) []string {
@@ -155,6 +827,10 @@ func goTestCmdArgs(opts *options) []string {
 	switch {
 	case opts.rawCommand:
 		return args
+	case len(opts.lastFailed) > 0:
+		return lastFailedCmdArgs(opts)
+	case len(args) == 0 && len(opts.packages) > 0:
+		return append(append(defaultArgs, "-json"), opts.packages...)
 	case len(args) == 0:
 		return append(defaultArgs, "-json", pathFromEnv("./..."))
 	case !hasJSONArg(args):
@@ -166,6 +842,24 @@ func goTestCmdArgs(opts *options) []string {
 	return append(defaultArgs, args...)
 }
 
+// testCommand returns the go test command line, with arguments which contain a
+// space or a quote quoted. Returns an empty string when the events are read
+// from stdin.
+func testCommand(opts *options) string {
+	if opts.stdin {
+		return ""
+	}
+	args := goTestCmdArgs(opts)
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
 func pathFromEnv(defaultPath string) string {
 	return lookEnvWithDefault("TEST_DIRECTORY", defaultPath)
 }
@@ -186,6 +880,50 @@ type proc struct {
 	cancel func()
 }
 
+// startProc starts go test, or when --stdin is set, returns a proc which reads
+// the events from stdin.
+func startProc(ctx context.Context, opts *options) (proc, error) {
+	if opts.stdin {
+		return proc{
+			stdout: os.Stdin,
+			stderr: strings.NewReader(""),
+			cancel: func() {},
+		}, nil
+	}
+	args := goTestCmdArgs(opts)
+	goTestProc, err := startGoTest(ctx, args)
+	if err != nil {
+		// cmd is nil when there is no command to run
+		return goTestProc, errors.Wrapf(err, "failed to run %s", strings.Join(args, " "))
+	}
+	if opts.deadline > 0 || opts.teardown != nil {
+		goTestProc = stopAtDeadline(ctx, goTestProc)
+	}
+	return goTestProc, nil
+}
+
+// wait for the process to exit. When there is no process, because events are
+// read from stdin, returns an exitCodeError if any tests failed.
+func (p proc) wait(exec *testjson.Execution) error {
+	if p.cmd == nil {
+		if len(exec.Failed()) > 0 || len(exec.Errors()) > 0 {
+			return &exitCodeError{code: 1}
+		}
+		return nil
+	}
+	return p.cmd.Wait()
+}
+
+// exitCodeError is returned by run to exit with a code, without printing an
+// error message.
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("exit code %d", e.code)
+}
+
 func startGoTest(ctx context.Context, args []string) (proc, error) {
 	if len(args) == 0 {
 		return proc{}, errors.New("missing command to run")
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/gotestsum/testjson"
+)
+
+var cmpExitCodeError = cmp.AllowUnexported(exitCodeError{})
+
+func TestTestCommand(t *testing.T) {
+	defer env.Patch(t, "TEST_DIRECTORY", "")()
+
+	opts := &options{args: []string{"-tags=integration", "-run", "Test Save", "./store/..."}}
+	assert.Equal(t, testCommand(opts),
+		`go test -json -tags=integration -run "Test Save" ./store/...`)
+
+	opts = &options{}
+	assert.Equal(t, testCommand(opts), "go test -json ./...")
+
+	opts = &options{stdin: true}
+	assert.Equal(t, testCommand(opts), "")
+}
+
+func TestStartProc_Stdin(t *testing.T) {
+	p, err := startProc(context.Background(), &options{stdin: true})
+	assert.NilError(t, err)
+	defer p.cancel()
+	assert.Assert(t, p.cmd == nil)
+
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"pkg/a","Test":"TestA"}
+{"Action":"fail","Package":"pkg/a","Test":"TestA"}
+{"Action":"fail","Package":"pkg/a"}
+`))
+	assert.NilError(t, err)
+	err = p.wait(exec)
+	assert.DeepEqual(t, err, &exitCodeError{code: 1}, cmpExitCodeError)
+}
+
+func TestStartProc_NoCommand(t *testing.T) {
+	_, err := startProc(context.Background(), &options{rawCommand: true})
+	assert.ErrorContains(t, err, "missing command to run")
+}
+
+func TestGoTestWarnings(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"output","Package":"pkg/a","Output":"testing: warning: no tests to run\n"}
+{"Action":"pass","Package":"pkg/a"}
+{"Action":"run","Package":"pkg/b","Test":"TestB"}
+{"Action":"output","Package":"pkg/b","Test":"TestB","Output":"testing: warning: a warning\n"}
+{"Action":"pass","Package":"pkg/b","Test":"TestB"}
+`))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, goTestWarnings(exec), []string{
+		"pkg/a: testing: warning: no tests to run",
+		"pkg/b#TestB: testing: warning: a warning",
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"time"
+
+	"gotest.tools/gotestsum/internal/manifest"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// reportFiles returns the paths of the report files written by the run.
+func reportFiles(opts *options) []string {
+	var files []string
+	if opts.jsonFile != "" {
+		files = append(files, opts.jsonFile)
+	}
+	for _, spec := range junitFileSpecs(opts) {
+		if !spec.stdout() {
+			files = append(files, spec.path)
+		}
+	}
+	files = append(files, opts.junitDirFiles...)
+	if opts.ndjsonFile != "" {
+		files = append(files, opts.ndjsonFile)
+	}
+	if opts.bepJSONFile != "" {
+		files = append(files, opts.bepJSONFile)
+	}
+	if opts.coverageDiffFile != "" {
+		files = append(files, opts.coverageDiffFile)
+	}
+	return files
+}
+
+// writeManifest signs the report files with the --sign-key, and writes the
+// --manifest-file with the digest of each report file. exec is nil when a
+// setup step failed, and go test did not run. The manifest then has the setup
+// steps, and no reports.
+func writeManifest(opts *options, exec *testjson.Execution) error {
+	if opts.manifestFile == "" && opts.signer == nil {
+		return nil
+	}
+	m := manifest.Manifest{
+		RunID:     opts.runID,
+		Command:   testCommand(opts),
+		Setup:     opts.setup,
+		Artifacts: opts.artifacts,
+	}
+	if exec == nil {
+		for _, step := range opts.setup {
+			m.ElapsedSeconds += step.ElapsedSeconds
+		}
+		m.Started = time.Now().UTC().Add(-time.Duration(m.ElapsedSeconds * float64(time.Second)))
+		return writeManifestFile(opts, m)
+	}
+	m.Started = exec.Started().UTC()
+	m.ElapsedSeconds = exec.Elapsed().Seconds()
+	if opts.teardown != nil {
+		m.Teardown = opts.teardown.step
+	}
+	for _, path := range reportFiles(opts) {
+		report, err := manifest.NewReport(path, opts.signer)
+		if err != nil {
+			return err
+		}
+		m.Reports = append(m.Reports, report)
+	}
+	return writeManifestFile(opts, m)
+}
+
+func writeManifestFile(opts *options, m manifest.Manifest) error {
+	if opts.manifestFile == "" {
+		return nil
+	}
+	return manifest.Write(opts.manifestFile, m, opts.signer)
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func validateGoVersionsOptions(opts *options) error {
+	if len(opts.goVersions) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, version := range opts.goVersions {
+		name, err := goVersionName(version)
+		if err != nil {
+			return err
+		}
+		if seen[name] {
+			return errors.Errorf("--go-versions has %s more than once", name)
+		}
+		seen[name] = true
+	}
+	switch {
+	case opts.stdin:
+		return errors.New("--go-versions can not be used with --stdin")
+	case junitFileToStdout(opts):
+		return errors.New("--go-versions can not be used with a --junitfile written to stdout")
+	case opts.serveUI != "":
+		return errors.New("--go-versions can not be used with --serve-ui")
+	}
+	return nil
+}
+
+var goVersionPattern = regexp.MustCompile(`^1\.(\d+)(\.\d+|rc\d+|beta\d+)?$`)
+
+// goVersionName returns the name of the Go release of a --go-versions version,
+// ex: go1.22, go1.22.5, or go1.23rc1.
+func goVersionName(version string) (string, error) {
+	v := strings.TrimPrefix(version, "go")
+	if !goVersionPattern.MatchString(v) {
+		return "", errors.Errorf("invalid Go version %q, must be like 1.22 or 1.22.5", version)
+	}
+	return "go" + v, nil
+}
+
+// goToolchainName returns the GOTOOLCHAIN which selects the release name.
+// Since Go 1.21 the first release of a version is 1.N.0, so 1.N selects
+// 1.N.0.
+func goToolchainName(name string) string {
+	match := goVersionPattern.FindStringSubmatch(strings.TrimPrefix(name, "go"))
+	minor, _ := strconv.Atoi(match[1])
+	if match[2] == "" && minor >= 21 {
+		return name + ".0"
+	}
+	return name
+}
+
+// goToolchain is the environment which selects the go command of a release of
+// Go, for go test, and for any other go command run by gotestsum.
+type goToolchain struct {
+	name string
+	env  map[string]string
+}
+
+// findGoToolchain returns the toolchain of a --go-versions version. When a
+// golang.org/dl command for the release, ex: go1.22.5, is in PATH, the release
+// is downloaded by the command, and its bin directory is added to the start of
+// PATH. Otherwise GOTOOLCHAIN selects the release, which is downloaded by the
+// go command.
+func findGoToolchain(version string) (goToolchain, error) {
+	name, err := goVersionName(version)
+	if err != nil {
+		return goToolchain{}, err
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return downloadedGoToolchain(name, path)
+	}
+	toolchain := goToolchain{
+		name: name,
+		env:  map[string]string{"GOTOOLCHAIN": goToolchainName(name)},
+	}
+	// go version downloads the release, so that a release which does not
+	// exist fails before any tests are run
+	cmd := exec.Command("go", "version")
+	cmd.Env = toolchain.environ(os.Environ())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return toolchain, errors.Wrapf(err, "failed to find %s: %s", name, strings.TrimSpace(string(out)))
+	}
+	return toolchain, nil
+}
+
+func downloadedGoToolchain(name, path string) (goToolchain, error) {
+	// the download command does nothing when the release was downloaded
+	if out, err := exec.Command(path, "download").CombinedOutput(); err != nil {
+		return goToolchain{}, errors.Wrapf(err, "failed to download %s: %s", name, strings.TrimSpace(string(out)))
+	}
+	out, err := exec.Command(path, "env", "GOROOT").Output()
+	if err != nil {
+		return goToolchain{}, errors.Wrapf(err, "failed to find the GOROOT of %s", name)
+	}
+	goroot := strings.TrimSpace(string(out))
+	return goToolchain{
+		name: name,
+		env: map[string]string{
+			"PATH":        filepath.Join(goroot, "bin") + string(os.PathListSeparator) + os.Getenv("PATH"),
+			"GOROOT":      goroot,
+			"GOTOOLCHAIN": "local",
+		},
+	}, nil
+}
+
+// environ returns environ with the variables of the toolchain.
+func (t goToolchain) environ(environ []string) []string {
+	result := make([]string, 0, len(environ)+len(t.env))
+	for _, kv := range environ {
+		if _, ok := t.env[strings.SplitN(kv, "=", 2)[0]]; !ok {
+			result = append(result, kv)
+		}
+	}
+	for _, name := range sortedKeys(t.env) {
+		result = append(result, name+"="+t.env[name])
+	}
+	return result
+}
+
+// use sets the environment variables of the toolchain, and returns a function
+// which restores their previous values.
+func (t goToolchain) use() func() {
+	previous := make(map[string]*string, len(t.env))
+	for name, value := range t.env {
+		if old, ok := os.LookupEnv(name); ok {
+			previous[name] = &old
+		} else {
+			previous[name] = nil
+		}
+		os.Setenv(name, value) // nolint: errcheck
+	}
+	return func() {
+		for name, old := range previous {
+			if old == nil {
+				os.Unsetenv(name) // nolint: errcheck
+				continue
+			}
+			os.Setenv(name, *old) // nolint: errcheck
+		}
+	}
+}
+
+// runGoVersions runs the tests with each of the --go-versions, one after the
+// other, and prints a matrix of the result of each package with each version.
+// Returns an exitCodeError if the tests failed with any version.
+func runGoVersions(opts *options, out io.Writer) error {
+	if err := validateGoVersionsOptions(opts); err != nil {
+		return err
+	}
+	// every release is found before any tests are run
+	toolchains := make([]goToolchain, 0, len(opts.goVersions))
+	for _, version := range opts.goVersions {
+		toolchain, err := findGoToolchain(version)
+		if err != nil {
+			return err
+		}
+		toolchains = append(toolchains, toolchain)
+	}
+	names := make([]string, 0, len(toolchains))
+	results := make([]*testjson.Execution, 0, len(toolchains))
+	failed := false
+	for _, toolchain := range toolchains {
+		fmt.Fprintf(out, "\n=== %s\n", toolchain.name)
+		versionOpts := withGoVersion(opts, toolchain.name)
+		restore := toolchain.use()
+		err := run(versionOpts)
+		restore()
+		switch err.(type) {
+		case nil:
+		case *exec.ExitError, *exitCodeError:
+			failed = true
+		default:
+			return errors.Wrap(err, toolchain.name)
+		}
+		names = append(names, toolchain.name)
+		results = append(results, versionOpts.execution)
+	}
+	printGoVersionMatrix(out, names, results)
+	if err := writeGoVersionsJUnitFiles(opts, names); err != nil {
+		return err
+	}
+	if failed {
+		return &exitCodeError{code: 1}
+	}
+	return nil
+}
+
+// withGoVersion returns a copy of opts for the run with the Go release name.
+// The release is added to the labels of the run, and to the name of each file
+// written by the run, so that the files of each release are kept.
+func withGoVersion(opts *options, name string) *options {
+	versionOpts := *opts
+	versionOpts.runLabels = map[string]string{"go-version": name}
+	for k, v := range opts.runLabels {
+		versionOpts.runLabels[k] = v
+	}
+	versionOpts.junitFiles = withGoVersionJUnitFiles(opts.junitFiles, name)
+	versionOpts.junitFailuresOnly = withGoVersionJUnitFiles(opts.junitFailuresOnly, name)
+	for _, path := range []*string{
+		&versionOpts.jsonFile,
+		&versionOpts.ndjsonFile,
+		&versionOpts.bepJSONFile,
+		&versionOpts.manifestFile,
+		&versionOpts.statusFile,
+		&versionOpts.coverageDiffFile,
+		&versionOpts.artifactDir,
+		&versionOpts.junitFileDir,
+	} {
+		*path = goVersionPath(*path, name)
+	}
+	return &versionOpts
+}
+
+func withGoVersionJUnitFiles(value *junitFileValue, name string) *junitFileValue {
+	result := *value
+	result.files = make([]junitFileSpec, 0, len(value.files))
+	for _, spec := range value.files {
+		spec.path = goVersionPath(spec.path, name)
+		result.files = append(result.files, spec)
+	}
+	return &result
+}
+
+// goVersionPath returns path with the Go release name added before the
+// extension of the file, ex: junit.xml.gz is junit-go1.22.xml.gz.
+func goVersionPath(path, name string) string {
+	if path == "" {
+		return ""
+	}
+	dir, base := filepath.Split(path)
+	if i := strings.Index(base, "."); i > 0 {
+		return dir + base[:i] + "-" + name + base[i:]
+	}
+	return path + "-" + name
+}
+
+// writeGoVersionsJUnitFiles writes each --junitfile with the testsuites from
+// the file written for each Go release. The release is added to the name of
+// each testsuite, so that the file has a testsuite for each package with each
+// release. A file with format=open-test-reporting is not combined.
+func writeGoVersionsJUnitFiles(opts *options, names []string) error {
+	for _, spec := range junitFileSpecs(opts) {
+		if junitFileConfig(opts, spec).Format != junitxml.FormatJUnit {
+			continue
+		}
+		reports := make([]junitxml.JUnitTestSuites, 0, len(names))
+		for _, name := range names {
+			suites, err := readJUnitFile(goVersionPath(spec.path, name))
+			if err != nil {
+				return err
+			}
+			for i := range suites.Suites {
+				suites.Suites[i].Name += " (" + name + ")"
+			}
+			reports = append(reports, suites)
+		}
+		if err := writeJUnitSuitesFile(spec.path, junitxml.Merge(reports...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJUnitSuitesFile writes suites to filename. A filename which ends in .gz
+// is compressed with gzip.
+func writeJUnitSuitesFile(filename string, suites junitxml.JUnitTestSuites) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to create JUnit file")
+	}
+	var w io.Writer = out
+	var gz *gzip.Writer
+	if strings.HasSuffix(filename, ".gz") {
+		gz = gzip.NewWriter(out)
+		w = gz
+	}
+	err = junitxml.WriteSuites(w, suites)
+	if err == nil && gz != nil {
+		err = errors.Wrap(gz.Close(), "failed to compress JUnit file")
+	}
+	if err != nil {
+		out.Close() // nolint: errcheck
+		return err
+	}
+	return errors.Wrap(out.Close(), "failed to close JUnit file")
+}
+
+// printGoVersionMatrix prints the result of each package with each Go
+// release. A package which was not run with a release is printed as -.
+func printGoVersionMatrix(out io.Writer, names []string, results []*testjson.Execution) {
+	packages := make(map[string]bool)
+	for _, exec := range results {
+		if exec == nil {
+			continue
+		}
+		for _, pkg := range exec.Packages() {
+			packages[pkg] = true
+		}
+	}
+	fmt.Fprintln(out, "\n=== Go versions")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\t"+strings.Join(names, "\t"))
+	for _, pkg := range sortedPackageNames(packages) {
+		row := []string{testjson.RelativePackagePath(pkg)}
+		for _, exec := range results {
+			result := "-"
+			if exec != nil && exec.Package(pkg) != nil && exec.Package(pkg).Result() != "" {
+				result = string(exec.Package(pkg).Result())
+			}
+			row = append(row, result)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush() // nolint: errcheck
+}
+
+func sortedPackageNames(packages map[string]bool) []string {
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
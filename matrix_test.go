@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/fs"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestGoVersionName(t *testing.T) {
+	for _, tc := range []struct {
+		version   string
+		name      string
+		toolchain string
+	}{
+		{version: "1.22", name: "go1.22", toolchain: "go1.22.0"},
+		{version: "go1.22.5", name: "go1.22.5", toolchain: "go1.22.5"},
+		{version: "1.23rc1", name: "go1.23rc1", toolchain: "go1.23rc1"},
+		{version: "1.20", name: "go1.20", toolchain: "go1.20"},
+	} {
+		name, err := goVersionName(tc.version)
+		assert.NilError(t, err)
+		assert.Equal(t, name, tc.name)
+		assert.Equal(t, goToolchainName(name), tc.toolchain)
+	}
+
+	_, err := goVersionName("latest")
+	assert.ErrorContains(t, err, `invalid Go version "latest"`)
+}
+
+func TestValidateGoVersionsOptions(t *testing.T) {
+	opts := &options{goVersions: []string{"1.22", "go1.22"}, junitFiles: &junitFileValue{}, junitFailuresOnly: &junitFileValue{}}
+	assert.ErrorContains(t, validateGoVersionsOptions(opts), "--go-versions has go1.22 more than once")
+
+	opts.goVersions = []string{"1.22", "1.23"}
+	assert.NilError(t, validateGoVersionsOptions(opts))
+
+	opts.stdin = true
+	assert.ErrorContains(t, validateGoVersionsOptions(opts), "can not be used with --stdin")
+}
+
+func TestWithGoVersion(t *testing.T) {
+	opts := &options{
+		junitFiles:        &junitFileValue{files: []junitFileSpec{{path: "out/junit.xml.gz"}}},
+		junitFailuresOnly: &junitFileValue{},
+		ndjsonFile:        "results.ndjson",
+		artifactDir:       "artifacts",
+		runLabels:         map[string]string{"team": "storage"},
+	}
+	versionOpts := withGoVersion(opts, "go1.22")
+	assert.Equal(t, versionOpts.junitFiles.files[0].path, "out/junit-go1.22.xml.gz")
+	assert.Equal(t, versionOpts.ndjsonFile, "results-go1.22.ndjson")
+	assert.Equal(t, versionOpts.artifactDir, "artifacts-go1.22")
+	assert.Equal(t, versionOpts.jsonFile, "")
+	assert.DeepEqual(t, versionOpts.runLabels, map[string]string{"team": "storage", "go-version": "go1.22"})
+
+	// the options of the other versions are not changed
+	assert.Equal(t, opts.junitFiles.files[0].path, "out/junit.xml.gz")
+	assert.DeepEqual(t, opts.runLabels, map[string]string{"team": "storage"})
+}
+
+func TestPrintGoVersionMatrix(t *testing.T) {
+	scan := func(events string) *testjson.Execution {
+		exec, err := testjson.ReadExecution(strings.NewReader(events))
+		assert.NilError(t, err)
+		return exec
+	}
+	older := scan(`{"Action":"run","Package":"example.com/mod/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/mod/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/mod/a"}
+{"Action":"run","Package":"example.com/mod/b","Test":"TestTwo"}
+{"Action":"fail","Package":"example.com/mod/b","Test":"TestTwo"}
+{"Action":"fail","Package":"example.com/mod/b"}
+`)
+	newer := scan(`{"Action":"run","Package":"example.com/mod/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/mod/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/mod/a"}
+`)
+
+	out := new(bytes.Buffer)
+	printGoVersionMatrix(out, []string{"go1.21", "go1.22", "go1.23"},
+		[]*testjson.Execution{older, newer, nil})
+	expected := `
+=== Go versions
+PACKAGE            go1.21  go1.22  go1.23
+example.com/mod/a  pass    pass    -
+example.com/mod/b  fail    -       -
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestWriteGoVersionsJUnitFiles(t *testing.T) {
+	report := func(failures, result string) string {
+		return `<testsuites><testsuite name="example.com/lib" tests="1" failures="` + failures + `" skipped="0" time="1">
+<testcase classname="example.com/lib" name="TestOne" time="1">` + result + `</testcase>
+</testsuite></testsuites>`
+	}
+	dir := fs.NewDir(t, "go-versions",
+		fs.WithFile("junit-go1.21.xml", report("1", `<failure message="Failed" type=""></failure>`)),
+		fs.WithFile("junit-go1.22.xml", report("0", "")))
+	defer dir.Remove()
+	_, opts := setupFlags("gotestsum")
+	assert.NilError(t, opts.junitFiles.Set(dir.Join("junit.xml")))
+
+	assert.NilError(t, writeGoVersionsJUnitFiles(opts, []string{"go1.21", "go1.22"}))
+	suites, err := readJUnitFile(dir.Join("junit.xml"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(suites.Suites), 2)
+	assert.Equal(t, suites.Suites[0].Name, "example.com/lib (go1.21)")
+	assert.Equal(t, suites.Suites[1].Name, "example.com/lib (go1.22)")
+	assert.Equal(t, suites.Tests, 2)
+	assert.Equal(t, suites.Failures, 1)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// overheadMetrics measure the overhead of gotestsum, and are printed by
+// --internal-metrics.
+type overheadMetrics struct {
+	scan testjson.ScanMetrics
+	// format is the time spent by the event handler formatting and writing
+	// the events.
+	format time.Duration
+	// reports is the time spent writing the summary and the report files.
+	reports time.Duration
+}
+
+// print the metrics. The memory is the total memory obtained from the OS by
+// the Go runtime, which is never released, so it is the high-water mark.
+func (m *overheadMetrics) print(out io.Writer) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fmt.Fprintf(out, "METRICS %d events, parse %s, format %s, reports %s, memory %.1fMB\n",
+		m.scan.Events,
+		m.scan.Parse.Round(time.Microsecond),
+		m.format.Round(time.Microsecond),
+		m.reports.Round(time.Microsecond),
+		float64(mem.Sys)/(1<<20))
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"gotest.tools/gotestsum/internal/email"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type emailOptions struct {
+	to       string
+	from     string
+	addr     string
+	username string
+	password string
+	tls      string
+	on       string
+}
+
+func setupEmailFlags(flags *pflag.FlagSet, opts *emailOptions) {
+	flags.StringVar(&opts.to, "email-to",
+		lookEnvWithDefault("GOTESTSUM_EMAIL_TO", ""),
+		"comma separated list of addresses to email the summary to")
+	flags.StringVar(&opts.from, "email-from",
+		lookEnvWithDefault("GOTESTSUM_EMAIL_FROM", "gotestsum@localhost"),
+		"sender address of the email")
+	flags.StringVar(&opts.addr, "email-smtp-addr",
+		lookEnvWithDefault("GOTESTSUM_EMAIL_SMTP_ADDR", "localhost:25"),
+		"host:port of the SMTP server")
+	flags.StringVar(&opts.username, "email-smtp-username",
+		lookEnvWithDefault("GOTESTSUM_EMAIL_SMTP_USERNAME", ""),
+		"username used to authenticate with the SMTP server")
+	opts.password = os.Getenv("GOTESTSUM_EMAIL_SMTP_PASSWORD")
+	flags.StringVar(&opts.tls, "email-tls", email.TLSStartTLS,
+		"TLS mode used to connect to the SMTP server: starttls, tls, none")
+	flags.StringVar(&opts.on, "email-on", "failure",
+		"when to send the email: failure, always")
+}
+
+func validateEmailOptions(opts *options) error {
+	switch opts.email.on {
+	case "failure", "always":
+	default:
+		return errors.Errorf("invalid --email-on %s, must be failure or always", opts.email.on)
+	}
+	switch opts.email.tls {
+	case email.TLSStartTLS, email.TLSImplicit, email.TLSNone:
+	default:
+		return errors.Errorf("invalid --email-tls %s, must be starttls, tls, or none", opts.email.tls)
+	}
+	return nil
+}
+
+// ansiEscape matches the escape sequences which may be in the summary: the
+// color codes, and the OSC 8 hyperlinks from --hyperlinks, which end with
+// either ST or BEL. The text of a hyperlink is not part of the match.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m|\x1b\\]8;[^\x07\x1b]*(?:\x07|\x1b\\\\)")
+
+// sendEmail sends the summary, with the JUnit XML file attached, to the
+// recipients from opts.
+func sendEmail(opts *options, exec *testjson.Execution, summary string, failed bool) error {
+	emailOpts := opts.email
+	switch {
+	case emailOpts.to == "":
+		return nil
+	case emailOpts.on == "failure" && !failed:
+		return nil
+	}
+	to, err := readAsCSV(emailOpts.to)
+	if err != nil {
+		return errors.Wrap(err, "invalid --email-to")
+	}
+
+	msg := email.Message{
+		Subject: emailSubject(exec, failed, opts.runLabels),
+		Body:    ansiEscape.ReplaceAllString(summary, ""),
+	}
+	if junitFile := opts.junitFiles.first(); junitFile != "" {
+		data, err := ioutil.ReadFile(junitFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read JUnit file")
+		}
+		contentType := "application/xml"
+		if strings.HasSuffix(junitFile, ".gz") {
+			contentType = "application/gzip"
+		}
+		msg.Attachments = append(msg.Attachments, email.Attachment{
+			Name:        filepath.Base(junitFile),
+			ContentType: contentType,
+			Data:        data,
+		})
+	}
+	return email.Send(email.Config{
+		Addr:     emailOpts.addr,
+		TLS:      emailOpts.tls,
+		Username: emailOpts.username,
+		Password: emailOpts.password,
+		From:     emailOpts.from,
+		To:       to,
+	}, msg)
+}
+
+func emailSubject(exec *testjson.Execution, failed bool, labels map[string]string) string {
+	result := "PASS"
+	if failed {
+		result = "FAIL"
+	}
+	subject := fmt.Sprintf("gotestsum %s: %d tests, %d failures, %d skipped",
+		result, exec.Total(), len(exec.Failed()), len(exec.Skipped()))
+	if len(labels) > 0 {
+		subject += " (" + formatRunLabels(labels) + ")"
+	}
+	return subject
+}
+
+// formatRunLabels returns the --label labels as NAME=VALUE, sorted by name.
+func formatRunLabels(labels map[string]string) string {
+	names := sortedKeys(labels)
+	for i, name := range names {
+		names[i] = name + "=" + labels[name]
+	}
+	return strings.Join(names, ", ")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// webhookTitleData is the data used to execute the --event-webhook-title
+// template.
+type webhookTitleData struct {
+	RunID  string
+	Labels map[string]string
+}
+
+// webhookTitle returns the title of the batches sent to --event-webhook-url,
+// created from the --event-webhook-title template.
+func webhookTitle(opts *options) (string, error) {
+	if opts.eventWebhookTitle == "" {
+		return "", nil
+	}
+	tmpl, err := template.New("webhook-title").Option("missingkey=zero").Parse(opts.eventWebhookTitle)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid --event-webhook-title template")
+	}
+	var buf strings.Builder
+	data := webhookTitleData{RunID: opts.runID, Labels: opts.runLabels}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", errors.Wrap(err, "failed to execute --event-webhook-title template")
+	}
+	return buf.String(), nil
+}
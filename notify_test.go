@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestFormatRunLabels(t *testing.T) {
+	labels := map[string]string{"team": "payments", "env": "ci"}
+	assert.Equal(t, formatRunLabels(labels), "env=ci, team=payments")
+	assert.Equal(t, formatRunLabels(nil), "")
+}
+
+func TestWebhookTitle(t *testing.T) {
+	opts := &options{
+		runID:             "run-1",
+		runLabels:         map[string]string{"team": "payments"},
+		eventWebhookTitle: "{{ .Labels.team }} tests {{ .Labels.missing }}({{ .RunID }})",
+	}
+	title, err := webhookTitle(opts)
+	assert.NilError(t, err)
+	assert.Equal(t, title, "payments tests (run-1)")
+
+	opts.eventWebhookTitle = "{{ .Labels"
+	_, err = webhookTitle(opts)
+	assert.ErrorContains(t, err, "invalid --event-webhook-title template")
+}
+
+func TestAnsiEscape(t *testing.T) {
+	summary := "\x1b[31mFAIL\x1b[0m " +
+		"\x1b]8;;file:///src/db_test.go#L9\x1b\\db_test.go:9\x1b]8;;\x1b\\ " +
+		"\x1b]8;id=1;file:///src/api_test.go\x07api_test.go\x1b]8;;\x07"
+	assert.Equal(t, ansiEscape.ReplaceAllString(summary, ""), "FAIL db_test.go:9 api_test.go")
+}
+
+func TestValidateEmailOptions(t *testing.T) {
+	opts := &options{email: emailOptions{on: "failure", tls: "starttls"}}
+	assert.NilError(t, validateEmailOptions(opts))
+
+	opts.email.on = "sometimes"
+	assert.ErrorContains(t, validateEmailOptions(opts), "invalid --email-on sometimes")
+
+	opts.email = emailOptions{on: "always", tls: "ssl"}
+	assert.ErrorContains(t, validateEmailOptions(opts), "invalid --email-tls ssl")
+}
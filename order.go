@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math/rand"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/internal/ndjson"
+)
+
+// Package orders used by --package-args-order.
+const (
+	orderFailedFirst = "failed-first"
+	orderSlowestLast = "slowest-last"
+	orderAlpha       = "alpha"
+	orderRandom      = "random"
+)
+
+func validateOrderOptions(opts *options) error {
+	switch opts.packageArgsOrder {
+	case "":
+		return nil
+	case orderFailedFirst, orderSlowestLast, orderAlpha, orderRandom:
+	default:
+		return errors.Errorf("unknown --package-args-order value %s", opts.packageArgsOrder)
+	}
+	switch {
+	case opts.rawCommand || opts.stdin || len(opts.args) > 0:
+		return errors.New("--package-args-order can not be used with go test args, --raw-command, or --stdin")
+	case opts.rerunLastFailed:
+		return errors.New("--package-args-order can not be used with --rerun-last-failed")
+	}
+	return nil
+}
+
+// listPackages returns the packages which match the pattern.
+func listPackages(pattern string) ([]string, error) {
+	cmd := exec.Command("go", "list", pattern)
+	log.Debugf("exec: %s", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list packages")
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// schedulePackages lists the packages to test, and sorts them by priority,
+// then by the order from --package-args-order. The default order is
+// slowest-last, so that with --deadline the packages most likely to finish
+// before the deadline run first.
+//
+// The packages are passed to a single go test in this order. go test starts
+// the packages about in the order of its arguments, but runs up to -p
+// packages at once, and does not promise an order, so the order is best
+// effort.
+func schedulePackages(opts *options) ([]string, error) {
+	pkgs, err := listPackages(pathFromEnv("./..."))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ndjson.ReadFiles(opts.history)
+	if err != nil {
+		return nil, err
+	}
+	order := opts.packageArgsOrder
+	if order == "" {
+		order = orderSlowestLast
+	}
+	less := packageOrder(order, rows, pkgs)
+	sort.SliceStable(pkgs, func(i, j int) bool {
+		pi := opts.packagePriority.priority(pkgs[i])
+		pj := opts.packagePriority.priority(pkgs[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return less(pkgs[i], pkgs[j])
+	})
+	log.Debugf("scheduled packages: %s", pkgs)
+	return pkgs, nil
+}
+
+// packageOrder returns a function which reports whether package a should run
+// before package b. failed-first runs the packages which failed in the most
+// runs from history first. slowest-last runs the packages with the shortest
+// average duration first, and packages without history last. For random the
+// pkgs are shuffled, and their order is kept.
+func packageOrder(order string, rows []ndjson.Row, pkgs []string) func(a, b string) bool {
+	switch order {
+	case orderFailedFirst:
+		failures := ndjson.PackageFailures(rows)
+		return func(a, b string) bool {
+			return failures[a] > failures[b]
+		}
+	case orderAlpha:
+		return func(a, b string) bool {
+			return a < b
+		}
+	case orderRandom:
+		seed := time.Now().UnixNano()
+		log.Debugf("random package order seed: %d", seed)
+		rand.New(rand.NewSource(seed)).Shuffle(len(pkgs), func(i, j int) {
+			pkgs[i], pkgs[j] = pkgs[j], pkgs[i]
+		})
+		return func(a, b string) bool {
+			return false
+		}
+	default:
+		durations := ndjson.PackageDurations(rows)
+		return func(a, b string) bool {
+			da, aok := durations[a]
+			db, bok := durations[b]
+			if aok != bok {
+				return aok
+			}
+			return da < db
+		}
+	}
+}
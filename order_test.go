@@ -0,0 +1,39 @@
+package main
+
+import (
+	"sort"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/internal/ndjson"
+)
+
+func TestPackageOrder(t *testing.T) {
+	rows := []ndjson.Row{
+		{RunID: "1", Package: "pkg/a", Outcome: ndjson.OutcomePass, ElapsedSeconds: 5},
+		{RunID: "1", Package: "pkg/b", Outcome: ndjson.OutcomeFail, ElapsedSeconds: 1},
+		{RunID: "1", Package: "pkg/c", Outcome: ndjson.OutcomeFail, ElapsedSeconds: 3},
+		{RunID: "2", Package: "pkg/c", Outcome: ndjson.OutcomeFail, ElapsedSeconds: 3},
+	}
+	sorted := func(order string) []string {
+		pkgs := []string{"pkg/d", "pkg/c", "pkg/b", "pkg/a"}
+		less := packageOrder(order, rows, pkgs)
+		sort.SliceStable(pkgs, func(i, j int) bool {
+			return less(pkgs[i], pkgs[j])
+		})
+		return pkgs
+	}
+
+	assert.DeepEqual(t, sorted(orderFailedFirst), []string{"pkg/c", "pkg/b", "pkg/d", "pkg/a"})
+	assert.DeepEqual(t, sorted(orderSlowestLast), []string{"pkg/b", "pkg/c", "pkg/a", "pkg/d"})
+	assert.DeepEqual(t, sorted(orderAlpha), []string{"pkg/a", "pkg/b", "pkg/c", "pkg/d"})
+	assert.Equal(t, len(sorted(orderRandom)), 4)
+}
+
+func TestValidateOrderOptions(t *testing.T) {
+	assert.NilError(t, validateOrderOptions(&options{packageArgsOrder: orderAlpha}))
+	assert.ErrorContains(t, validateOrderOptions(&options{packageArgsOrder: "fastest"}),
+		"unknown --package-args-order value fastest")
+	assert.ErrorContains(t, validateOrderOptions(&options{packageArgsOrder: orderAlpha, args: []string{"./..."}}),
+		"can not be used with go test args")
+}
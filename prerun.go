@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/internal/manifest"
+)
+
+// waitPollInterval is the time between each readiness check of --wait-for.
+var waitPollInterval = 250 * time.Millisecond
+
+func validatePreRunOptions(opts *options) error {
+	for _, target := range opts.waitFor {
+		switch {
+		case strings.HasPrefix(target, "tcp://"):
+		case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		default:
+			return errors.Errorf("--wait-for must be a tcp://, http://, or https:// URL, not %s", target)
+		}
+	}
+	if opts.waitTimeout <= 0 {
+		return errors.New("--wait-timeout must be greater than 0")
+	}
+	return nil
+}
+
+// runSetup runs the --pre-run-command, and then waits for each --wait-for
+// target to be ready. Returns the steps, which are written to the
+// --manifest-file.
+func runSetup(ctx context.Context, opts *options) ([]manifest.Step, error) {
+	var steps []manifest.Step
+	if opts.preRunCommand != "" {
+		step := runCommand(ctx, opts.preRunCommand, nil)
+		steps = append(steps, step)
+		if step.Error != "" {
+			return steps, errors.Errorf("--pre-run-command failed: %s", step.Error)
+		}
+	}
+	for _, target := range opts.waitFor {
+		step := waitFor(ctx, target, opts.waitTimeout)
+		steps = append(steps, step)
+		if step.Error != "" {
+			return steps, errors.Errorf("--wait-for %s: %s", target, step.Error)
+		}
+	}
+	return steps, nil
+}
+
+// runCommand runs the command with the shell, and the env. The output is
+// printed to stderr, and captured in the step.
+func runCommand(ctx context.Context, command string, env []string) manifest.Step {
+	start := time.Now()
+	cmd := shellCommand(ctx, command)
+	cmd.Env = env
+	output := new(bytes.Buffer)
+	cmd.Stdout = io.MultiWriter(os.Stderr, output)
+	cmd.Stderr = cmd.Stdout
+	log.Debugf("exec: %s", cmd.Args)
+
+	step := manifest.Step{Command: command}
+	if err := cmd.Run(); err != nil {
+		step.Error = err.Error()
+	}
+	step.Output = output.String()
+	step.ElapsedSeconds = time.Since(start).Seconds()
+	return step
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// waitFor checks the target until it is ready, or the timeout is reached. A
+// tcp:// target is ready when a connection can be opened, and an http(s)://
+// target is ready when a GET returns 200 OK.
+func waitFor(ctx context.Context, target string, timeout time.Duration) manifest.Step {
+	start := time.Now()
+	step := manifest.Step{WaitFor: target}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lastErr error
+	for {
+		if lastErr = checkReady(ctx, target); lastErr == nil {
+			break
+		}
+		log.Debugf("waiting for %s: %s", target, lastErr)
+		select {
+		case <-ctx.Done():
+			step.Error = "not ready after " + timeout.String() + ": " + lastErr.Error()
+			step.ElapsedSeconds = time.Since(start).Seconds()
+			return step
+		case <-time.After(waitPollInterval):
+		}
+	}
+	step.ElapsedSeconds = time.Since(start).Seconds()
+	return step
+}
+
+func checkReady(ctx context.Context, target string) error {
+	if strings.HasPrefix(target, "tcp://") {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", strings.TrimPrefix(target, "tcp://"))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
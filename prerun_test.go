@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/fs"
+	"gotest.tools/gotestsum/internal/manifest"
+)
+
+func TestRunSetup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer listener.Close() // nolint: errcheck
+
+	ready := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if !ready {
+			ready = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	defer patchWaitPollInterval(time.Millisecond)()
+
+	opts := &options{
+		preRunCommand: "echo starting",
+		waitFor:       []string{"tcp://" + listener.Addr().String(), server.URL},
+		waitTimeout:   5 * time.Second,
+	}
+	assert.NilError(t, validatePreRunOptions(opts))
+	steps, err := runSetup(context.Background(), opts)
+	assert.NilError(t, err)
+	assert.Equal(t, len(steps), 3)
+	assert.Equal(t, steps[0].Command, "echo starting")
+	assert.Equal(t, steps[0].Output, "starting\n")
+	assert.Equal(t, steps[1].WaitFor, "tcp://"+listener.Addr().String())
+	assert.Equal(t, steps[2].Error, "")
+}
+
+func TestRunSetup_Failures(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	defer patchWaitPollInterval(time.Millisecond)()
+
+	opts := &options{preRunCommand: "echo broken; exit 3", waitTimeout: time.Second}
+	steps, err := runSetup(context.Background(), opts)
+	assert.ErrorContains(t, err, "--pre-run-command failed: exit status 3")
+	assert.Equal(t, steps[0].Output, "broken\n")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	addr := listener.Addr().String()
+	assert.NilError(t, listener.Close())
+
+	opts = &options{waitFor: []string{"tcp://" + addr}, waitTimeout: 20 * time.Millisecond}
+	_, err = runSetup(context.Background(), opts)
+	assert.ErrorContains(t, err, "not ready after 20ms")
+
+	opts = &options{waitFor: []string{"localhost:5432"}, waitTimeout: time.Second}
+	assert.ErrorContains(t, validatePreRunOptions(opts), "must be a tcp://")
+}
+
+func TestWriteManifest_SetupFailed(t *testing.T) {
+	defer env.Patch(t, "TEST_DIRECTORY", "")()
+	dir := fs.NewDir(t, "manifest")
+	defer dir.Remove()
+
+	opts := &options{
+		runID:        "run-1",
+		manifestFile: dir.Join("manifest.json"),
+		jsonFile:     dir.Join("not-written.json"),
+		setup: []manifest.Step{
+			{Command: "docker compose up -d", ElapsedSeconds: 2},
+			{WaitFor: "tcp://localhost:5432", ElapsedSeconds: 1, Error: "not ready after 1s"},
+		},
+	}
+	assert.NilError(t, writeManifest(opts, nil))
+
+	raw, err := ioutil.ReadFile(opts.manifestFile)
+	assert.NilError(t, err)
+	var m manifest.Manifest
+	assert.NilError(t, json.Unmarshal(raw, &m))
+	assert.Equal(t, m.RunID, "run-1")
+	assert.Equal(t, m.ElapsedSeconds, float64(3))
+	assert.DeepEqual(t, m.Setup, opts.setup)
+	assert.Equal(t, len(m.Reports), 0)
+}
+
+func patchWaitPollInterval(interval time.Duration) func() {
+	orig := waitPollInterval
+	waitPollInterval = interval
+	return func() {
+		waitPollInterval = orig
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type packagePriority int
+
+const (
+	priorityCritical packagePriority = iota
+	priorityNormal
+	priorityExperimental
+)
+
+var priorityNames = map[string]packagePriority{
+	"critical":     priorityCritical,
+	"normal":       priorityNormal,
+	"experimental": priorityExperimental,
+}
+
+type priorityRule struct {
+	pattern  string
+	match    func(pkg string) bool
+	priority packagePriority
+	name     string
+}
+
+// priorityValue is a flag.Value which maps package patterns to a priority.
+type priorityValue struct {
+	rules []priorityRule
+}
+
+func (v *priorityValue) Set(val string) error {
+	items, err := readAsCSV(val)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return errors.Errorf("value must be PATTERN=PRIORITY, not %s", item)
+		}
+		priority, ok := priorityNames[parts[1]]
+		if !ok {
+			return errors.Errorf("priority must be one of critical, normal, experimental")
+		}
+		v.rules = append(v.rules, priorityRule{
+			pattern:  parts[0],
+			match:    matchPackagePattern(parts[0]),
+			priority: priority,
+			name:     parts[1],
+		})
+	}
+	return nil
+}
+
+func (v *priorityValue) Type() string {
+	return "pattern=priority"
+}
+
+func (v *priorityValue) String() string {
+	items := make([]string, 0, len(v.rules))
+	for _, rule := range v.rules {
+		items = append(items, rule.pattern+"="+rule.name)
+	}
+	return strings.Join(items, ",")
+}
+
+// priority returns the priority of the last rule which matches the package,
+// or priorityNormal if no rules match.
+func (v *priorityValue) priority(pkg string) packagePriority {
+	priority := priorityNormal
+	for _, rule := range v.rules {
+		if rule.match(pkg) {
+			priority = rule.priority
+		}
+	}
+	return priority
+}
+
+// name returns the name of the priority of the package, or an empty string
+// if there are no rules.
+func (v *priorityValue) name(pkg string) string {
+	if len(v.rules) == 0 {
+		return ""
+	}
+	priority := v.priority(pkg)
+	for name, value := range priorityNames {
+		if value == priority {
+			return name
+		}
+	}
+	return ""
+}
+
+func (v *priorityValue) rank(pkg string) int {
+	return int(v.priority(pkg))
+}
+
+// matchPackagePattern returns a function which matches a package path against
+// a go package pattern. A '...' in the pattern matches any string. The pattern
+// is matched against the full package path, and the path relative to the
+// module, so both example.com/project/pkg/... and ./pkg/... are supported.
+func matchPackagePattern(pattern string) func(pkg string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.Replace(expr, `\.\.\.`, `.*`, -1)
+	// a trailing /... also matches the parent package
+	if strings.HasSuffix(expr, `/.*`) {
+		expr = strings.TrimSuffix(expr, `/.*`) + `(/.*)?`
+	}
+	re := regexp.MustCompile("^" + expr + "$")
+	return func(pkg string) bool {
+		return re.MatchString(pkg) || re.MatchString(testjson.RelativePackagePath(pkg))
+	}
+}
+
+// onlyExperimentalFailures returns true if every failed test, and every
+// package which failed, has experimental priority, and there were no errors.
+func onlyExperimentalFailures(exec *testjson.Execution, priorities *priorityValue) bool {
+	if len(exec.Errors()) > 0 {
+		return false
+	}
+	for _, tc := range exec.Failed() {
+		if priorities.priority(tc.Package) != priorityExperimental {
+			return false
+		}
+	}
+	return true
+}
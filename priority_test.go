@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestPriorityValue(t *testing.T) {
+	value := &priorityValue{}
+	assert.NilError(t, value.Set("example.com/project/core/...=critical"))
+	assert.NilError(t, value.Set("./labs/...=experimental,./labs/stable=normal"))
+	assert.Equal(t, value.String(),
+		"example.com/project/core/...=critical,./labs/...=experimental,./labs/stable=normal")
+
+	assert.Equal(t, value.priority("example.com/project/core"), priorityCritical)
+	assert.Equal(t, value.priority("example.com/project/core/db"), priorityCritical)
+	assert.Equal(t, value.priority("example.com/project/corey"), priorityNormal)
+	assert.Equal(t, value.priority("labs/new"), priorityExperimental)
+	assert.Equal(t, value.priority("labs/stable"), priorityNormal)
+
+	assert.ErrorContains(t, value.Set("./foo"), "must be PATTERN=PRIORITY")
+	assert.ErrorContains(t, value.Set("./foo=urgent"), "priority must be one of")
+}
+
+func TestOnlyExperimentalFailures(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"labs/new","Test":"TestA"}
+{"Action":"fail","Package":"labs/new","Test":"TestA"}
+{"Action":"fail","Package":"labs/new"}
+{"Action":"run","Package":"core","Test":"TestB"}
+{"Action":"pass","Package":"core","Test":"TestB"}
+{"Action":"pass","Package":"core"}
+`))
+	assert.NilError(t, err)
+
+	value := &priorityValue{}
+	assert.Assert(t, !onlyExperimentalFailures(exec, value))
+	assert.NilError(t, value.Set("labs/...=experimental"))
+	assert.Assert(t, onlyExperimentalFailures(exec, value))
+}
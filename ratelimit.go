@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// lineRateLimiter is an io.Writer which writes at most limit lines each
+// second. Lines written after the limit is reached are dropped. The number of
+// dropped lines is written when the next second starts, or when the writer is
+// flushed.
+type lineRateLimiter struct {
+	out     io.Writer
+	limit   int
+	clock   clockwork.Clock
+	start   time.Time
+	lines   int
+	dropped int
+	// midLine is true when the last byte written was not a newline.
+	midLine bool
+}
+
+func newLineRateLimiter(out io.Writer, limit int, clock clockwork.Clock) *lineRateLimiter {
+	return &lineRateLimiter{out: out, limit: limit, clock: clock}
+}
+
+func (w *lineRateLimiter) Write(p []byte) (int, error) {
+	if now := w.clock.Now(); now.Sub(w.start) >= time.Second {
+		if err := w.Flush(); err != nil {
+			return 0, err
+		}
+		w.start = now
+		w.lines = 0
+	}
+
+	count := bytes.Count(p, []byte("\n"))
+	if w.lines+count <= w.limit {
+		w.lines += count
+		return len(p), w.write(p)
+	}
+
+	allowed := w.limit - w.lines
+	end := 0
+	for i := 0; i < allowed; i++ {
+		end += bytes.IndexByte(p[end:], '\n') + 1
+	}
+	w.lines = w.limit
+	w.dropped += count - allowed
+	return len(p), w.write(p[:end])
+}
+
+func (w *lineRateLimiter) write(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	w.midLine = p[len(p)-1] != '\n'
+	_, err := w.out.Write(p)
+	return err
+}
+
+// Flush writes the number of lines which were dropped, if any.
+func (w *lineRateLimiter) Flush() error {
+	if w.dropped == 0 {
+		return nil
+	}
+	prefix := ""
+	if w.midLine {
+		prefix = "\n"
+	}
+	_, err := fmt.Fprintf(w.out, "%s... %d lines not shown, more than %d lines per second\n",
+		prefix, w.dropped, w.limit)
+	w.dropped, w.midLine = 0, false
+	return err
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"gotest.tools/assert"
+)
+
+func TestLineRateLimiter(t *testing.T) {
+	out := new(bytes.Buffer)
+	clock := clockwork.NewFakeClock()
+	w := newLineRateLimiter(out, 3, clock)
+
+	write := func(s string) {
+		n, err := w.Write([]byte(s))
+		assert.NilError(t, err)
+		assert.Equal(t, n, len(s))
+	}
+	write("one\n")
+	write("two\nthree\nfour\n")
+	write("five\n")
+	write("..")
+	clock.Advance(time.Second)
+	write("six\n")
+	write("seven\neight\nnine\n")
+	assert.NilError(t, w.Flush())
+
+	expected := `one
+two
+three
+..
+... 2 lines not shown, more than 3 lines per second
+six
+seven
+eight
+... 1 lines not shown, more than 3 lines per second
+`
+	assert.Equal(t, out.String(), expected)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// rerunIsolation is how a rerun of --rerun-fails runs the failed tests.
+type rerunIsolation string
+
+const (
+	// rerunPackage reruns the failed tests of each package with one go test
+	// command.
+	rerunPackage rerunIsolation = "package"
+	// rerunTest reruns each failed test with its own go test command, one at
+	// a time, so the test does not run in parallel with other tests.
+	rerunTest rerunIsolation = "test"
+	// rerunIsolated reruns each failed test like rerunTest, with -parallel=1,
+	// so the subtests of the test also run one at a time.
+	rerunIsolated rerunIsolation = "isolated"
+)
+
+func validateRerunOptions(opts *options) error {
+	switch {
+	case opts.rerunFails < 0:
+		return errors.New("--rerun-fails must not be negative")
+	case opts.rerunFails > 0 && (opts.rawCommand || opts.stdin):
+		return errors.New("--rerun-fails can not be used with --raw-command or --stdin")
+	case opts.perTestTimeout > 0 && opts.rerunFails == 0:
+		return errors.New("--per-test-timeout requires --rerun-fails")
+	case opts.rerunDelay < 0:
+		return errors.New("--rerun-fails-delay must not be negative")
+	case opts.rerunDelay > 0 && opts.rerunFails == 0:
+		return errors.New("--rerun-fails-delay requires --rerun-fails")
+	case len(opts.rerunIsolation.levels) > 0 && opts.rerunFails == 0:
+		return errors.New("--rerun-fails-isolation requires --rerun-fails")
+	case opts.infraRetries < 0:
+		return errors.New("--infra-retries must not be negative")
+	case len(opts.infraErrors.patterns) > 0 && (opts.rawCommand || opts.stdin):
+		return errors.New("--infra-error can not be used with --raw-command or --stdin")
+	}
+	return nil
+}
+
+// rerunFailed reruns the failed tests, up to opts.rerunFails times. The events
+// are added to exec. Returns true if every failed test passed when it was
+// rerun.
+func rerunFailed(ctx context.Context, opts *options, handler testjson.EventHandler, exec *testjson.Execution) (bool, error) {
+	if len(exec.Errors()) > 0 {
+		log.Warn("failed tests were not rerun because of errors")
+		return false, nil
+	}
+	failed, ok := failedRootTests(rerunCandidates(opts, exec))
+	if !ok {
+		log.Warn("failed tests were not rerun because a package failed")
+		return false, nil
+	}
+	for attempt := 1; attempt <= opts.rerunFails && len(failed) > 0; attempt++ {
+		isolation := opts.rerunIsolation.attempt(attempt)
+		log.Debugf("rerun attempt %d of %d, isolation %s", attempt, opts.rerunFails, isolation)
+		if !waitForRerun(ctx, opts.rerunDelay) {
+			return false, nil
+		}
+		next := make(map[string][]string)
+		for _, pkg := range sortedPackages(failed) {
+			for _, tests := range rerunBatches(opts, isolation, failed[pkg]) {
+				stillFailing, err := rerunTests(ctx, opts, isolation, handler, exec, pkg, tests)
+				if err != nil {
+					return false, err
+				}
+				if len(stillFailing) > 0 {
+					next[pkg] = append(next[pkg], stillFailing...)
+				}
+			}
+		}
+		failed = next
+	}
+	return len(failed) == 0, nil
+}
+
+// waitForRerun waits for the --rerun-fails-delay. Returns false if ctx is done
+// first.
+func waitForRerun(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// rerunCandidates returns the failed tests, and the tests which did not
+// finish, except for the tests of packages which passed when they were
+// retried because of an infrastructure error, or which still failed with an
+// infrastructure error.
+func rerunCandidates(opts *options, exec *testjson.Execution) []testjson.TestCase {
+	var candidates []testjson.TestCase
+	for _, tc := range append(exec.Failed(), exec.NotRun()...) {
+		if _, ok := opts.infraErrorPackages[tc.Package]; ok {
+			continue
+		}
+		if len(opts.infraErrors.patterns) > 0 && exec.Package(tc.Package).Result() == testjson.ActionPass {
+			continue
+		}
+		candidates = append(candidates, tc)
+	}
+	return candidates
+}
+
+// failedRootTests returns the names of the top level tests of the failed
+// tests, by package. A test which did not finish, because the package timed
+// out, is rerun as a failed test. Returns false if a package failed without a
+// failed test, because a package level failure, such as a panic in TestMain,
+// can not be rerun.
+func failedRootTests(failed []testjson.TestCase) (map[string][]string, bool) {
+	tests := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, tc := range failed {
+		if tc.Test == "" {
+			if hasTestInPackage(failed, tc.Package) {
+				continue
+			}
+			return nil, false
+		}
+		name := rootTestName(tc.Test)
+		if seen[tc.Package+"#"+name] {
+			continue
+		}
+		seen[tc.Package+"#"+name] = true
+		tests[tc.Package] = append(tests[tc.Package], name)
+	}
+	return tests, true
+}
+
+func hasTestInPackage(tests []testjson.TestCase, pkg string) bool {
+	for _, tc := range tests {
+		if tc.Package == pkg && tc.Test != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedPackages(tests map[string][]string) []string {
+	pkgs := make([]string, 0, len(tests))
+	for pkg := range tests {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// rerunBatches groups the tests which are run by a single go test command.
+// With --per-test-timeout each test is run by its own command, so that the
+// timeout applies to each test, and with an isolation of test or isolated each
+// test is run by its own command, so that it does not run in parallel with
+// other tests.
+func rerunBatches(opts *options, isolation rerunIsolation, tests []string) [][]string {
+	if opts.perTestTimeout == 0 && isolation == rerunPackage {
+		return [][]string{tests}
+	}
+	batches := make([][]string, 0, len(tests))
+	for _, test := range tests {
+		batches = append(batches, []string{test})
+	}
+	return batches
+}
+
+// rerunTests runs the tests in pkg, and returns the names of the tests which
+// did not pass. With --rerun-fails-isolation the isolation is recorded for each
+// test which passed.
+func rerunTests(
+	ctx context.Context,
+	opts *options,
+	isolation rerunIsolation,
+	handler testjson.EventHandler,
+	exec *testjson.Execution,
+	pkg string,
+	tests []string,
+) ([]string, error) {
+	before := len(exec.Package(pkg).Passed)
+	p, err := startGoTest(ctx, rerunCmdArgs(opts, isolation, tests, pkg))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to rerun tests in %s", pkg)
+	}
+	defer p.cancel()
+	if _, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    p.stdout,
+		Stderr:    p.stderr,
+		Handler:   handler,
+		Execution: exec,
+		Rewrite:   opts.rewriteRules.rules,
+	}); err != nil {
+		return nil, err
+	}
+	// the exit code is ignored, the result of each test is used instead.
+	p.cmd.Wait() // nolint: errcheck
+
+	passed := make(map[string]bool)
+	for _, tc := range exec.Package(pkg).Passed[before:] {
+		passed[tc.Test] = true
+	}
+	var failed []string
+	for _, test := range tests {
+		switch {
+		case !passed[test]:
+			failed = append(failed, test)
+		case len(opts.rerunIsolation.levels) > 0:
+			if opts.rerunPassedIsolation == nil {
+				opts.rerunPassedIsolation = make(map[string]string)
+			}
+			opts.rerunPassedIsolation[testjson.TestCase{Package: pkg, Test: test}.ID()] = string(isolation)
+		}
+	}
+	return failed, nil
+}
+
+// rerunCmdArgs returns the go test command used to rerun tests in pkgs. The
+// flags from the go test args are used, except for the flags which select the
+// tests and the number of runs, and any arguments after -args are passed to
+// the test binary.
+func rerunCmdArgs(opts *options, isolation rerunIsolation, tests []string, pkgs ...string) []string {
+	args := []string{"go", "test", "-json", "-count=1", "-run", runPattern(tests)}
+	exclude := []string{"json", "run", "count"}
+	if opts.perTestTimeout > 0 {
+		args = append(args, fmt.Sprintf("-timeout=%s", opts.perTestTimeout))
+		exclude = append(exclude, "timeout")
+	}
+	if isolation == rerunIsolated {
+		args = append(args, "-parallel=1")
+		exclude = append(exclude, "parallel")
+	}
+	goTestArgs := parseGoTestArgs(opts.args)
+	args = append(args, goTestArgs.flagArgs(exclude...)...)
+	args = append(args, pkgs...)
+	return append(args, goTestArgs.testArgs...)
+}
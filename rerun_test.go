@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestFailedRootTests(t *testing.T) {
+	failed := []testjson.TestCase{
+		{Package: "pkg/a", Test: "TestOne/sub"},
+		{Package: "pkg/a", Test: "TestOne"},
+		{Package: "pkg/a", Test: "TestTwo"},
+		{Package: "pkg/b", Test: "TestThree"},
+	}
+	tests, ok := failedRootTests(failed)
+	assert.Assert(t, ok)
+	expected := map[string][]string{
+		"pkg/a": {"TestOne", "TestTwo"},
+		"pkg/b": {"TestThree"},
+	}
+	assert.DeepEqual(t, tests, expected)
+
+	_, ok = failedRootTests(append(failed, testjson.TestCase{Package: "pkg/c"}))
+	assert.Assert(t, !ok)
+}
+
+func TestRerunCmdArgs(t *testing.T) {
+	opts := &options{
+		args: []string{"-tags=integration", "-run=TestOne", "-timeout=1h", "./pkg/..."},
+	}
+	tests := []string{"TestOne", "TestTwo"}
+	assert.DeepEqual(t, rerunBatches(opts, rerunPackage, tests), [][]string{tests})
+	assert.DeepEqual(t, rerunCmdArgs(opts, rerunPackage, tests, "pkg/a"), []string{
+		"go", "test", "-json", "-count=1", "-run", "^(TestOne|TestTwo)$",
+		"-tags=integration", "-timeout=1h", "pkg/a",
+	})
+
+	opts.perTestTimeout = 30 * time.Second
+	assert.DeepEqual(t, rerunBatches(opts, rerunPackage, tests), [][]string{{"TestOne"}, {"TestTwo"}})
+	assert.DeepEqual(t, rerunCmdArgs(opts, rerunPackage, tests[:1], "pkg/a"), []string{
+		"go", "test", "-json", "-count=1", "-run", "^(TestOne)$",
+		"-timeout=30s", "-tags=integration", "pkg/a",
+	})
+}
+
+func TestRerunCmdArgs_Isolation(t *testing.T) {
+	opts := &options{args: []string{"-parallel=8", "-race", "./..."}}
+	tests := []string{"TestOne", "TestTwo"}
+	assert.DeepEqual(t, rerunBatches(opts, rerunTest, tests), [][]string{{"TestOne"}, {"TestTwo"}})
+	assert.DeepEqual(t, rerunCmdArgs(opts, rerunTest, tests[:1], "pkg/a"), []string{
+		"go", "test", "-json", "-count=1", "-run", "^(TestOne)$",
+		"-parallel=8", "-race", "pkg/a",
+	})
+	assert.DeepEqual(t, rerunCmdArgs(opts, rerunIsolated, tests[:1], "pkg/a"), []string{
+		"go", "test", "-json", "-count=1", "-run", "^(TestOne)$",
+		"-parallel=1", "-race", "pkg/a",
+	})
+}
+
+func TestRerunCmdArgs_FlagValues(t *testing.T) {
+	opts := &options{
+		args: []string{
+			"-tags", "integration", "-run", "TestOne", "-timeout", "5m", "-v",
+			"--count", "3", "./pkg/...", "-args", "-update", "./testdata",
+		},
+	}
+	assert.DeepEqual(t, rerunCmdArgs(opts, rerunPackage, []string{"TestOne"}, "pkg/a", "pkg/b"), []string{
+		"go", "test", "-json", "-count=1", "-run", "^(TestOne)$",
+		"-tags", "integration", "-timeout", "5m", "-v", "pkg/a", "pkg/b",
+		"-args", "-update", "./testdata",
+	})
+
+	opts.perTestTimeout = time.Minute
+	assert.DeepEqual(t, rerunCmdArgs(opts, rerunIsolated, []string{"TestOne"}, "pkg/a"), []string{
+		"go", "test", "-json", "-count=1", "-run", "^(TestOne)$", "-timeout=1m0s", "-parallel=1",
+		"-tags", "integration", "-v", "pkg/a", "-args", "-update", "./testdata",
+	})
+}
+
+func TestRerunIsolationValue(t *testing.T) {
+	var value rerunIsolationValue
+	assert.Equal(t, value.attempt(1), rerunPackage)
+
+	assert.NilError(t, value.Set("package,isolated"))
+	assert.Equal(t, value.attempt(1), rerunPackage)
+	assert.Equal(t, value.attempt(2), rerunIsolated)
+	assert.Equal(t, value.attempt(3), rerunIsolated)
+	assert.Equal(t, value.String(), "package,isolated")
+
+	assert.ErrorContains(t, value.Set("serial"), `"serial" must be one of: package, test, isolated`)
+}
+
+func TestValidateRerunOptions_Isolation(t *testing.T) {
+	opts := &options{rerunDelay: time.Second}
+	assert.ErrorContains(t, validateRerunOptions(opts), "--rerun-fails-delay requires --rerun-fails")
+
+	opts = &options{rerunIsolation: rerunIsolationValue{levels: []rerunIsolation{rerunTest}}}
+	assert.ErrorContains(t, validateRerunOptions(opts), "--rerun-fails-isolation requires --rerun-fails")
+
+	opts.rerunFails = 2
+	assert.NilError(t, validateRerunOptions(opts))
+}
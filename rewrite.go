@@ -0,0 +1,45 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// rewriteSeparator separates the regexp from the replacement of a
+// --rewrite-output rule. It is not "=" because a regexp often contains "=".
+const rewriteSeparator = "=>"
+
+// rewriteRuleValue is a flag.Value which adds a rule to rewrite the output of
+// tests. Each value is a single REGEXP=>REPLACEMENT, so that the regexp may
+// contain commas.
+type rewriteRuleValue struct {
+	rules testjson.RewriteRules
+}
+
+func (v *rewriteRuleValue) Set(val string) error {
+	parts := strings.SplitN(val, rewriteSeparator, 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return errors.Errorf("value must be REGEXP%sREPLACEMENT, not %s", rewriteSeparator, val)
+	}
+	pattern, err := regexp.Compile(parts[0])
+	if err != nil {
+		return errors.Wrap(err, "invalid pattern for output rewrite rule")
+	}
+	v.rules = append(v.rules, testjson.RewriteRule{Pattern: pattern, Replacement: parts[1]})
+	return nil
+}
+
+func (v *rewriteRuleValue) Type() string {
+	return "regexp=>replacement"
+}
+
+func (v *rewriteRuleValue) String() string {
+	items := make([]string, 0, len(v.rules))
+	for _, rule := range v.rules {
+		items = append(items, rule.Pattern.String()+rewriteSeparator+rule.Replacement)
+	}
+	return strings.Join(items, " ")
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRewriteRuleValue(t *testing.T) {
+	value := &rewriteRuleValue{}
+	assert.NilError(t, value.Set(`container [0-9a-f]{12}=>container ID`))
+	assert.NilError(t, value.Set(`GOFLAGS=\S+=>GOFLAGS=`))
+	assert.Equal(t, len(value.rules), 2)
+	assert.Equal(t, value.rules[1].Pattern.String(), `GOFLAGS=\S+`)
+	assert.Equal(t, value.rules[1].Replacement, "GOFLAGS=")
+	assert.Equal(t, value.rules[0].Pattern.ReplaceAllString("in container 3f2a9c1b0d7e", value.rules[0].Replacement),
+		"in container ID")
+
+	assert.ErrorContains(t, value.Set("no-replacement"), "value must be REGEXP=>REPLACEMENT")
+	assert.ErrorContains(t, value.Set("(=>x"), "invalid pattern for output rewrite rule")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// skipCategoryValue is a flag.Value which adds a category of skipped tests.
+// Each value is a single NAME=REGEXP, so that the regexp may contain commas.
+type skipCategoryValue struct {
+	categories testjson.SkipCategories
+}
+
+func (v *skipCategoryValue) Set(val string) error {
+	parts := strings.SplitN(val, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return errors.Errorf("value must be NAME=REGEXP, not %s", val)
+	}
+	pattern, err := regexp.Compile(parts[1])
+	if err != nil {
+		return errors.Wrapf(err, "invalid pattern for skip category %s", parts[0])
+	}
+	v.categories = append(v.categories, testjson.SkipCategory{Name: parts[0], Pattern: pattern})
+	return nil
+}
+
+func (v *skipCategoryValue) Type() string {
+	return "name=regexp"
+}
+
+func (v *skipCategoryValue) String() string {
+	items := make([]string, 0, len(v.categories))
+	for _, category := range v.categories {
+		items = append(items, category.Name+"="+category.Pattern.String())
+	}
+	return strings.Join(items, " ")
+}
+
+// skipThresholdValue is a flag.Value which maps the name of a skip category
+// to the number of skipped tests which causes a warning.
+type skipThresholdValue struct {
+	thresholds map[string]int
+}
+
+func (v *skipThresholdValue) Set(val string) error {
+	items, err := readAsCSV(val)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return errors.Errorf("value must be NAME=COUNT, not %s", item)
+		}
+		count, err := strconv.Atoi(parts[1])
+		if err != nil || count < 0 {
+			return errors.Errorf("invalid count %s, must be 0 or more", parts[1])
+		}
+		if v.thresholds == nil {
+			v.thresholds = make(map[string]int)
+		}
+		v.thresholds[parts[0]] = count
+	}
+	return nil
+}
+
+func (v *skipThresholdValue) Type() string {
+	return "name=count"
+}
+
+func (v *skipThresholdValue) String() string {
+	items := make([]string, 0, len(v.thresholds))
+	for name, count := range v.thresholds {
+		items = append(items, fmt.Sprintf("%s=%d", name, count))
+	}
+	return strings.Join(items, ",")
+}
+
+func validateSkipCategoryOptions(opts *options) error {
+	for name := range opts.skipThresholds.thresholds {
+		if !hasSkipCategory(opts.skipCategories.categories, name) {
+			return errors.Errorf("--skip-category-threshold for unknown skip category %s", name)
+		}
+	}
+	return nil
+}
+
+func hasSkipCategory(categories testjson.SkipCategories, name string) bool {
+	for _, category := range categories {
+		if category.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// skipCategoryWarnings returns a summary warning for each skip category with
+// more skipped tests than its threshold.
+func skipCategoryWarnings(exec *testjson.Execution, opts *options) []string {
+	if len(opts.skipThresholds.thresholds) == 0 {
+		return nil
+	}
+	var warnings []string
+	for _, count := range opts.skipCategories.categories.Count(exec) {
+		threshold, ok := opts.skipThresholds.thresholds[count.Name]
+		if !ok || count.Count <= threshold {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf(
+			"%d tests were skipped with category %s, more than the threshold of %d",
+			count.Count, count.Name, threshold))
+	}
+	return warnings
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestSkipCategoryValue(t *testing.T) {
+	value := &skipCategoryValue{}
+	assert.NilError(t, value.Set(`missing-credential=(KEY|TOKEN),? is not set`))
+	assert.NilError(t, value.Set(`quarantined=quarantine`))
+	assert.Equal(t, len(value.categories), 2)
+	assert.Equal(t, value.categories[0].Name, "missing-credential")
+	assert.Equal(t, value.categories[0].Pattern.String(), "(KEY|TOKEN),? is not set")
+
+	assert.ErrorContains(t, value.Set("quarantined"), "value must be NAME=REGEXP")
+	assert.ErrorContains(t, value.Set("bad=("), "invalid pattern for skip category bad")
+}
+
+func TestSkipThresholdValue(t *testing.T) {
+	value := &skipThresholdValue{}
+	assert.NilError(t, value.Set("missing-credential=500,quarantined=10"))
+	assert.DeepEqual(t, value.thresholds, map[string]int{"missing-credential": 500, "quarantined": 10})
+
+	assert.ErrorContains(t, value.Set("quarantined"), "value must be NAME=COUNT")
+	assert.ErrorContains(t, value.Set("quarantined=-1"), "invalid count -1")
+}
+
+func TestSkipCategoryWarnings(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestA"}
+{"Action":"output","Package":"pkg","Test":"TestA","Output":"    a_test.go:3: API_KEY is not set\n"}
+{"Action":"skip","Package":"pkg","Test":"TestA"}
+{"Action":"run","Package":"pkg","Test":"TestB"}
+{"Action":"output","Package":"pkg","Test":"TestB","Output":"    b_test.go:3: API_KEY is not set\n"}
+{"Action":"skip","Package":"pkg","Test":"TestB"}
+{"Action":"run","Package":"pkg","Test":"TestC"}
+{"Action":"output","Package":"pkg","Test":"TestC","Output":"    c_test.go:3: quarantined\n"}
+{"Action":"skip","Package":"pkg","Test":"TestC"}
+{"Action":"skip","Package":"pkg"}
+`))
+	assert.NilError(t, err)
+
+	opts := &options{skipCategories: &skipCategoryValue{}, skipThresholds: &skipThresholdValue{}}
+	assert.NilError(t, opts.skipCategories.Set("missing-credential=_KEY is not set"))
+	assert.NilError(t, opts.skipCategories.Set("quarantined=quarantined"))
+	assert.NilError(t, opts.skipThresholds.Set("missing-credential=1,quarantined=1"))
+	assert.NilError(t, validateSkipCategoryOptions(opts))
+
+	warnings := skipCategoryWarnings(exec, opts)
+	assert.DeepEqual(t, warnings, []string{
+		"2 tests were skipped with category missing-credential, more than the threshold of 1",
+	})
+
+	assert.NilError(t, opts.skipThresholds.Set("other=1"))
+	assert.ErrorContains(t, validateSkipCategoryOptions(opts), "unknown skip category other")
+}
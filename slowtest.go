@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// defaultGoTestTimeout is the default value of the go test -timeout flag.
+const defaultGoTestTimeout = 10 * time.Minute
+
+// maxTimeoutWarnings is the number of tests listed by timeoutWarnings.
+const maxTimeoutWarnings = 5
+
+func validateTimeoutWarningOptions(opts *options) error {
+	if opts.timeoutWarning < 0 || opts.timeoutWarning > 100 {
+		return errors.New("--timeout-warning must be a percent from 0 to 100")
+	}
+	return nil
+}
+
+// goTestTimeout returns the value of the -timeout flag from the go test args,
+// which is the deadline returned by t.Deadline. Returns 0 when the timeout is
+// disabled, or not known because the events are read from stdin.
+func goTestTimeout(opts *options) time.Duration {
+	if opts.stdin {
+		return 0
+	}
+	timeout := defaultGoTestTimeout
+	for i, arg := range opts.args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		value := ""
+		switch {
+		case name == "timeout" || name == "test.timeout":
+			if i+1 < len(opts.args) {
+				value = opts.args[i+1]
+			}
+		case strings.HasPrefix(name, "timeout="), strings.HasPrefix(name, "test.timeout="):
+			value = name[strings.Index(name, "=")+1:]
+		default:
+			continue
+		}
+		if d, err := time.ParseDuration(value); err == nil {
+			timeout = d
+		}
+	}
+	return timeout
+}
+
+type slowTest struct {
+	testjson.TestCase
+	ratio float64
+}
+
+// timeoutWarnings returns a warning for the tests with an elapsed time of
+// at least --timeout-warning percent of the go test -timeout. Only the
+// slowest tests are listed.
+func timeoutWarnings(exec *testjson.Execution, opts *options) []string {
+	timeout := goTestTimeout(opts)
+	if opts.timeoutWarning == 0 || timeout == 0 {
+		return nil
+	}
+	var slow []slowTest
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		cases := append(append([]testjson.TestCase{}, pkg.Passed...), pkg.Failed...)
+		for _, tc := range cases {
+			// the elapsed time of a subtest is included in the root test
+			if strings.Contains(tc.Test, "/") {
+				continue
+			}
+			ratio := tc.Elapsed.Seconds() / timeout.Seconds()
+			if ratio*100 >= float64(opts.timeoutWarning) {
+				slow = append(slow, slowTest{TestCase: tc, ratio: ratio})
+			}
+		}
+	}
+	sort.SliceStable(slow, func(i, j int) bool {
+		return slow[i].ratio > slow[j].ratio
+	})
+
+	var warnings []string
+	for i, tc := range slow {
+		if i == maxTimeoutWarnings {
+			warnings = append(warnings, fmt.Sprintf(
+				"%d more tests used at least %d%% of the -timeout", len(slow)-i, opts.timeoutWarning))
+			break
+		}
+		warnings = append(warnings, fmt.Sprintf(
+			"%s took %s, %.0f%% of the -timeout of %s, and is at risk of causing a timeout",
+			tc.ID(), tc.Elapsed.Round(time.Millisecond), tc.ratio*100, timeout))
+	}
+	return warnings
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestGoTestTimeout(t *testing.T) {
+	var testcases = []struct {
+		args     []string
+		stdin    bool
+		expected time.Duration
+	}{
+		{args: nil, expected: 10 * time.Minute},
+		{args: []string{"-race", "./..."}, expected: 10 * time.Minute},
+		{args: []string{"-timeout=2m", "./..."}, expected: 2 * time.Minute},
+		{args: []string{"-timeout", "30s", "./..."}, expected: 30 * time.Second},
+		{args: []string{"--test.timeout=1h"}, expected: time.Hour},
+		{args: []string{"-timeout=0"}, expected: 0},
+		{args: []string{"./...", "--", "-timeout=1s"}, expected: 10 * time.Minute},
+		{stdin: true, expected: 0},
+	}
+	for _, tc := range testcases {
+		opts := &options{args: tc.args, stdin: tc.stdin}
+		assert.Equal(t, goTestTimeout(opts), tc.expected, "args: %v", tc.args)
+	}
+}
+
+func TestTimeoutWarnings(t *testing.T) {
+	var events strings.Builder
+	for i, elapsed := range []string{"30", "50", "5", "70", "45", "40", "35", "60"} {
+		test := "Test" + string(rune('A'+i))
+		events.WriteString(`{"Action":"run","Package":"pkg","Test":"` + test + `"}` + "\n")
+		events.WriteString(`{"Action":"pass","Package":"pkg","Test":"` + test + `","Elapsed":` + elapsed + "}\n")
+	}
+	events.WriteString(`{"Action":"run","Package":"pkg","Test":"TestD/sub"}` + "\n")
+	events.WriteString(`{"Action":"pass","Package":"pkg","Test":"TestD/sub","Elapsed":69}` + "\n")
+	exec, err := testjson.ReadExecution(strings.NewReader(events.String()))
+	assert.NilError(t, err)
+
+	opts := &options{args: []string{"-timeout=100s"}}
+	assert.Assert(t, timeoutWarnings(exec, opts) == nil)
+
+	opts.timeoutWarning = 30
+	expected := []string{
+		"pkg#TestD took 1m10s, 70% of the -timeout of 1m40s, and is at risk of causing a timeout",
+		"pkg#TestH took 1m0s, 60% of the -timeout of 1m40s, and is at risk of causing a timeout",
+		"pkg#TestB took 50s, 50% of the -timeout of 1m40s, and is at risk of causing a timeout",
+		"pkg#TestE took 45s, 45% of the -timeout of 1m40s, and is at risk of causing a timeout",
+		"pkg#TestF took 40s, 40% of the -timeout of 1m40s, and is at risk of causing a timeout",
+		"2 more tests used at least 30% of the -timeout",
+	}
+	assert.DeepEqual(t, timeoutWarnings(exec, opts), expected)
+
+	opts.args = []string{"-timeout=0"}
+	assert.Assert(t, timeoutWarnings(exec, opts) == nil)
+}
+
+func TestValidateTimeoutWarningOptions(t *testing.T) {
+	assert.NilError(t, validateTimeoutWarningOptions(&options{timeoutWarning: 50}))
+	assert.ErrorContains(t, validateTimeoutWarningOptions(&options{timeoutWarning: 150}),
+		"must be a percent from 0 to 100")
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// gitSourceLinks returns the SourceLinks for the --source-url-template, with
+// the commit and root directory of the git repository of the working
+// directory.
+func gitSourceLinks(url string) (*testjson.SourceLinks, error) {
+	commit, err := gitRevParse("HEAD")
+	if err != nil {
+		return nil, errors.Wrap(err, "--source-url-template requires a git repository")
+	}
+	root, err := gitRevParse("--show-toplevel")
+	if err != nil {
+		return nil, errors.Wrap(err, "--source-url-template requires a git repository")
+	}
+	links, err := testjson.NewSourceLinks(url, commit, root)
+	return links, errors.Wrap(err, "invalid --source-url-template")
+}
+
+func gitRevParse(arg string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", arg)
+	log.Debugf("exec: %s", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to run git rev-parse %s", arg)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
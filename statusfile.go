@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// statusFileInterval is the shortest time between two writes of the
+// --status-file, except for the end of a package and the end of the run.
+const statusFileInterval = 500 * time.Millisecond
+
+// runStatus is the progress of the run written to the --status-file.
+type runStatus struct {
+	RunID        string `json:"run_id"`
+	PackagesDone int    `json:"packages_done"`
+	// PackagesTotal is the number of packages which will run, when it is known
+	// before the run starts.
+	PackagesTotal  int       `json:"packages_total,omitempty"`
+	TestsDone      int       `json:"tests_done"`
+	Failures       int       `json:"failures"`
+	CurrentPackage string    `json:"current_package,omitempty"`
+	Done           bool      `json:"done"`
+	Updated        time.Time `json:"updated"`
+}
+
+// statusFile keeps the --status-file updated with the progress of the run, so
+// that a program which wraps gotestsum can show the progress without reading
+// the output.
+type statusFile struct {
+	path    string
+	runID   string
+	total   int
+	current string
+	exec    *testjson.Execution
+	written time.Time
+	// failed is true after a write failed, so that the error is logged once.
+	failed bool
+	now    func() time.Time
+}
+
+func newStatusFile(opts *options) *statusFile {
+	if opts.statusFile == "" {
+		return nil
+	}
+	return &statusFile{
+		path:  opts.statusFile,
+		runID: opts.runID,
+		total: plannedPackageCount(opts),
+		now:   time.Now,
+	}
+}
+
+// plannedPackageCount returns the number of packages which will be tested.
+// The packages are listed with go list, unless they were already listed to
+// schedule them. Returns 0 when the number is not known, because the events
+// are read from stdin or from a --raw-command, or the packages can not be
+// listed.
+func plannedPackageCount(opts *options) int {
+	if len(opts.packages) > 0 {
+		return len(opts.packages)
+	}
+	if len(opts.lastFailed) > 0 {
+		return len(opts.lastFailed)
+	}
+	args := goListArgs(opts)
+	if len(args) == 0 {
+		return 0
+	}
+	cmd := exec.Command("go", args...)
+	log.Debugf("exec: %s", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		log.WithError(err).Debug("failed to list the packages for the status file")
+		return 0
+	}
+	return len(strings.Fields(string(out)))
+}
+
+// goListBuildFlags are the build flags of go test which change the packages
+// which match a pattern.
+var goListBuildFlags = map[string]bool{"C": true, "mod": true, "modfile": true, "overlay": true, "tags": true}
+
+// goListArgs returns the go list args which list the same packages as the go
+// test command, or nil if the packages are not from go test args.
+func goListArgs(opts *options) []string {
+	if opts.rawCommand || opts.stdin {
+		return nil
+	}
+	listArgs := []string{"list", "-e"}
+	if len(opts.args) == 0 {
+		return append(listArgs, pathFromEnv("./..."))
+	}
+	args := parseGoTestArgs(opts.args)
+	for _, flag := range args.flags {
+		if goListBuildFlags[flag.name] {
+			listArgs = append(listArgs, flag.args...)
+		}
+	}
+	pkgs := args.packages
+	if testPath := pathFromEnv(""); testPath != "" {
+		pkgs = append(pkgs, testPath)
+	}
+	return append(listArgs, pkgs...)
+}
+
+// Event updates the status with event, and writes the file when the package
+// ended, or when the file was not written recently.
+func (s *statusFile) Event(event testjson.TestEvent, exec *testjson.Execution) {
+	s.exec = exec
+	if event.Package != "" {
+		s.current = event.Package
+	}
+	packageEnded := event.PackageEvent() &&
+		(event.Action == testjson.ActionPass || event.Action == testjson.ActionFail || event.Action == testjson.ActionSkip)
+	if !packageEnded && s.now().Sub(s.written) < statusFileInterval {
+		return
+	}
+	s.write(false)
+}
+
+// Close writes the final status of the run.
+func (s *statusFile) Close() {
+	s.current = ""
+	s.write(true)
+}
+
+func (s *statusFile) write(done bool) {
+	status := runStatus{
+		RunID:          s.runID,
+		PackagesTotal:  s.total,
+		CurrentPackage: s.current,
+		Done:           done,
+		Updated:        s.now().UTC(),
+	}
+	if s.exec != nil {
+		for _, name := range s.exec.Packages() {
+			pkg := s.exec.Package(name)
+			if pkg.Result() != "" {
+				status.PackagesDone++
+			}
+			status.TestsDone += len(pkg.Passed) + len(pkg.Failed) + len(pkg.Skipped)
+		}
+		status.Failures = len(s.exec.Failed())
+	}
+	s.written = s.now()
+	if err := writeStatusFile(s.path, status); err != nil && !s.failed {
+		s.failed = true
+		log.WithError(err).Warn("failed to write status file")
+	}
+}
+
+// writeStatusFile writes status to a temporary file in the same directory, and
+// renames it to filename, so that a program which reads the file never reads
+// a partial write.
+func writeStatusFile(filename string, status runStatus) error {
+	content, err := json.Marshal(status)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode status")
+	}
+	f, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // nolint: errcheck
+	_, err = f.Write(append(content, '\n'))
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if err != nil {
+		f.Close() // nolint: errcheck
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
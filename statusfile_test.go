@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	"gotest.tools/env"
+	"gotest.tools/fs"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type statusHandler struct {
+	status *statusFile
+}
+
+func (h *statusHandler) Event(event testjson.TestEvent, exec *testjson.Execution) error {
+	h.status.Event(event, exec)
+	return nil
+}
+
+func (h *statusHandler) Err(string) error {
+	return nil
+}
+
+func TestStatusFile(t *testing.T) {
+	dir := fs.NewDir(t, "status-file")
+	defer dir.Remove()
+
+	now := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	opts := &options{statusFile: dir.Join("status.json"), runID: "run-1", packages: []string{"example.com/a", "example.com/b"}}
+	status := newStatusFile(opts)
+	status.now = func() time.Time { return now }
+
+	exec := testjson.NewExecution()
+	read := func() runStatus {
+		raw, err := ioutil.ReadFile(opts.statusFile)
+		assert.NilError(t, err)
+		var actual runStatus
+		assert.NilError(t, json.Unmarshal(raw, &actual))
+		return actual
+	}
+	scan := func(events string) {
+		_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+			Stdout:    strings.NewReader(events),
+			Stderr:    strings.NewReader(""),
+			Handler:   &statusHandler{status: status},
+			Execution: exec,
+		})
+		assert.NilError(t, err)
+	}
+
+	scan(`{"Action":"run","Package":"example.com/a","Test":"TestOne"}
+{"Action":"fail","Package":"example.com/a","Test":"TestOne"}
+{"Action":"fail","Package":"example.com/a"}
+`)
+	assert.DeepEqual(t, read(), runStatus{
+		RunID:          "run-1",
+		PackagesDone:   1,
+		PackagesTotal:  2,
+		TestsDone:      1,
+		Failures:       1,
+		CurrentPackage: "example.com/a",
+		Updated:        now,
+	})
+
+	// the file is not written again until the interval has passed
+	scan(`{"Action":"run","Package":"example.com/b","Test":"TestTwo"}
+`)
+	assert.Equal(t, read().CurrentPackage, "example.com/a")
+	now = now.Add(statusFileInterval)
+	scan(`{"Action":"pass","Package":"example.com/b","Test":"TestTwo"}
+`)
+	assert.Equal(t, read().CurrentPackage, "example.com/b")
+	assert.Equal(t, read().TestsDone, 2)
+
+	status.Close()
+	final := read()
+	assert.Assert(t, final.Done)
+	assert.Equal(t, final.CurrentPackage, "")
+}
+
+func TestGoListArgs(t *testing.T) {
+	defer env.Patch(t, "TEST_DIRECTORY", "")()
+
+	var testCases = []struct {
+		name     string
+		opts     *options
+		expected []string
+	}{
+		{
+			name:     "no args",
+			opts:     &options{},
+			expected: []string{"list", "-e", "./..."},
+		},
+		{
+			name: "go test args",
+			opts: &options{args: []string{
+				"-tags", "integration", "-run", "TestA", "-mod=vendor", "./cmd/...", "-count=1", "./internal/...",
+			}},
+			expected: []string{"list", "-e", "-tags", "integration", "-mod=vendor", "./cmd/...", "./internal/..."},
+		},
+		{
+			name:     "current directory",
+			opts:     &options{args: []string{"-v"}},
+			expected: []string{"list", "-e"},
+		},
+		{
+			name: "raw command",
+			opts: &options{rawCommand: true, args: []string{"./test.sh"}},
+		},
+		{
+			name: "stdin",
+			opts: &options{stdin: true},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.DeepEqual(t, goListArgs(tc.opts), tc.expected)
+		})
+	}
+}
+
+func TestPlannedPackageCount(t *testing.T) {
+	opts := &options{packages: []string{"example.com/a", "example.com/b"}}
+	assert.Equal(t, plannedPackageCount(opts), 2)
+
+	opts = &options{lastFailed: map[string][]string{"example.com/a": {"TestA", "TestB"}}}
+	assert.Equal(t, plannedPackageCount(opts), 1)
+
+	opts = &options{stdin: true}
+	assert.Equal(t, plannedPackageCount(opts), 0)
+}
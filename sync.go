@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// syncedReportFiles returns the paths of the report files, the --manifest-file,
+// and the signature of each of them, which are flushed to disk by
+// --sync-reports.
+func syncedReportFiles(opts *options) []string {
+	files := reportFiles(opts)
+	if opts.manifestFile != "" {
+		files = append(files, opts.manifestFile)
+	}
+	if opts.signer == nil {
+		return files
+	}
+	for _, path := range files {
+		files = append(files, path+".sig")
+	}
+	return files
+}
+
+// syncReports flushes the report files, and the directories which contain
+// them, to disk, so that a report is not truncated or missing when the machine
+// stops after the run, for example when a CI runner is preempted.
+func syncReports(opts *options) error {
+	if !opts.syncReports {
+		return nil
+	}
+	dirs := make(map[string]bool)
+	for _, path := range syncedReportFiles(opts) {
+		if err := syncPath(path); err != nil {
+			return errors.Wrapf(err, "failed to sync %s", path)
+		}
+		dirs[filepath.Dir(path)] = true
+	}
+	// a directory can not be opened for sync on windows, and NTFS writes the
+	// directory entries of a synced file
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	for dir := range dirs {
+		if err := syncPath(dir); err != nil {
+			return errors.Wrapf(err, "failed to sync directory %s", dir)
+		}
+	}
+	return nil
+}
+
+func syncPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close() // nolint: errcheck
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/fs"
+)
+
+func TestSyncReports(t *testing.T) {
+	dir := fs.NewDir(t, "sync-reports",
+		fs.WithFile("report.xml", "<testsuites></testsuites>"),
+		fs.WithFile("report.ndjson", ""),
+		fs.WithFile("manifest.json", "{}"))
+	defer dir.Remove()
+
+	newOpts := func(ndjsonFile string) *options {
+		opts := &options{
+			junitFiles:        newJUnitFileValue(""),
+			junitFailuresOnly: newJUnitFileValue(""),
+			syncReports:       true,
+			ndjsonFile:        dir.Join(ndjsonFile),
+			manifestFile:      dir.Join("manifest.json"),
+		}
+		assert.NilError(t, opts.junitFiles.Set(dir.Join("report.xml")))
+		return opts
+	}
+
+	t.Run("synced", func(t *testing.T) {
+		assert.NilError(t, syncReports(newOpts("report.ndjson")))
+	})
+	t.Run("missing file", func(t *testing.T) {
+		err := syncReports(newOpts("missing.ndjson"))
+		assert.ErrorContains(t, err, "failed to sync "+dir.Join("missing.ndjson"))
+	})
+	t.Run("disabled", func(t *testing.T) {
+		opts := newOpts("missing.ndjson")
+		opts.syncReports = false
+		assert.NilError(t, syncReports(opts))
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// defaultTailTestLinesPerSecond is the default of --tail-test-lines-per-second.
+const defaultTailTestLinesPerSecond = 20
+
+// testTail prints the output of the tests which match --tail-test while they
+// run, with any --format. Each line is prefixed with the name of the test.
+type testTail struct {
+	pattern *regexp.Regexp
+	out     *lineRateLimiter
+}
+
+func newTestTail(opts *options, out io.Writer, clock clockwork.Clock) (*testTail, error) {
+	if opts.tailTest == "" {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(opts.tailTest)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid --tail-test")
+	}
+	limit := opts.tailTestLinesPerSecond
+	if limit <= 0 {
+		limit = defaultTailTestLinesPerSecond
+	}
+	return &testTail{pattern: pattern, out: newLineRateLimiter(out, limit, clock)}, nil
+}
+
+// Event prints the output of the event, if it is the output of a matching
+// test. formatted is the text printed for the event by the --format. The
+// output is not printed again when the --format printed it for the event,
+// which is when formatted ends with the output, after any prefix like a
+// timestamp.
+func (t *testTail) Event(event testjson.TestEvent, formatted string) error {
+	if event.Action != testjson.ActionOutput || event.Test == "" {
+		return nil
+	}
+	if !t.pattern.MatchString(event.Test) || printedByFormat(event.Output, formatted) {
+		return nil
+	}
+	text := event.Test + ": " + strings.TrimSuffix(event.Output, "\n") + "\n"
+	_, err := t.out.Write([]byte(text))
+	return errors.Wrap(err, "failed to write --tail-test output")
+}
+
+func printedByFormat(output, formatted string) bool {
+	return output != "" && strings.HasSuffix(formatted, output)
+}
+
+// Flush writes the number of lines dropped by --tail-test-lines-per-second.
+func (t *testTail) Flush() error {
+	return t.out.Flush()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestTestTail(t *testing.T) {
+	out := new(bytes.Buffer)
+	clock := clockwork.NewFakeClock()
+	tail, err := newTestTail(&options{tailTest: "^TestIntegration$", tailTestLinesPerSecond: 2}, out, clock)
+	assert.NilError(t, err)
+
+	event := func(test, output string) testjson.TestEvent {
+		return testjson.TestEvent{Action: testjson.ActionOutput, Package: "pkg", Test: test, Output: output}
+	}
+	assert.NilError(t, tail.Event(event("TestIntegration", "=== RUN   TestIntegration\n"), ""))
+	assert.NilError(t, tail.Event(event("TestOther", "other\n"), ""))
+	assert.NilError(t, tail.Event(event("TestIntegration", "    db_test.go:9: connected\n"), "."))
+	// already printed by the --format
+	assert.NilError(t, tail.Event(event("TestIntegration", "verbose\n"), "verbose\n"))
+	assert.NilError(t, tail.Event(event("TestIntegration", "stamped\n"), "10:00:00 stamped\n"))
+	assert.NilError(t, tail.Event(event("TestIntegration", "dropped\n"), ""))
+	clock.Advance(time.Second)
+	// the output is part of the text printed by the --format, but is not
+	// what the --format printed for the event
+	assert.NilError(t, tail.Event(event("TestIntegration", "ok\n"), "ok\nFAIL pkg.TestOther\n"))
+	assert.NilError(t, tail.Flush())
+
+	expected := `TestIntegration: === RUN   TestIntegration
+TestIntegration:     db_test.go:9: connected
+... 1 lines not shown, more than 2 lines per second
+TestIntegration: ok
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestNewTestTail(t *testing.T) {
+	tail, err := newTestTail(&options{}, new(bytes.Buffer), clockwork.NewFakeClock())
+	assert.NilError(t, err)
+	assert.Assert(t, tail == nil)
+
+	_, err = newTestTail(&options{tailTest: "Test("}, new(bytes.Buffer), clockwork.NewFakeClock())
+	assert.ErrorContains(t, err, "invalid --tail-test")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/internal/manifest"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// exitCodeInterrupted is the exit code used when the run was stopped by a
+// signal, and the --teardown-command was run.
+const exitCodeInterrupted = 130
+
+// Results of a run, passed to the --teardown-command in GOTESTSUM_RESULT.
+const (
+	resultPass        = "pass"
+	resultFail        = "fail"
+	resultInterrupted = "interrupted"
+	resultError       = "error"
+)
+
+// teardown runs the --teardown-command once, after the tests, or when the run
+// stops early because of an error or a signal.
+type teardown struct {
+	command string
+	runID   string
+	signals *signalCancel
+	// step is set once the command has run.
+	step *manifest.Step
+}
+
+// run the command, with the result of the run in the environment. exec may be
+// nil when the run stopped before the tests ran.
+func (t *teardown) run(result string, exec *testjson.Execution) {
+	if t == nil || t.step != nil {
+		return
+	}
+	t.signals.stop()
+	if t.signals.interrupted() {
+		result = resultInterrupted
+	}
+	env := append(os.Environ(), teardownEnv(t.runID, result, exec)...)
+	step := runCommand(context.Background(), t.command, env)
+	if step.Error != "" {
+		log.Warnf("--teardown-command failed: %s", step.Error)
+	}
+	t.step = &step
+}
+
+func teardownEnv(runID, result string, exec *testjson.Execution) []string {
+	env := []string{
+		"GOTESTSUM_RESULT=" + result,
+		"GOTESTSUM_RESULT_RUN_ID=" + runID,
+	}
+	if exec == nil {
+		return env
+	}
+	return append(env,
+		"GOTESTSUM_RESULT_TOTAL="+strconv.Itoa(exec.Total()),
+		"GOTESTSUM_RESULT_FAILED="+strconv.Itoa(len(exec.Failed())),
+		"GOTESTSUM_RESULT_SKIPPED="+strconv.Itoa(len(exec.Skipped())),
+		"GOTESTSUM_RESULT_NOT_RUN="+strconv.Itoa(len(exec.NotRun())))
+}
+
+// signalCancel cancels a context when gotestsum receives an interrupt or a
+// terminate signal, so that go test is stopped, and the run ends normally
+// instead of exiting before the --teardown-command runs.
+type signalCancel struct {
+	signals  chan os.Signal
+	done     chan struct{}
+	received int32
+}
+
+func cancelOnSignal(ctx context.Context) (context.Context, *signalCancel) {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &signalCancel{
+		signals: make(chan os.Signal, 1),
+		done:    make(chan struct{}),
+	}
+	signal.Notify(s.signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer cancel()
+		select {
+		case sig := <-s.signals:
+			log.Warnf("received %s, stopping the run", sig)
+			atomic.StoreInt32(&s.received, 1)
+		case <-s.done:
+		}
+	}()
+	return ctx, s
+}
+
+func (s *signalCancel) interrupted() bool {
+	return atomic.LoadInt32(&s.received) == 1
+}
+
+// stop restores the default handling of signals, so that a second signal
+// stops gotestsum.
+func (s *signalCancel) stop() {
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	signal.Stop(s.signals)
+	close(s.done)
+}
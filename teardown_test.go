@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestTeardown(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestA"}
+{"Action":"fail","Package":"pkg","Test":"TestA"}
+{"Action":"run","Package":"pkg","Test":"TestB"}
+{"Action":"pass","Package":"pkg","Test":"TestB"}
+{"Action":"fail","Package":"pkg"}
+`))
+	assert.NilError(t, err)
+
+	td := &teardown{
+		command: `echo "$GOTESTSUM_RESULT $GOTESTSUM_RESULT_RUN_ID $GOTESTSUM_RESULT_TOTAL $GOTESTSUM_RESULT_FAILED"`,
+		runID:   "run-1",
+	}
+	_, td.signals = cancelOnSignal(context.Background())
+	td.run(resultFail, exec)
+	assert.Equal(t, td.step.Output, "fail run-1 2 1\n")
+	assert.Equal(t, td.step.Error, "")
+
+	// the command only runs once
+	step := td.step
+	td.run(resultError, nil)
+	assert.Assert(t, td.step == step)
+}
+
+func TestTeardown_NoExecution(t *testing.T) {
+	assert.DeepEqual(t, teardownEnv("run-1", resultError, nil), []string{
+		"GOTESTSUM_RESULT=error",
+		"GOTESTSUM_RESULT_RUN_ID=run-1",
+	})
+}
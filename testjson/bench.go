@@ -0,0 +1,131 @@
+package testjson
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BenchmarkResult is the result of a benchmark, from the line printed by the
+// testing package when the benchmark ends, for example:
+//
+//	BenchmarkFoo-8   	 1000000	      1234 ns/op	      64 B/op	       2 allocs/op
+type BenchmarkResult struct {
+	// Iterations is the number of times the benchmark function was run.
+	Iterations int
+	// Metrics are the measurements of the benchmark, in the order they were
+	// printed, including ns/op, B/op, allocs/op, and any metric reported with
+	// b.ReportMetric.
+	Metrics []BenchmarkMetric
+}
+
+// BenchmarkMetric is a single measurement of a benchmark.
+type BenchmarkMetric struct {
+	Value float64
+	// Unit of the value, for example ns/op.
+	Unit string
+}
+
+// Metric returns the value of the metric with unit, and false if the
+// benchmark did not report the metric.
+func (r BenchmarkResult) Metric(unit string) (float64, bool) {
+	for _, metric := range r.Metrics {
+		if metric.Unit == unit {
+			return metric.Value, true
+		}
+	}
+	return 0, false
+}
+
+// isBenchmark returns true if test is a benchmark or a sub-benchmark.
+func isBenchmark(test string) bool {
+	return strings.HasPrefix(test, "Benchmark")
+}
+
+// parseBenchmarkLine returns the result of the benchmark test from a line of
+// its output. Returns false if the line is not the result of the benchmark.
+func parseBenchmarkLine(test string, line string) (BenchmarkResult, bool) {
+	if !isBenchmark(test) {
+		return BenchmarkResult{}, false
+	}
+	fields := strings.Split(strings.TrimSpace(line), "\t")
+	if len(fields) < 3 {
+		return BenchmarkResult{}, false
+	}
+	// the name has a suffix with GOMAXPROCS when it is greater than 1
+	name := strings.TrimSpace(fields[0])
+	if name != test && !strings.HasPrefix(name, test+"-") {
+		return BenchmarkResult{}, false
+	}
+	iterations, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+	if err != nil {
+		return BenchmarkResult{}, false
+	}
+	result := BenchmarkResult{Iterations: iterations}
+	for _, field := range fields[2:] {
+		parts := strings.Fields(field)
+		if len(parts) != 2 {
+			return BenchmarkResult{}, false
+		}
+		value, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return BenchmarkResult{}, false
+		}
+		result.Metrics = append(result.Metrics, BenchmarkMetric{Value: value, Unit: parts[1]})
+	}
+	return result, true
+}
+
+// benchmarkLine returns the line of output of the benchmark test which ends
+// with output, and false if the line has not ended yet, or test is not a
+// benchmark. The start of the line is kept until the output which ends it.
+func (p *Package) benchmarkLine(test string, output string) (string, bool) {
+	if !isBenchmark(test) {
+		return "", false
+	}
+	line := p.benchmarkLines[test] + output
+	if !strings.HasSuffix(line, "\n") {
+		if p.benchmarkLines == nil {
+			p.benchmarkLines = make(map[string]string)
+		}
+		p.benchmarkLines[test] = line
+		return "", false
+	}
+	delete(p.benchmarkLines, test)
+	return line, true
+}
+
+// Benchmark returns the result of the benchmark test, and false if the test
+// is not a benchmark, or the benchmark did not print a result.
+func (p *Package) Benchmark(test string) (BenchmarkResult, bool) {
+	result, ok := p.benchmarks[test]
+	return result, ok
+}
+
+// endBenchmarks passes the benchmarks which are still running when the
+// package ends, but have a result from a sub-benchmark. The testing package
+// does not print a result, or emit a pass event, for a benchmark with
+// sub-benchmarks. A benchmark which failed has a fail event.
+func (e *Execution) endBenchmarks(pkgname string, pkg *Package) {
+	var parents []string
+	for test := range pkg.running {
+		if isBenchmark(test) && pkg.hasSubBenchmarkResult(test) {
+			parents = append(parents, test)
+		}
+	}
+	sort.Strings(parents)
+	for _, test := range parents {
+		for pkg.running[test] > 0 {
+			e.passTest(pkg, TestEvent{Package: pkgname, Test: test, Action: ActionPass})
+		}
+	}
+}
+
+func (p *Package) hasSubBenchmarkResult(test string) bool {
+	for name := range p.benchmarks {
+		if strings.HasPrefix(name, test+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseBenchmarkLine(t *testing.T) {
+	result, ok := parseBenchmarkLine("BenchmarkFoo",
+		"BenchmarkFoo-8   \t 1000000\t      1234 ns/op\t      64 B/op\t       2 allocs/op\n")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, result, BenchmarkResult{
+		Iterations: 1000000,
+		Metrics: []BenchmarkMetric{
+			{Value: 1234, Unit: "ns/op"},
+			{Value: 64, Unit: "B/op"},
+			{Value: 2, Unit: "allocs/op"},
+		},
+	})
+	ns, ok := result.Metric("ns/op")
+	assert.Assert(t, ok)
+	assert.Equal(t, ns, 1234.0)
+	_, ok = result.Metric("MB/s")
+	assert.Assert(t, !ok)
+
+	result, ok = parseBenchmarkLine("BenchmarkSub/small", "BenchmarkSub/small   \t     100\t         1.330 ns/op\n")
+	assert.Assert(t, ok)
+	assert.Equal(t, result.Iterations, 100)
+
+	for _, line := range []string{
+		"BenchmarkFoo\n",
+		"    foo_test.go:12: BenchmarkFoo\t1\t2 ns/op\n",
+		"BenchmarkFooBar \t 100\t 2 ns/op\n",
+		"BenchmarkFoo \t many\t 2 ns/op\n",
+	} {
+		_, ok := parseBenchmarkLine("BenchmarkFoo", line)
+		assert.Assert(t, !ok, line)
+	}
+	_, ok = parseBenchmarkLine("TestFoo", "TestFoo \t 100\t 2 ns/op\n")
+	assert.Assert(t, !ok)
+}
+
+func TestScanTestOutput_Benchmarks(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/bench","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/bench","Test":"TestOne"}
+{"Action":"run","Package":"example.com/bench","Test":"BenchmarkSprintf"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkSprintf","Output":"BenchmarkSprintf\n"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkSprintf","Output":"BenchmarkSprintf \t     100\t       107.8 ns/op\t       3 B/op\t       0 allocs/op\n"}
+{"Action":"run","Package":"example.com/bench","Test":"BenchmarkSub"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkSub","Output":"BenchmarkSub\n"}
+{"Action":"run","Package":"example.com/bench","Test":"BenchmarkSub/small"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkSub/small","Output":"BenchmarkSub/small         \t     100\t         1.330 ns/op\n"}
+{"Action":"run","Package":"example.com/bench","Test":"BenchmarkBroken"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkBroken","Output":"--- FAIL: BenchmarkBroken\n"}
+{"Action":"fail","Package":"example.com/bench","Test":"BenchmarkBroken"}
+{"Action":"fail","Package":"example.com/bench"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &fakeHandler{formatter: standardQuietFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(exec.NotRun()), 0)
+
+	pkg := exec.Package("example.com/bench")
+	var passed []string
+	for _, tc := range pkg.Passed {
+		passed = append(passed, tc.Test)
+	}
+	assert.DeepEqual(t, passed, []string{"TestOne", "BenchmarkSprintf", "BenchmarkSub/small", "BenchmarkSub"})
+	assert.Equal(t, len(pkg.Failed), 1)
+	assert.Assert(t, pkg.Crash() == nil)
+
+	result, ok := pkg.Benchmark("BenchmarkSprintf")
+	assert.Assert(t, ok)
+	assert.Equal(t, result.Iterations, 100)
+	_, ok = pkg.Benchmark("BenchmarkSub")
+	assert.Assert(t, !ok)
+}
+
+func TestScanTestOutput_BenchmarkResultSplitAcrossEvents(t *testing.T) {
+	// from go test -json -bench . -benchtime 10ms with go1.27.1
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"start","Package":"example.com/bench"}
+{"Action":"run","Package":"example.com/bench","Test":"BenchmarkFoo"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkFoo","Output":"=== RUN   BenchmarkFoo\n","OutputType":"frame"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkFoo","Output":"BenchmarkFoo\n"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkFoo","Output":"BenchmarkFoo \t25986453\t         0.5062 ns/op\n"}
+{"Action":"run","Package":"example.com/bench","Test":"BenchmarkBar"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkBar","Output":"=== RUN   BenchmarkBar\n","OutputType":"frame"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkBar","Output":"BenchmarkBar\n"}
+{"Action":"run","Package":"example.com/bench","Test":"BenchmarkBar/sub"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkBar/sub","Output":"=== RUN   BenchmarkBar/sub\n","OutputType":"frame"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkBar/sub","Output":"BenchmarkBar/sub\n"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkBar/sub","Output":"BenchmarkBar/sub         \t"}
+{"Action":"output","Package":"example.com/bench","Test":"BenchmarkBar/sub","Output":"29997974\t         0.4376 ns/op\n"}
+{"Action":"output","Package":"example.com/bench","Output":"PASS\n","OutputType":"frame"}
+{"Action":"output","Package":"example.com/bench","Output":"ok  \texample.com/bench\t0.042s\n"}
+{"Action":"pass","Package":"example.com/bench","Elapsed":0.042}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &fakeHandler{formatter: standardQuietFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(exec.NotRun()), 0)
+
+	pkg := exec.Package("example.com/bench")
+	var passed []string
+	for _, tc := range pkg.Passed {
+		passed = append(passed, tc.Test)
+	}
+	assert.DeepEqual(t, passed, []string{"BenchmarkFoo", "BenchmarkBar/sub", "BenchmarkBar"})
+
+	result, ok := pkg.Benchmark("BenchmarkBar/sub")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, result, BenchmarkResult{
+		Iterations: 29997974,
+		Metrics:    []BenchmarkMetric{{Value: 0.4376, Unit: "ns/op"}},
+	})
+}
@@ -0,0 +1,118 @@
+package testjson
+
+import (
+	"strings"
+	"time"
+)
+
+// Builder creates an Execution from the results of tests, without a stream of
+// go test -json events, for example to create the Execution used by a test of
+// a report. The results are recorded in the same way as the events read by
+// ScanTestOutput, so the Execution is the same as one read from the events.
+//
+// The output of passed tests is kept, as if ScanConfig.KeepPassedOutput was
+// set.
+type Builder struct {
+	exec *Execution
+}
+
+// NewBuilder returns a Builder for a new Execution.
+func NewBuilder() *Builder {
+	exec := NewExecution()
+	exec.keepPassedOutput = true
+	return &Builder{exec: exec}
+}
+
+// Package returns a PackageBuilder which adds the results of the tests in the
+// package pkg.
+func (b *Builder) Package(pkg string) *PackageBuilder {
+	return &PackageBuilder{builder: b, name: pkg}
+}
+
+// Execution ends each package which was not ended with PackageBuilder.End,
+// and returns the Execution. A package with a failed test fails, a package
+// without any tests is skipped, and any other package passes. The Builder
+// must not be used after Execution is called.
+func (b *Builder) Execution() *Execution {
+	for _, name := range b.exec.Packages() {
+		pkg := b.exec.packages[name]
+		if pkg.action != "" {
+			continue
+		}
+		action := ActionPass
+		switch {
+		case len(pkg.Failed) > 0:
+			action = ActionFail
+		case pkg.Total == 0:
+			action = ActionSkip
+		}
+		b.exec.add(TestEvent{Package: name, Action: action, Elapsed: pkg.Elapsed().Seconds()})
+	}
+	return b.exec
+}
+
+// PackageBuilder adds the results of the tests in a package to the Execution
+// of a Builder.
+type PackageBuilder struct {
+	builder *Builder
+	name    string
+}
+
+// Pass adds a test which passed, and its output. A newline is added to each
+// line of output which does not end with one.
+func (p *PackageBuilder) Pass(test string, elapsed time.Duration, output ...string) *PackageBuilder {
+	return p.test(test, ActionPass, elapsed, output)
+}
+
+// Fail adds a test which failed, and its output.
+func (p *PackageBuilder) Fail(test string, elapsed time.Duration, output ...string) *PackageBuilder {
+	return p.test(test, ActionFail, elapsed, output)
+}
+
+// Skip adds a test which was skipped, and its output, which usually includes
+// the message passed to t.Skip.
+func (p *PackageBuilder) Skip(test string, elapsed time.Duration, output ...string) *PackageBuilder {
+	return p.test(test, ActionSkip, elapsed, output)
+}
+
+// Run adds a test which started, but never finished, and its output. Unless
+// the package fails, the test is reported by Execution.NotRun.
+func (p *PackageBuilder) Run(test string, output ...string) *PackageBuilder {
+	p.add(TestEvent{Test: test, Action: ActionRun})
+	p.output(test, output)
+	return p
+}
+
+// Output adds output of the package which is not from a test.
+func (p *PackageBuilder) Output(output ...string) *PackageBuilder {
+	p.output("", output)
+	return p
+}
+
+// End ends the package with action, which is one of ActionPass, ActionFail,
+// or ActionSkip.
+func (p *PackageBuilder) End(action Action, elapsed time.Duration) *PackageBuilder {
+	p.add(TestEvent{Action: action, Elapsed: elapsed.Seconds()})
+	return p
+}
+
+func (p *PackageBuilder) test(test string, action Action, elapsed time.Duration, output []string) *PackageBuilder {
+	p.add(TestEvent{Test: test, Action: ActionRun})
+	p.output(test, output)
+	p.add(TestEvent{Test: test, Action: action, Elapsed: elapsed.Seconds()})
+	return p
+}
+
+func (p *PackageBuilder) output(test string, output []string) {
+	for _, line := range output {
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		p.add(TestEvent{Test: test, Action: ActionOutput, Output: line})
+	}
+}
+
+func (p *PackageBuilder) add(event TestEvent) {
+	event.Package = p.name
+	p.builder.exec.add(event)
+}
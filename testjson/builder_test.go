@@ -0,0 +1,78 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/assert"
+	"gotest.tools/assert/opt"
+)
+
+func TestBuilder(t *testing.T) {
+	builder := NewBuilder()
+	builder.Package("example.com/a").
+		Pass("TestOne", 200*time.Millisecond, "=== RUN   TestOne", "    one_test.go:4: ok").
+		Fail("TestTwo", 1500*time.Millisecond, "=== RUN   TestTwo", "    two_test.go:9: broken").
+		Skip("TestThree", 0, "=== RUN   TestThree", "    three_test.go:3: requires docker", "--- SKIP: TestThree (0.00s)")
+	builder.Package("example.com/b").
+		Pass("TestFour", 10*time.Millisecond).
+		Run("TestFive", "=== RUN   TestFive")
+	builder.Package("example.com/empty").
+		Output("?   \texample.com/empty\t[no test files]")
+	exec := builder.Execution()
+
+	scanned, err := ScanTestOutput(ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/a","Test":"TestOne"}
+{"Action":"output","Package":"example.com/a","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestOne","Output":"    one_test.go:4: ok\n"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOne","Elapsed":0.2}
+{"Action":"run","Package":"example.com/a","Test":"TestTwo"}
+{"Action":"output","Package":"example.com/a","Test":"TestTwo","Output":"=== RUN   TestTwo\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestTwo","Output":"    two_test.go:9: broken\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestTwo","Elapsed":1.5}
+{"Action":"run","Package":"example.com/a","Test":"TestThree"}
+{"Action":"output","Package":"example.com/a","Test":"TestThree","Output":"=== RUN   TestThree\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestThree","Output":"    three_test.go:3: requires docker\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestThree","Output":"--- SKIP: TestThree (0.00s)\n"}
+{"Action":"skip","Package":"example.com/a","Test":"TestThree"}
+{"Action":"run","Package":"example.com/b","Test":"TestFour"}
+{"Action":"pass","Package":"example.com/b","Test":"TestFour","Elapsed":0.01}
+{"Action":"run","Package":"example.com/b","Test":"TestFive"}
+{"Action":"output","Package":"example.com/b","Test":"TestFive","Output":"=== RUN   TestFive\n"}
+{"Action":"output","Package":"example.com/empty","Output":"?   \texample.com/empty\t[no test files]\n"}
+{"Action":"fail","Package":"example.com/a","Elapsed":1.7}
+{"Action":"pass","Package":"example.com/b","Elapsed":0.01}
+{"Action":"skip","Package":"example.com/empty"}
+`),
+		Stderr:           strings.NewReader(""),
+		Handler:          &fakeHandler{formatter: standardQuietFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)},
+		KeepPassedOutput: true,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, exec, scanned,
+		gocmp.AllowUnexported(Execution{}, Package{}),
+		gocmp.FilterPath(stringPath("started"), opt.TimeWithThreshold(10*time.Second)),
+		gocmp.FilterPath(stringPath("warningsLock"), gocmp.Ignore()))
+
+	assert.Equal(t, exec.Package("example.com/a").Result(), ActionFail)
+	assert.Equal(t, exec.Package("example.com/empty").Result(), ActionSkip)
+	assert.Equal(t, exec.Output("example.com/a", "TestOne"), "=== RUN   TestOne\n    one_test.go:4: ok\n")
+	assert.DeepEqual(t, exec.NotRun(), []TestCase{{Package: "example.com/b", Test: "TestFive"}})
+}
+
+func TestBuilder_End(t *testing.T) {
+	builder := NewBuilder()
+	builder.Package("example.com/a").
+		Pass("TestOne", 0).
+		Output("panic: init failed").
+		End(ActionFail, time.Second)
+	exec := builder.Execution()
+
+	pkg := exec.Package("example.com/a")
+	assert.Equal(t, pkg.Result(), ActionFail)
+	assert.Equal(t, len(pkg.Passed), 1)
+	assert.Equal(t, exec.Output("example.com/a", ""), "panic: init failed\n")
+}
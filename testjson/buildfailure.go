@@ -0,0 +1,42 @@
+package testjson
+
+// BuildFailure is a build failure of one or more packages. When a package
+// can not be built, every package which depends on it fails to build with the
+// same output.
+type BuildFailure struct {
+	// ImportPath is the build which failed, from the FailedBuild of the first
+	// package.
+	ImportPath string
+	// Output is the output of the build.
+	Output string
+	// Packages are the names of the packages which failed to build with the
+	// same output, sorted by name.
+	Packages []string
+}
+
+// BuildFailures returns the packages which failed to build, grouped by the
+// output of the build which failed. Packages without any build output are
+// grouped by the ImportPath of the build.
+func (e *Execution) BuildFailures() []BuildFailure {
+	var failures []BuildFailure
+	index := make(map[string]int)
+	for _, name := range e.Packages() {
+		pkg := e.packages[name]
+		if !pkg.buildFailed {
+			continue
+		}
+		output := joinOutput(e.buildOutput[pkg.failedBuild])
+		key := "output:" + output
+		if output == "" {
+			key = "build:" + pkg.failedBuild
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(failures)
+			index[key] = i
+			failures = append(failures, BuildFailure{ImportPath: pkg.failedBuild, Output: output})
+		}
+		failures[i].Packages = append(failures[i].Packages, name)
+	}
+	return failures
+}
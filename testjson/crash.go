@@ -0,0 +1,98 @@
+package testjson
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Crash describes a test binary which exited while tests were running, for
+// example because of a fatal error in the runtime, or a signal when
+// GOTRACEBACK=crash.
+type Crash struct {
+	// Tests which were running when the test binary crashed. The tests are
+	// included in Package.Failed, and the output of the crash is included in
+	// the output of each test.
+	Tests []string
+	// CoreDumped is true if go test reported that a core file was written.
+	CoreDumped bool
+	// CoreFile is the path of the core file, when it was found. It is not set
+	// by ScanTestOutput.
+	CoreFile string
+}
+
+var crashLine = regexp.MustCompile(
+	`^(panic: |fatal error: |unexpected fault address|SIG[A-Z]+: |signal: |runtime: )`)
+
+// isCrashOutput returns true if the output includes the start of a crash. A
+// test timeout is not a crash, the tests which were running when the timeout
+// panic happened are reported as not run.
+func isCrashOutput(lines []OutputLine) bool {
+	crashed := false
+	for _, line := range lines {
+		if strings.HasPrefix(line.Text, "panic: test timed out after") {
+			return false
+		}
+		if crashLine.MatchString(line.Text) {
+			crashed = true
+		}
+	}
+	return crashed
+}
+
+// attributeCrash is called when a package fails while tests are still
+// running. If the output of one of the running tests is the output of a crash,
+// that test is marked as failed by the crash. If the output of the crash is
+// package output, all the running tests are marked as failed by the crash, and
+// the package output is added to the output of each test. The other running
+// tests are reported as not run.
+func (p *Package) attributeCrash(name string) {
+	var tests []string
+	for test := range p.running {
+		if isCrashOutput(p.output[test]) {
+			tests = append(tests, test)
+		}
+	}
+	if len(tests) == 0 {
+		if !isCrashOutput(p.output[""]) {
+			return
+		}
+		for test := range p.running {
+			tests = append(tests, test)
+		}
+	}
+	sort.Strings(tests)
+
+	var pkgOutput []OutputLine
+	crash := &Crash{Tests: tests}
+	for _, line := range p.output[""] {
+		if strings.Contains(line.Text, "(core dumped)") {
+			crash.CoreDumped = true
+		}
+		if !isPkgFrameLine(name, line.Text) {
+			pkgOutput = append(pkgOutput, line)
+		}
+	}
+	for _, test := range tests {
+		for _, line := range p.output[test] {
+			if strings.Contains(line.Text, "(core dumped)") {
+				crash.CoreDumped = true
+			}
+		}
+		p.output[test] = append(p.output[test], pkgOutput...)
+		p.Failed = append(p.Failed, TestCase{Package: name, Test: test})
+		delete(p.running, test)
+	}
+	p.crash = crash
+}
+
+// isPkgFrameLine returns true if the line is the result line of a package.
+func isPkgFrameLine(pkg string, line string) bool {
+	return line == "FAIL\n" || strings.HasPrefix(line, "FAIL\t"+pkg)
+}
+
+// Crash returns details of the crash of the test binary, or nil if the test
+// binary did not crash while tests were running.
+func (p Package) Crash() *Crash {
+	return p.crash
+}
@@ -0,0 +1,69 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func scanString(t *testing.T, stdout string) *Execution {
+	t.Helper()
+	handler := &fakeHandler{formatter: shortVerboseFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  strings.NewReader(stdout),
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestPackage_Crash(t *testing.T) {
+	exec := scanString(t, `{"Action":"run","Package":"pkg","Test":"TestA"}
+{"Action":"pause","Package":"pkg","Test":"TestA"}
+{"Action":"run","Package":"pkg","Test":"TestB"}
+{"Action":"output","Package":"pkg","Test":"TestB","Output":"=== RUN   TestB\n"}
+{"Action":"output","Package":"pkg","Test":"TestB","Output":"fatal error: concurrent map writes\n"}
+{"Action":"output","Package":"pkg","Test":"TestB","Output":"signal: aborted (core dumped)\n"}
+{"Action":"output","Package":"pkg","Output":"FAIL\tpkg\t0.010s\n"}
+{"Action":"fail","Package":"pkg","Elapsed":0.01}
+`)
+	pkg := exec.Package("pkg")
+	crash := pkg.Crash()
+	assert.Assert(t, crash != nil)
+	assert.DeepEqual(t, crash.Tests, []string{"TestB"})
+	assert.Assert(t, crash.CoreDumped)
+	assert.Equal(t, len(pkg.Failed), 1)
+	assert.Assert(t, !pkg.TestMainFailed())
+	assert.Equal(t, pkg.Output("TestB"), `=== RUN   TestB
+fatal error: concurrent map writes
+signal: aborted (core dumped)
+`)
+	assert.DeepEqual(t, exec.NotRun(), []TestCase{{Package: "pkg", Test: "TestA"}})
+}
+
+func TestPackage_Crash_PackageOutput(t *testing.T) {
+	exec := scanString(t, `{"Action":"run","Package":"pkg","Test":"TestA"}
+{"Action":"run","Package":"pkg","Test":"TestB"}
+{"Action":"output","Package":"pkg","Output":"SIGSEGV: segmentation violation\n"}
+{"Action":"output","Package":"pkg","Output":"FAIL\tpkg\t0.010s\n"}
+{"Action":"fail","Package":"pkg","Elapsed":0.01}
+`)
+	pkg := exec.Package("pkg")
+	assert.DeepEqual(t, pkg.Crash().Tests, []string{"TestA", "TestB"})
+	assert.Assert(t, !pkg.Crash().CoreDumped)
+	assert.Equal(t, pkg.Output("TestA"), "SIGSEGV: segmentation violation\n")
+	assert.Equal(t, len(exec.NotRun()), 0)
+}
+
+func TestPackage_Crash_Timeout(t *testing.T) {
+	exec := scanString(t, `{"Action":"run","Package":"pkg","Test":"TestA"}
+{"Action":"output","Package":"pkg","Test":"TestA","Output":"panic: test timed out after 1s\n"}
+{"Action":"fail","Package":"pkg","Elapsed":1}
+`)
+	pkg := exec.Package("pkg")
+	assert.Assert(t, pkg.Crash() == nil)
+	assert.DeepEqual(t, exec.NotRun(), []TestCase{{Package: "pkg", Test: "TestA"}})
+}
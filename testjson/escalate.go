@@ -0,0 +1,42 @@
+package testjson
+
+import "strings"
+
+// withVerboseAfterFailure returns a formatter which uses formatter until the
+// first test or package fails, and the standard-verbose format for every
+// event after it. The output of the first failed test, which was not printed
+// by formatter, is printed when the test fails.
+func withVerboseAfterFailure(formatter EventFormatter, enabled bool) EventFormatter {
+	if !enabled {
+		return formatter
+	}
+	verbose := false
+	startOfLine := true
+	return func(event TestEvent, exec *Execution) (string, error) {
+		if verbose {
+			return standardVerboseFormat(event, exec)
+		}
+		text, err := formatter(event, exec)
+		if err != nil {
+			return text, err
+		}
+		if event.Action != ActionFail {
+			if text != "" {
+				startOfLine = strings.HasSuffix(text, "\n")
+			}
+			return text, nil
+		}
+
+		verbose = true
+		if text != "" {
+			startOfLine = strings.HasSuffix(text, "\n")
+		}
+		if !startOfLine {
+			text += "\n"
+		}
+		if output := exec.Output(event.Package, event.Test); event.Test != "" && !strings.Contains(text, output) {
+			text += output
+		}
+		return text, nil
+	}
+}
@@ -1,19 +1,19 @@
 package testjson
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jonboulle/clockwork"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/sync/errgroup"
 )
 
 // Action of TestEvent
@@ -29,6 +29,14 @@ const (
 	ActionFail   Action = "fail"
 	ActionOutput Action = "output"
 	ActionSkip   Action = "skip"
+	// ActionNotRun is not output by go test. It is the result of a test or
+	// package which was expected to run, but never finished.
+	ActionNotRun Action = "notrun"
+	// ActionBuildOutput and ActionBuildFail are output by go test -json, since
+	// go1.24, when building a package. The events have an ImportPath instead
+	// of a Package.
+	ActionBuildOutput Action = "build-output"
+	ActionBuildFail   Action = "build-fail"
 )
 
 // TestEvent is a structure output by go tool test2json and go test -json.
@@ -42,6 +50,13 @@ type TestEvent struct {
 	Elapsed float64
 	// Output of test or benchmark
 	Output string
+	// ImportPath of the package being built, set on build events. It may
+	// include a suffix which identifies the test variant of the package,
+	// ex: "example.com/pkg [example.com/pkg.test]".
+	ImportPath string
+	// FailedBuild is the ImportPath of the build which failed, set on the
+	// package fail event of a package which could not be built.
+	FailedBuild string
 	// raw is the raw JSON bytes of the event
 	raw []byte
 }
@@ -51,6 +66,11 @@ func (e TestEvent) PackageEvent() bool {
 	return e.Test == ""
 }
 
+// BuildEvent returns true if the event is output from building a package.
+func (e TestEvent) BuildEvent() bool {
+	return e.Action == ActionBuildOutput || e.Action == ActionBuildFail
+}
+
 // ElapsedFormatted returns Elapsed formatted in the go test format, ex (0.00s).
 func (e TestEvent) ElapsedFormatted() string {
 	return fmt.Sprintf("(%.2fs)", e.Elapsed)
@@ -68,11 +88,72 @@ type Package struct {
 	Failed  []TestCase
 	Skipped []TestCase
 	Passed  []TestCase
-	output  map[string][]string
+	output  map[string][]OutputLine
 	// action identifies if the package passed or failed. A package may fail
 	// with no test failures if an init() or TestMain exits non-zero.
 	// skip indicates there were no tests.
 	action Action
+	// elapsed is the time reported by the package end event.
+	elapsed time.Duration
+	// started is the time of the first event of the package which has a time.
+	started time.Time
+	// running is the set of tests which have a run event, but no pass, fail,
+	// or skip event yet.
+	running map[string]int
+	// unstarted is the number of tests which ended without a run event.
+	unstarted int
+	// assertions is the assertion count reported by a marker in the test
+	// output, by test name.
+	assertions map[string]int
+	// errorLines is the number of t.Error style lines in the test output, by
+	// test name.
+	errorLines map[string]int
+	// buildFailed is true if the package could not be built.
+	buildFailed bool
+	// failedBuild is the ImportPath of the build which failed, from the
+	// FailedBuild of the package event.
+	failedBuild string
+	// crash is set when the test binary crashed while tests were running.
+	crash *Crash
+	// coverage is the percent of statements covered by the tests, from the
+	// output of go test -cover, when hasCoverage is true.
+	coverage    float64
+	hasCoverage bool
+	// subtests are the counts of the subtests of each test, by the name of the
+	// parent test.
+	subtests map[string]SubtestCounts
+	// results are the pass, fail, or skip action of each run of a test, in
+	// the order the runs ended, by test name.
+	results map[string][]Action
+	// benchmarks are the results of the benchmarks, by test name.
+	benchmarks map[string]BenchmarkResult
+	// benchmarkLines are the start of the last line of output of each
+	// benchmark, by test name, until the rest of the line is received. The
+	// testing package prints the name of a benchmark before it runs, and the
+	// result when it ends, so a result line is split across output events.
+	benchmarkLines map[string]string
+	// lifecycle is the last run event, and the last pass, fail, or skip
+	// event, of each test, by test name, and of the package, used to find
+	// duplicate events.
+	lifecycle map[string]lifecycle
+	// duplicates is the number of events which were ignored because they were
+	// the same as the previous event of the test.
+	duplicates int
+	// endedBeforeRun is the number of results of each test which were
+	// received before a run event, by test name.
+	endedBeforeRun map[string]int
+	// runAfterEnd is the number of run events which were received after the
+	// result of the test.
+	runAfterEnd int
+}
+
+// lifecycle is the part of the run event, and of the pass, fail, or skip event
+// of a test which is compared to find a duplicate event.
+type lifecycle struct {
+	run     time.Time
+	end     Action
+	endTime time.Time
+	elapsed float64
 }
 
 // Result returns if the package passed, failed, or was skipped because there
@@ -81,6 +162,12 @@ func (p Package) Result() Action {
 	return p.action
 }
 
+// Started returns the time of the first event of the package which has a
+// time, or the zero time if none of the events had a time.
+func (p Package) Started() time.Time {
+	return p.started
+}
+
 // Elapsed returns the sum of the elapsed time for all tests in the package.
 func (p Package) Elapsed() time.Duration {
 	elapsed := time.Duration(0)
@@ -97,7 +184,47 @@ func (p Package) TestCases() []TestCase {
 
 // Output returns the full test output for a test.
 func (p Package) Output(test string) string {
-	return strings.Join(p.output[test], "")
+	return joinOutput(p.output[test])
+}
+
+// OutputLines returns the full test output for a test as an array of lines.
+func (p Package) OutputLines(test string) []string {
+	lines := make([]string, 0, len(p.output[test]))
+	for _, line := range p.output[test] {
+		lines = append(lines, line.Text)
+	}
+	return lines
+}
+
+// TimedOutput returns the output of a test, one OutputLine for each output
+// event, with the time of the event. The lines are shared with the Package and
+// must not be modified.
+func (p Package) TimedOutput(test string) []OutputLine {
+	return p.output[test]
+}
+
+// OutputLine is a line of output from a test, or from a package when the
+// test is empty.
+type OutputLine struct {
+	// Time of the output event, which may be zero if the event had no time.
+	Time time.Time
+	// Text of the line, including the trailing newline.
+	Text string
+}
+
+func joinOutput(lines []OutputLine) string {
+	var builder strings.Builder
+	for _, line := range lines {
+		builder.WriteString(line.Text)
+	}
+	return builder.String()
+}
+
+// BuildFailed returns true if the package failed because it, or one of its
+// dependencies, could not be built. The build output is included in the
+// package output.
+func (p Package) BuildFailed() bool {
+	return p.buildFailed
 }
 
 // TestMainFailed returns true if the package failed, but there were no tests.
@@ -114,7 +241,33 @@ type TestCase struct {
 }
 
 func newPackage() *Package {
-	return &Package{output: make(map[string][]string)}
+	return &Package{
+		output:     make(map[string][]OutputLine),
+		running:    make(map[string]int),
+		assertions: make(map[string]int),
+		errorLines: make(map[string]int),
+		subtests:   make(map[string]SubtestCounts),
+		results:    make(map[string][]Action),
+	}
+}
+
+// end records the end of a test. A stream which was not created by
+// go test -json may not include a run event for every test, so the test is
+// counted when the end event is received if it was never started.
+func (p *Package) end(test string) {
+	switch p.running[test] {
+	case 0:
+		p.Total++
+		p.unstarted++
+		if p.endedBeforeRun == nil {
+			p.endedBeforeRun = make(map[string]int)
+		}
+		p.endedBeforeRun[test]++
+	case 1:
+		delete(p.running, test)
+	default:
+		p.running[test]--
+	}
 }
 
 // Execution of one or more test packages
@@ -122,34 +275,102 @@ type Execution struct {
 	started  time.Time
 	packages map[string]*Package
 	errors   []string
+	// badEvents is the number of lines which were not valid events.
+	badEvents int
+	// unattributed is the number of output events without a package.
+	unattributed int
+	// buildOutput is the output of build events, by ImportPath.
+	buildOutput map[string][]OutputLine
+	// warnings are the warnings written by go test, and by the testing
+	// package, from both stdout and stderr.
+	warnings []GoTestWarning
+	// keepPassedOutput is set by ScanConfig.KeepPassedOutput.
+	keepPassedOutput bool
+	// firstEvent and lastEvent are the earliest and latest times of the
+	// events.
+	firstEvent time.Time
+	lastEvent  time.Time
+	// ended is set when the Execution is read from a file, so that Elapsed
+	// is the time between the first and last event.
+	ended time.Time
 }
 
 func (e *Execution) add(event TestEvent) {
+	if !event.Time.IsZero() {
+		if e.firstEvent.IsZero() || event.Time.Before(e.firstEvent) {
+			e.firstEvent = event.Time
+		}
+		if event.Time.After(e.lastEvent) {
+			e.lastEvent = event.Time
+		}
+	}
+	if event.BuildEvent() {
+		if event.Action == ActionBuildOutput {
+			e.buildOutput[event.ImportPath] = append(e.buildOutput[event.ImportPath], newOutputLine(event))
+		}
+		return
+	}
+	if event.Package == "" && event.Action == ActionOutput {
+		e.unattributed++
+	}
 	pkg, ok := e.packages[event.Package]
 	if !ok {
 		pkg = newPackage()
 		e.packages[event.Package] = pkg
 	}
+	if pkg.started.IsZero() {
+		pkg.started = event.Time
+	}
+	pkg.recordLifecycle(event)
 	if event.PackageEvent() {
 		switch event.Action {
-		case ActionPass, ActionFail:
+		case ActionPass, ActionFail, ActionSkip:
 			pkg.action = event.Action
+			pkg.elapsed = elapsedDuration(event.Elapsed)
+			if event.FailedBuild != "" {
+				pkg.buildFailed = true
+				pkg.failedBuild = event.FailedBuild
+				output := append([]OutputLine{}, e.buildOutput[event.FailedBuild]...)
+				pkg.output[""] = append(output, pkg.output[""]...)
+			}
+			e.endBenchmarks(event.Package, pkg)
+			if event.Action == ActionFail && len(pkg.running) > 0 {
+				pkg.attributeCrash(event.Package)
+			}
 		case ActionOutput:
-			pkg.output[""] = append(pkg.output[""], event.Output)
+			pkg.output[""] = append(pkg.output[""], newOutputLine(event))
+			if isGoTestWarning(event.Output) {
+				e.addWarning(event.Package, event.Test, event.Output)
+			}
+			pkg.recordCoverage(event.Output)
 		}
 		return
 	}
 
+	pkg.countSubtest(event)
 	switch event.Action {
 	case ActionRun:
+		if pkg.endedBeforeRun[event.Test] > 0 {
+			// the result of the test was received before the run event, and
+			// the test was already counted
+			pkg.endedBeforeRun[event.Test]--
+			pkg.unstarted--
+			pkg.runAfterEnd++
+			return
+		}
 		pkg.Total++
+		pkg.running[event.Test]++
 	case ActionFail:
+		pkg.end(event.Test)
+		pkg.results[event.Test] = append(pkg.results[event.Test], event.Action)
 		pkg.Failed = append(pkg.Failed, TestCase{
 			Package: event.Package,
 			Test:    event.Test,
 			Elapsed: elapsedDuration(event.Elapsed),
 		})
 	case ActionSkip:
+		pkg.end(event.Test)
+		pkg.results[event.Test] = append(pkg.results[event.Test], event.Action)
 		pkg.Skipped = append(pkg.Skipped, TestCase{
 			Package: event.Package,
 			Test:    event.Test,
@@ -157,30 +378,234 @@ func (e *Execution) add(event TestEvent) {
 		})
 	case ActionOutput, ActionBench:
 		// TODO: limit size of buffered test output
-		pkg.output[event.Test] = append(pkg.output[event.Test], event.Output)
+		pkg.output[event.Test] = append(pkg.output[event.Test], newOutputLine(event))
+		if isGoTestWarning(event.Output) {
+			e.addWarning(event.Package, event.Test, event.Output)
+		}
+		pkg.countAssertions(event.Test, event.Output)
+		// go test does not emit a pass event for a benchmark, the line with
+		// the result is the end of the benchmark.
+		line, ok := pkg.benchmarkLine(event.Test, event.Output)
+		if !ok {
+			return
+		}
+		if result, ok := parseBenchmarkLine(event.Test, line); ok {
+			if pkg.benchmarks == nil {
+				pkg.benchmarks = make(map[string]BenchmarkResult)
+			}
+			pkg.benchmarks[event.Test] = result
+			e.passTest(pkg, TestEvent{Package: event.Package, Test: event.Test, Action: ActionPass})
+		}
 	case ActionPass:
-		pkg.Passed = append(pkg.Passed, TestCase{
-			Package: event.Package,
-			Test:    event.Test,
-			Elapsed: elapsedDuration(event.Elapsed),
-		})
-		// Remove test output once a test passes, it wont be used
+		e.passTest(pkg, event)
+	}
+}
+
+func (e *Execution) passTest(pkg *Package, event TestEvent) {
+	pkg.end(event.Test)
+	pkg.results[event.Test] = append(pkg.results[event.Test], event.Action)
+	pkg.Passed = append(pkg.Passed, TestCase{
+		Package: event.Package,
+		Test:    event.Test,
+		Elapsed: elapsedDuration(event.Elapsed),
+	})
+	// Remove test output once a test passes, it wont be used, unless the
+	// test failed in an earlier run.
+	if !e.keepPassedOutput && !pkg.failed(event.Test) {
 		delete(pkg.output, event.Test)
 	}
 }
 
+func (p *Package) failed(test string) bool {
+	for _, tc := range p.Failed {
+		if tc.Test == test {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	assertionMarker = regexp.MustCompile(`^\s+\S+\.go:\d+: gotestsum: assertions=(\d+)\s*$`)
+	errorLine       = regexp.MustCompile(`^\s+\S+\.go:\d+: `)
+)
+
+// countAssertions records an assertion count marker, or a line which was
+// written by t.Error, t.Fatal, or t.Log.
+func (p *Package) countAssertions(test string, line string) {
+	if match := assertionMarker.FindStringSubmatch(line); match != nil {
+		n, err := strconv.Atoi(match[1])
+		if err == nil {
+			p.assertions[test] = n
+		}
+		return
+	}
+	if errorLine.MatchString(line) {
+		p.errorLines[test]++
+	}
+}
+
+// Assertions returns the number of assertions made by a test. A test may
+// report the count by logging a line with the format
+// "gotestsum: assertions=N". Otherwise the count is only known for failed
+// tests, and is the number of lines written by t.Error, or t.Fatal. Returns
+// false if the count is not known.
+func (p *Package) Assertions(test string) (int, bool) {
+	if n, ok := p.assertions[test]; ok {
+		return n, true
+	}
+	if p.failed(test) {
+		return p.errorLines[test], true
+	}
+	return 0, false
+}
+
+// SubtestCounts are the number of subtests of a test which passed, failed,
+// and were skipped. Only the direct subtests of the test are counted, so the
+// cases of a table driven test are counted once.
+type SubtestCounts struct {
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// Total returns the number of subtests which ended.
+func (c SubtestCounts) Total() int {
+	return c.Passed + c.Failed + c.Skipped
+}
+
+// String returns the counts which are not zero, ex: 12 passed, 30 skipped.
+func (c SubtestCounts) String() string {
+	var parts []string
+	add := func(n int, name string) {
+		if n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, name))
+		}
+	}
+	add(c.Passed, "passed")
+	add(c.Failed, "failed")
+	add(c.Skipped, "skipped")
+	return strings.Join(parts, ", ")
+}
+
+func (p *Package) countSubtest(event TestEvent) {
+	i := strings.LastIndex(event.Test, "/")
+	if i < 0 {
+		return
+	}
+	parent := event.Test[:i]
+	counts := p.subtests[parent]
+	switch event.Action {
+	case ActionPass:
+		counts.Passed++
+	case ActionFail:
+		counts.Failed++
+	case ActionSkip:
+		counts.Skipped++
+	default:
+		return
+	}
+	p.subtests[parent] = counts
+}
+
+// SubtestCounts returns the number of subtests of test which passed, failed,
+// and were skipped.
+func (p *Package) SubtestCounts(test string) SubtestCounts {
+	return p.subtests[test]
+}
+
+// Results returns the result of each run of test, in the order the runs
+// ended. A test has more than one result when it was rerun, for example with
+// --rerun-fails, or with go test -count. The slice is shared with the Package
+// and must not be modified.
+func (p *Package) Results(test string) []Action {
+	return p.results[test]
+}
+
+var coverageLine = regexp.MustCompile(`coverage: (\d+(?:\.\d+)?)% of statements`)
+
+// recordCoverage records the coverage from a line of package output written
+// by go test -cover.
+func (p *Package) recordCoverage(line string) {
+	match := coverageLine.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	if pct, err := strconv.ParseFloat(match[1], 64); err == nil {
+		p.coverage, p.hasCoverage = pct, true
+	}
+}
+
+// Coverage returns the percent of statements covered by the tests of the
+// package, from the output of go test -cover. Returns false if the output
+// did not include the coverage.
+func (p *Package) Coverage() (float64, bool) {
+	return p.coverage, p.hasCoverage
+}
+
+func newOutputLine(event TestEvent) OutputLine {
+	return OutputLine{Time: event.Time, Text: event.Output}
+}
+
 func elapsedDuration(elapsed float64) time.Duration {
 	return time.Duration(elapsed*1000) * time.Millisecond
 }
 
 // Output returns the full test output for a test.
 func (e *Execution) Output(pkg, test string) string {
-	return strings.Join(e.packages[pkg].output[test], "")
+	return joinOutput(e.packages[pkg].output[test])
 }
 
 // OutputLines returns the full test output for a test as an array of lines.
 func (e *Execution) OutputLines(pkg, test string) []string {
-	return e.packages[pkg].output[test]
+	return e.packages[pkg].OutputLines(test)
+}
+
+// OutputMatch is a line of output which matched the pattern passed to
+// Execution.FilterOutput.
+type OutputMatch struct {
+	Package string
+	Test    string
+	// Index of the line in Package.OutputLines(Test).
+	Index int
+	OutputLine
+}
+
+// FilterOutput returns the lines of output which match pattern, sorted by
+// package, test, and the order of the lines. The output of the package, which
+// is not from a test, sorts first. The output of a test which passed is only
+// included when the test failed in an earlier run, or when the output of
+// passed tests is kept by ScanConfig.KeepPassedOutput. Use offset and limit to
+// return one page of the matches, a limit of 0 returns all the matches after
+// offset.
+func (e *Execution) FilterOutput(pattern *regexp.Regexp, offset, limit int) []OutputMatch {
+	var matches []OutputMatch
+	for _, name := range sortedKeys(e.packages) {
+		pkg := e.packages[name]
+		tests := make([]string, 0, len(pkg.output))
+		for test := range pkg.output {
+			tests = append(tests, test)
+		}
+		sort.Strings(tests)
+		for _, test := range tests {
+			for i, line := range pkg.output[test] {
+				if !pattern.MatchString(line.Text) {
+					continue
+				}
+				if offset > 0 {
+					offset--
+					continue
+				}
+				matches = append(matches, OutputMatch{
+					Package: name, Test: test, Index: i, OutputLine: line,
+				})
+				if limit > 0 && len(matches) == limit {
+					return matches
+				}
+			}
+		}
+	}
+	return matches
 }
 
 // Package returns the Package by name.
@@ -193,13 +618,60 @@ func (e *Execution) Packages() []string {
 	return sortedKeys(e.packages)
 }
 
+// Subset returns an Execution with only the packages with the names, for
+// example to write a report for a package as soon as it ends. The packages
+// are shared with e, and are not copied. Names of packages which are not in e
+// are ignored.
+func (e *Execution) Subset(names ...string) *Execution {
+	subset := &Execution{
+		started:          e.started,
+		packages:         make(map[string]*Package, len(names)),
+		buildOutput:      e.buildOutput,
+		keepPassedOutput: e.keepPassedOutput,
+		firstEvent:       e.firstEvent,
+		lastEvent:        e.lastEvent,
+		ended:            e.ended,
+	}
+	for _, name := range names {
+		if pkg, ok := e.packages[name]; ok {
+			subset.packages[name] = pkg
+		}
+	}
+	return subset
+}
+
 var clock = clockwork.NewRealClock()
 
-// Elapsed returns the time elapsed since the execution started.
+// Started returns the time the execution started.
+func (e *Execution) Started() time.Time {
+	return e.started
+}
+
+// Elapsed returns the time elapsed since the execution started. For an
+// Execution created by ReadExecution it is the time between the first and
+// last event.
 func (e *Execution) Elapsed() time.Duration {
+	if !e.ended.IsZero() {
+		return e.ended.Sub(e.started)
+	}
 	return clock.Now().Sub(e.started)
 }
 
+// CumulativeElapsed returns the sum of the time spent running each package.
+// When a package did not report its elapsed time the sum of the elapsed time
+// of its tests is used instead.
+func (e *Execution) CumulativeElapsed() time.Duration {
+	total := time.Duration(0)
+	for _, pkg := range e.packages {
+		if pkg.elapsed > 0 {
+			total += pkg.elapsed
+			continue
+		}
+		total += pkg.Elapsed()
+	}
+	return total
+}
+
 // Failed returns a list of all the failed test cases.
 func (e *Execution) Failed() []TestCase {
 	var failed []TestCase
@@ -216,6 +688,22 @@ func (e *Execution) Failed() []TestCase {
 	return failed
 }
 
+// Flaky returns the failed test cases of the tests which passed the last time
+// they were run, for example when the failed tests were rerun with
+// --rerun-fails. These test cases are also returned by Failed.
+func (e *Execution) Flaky() []TestCase {
+	var flaky []TestCase
+	for _, name := range sortedKeys(e.packages) {
+		pkg := e.packages[name]
+		for _, tc := range pkg.Failed {
+			if results := pkg.results[tc.Test]; len(results) > 0 && results[len(results)-1] == ActionPass {
+				flaky = append(flaky, tc)
+			}
+		}
+	}
+	return flaky
+}
+
 func sortedKeys(pkgs map[string]*Package) []string {
 	keys := make([]string, 0, len(pkgs))
 	for key := range pkgs {
@@ -234,6 +722,146 @@ func (e *Execution) Skipped() []TestCase {
 	return skipped
 }
 
+// NotRun returns a list of the test cases which started but never passed,
+// failed, or were skipped. Packages which never finished, and had no tests
+// which started, are included as a TestCase with an empty Test. This may occur
+// when the run is stopped early, or if the test binary crashed or timed out.
+func (e *Execution) NotRun() []TestCase {
+	var notRun []TestCase
+	for _, name := range sortedKeys(e.packages) {
+		pkg := e.packages[name]
+		if pkg.action == "" && len(pkg.running) == 0 && pkg.Total == 0 {
+			notRun = append(notRun, TestCase{Package: name})
+			continue
+		}
+		tests := make([]string, 0, len(pkg.running))
+		for test := range pkg.running {
+			tests = append(tests, test)
+		}
+		sort.Strings(tests)
+		for _, test := range tests {
+			notRun = append(notRun, TestCase{Package: name, Test: test})
+		}
+	}
+	return notRun
+}
+
+// failSkippedResults changes the skipped results of test to failed.
+func (p *Package) failSkippedResults(test string) {
+	for i, result := range p.results[test] {
+		if result == ActionSkip {
+			p.results[test][i] = ActionFail
+		}
+	}
+}
+
+// FailSkipped moves each skipped test case which matches into the failed test
+// cases of the package, and returns the test cases which were moved.
+func (e *Execution) FailSkipped(match func(TestCase) bool) []TestCase {
+	var moved []TestCase
+	for _, name := range sortedKeys(e.packages) {
+		pkg := e.packages[name]
+		skipped := pkg.Skipped[:0]
+		for _, tc := range pkg.Skipped {
+			if match(tc) {
+				pkg.Failed = append(pkg.Failed, tc)
+				moved = append(moved, tc)
+				pkg.failSkippedResults(tc.Test)
+				continue
+			}
+			skipped = append(skipped, tc)
+		}
+		pkg.Skipped = skipped
+	}
+	return moved
+}
+
+// ignoreDuplicate returns true, and counts the event, if the event is a run
+// event which is the same as the previous run event of the test, or a pass,
+// fail, or skip event which is the same as the previous result of the test or
+// package. Some tools which relay events, for example over a network, send an
+// event more than once. go test -json always sets the time, and the time of the
+// events of two runs of a test are different, so an event without a time is
+// never a duplicate.
+func (e *Execution) ignoreDuplicate(event TestEvent) bool {
+	pkg, ok := e.packages[event.Package]
+	if !ok || event.Time.IsZero() {
+		return false
+	}
+	last, ok := pkg.lifecycle[event.Test]
+	if !ok {
+		return false
+	}
+	switch event.Action {
+	case ActionRun:
+		if !last.run.Equal(event.Time) {
+			return false
+		}
+	case ActionPass, ActionFail, ActionSkip:
+		if last.end != event.Action || !last.endTime.Equal(event.Time) || last.elapsed != event.Elapsed {
+			return false
+		}
+	default:
+		return false
+	}
+	pkg.duplicates++
+	return true
+}
+
+// recordLifecycle records the time of a run, pass, fail, or skip event, to
+// find a duplicate of the event.
+func (p *Package) recordLifecycle(event TestEvent) {
+	if event.Time.IsZero() {
+		return
+	}
+	last := p.lifecycle[event.Test]
+	switch event.Action {
+	case ActionRun:
+		last.run = event.Time
+	case ActionPass, ActionFail, ActionSkip:
+		last.end, last.endTime, last.elapsed = event.Action, event.Time, event.Elapsed
+	default:
+		return
+	}
+	if p.lifecycle == nil {
+		p.lifecycle = make(map[string]lifecycle)
+	}
+	p.lifecycle[event.Test] = last
+}
+
+// Discrepancies returns a description of each problem which may make the
+// Execution incomplete: lines which were not valid events, output which was
+// not attributed to a package, and packages with test results which do not
+// match a run event. go test -json always sends a run event before the result,
+// so a missing run event means that events were lost.
+func (e *Execution) Discrepancies() []string {
+	var problems []string
+	if e.badEvents > 0 {
+		problems = append(problems,
+			fmt.Sprintf("%d lines of output were not valid test2json events", e.badEvents))
+	}
+	if e.unattributed > 0 {
+		problems = append(problems,
+			fmt.Sprintf("%d lines of output were not attributed to a package", e.unattributed))
+	}
+	for _, name := range sortedKeys(e.packages) {
+		pkg := e.packages[name]
+		if pkg.unstarted > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %d test results without a run event",
+				RelativePackagePath(name), pkg.unstarted))
+		}
+		if pkg.duplicates > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %d duplicate events were ignored",
+				RelativePackagePath(name), pkg.duplicates))
+		}
+		if pkg.runAfterEnd > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %d run events were received after the test result",
+				RelativePackagePath(name), pkg.runAfterEnd))
+		}
+	}
+	return problems
+}
+
 // Total returns a count of all test cases.
 func (e *Execution) Total() int {
 	total := 0
@@ -248,10 +876,40 @@ func (e *Execution) addError(err string) {
 	if strings.HasPrefix(err, "# ") {
 		return
 	}
-	// TODO: may need locking, or use a channel
 	e.errors = append(e.errors, err)
 }
 
+// GoTestWarning is a warning written by go test, or by the testing package,
+// for example "testing: warning: no tests to run".
+type GoTestWarning struct {
+	// Package and Test which printed the warning, both are empty for a warning
+	// written to stderr by go test.
+	Package string
+	Test    string
+	// Text of the warning, without the trailing newline.
+	Text string
+}
+
+// isGoTestWarning returns true if the line is a warning from go test, or from
+// the testing package.
+func isGoTestWarning(line string) bool {
+	return strings.HasPrefix(line, "testing: ") || strings.HasPrefix(line, "warning: ")
+}
+
+func (e *Execution) addWarning(pkg, test, text string) {
+	e.warnings = append(e.warnings, GoTestWarning{
+		Package: pkg,
+		Test:    test,
+		Text:    strings.TrimSuffix(text, "\n"),
+	})
+}
+
+// GoTestWarnings returns the warnings written by go test, and by the testing
+// package, in the order they were received.
+func (e *Execution) GoTestWarnings() []GoTestWarning {
+	return append([]GoTestWarning(nil), e.warnings...)
+}
+
 // Errors returns a list of all the errors.
 func (e *Execution) Errors() []string {
 	return e.errors
@@ -261,8 +919,9 @@ func (e *Execution) Errors() []string {
 // time the test execution started.
 func NewExecution() *Execution {
 	return &Execution{
-		started:  time.Now(),
-		packages: make(map[string]*Package),
+		started:     time.Now(),
+		packages:    make(map[string]*Package),
+		buildOutput: make(map[string][]OutputLine),
 	}
 }
 
@@ -271,6 +930,33 @@ type ScanConfig struct {
 	Stdout  io.Reader
 	Stderr  io.Reader
 	Handler EventHandler
+	// PlannedPackages are the packages which are expected to run. Any package
+	// which does not finish is reported by Execution.NotRun.
+	PlannedPackages []string
+	// Execution to add the events to, used to add the events of a rerun to the
+	// Execution of the first run. When nil, a new Execution is created.
+	Execution *Execution
+	// Metrics, when not nil, records the number of events and the time spent
+	// parsing them.
+	Metrics *ScanMetrics
+	// MaxLineSize is the size of the longest line read from Stdout or Stderr.
+	// Longer lines are truncated. Defaults to DefaultMaxLineSize.
+	MaxLineSize int
+	// KeepPassedOutput keeps the output of tests which passed. By default the
+	// output is removed when a test passes, because it is only used to report
+	// failures.
+	KeepPassedOutput bool
+	// Rewrite is applied to the output of each event, and each line of
+	// Stderr, before it is added to the Execution or handled by the Handler.
+	Rewrite RewriteRules
+}
+
+// ScanMetrics are the measurements of ScanTestOutput.
+type ScanMetrics struct {
+	Events int
+	// Parse is the time spent parsing events and adding them to the
+	// Execution. It does not include the time spent by the EventHandler.
+	Parse time.Duration
 }
 
 // EventHandler is called by ScanTestOutput for each event and write to stderr.
@@ -282,49 +968,191 @@ type EventHandler interface {
 // ScanTestOutput reads lines from stdout and stderr, creates an Execution,
 // calls the Handler for each event, and returns the Execution.
 func ScanTestOutput(config ScanConfig) (*Execution, error) {
-	execution := NewExecution()
-	var group errgroup.Group
-	group.Go(func() error {
-		return readStdout(config, execution)
-	})
-	group.Go(func() error {
-		return readStderr(config, execution)
-	})
-	return execution, group.Wait()
-}
-
-func readStdout(config ScanConfig, execution *Execution) error {
-	scanner := bufio.NewScanner(config.Stdout)
-	for scanner.Scan() {
-		raw := scanner.Bytes()
-		event, err := parseEvent(raw)
-		switch {
-		case err == errBadEvent:
-			// nolint: errcheck
-			config.Handler.Err(errBadEvent.Error() + ": " + scanner.Text())
-			continue
-		case err != nil:
-			return errors.Wrapf(err, "failed to parse test output: %s", string(raw))
-		}
-		execution.add(event)
-		if err := config.Handler.Event(event, execution); err != nil {
-			return err
+	execution := config.Execution
+	if execution == nil {
+		execution = NewExecution()
+	}
+	if config.KeepPassedOutput {
+		execution.keepPassedOutput = true
+	}
+	for _, name := range config.PlannedPackages {
+		if _, ok := execution.packages[name]; !ok {
+			execution.packages[name] = newPackage()
 		}
 	}
-	return errors.Wrap(scanner.Err(), "failed to scan test output")
+	stdout := readLines(config.Stdout, config.MaxLineSize)
+	stderr := readLines(config.Stderr, config.MaxLineSize)
+	return execution, scanLines(config, execution, stdout, stderr)
 }
 
-func readStderr(config ScanConfig, execution *Execution) error {
-	scanner := bufio.NewScanner(config.Stderr)
-	for scanner.Scan() {
-		line := scanner.Text()
-		config.Handler.Err(line) // nolint: errcheck
-		if isGoModuleOutput(line) {
-			continue
+// scanBufferLines is the number of lines from each of stdout and stderr which
+// are buffered while the EventHandler handles an earlier line.
+const scanBufferLines = 1024
+
+// scanLine is a line read from stdout or stderr, or the error from reading it.
+type scanLine struct {
+	raw     []byte
+	dropped int
+	err     error
+}
+
+// readLines reads lines from in, in a goroutine, until EOF or an error. A
+// burst of lines on one stream does not stop the other stream from being
+// read, until its buffer is full. When the buffer is full the goroutine
+// waits, and does not drop lines, which applies backpressure to go test.
+func readLines(in io.Reader, maxLineSize int) <-chan scanLine {
+	lines := make(chan scanLine, scanBufferLines)
+	go func() {
+		defer close(lines)
+		reader := newLineReader(in, maxLineSize)
+		for {
+			raw, dropped, err := reader.readLine()
+			switch {
+			case err == io.EOF:
+				return
+			case err != nil:
+				lines <- scanLine{err: err}
+				return
+			}
+			lines <- scanLine{raw: raw, dropped: dropped}
+		}
+	}()
+	return lines
+}
+
+// scanLines handles the lines from stdout and stderr in a single goroutine,
+// so the Execution and the EventHandler are never used concurrently. Lines are
+// taken from both streams as they arrive, so that neither is starved by the
+// other. After an error the remaining lines are read and discarded, so that go
+// test never blocks on a full pipe, and the first error is returned once both
+// streams are closed.
+func scanLines(config ScanConfig, execution *Execution, stdout, stderr <-chan scanLine) error {
+	maxLineSize := newLineReader(nil, config.MaxLineSize).max
+	var firstErr error
+	for stdout != nil || stderr != nil {
+		var err error
+		select {
+		case line, ok := <-stdout:
+			if !ok {
+				stdout = nil
+				continue
+			}
+			if firstErr == nil {
+				err = handleStdout(config, execution, line, maxLineSize)
+			}
+		case line, ok := <-stderr:
+			if !ok {
+				stderr = nil
+				continue
+			}
+			if firstErr == nil {
+				err = handleStderr(config, execution, line)
+			}
 		}
-		execution.addError(line)
+		if err != nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// ReadExecution creates an Execution from the TestEvents in in, for example a
+// file written by --jsonfile, without printing the events. The start time
+// and elapsed time of the Execution are read from the time of the events.
+func ReadExecution(in io.Reader) (*Execution, error) {
+	execution, err := ScanTestOutput(ScanConfig{
+		Stdout:  in,
+		Stderr:  strings.NewReader(""),
+		Handler: discardHandler{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !execution.firstEvent.IsZero() {
+		execution.started = execution.firstEvent
+		execution.ended = execution.lastEvent
+	}
+	return execution, nil
+}
+
+// discardHandler is an EventHandler which ignores all events and errors.
+type discardHandler struct{}
+
+func (discardHandler) Event(TestEvent, *Execution) error {
+	return nil
+}
+
+func (discardHandler) Err(string) error {
+	return nil
+}
+
+func handleStdout(config ScanConfig, execution *Execution, line scanLine, maxLineSize int) error {
+	raw := line.raw
+	switch {
+	case line.err != nil:
+		return errors.Wrap(line.err, "failed to read test output")
+	case line.dropped > 0:
+		// a truncated event can not be parsed
+		execution.badEvents++
+		// nolint: errcheck
+		config.Handler.Err(fmt.Sprintf("%s: event longer than %d bytes: %s%s",
+			errBadEvent, maxLineSize, abbreviate(raw), truncatedMessage(line.dropped)))
+		return nil
+	}
+	start := time.Now()
+	event, err := parseEvent(raw)
+	switch {
+	case err == errBadEvent:
+		execution.badEvents++
+		// nolint: errcheck
+		config.Handler.Err(errBadEvent.Error() + ": " + string(raw))
+		return nil
+	case err != nil:
+		return errors.Wrapf(err, "failed to parse test output: %s", abbreviate(raw))
+	}
+	event = config.Rewrite.rewriteEvent(event)
+	if execution.ignoreDuplicate(event) {
+		return nil
+	}
+	execution.add(event)
+	if event.Action == ActionBuildOutput {
+		// build output was written to stderr before go1.24
+		config.Handler.Err(strings.TrimSuffix(event.Output, "\n")) // nolint: errcheck
+	}
+	if config.Metrics != nil {
+		config.Metrics.Events++
+		config.Metrics.Parse += time.Since(start)
+	}
+	return config.Handler.Event(event, execution)
+}
+
+// abbreviate returns the start of a line which may be too long to print in an
+// error message.
+func abbreviate(raw []byte) string {
+	const max = 200
+	if len(raw) <= max {
+		return string(raw)
+	}
+	return string(raw[:max]) + "..."
+}
+
+func handleStderr(config ScanConfig, execution *Execution, line scanLine) error {
+	if line.err != nil {
+		return errors.Wrap(line.err, "failed to read test stderr")
+	}
+	text := config.Rewrite.Apply(string(line.raw))
+	if line.dropped > 0 {
+		text += truncatedMessage(line.dropped)
+	}
+	config.Handler.Err(text) // nolint: errcheck
+	switch {
+	case isGoModuleOutput(text):
+	case isGoTestWarning(text):
+		execution.addWarning("", "", text)
+	default:
+		execution.addError(text)
 	}
-	return errors.Wrap(scanner.Err(), "failed to scan test stderr")
+	return nil
 }
 
 func isGoModuleOutput(scannerText string) bool {
@@ -353,6 +1181,8 @@ func parseEvent(raw []byte) (TestEvent, error) {
 
 	event := TestEvent{}
 	err := json.Unmarshal(raw, &event)
+	// Streams which were not created by go test may use a different case.
+	event.Action = Action(strings.ToLower(string(event.Action)))
 	event.raw = raw
 	return event, err
 }
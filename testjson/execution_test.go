@@ -1,6 +1,10 @@
 package testjson
 
 import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,3 +26,382 @@ func TestPackage_Elapsed(t *testing.T) {
 	}
 	assert.Equal(t, pkg.Elapsed(), 3100*time.Millisecond)
 }
+
+func TestPackage_Started(t *testing.T) {
+	stdout := strings.NewReader(`{"Action":"start","Package":"example.com/pkg"}
+{"Time":"2020-05-01T10:00:01Z","Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Time":"2020-05-01T10:00:02Z","Action":"pass","Package":"example.com/pkg","Test":"TestOne"}
+{"Time":"2020-05-01T10:00:03Z","Action":"pass","Package":"example.com/pkg"}
+`)
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:          stdout,
+		Stderr:          strings.NewReader(""),
+		Handler:         &fakeHandler{formatter: standardQuietFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)},
+		PlannedPackages: []string{"example.com/notrun"},
+	})
+	assert.NilError(t, err)
+	expected := time.Date(2020, 5, 1, 10, 0, 1, 0, time.UTC)
+	assert.Assert(t, exec.Package("example.com/pkg").Started().Equal(expected))
+	assert.Assert(t, exec.Package("example.com/notrun").Started().IsZero())
+}
+
+func TestReadExecution(t *testing.T) {
+	in := strings.NewReader(`{"Time":"2020-05-01T10:00:01Z","Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Time":"2020-05-01T10:00:04Z","Action":"fail","Package":"example.com/pkg","Test":"TestOne","Elapsed":3}
+{"Time":"2020-05-01T10:00:05Z","Action":"fail","Package":"example.com/pkg","Elapsed":4}
+`)
+	exec, err := ReadExecution(in)
+	assert.NilError(t, err)
+	assert.Equal(t, exec.Total(), 1)
+	assert.Equal(t, len(exec.Failed()), 1)
+	assert.Assert(t, exec.Started().Equal(time.Date(2020, 5, 1, 10, 0, 1, 0, time.UTC)))
+	assert.Equal(t, exec.Elapsed(), 4*time.Second)
+}
+
+func TestScanTestOutput_ForeignStream(t *testing.T) {
+	// A stream created by an adapter for another test runner may include
+	// unknown actions and fields, use a different case for the action, and
+	// omit run events.
+	stdout := strings.NewReader(`{"Action":"start","Package":"web/app"}
+{"Action":"RUN","Package":"web/app","Test":"renders","Runner":"jest"}
+{"Action":"output","Package":"web/app","Test":"renders","Output":"ok\n","Retry":0}
+{"Action":"PASS","Package":"web/app","Test":"renders","Elapsed":0.25}
+{"Action":"fail","Package":"web/app","Test":"submits","Elapsed":1.5}
+{"Action":"skip","Package":"web/app","Test":"uploads"}
+{"Action":"flaky","Package":"web/app","Test":"uploads"}
+{"Action":"fail","Package":"web/app","Elapsed":2}
+`)
+	handler := &fakeHandler{formatter: shortVerboseFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	metrics := &ScanMetrics{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  stdout,
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+		Metrics: metrics,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, handler.err.String(), "")
+	assert.Equal(t, metrics.Events, 8)
+
+	pkg := exec.Package("web/app")
+	assert.Equal(t, pkg.Total, 3)
+	assert.Equal(t, len(pkg.Passed), 1)
+	assert.Equal(t, len(pkg.Failed), 1)
+	assert.Equal(t, len(pkg.Skipped), 1)
+	assert.Equal(t, pkg.Result(), ActionFail)
+	assert.Equal(t, exec.Total(), 3)
+}
+
+func TestExecution_NotRun(t *testing.T) {
+	stdout := strings.NewReader(`{"Action":"run","Package":"done","Test":"TestA"}
+{"Action":"pass","Package":"done","Test":"TestA"}
+{"Action":"pass","Package":"done"}
+{"Action":"run","Package":"running","Test":"TestB"}
+{"Action":"pass","Package":"running","Test":"TestB"}
+{"Action":"run","Package":"running","Test":"TestC"}
+{"Action":"run","Package":"running","Test":"TestD"}
+{"Action":"output","Package":"running","Test":"TestD","Output":"still going\n"}
+{"Action":"run","Package":"timeout","Test":"TestE"}
+{"Action":"output","Package":"timeout","Output":"panic: test timed out after 1s\n"}
+{"Action":"fail","Package":"timeout","Elapsed":1}
+`)
+	handler := &fakeHandler{formatter: shortVerboseFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:          stdout,
+		Stderr:          strings.NewReader(""),
+		Handler:         handler,
+		PlannedPackages: []string{"done", "running", "never-started"},
+	})
+	assert.NilError(t, err)
+
+	expected := []TestCase{
+		{Package: "never-started"},
+		{Package: "running", Test: "TestC"},
+		{Package: "running", Test: "TestD"},
+		{Package: "timeout", Test: "TestE"},
+	}
+	assert.DeepEqual(t, exec.NotRun(), expected)
+	assert.DeepEqual(t, exec.Packages(), []string{"done", "never-started", "running", "timeout"})
+}
+
+func TestExecution_Discrepancies(t *testing.T) {
+	stdout := strings.NewReader(`{"Action":"run","Package":"pkg/a","Test":"TestA"}
+{"Action":"pass","Package":"pkg/a","Test":"TestA"}
+{"Action":"pass","Package":"pkg/b","Test":"TestB"}
+{"Action":"output","Output":"some output\n"}
+FAIL
+`)
+	handler := &fakeHandler{formatter: shortVerboseFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  stdout,
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	expected := []string{
+		"1 lines of output were not valid test2json events",
+		"1 lines of output were not attributed to a package",
+		"pkg/b: 1 test results without a run event",
+	}
+	assert.DeepEqual(t, exec.Discrepancies(), expected)
+}
+
+func TestExecution_DuplicateAndReorderedEvents(t *testing.T) {
+	stdout := strings.NewReader(`{"Time":"2024-03-01T10:00:00.1Z","Action":"run","Package":"pkg/a","Test":"TestA"}
+{"Time":"2024-03-01T10:00:00.1Z","Action":"run","Package":"pkg/a","Test":"TestA"}
+{"Time":"2024-03-01T10:00:00.2Z","Action":"pass","Package":"pkg/a","Test":"TestA","Elapsed":0.1}
+{"Time":"2024-03-01T10:00:00.2Z","Action":"pass","Package":"pkg/a","Test":"TestA","Elapsed":0.1}
+{"Time":"2024-03-01T10:00:00.4Z","Action":"fail","Package":"pkg/a","Test":"TestB","Elapsed":0.1}
+{"Time":"2024-03-01T10:00:00.3Z","Action":"run","Package":"pkg/a","Test":"TestB"}
+{"Time":"2024-03-01T10:00:00.5Z","Action":"run","Package":"pkg/a","Test":"TestA"}
+{"Time":"2024-03-01T10:00:00.6Z","Action":"pass","Package":"pkg/a","Test":"TestA","Elapsed":0.1}
+{"Time":"2024-03-01T10:00:00.7Z","Action":"fail","Package":"pkg/a","Elapsed":0.6}
+{"Time":"2024-03-01T10:00:00.7Z","Action":"fail","Package":"pkg/a","Elapsed":0.6}
+`)
+	handler := &fakeHandler{formatter: shortVerboseFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  stdout,
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	pkg := exec.Package("pkg/a")
+	assert.Equal(t, pkg.Total, 3)
+	assert.Equal(t, len(pkg.Passed), 2)
+	assert.Equal(t, len(pkg.Failed), 1)
+	assert.Equal(t, len(exec.NotRun()), 0)
+	assert.Equal(t, exec.Total(), 3)
+
+	expected := []string{
+		"pkg/a: 3 duplicate events were ignored",
+		"pkg/a: 1 run events were received after the test result",
+	}
+	assert.DeepEqual(t, exec.Discrepancies(), expected)
+}
+
+func TestExecution_FailSkipped(t *testing.T) {
+	exec := &Execution{
+		packages: map[string]*Package{
+			"pkg/a": {
+				Skipped: []TestCase{
+					{Package: "pkg/a", Test: "TestCritical"},
+					{Package: "pkg/a", Test: "TestOptional"},
+				},
+			},
+		},
+	}
+	moved := exec.FailSkipped(func(tc TestCase) bool {
+		return tc.Test == "TestCritical"
+	})
+	assert.DeepEqual(t, moved, []TestCase{{Package: "pkg/a", Test: "TestCritical"}})
+	assert.DeepEqual(t, exec.Failed(), []TestCase{{Package: "pkg/a", Test: "TestCritical"}})
+	assert.DeepEqual(t, exec.Skipped(), []TestCase{{Package: "pkg/a", Test: "TestOptional"}})
+}
+
+func TestPackage_Coverage(t *testing.T) {
+	stdout := strings.NewReader(`{"Action":"run","Package":"pkg/a","Test":"TestOne"}
+{"Action":"output","Package":"pkg/a","Test":"TestOne","Output":"    coverage: 99.0% of statements in a log line\n"}
+{"Action":"pass","Package":"pkg/a","Test":"TestOne"}
+{"Action":"output","Package":"pkg/a","Output":"PASS\n"}
+{"Action":"output","Package":"pkg/a","Output":"coverage: 83.3% of statements\n"}
+{"Action":"output","Package":"pkg/a","Output":"ok  \tpkg/a\t0.01s\tcoverage: 83.3% of statements\n"}
+{"Action":"pass","Package":"pkg/a"}
+{"Action":"output","Package":"pkg/b","Output":"coverage: [no statements]\n"}
+{"Action":"pass","Package":"pkg/b"}
+`)
+	handler := &fakeHandler{formatter: shortVerboseFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  stdout,
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	pct, ok := exec.Package("pkg/a").Coverage()
+	assert.Assert(t, ok)
+	assert.Equal(t, pct, 83.3)
+
+	_, ok = exec.Package("pkg/b").Coverage()
+	assert.Assert(t, !ok)
+}
+
+func TestPackage_SubtestCounts(t *testing.T) {
+	exec := NewExecution()
+	for _, event := range []TestEvent{
+		{Action: ActionPass, Package: "pkg", Test: "TestTable/a"},
+		{Action: ActionFail, Package: "pkg", Test: "TestTable/b"},
+		{Action: ActionPass, Package: "pkg", Test: "TestTable/b/nested"},
+		{Action: ActionSkip, Package: "pkg", Test: "TestTable/c"},
+		{Action: ActionSkip, Package: "pkg", Test: "TestTable/d"},
+		{Action: ActionFail, Package: "pkg", Test: "TestTable"},
+	} {
+		exec.add(event)
+	}
+
+	counts := exec.Package("pkg").SubtestCounts("TestTable")
+	assert.Equal(t, counts, SubtestCounts{Passed: 1, Failed: 1, Skipped: 2})
+	assert.Equal(t, counts.Total(), 4)
+	assert.Equal(t, counts.String(), "1 passed, 1 failed, 2 skipped")
+	assert.Equal(t, exec.Package("pkg").SubtestCounts("TestTable/b").String(), "1 passed")
+	assert.Equal(t, exec.Package("pkg").SubtestCounts("TestOther"), SubtestCounts{})
+}
+
+func TestPackage_Results(t *testing.T) {
+	exec := NewExecution()
+	for _, event := range []TestEvent{
+		{Action: ActionFail, Package: "pkg", Test: "TestFlaky"},
+		{Action: ActionSkip, Package: "pkg", Test: "TestSkipped"},
+		{Action: ActionFail, Package: "pkg", Test: "TestFlaky"},
+		{Action: ActionPass, Package: "pkg", Test: "TestFlaky"},
+	} {
+		exec.add(event)
+	}
+
+	pkg := exec.Package("pkg")
+	assert.DeepEqual(t, pkg.Results("TestFlaky"), []Action{ActionFail, ActionFail, ActionPass})
+	assert.DeepEqual(t, pkg.Results("TestSkipped"), []Action{ActionSkip})
+	assert.Equal(t, len(pkg.Results("TestOther")), 0)
+
+	exec.FailSkipped(func(TestCase) bool { return true })
+	assert.DeepEqual(t, pkg.Results("TestSkipped"), []Action{ActionFail})
+}
+
+func TestPackage_Assertions(t *testing.T) {
+	stdout := strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestMarker"}
+{"Action":"output","Package":"pkg","Test":"TestMarker","Output":"    a_test.go:10: gotestsum: assertions=7\n"}
+{"Action":"pass","Package":"pkg","Test":"TestMarker"}
+{"Action":"run","Package":"pkg","Test":"TestFails"}
+{"Action":"output","Package":"pkg","Test":"TestFails","Output":"    a_test.go:20: expected 1, got 2\n"}
+{"Action":"output","Package":"pkg","Test":"TestFails","Output":"        continued message\n"}
+{"Action":"output","Package":"pkg","Test":"TestFails","Output":"    a_test.go:21: expected 3, got 4\n"}
+{"Action":"fail","Package":"pkg","Test":"TestFails"}
+{"Action":"run","Package":"pkg","Test":"TestPasses"}
+{"Action":"output","Package":"pkg","Test":"TestPasses","Output":"    a_test.go:30: a log line\n"}
+{"Action":"pass","Package":"pkg","Test":"TestPasses"}
+{"Action":"fail","Package":"pkg"}
+`)
+	handler := &fakeHandler{formatter: shortVerboseFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  stdout,
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	pkg := exec.Package("pkg")
+	n, ok := pkg.Assertions("TestMarker")
+	assert.Assert(t, ok)
+	assert.Equal(t, n, 7)
+
+	n, ok = pkg.Assertions("TestFails")
+	assert.Assert(t, ok)
+	assert.Equal(t, n, 2)
+
+	_, ok = pkg.Assertions("TestPasses")
+	assert.Assert(t, !ok)
+}
+
+func TestScanTestOutput_BuildEvents(t *testing.T) {
+	stdout := strings.NewReader(`{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"# example.com/broken [example.com/broken.test]\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"./broken_test.go:5:2: undefined: missing\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-fail"}
+{"Action":"start","Package":"example.com/broken"}
+{"Action":"output","Package":"example.com/broken","Output":"FAIL\texample.com/broken [build failed]\n"}
+{"Action":"fail","Package":"example.com/broken","Elapsed":0,"FailedBuild":"example.com/broken [example.com/broken.test]"}
+{"Action":"start","Package":"example.com/good"}
+{"Action":"run","Package":"example.com/good","Test":"TestOk"}
+{"Action":"pass","Package":"example.com/good","Test":"TestOk"}
+{"Action":"pass","Package":"example.com/good"}
+`)
+	handler := &fakeHandler{formatter: standardQuietFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  stdout,
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, exec.Packages(), []string{"example.com/broken", "example.com/good"})
+	assert.Equal(t, len(exec.Errors()), 0)
+	assert.Equal(t, len(exec.Discrepancies()), 0)
+
+	pkg := exec.Package("example.com/broken")
+	assert.Assert(t, pkg.BuildFailed())
+	assert.Assert(t, pkg.TestMainFailed())
+	assert.Equal(t, pkg.Output(""), `# example.com/broken [example.com/broken.test]
+./broken_test.go:5:2: undefined: missing
+FAIL	example.com/broken [build failed]
+`)
+	assert.Assert(t, !exec.Package("example.com/good").BuildFailed())
+
+	assert.Equal(t, handler.err.String(), `# example.com/broken [example.com/broken.test]
+./broken_test.go:5:2: undefined: missing
+`)
+	assert.Equal(t, handler.out.String(), "FAIL\texample.com/broken [build failed]\n")
+}
+
+func TestExecution_FilterOutput(t *testing.T) {
+	stdout := strings.NewReader(`{"Time":"2019-04-01T10:00:01Z","Action":"run","Package":"pkg/a","Test":"TestOne"}
+{"Time":"2019-04-01T10:00:01Z","Action":"output","Package":"pkg/a","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Time":"2019-04-01T10:00:02Z","Action":"output","Package":"pkg/a","Test":"TestOne","Output":"    a_test.go:10: connection refused\n"}
+{"Time":"2019-04-01T10:00:03Z","Action":"output","Package":"pkg/a","Test":"TestOne","Output":"    a_test.go:11: retry: connection refused\n"}
+{"Time":"2019-04-01T10:00:03Z","Action":"fail","Package":"pkg/a","Test":"TestOne"}
+{"Time":"2019-04-01T10:00:04Z","Action":"run","Package":"pkg/b","Test":"TestTwo"}
+{"Time":"2019-04-01T10:00:05Z","Action":"output","Package":"pkg/b","Test":"TestTwo","Output":"    b_test.go:20: connection refused\n"}
+{"Time":"2019-04-01T10:00:05Z","Action":"fail","Package":"pkg/b","Test":"TestTwo"}
+`)
+	handler := &fakeHandler{formatter: shortVerboseFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  stdout,
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	lines := exec.Package("pkg/a").TimedOutput("TestOne")
+	assert.Equal(t, len(lines), 3)
+	assert.Equal(t, lines[1].Text, "    a_test.go:10: connection refused\n")
+	assert.Equal(t, lines[1].Time, time.Date(2019, 4, 1, 10, 0, 2, 0, time.UTC))
+	assert.DeepEqual(t, exec.Package("pkg/a").OutputLines("TestOne"), exec.OutputLines("pkg/a", "TestOne"))
+
+	pattern := regexp.MustCompile("connection refused")
+	matches := exec.FilterOutput(pattern, 0, 0)
+	assert.Equal(t, len(matches), 3)
+	assert.Equal(t, matches[0].Package, "pkg/a")
+	assert.Equal(t, matches[0].Index, 1)
+	assert.Equal(t, matches[2].Package, "pkg/b")
+	assert.Equal(t, matches[2].Test, "TestTwo")
+
+	page := exec.FilterOutput(pattern, 1, 1)
+	assert.Equal(t, len(page), 1)
+	assert.Equal(t, page[0].Text, "    a_test.go:11: retry: connection refused\n")
+}
+
+func TestExecution_GoTestWarnings(t *testing.T) {
+	stdout := strings.NewReader(`{"Action":"output","Package":"pkg/a","Output":"testing: warning: no tests to run\n"}
+{"Action":"output","Package":"pkg/a","Output":"PASS\n"}
+{"Action":"pass","Package":"pkg/a"}
+{"Action":"run","Package":"pkg/b","Test":"TestB"}
+{"Action":"output","Package":"pkg/b","Test":"TestB","Output":"testing: warning: -parallel=0 is not valid, using 1\n"}
+{"Action":"pass","Package":"pkg/b","Test":"TestB"}
+`)
+	handler := &fakeHandler{formatter: shortVerboseFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  stdout,
+		Stderr:  strings.NewReader("warning: no packages being tested depend on matches for pattern ./other/...\n"),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(exec.Errors()), 0)
+
+	warnings := exec.GoTestWarnings()
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Package < warnings[j].Package
+	})
+	assert.DeepEqual(t, warnings, []GoTestWarning{
+		{Text: "warning: no packages being tested depend on matches for pattern ./other/..."},
+		{Package: "pkg/a", Text: "testing: warning: no tests to run"},
+		{Package: "pkg/b", Test: "TestB", Text: "testing: warning: -parallel=0 is not valid, using 1"},
+	})
+}
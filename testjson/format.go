@@ -31,7 +31,7 @@ func standardVerboseFormat(event TestEvent, _ *Execution) (string, error) {
 
 // go test
 func standardQuietFormat(event TestEvent, _ *Execution) (string, error) {
-	if event.PackageEvent() && event.Output != "PASS\n" {
+	if event.PackageEvent() && event.Action == ActionOutput && event.Output != "PASS\n" {
 		return event.Output, nil
 	}
 	return "", nil
@@ -42,7 +42,7 @@ func shortVerboseFormat(event TestEvent, exec *Execution) (string, error) {
 	formatTest := func() string {
 		return fmt.Sprintf("%s %s.%s %s\n",
 			result,
-			relativePackagePath(event.Package),
+			RelativePackagePath(event.Package),
 			event.Test,
 			event.ElapsedFormatted())
 	}
@@ -57,7 +57,7 @@ func shortVerboseFormat(event TestEvent, exec *Execution) (string, error) {
 			result = colorEvent(event)("EMPTY")
 			fallthrough
 		case ActionPass, ActionFail:
-			return fmt.Sprintf("%s %s\n", result, relativePackagePath(event.Package)), nil
+			return fmt.Sprintf("%s %s\n", result, RelativePackagePath(event.Package)), nil
 		}
 
 	case event.Action == ActionFail:
@@ -96,31 +96,58 @@ func all(cond ...bool) bool {
 	return true
 }
 
-func shortFormat(event TestEvent, _ *Execution) (string, error) {
-	if !event.PackageEvent() {
-		return "", nil
-	}
-	fmtElapsed := func() string {
-		d := elapsedDuration(event.Elapsed)
-		if d == 0 {
-			return ""
+func shortFormat(event TestEvent, exec *Execution) (string, error) {
+	return newShortFormat(shortSymbols, nil)(event, exec)
+}
+
+// shortResults are the strings used by the short format to indicate the
+// result of a package.
+type shortResults struct {
+	skip string
+	pass string
+	fail string
+}
+
+var shortSymbols = shortResults{skip: "∅", pass: "✓", fail: "✖"}
+
+var shortWords = shortResults{skip: "EMPTY", pass: "PASS ", fail: "FAIL "}
+
+// newShortFormat returns the short format. flakeRates are printed after the
+// elapsed time of each package with a rate.
+func newShortFormat(results shortResults, flakeRates map[string]float64) EventFormatter {
+	return func(event TestEvent, _ *Execution) (string, error) {
+		if !event.PackageEvent() {
+			return "", nil
 		}
-		return fmt.Sprintf(" (%s)", d)
-	}
-	fmtEvent := func(action string) (string, error) {
-		return fmt.Sprintf("%s  %s%s\n",
-			action, relativePackagePath(event.Package), fmtElapsed()), nil
-	}
-	withColor := colorEvent(event)
-	switch event.Action {
-	case ActionSkip:
-		return fmtEvent(withColor("∅"))
-	case ActionPass:
-		return fmtEvent(withColor("✓"))
-	case ActionFail:
-		return fmtEvent(withColor("✖"))
+		fmtElapsed := func() string {
+			d := elapsedDuration(event.Elapsed)
+			if d == 0 {
+				return ""
+			}
+			return fmt.Sprintf(" (%s)", d)
+		}
+		fmtFlakeRate := func() string {
+			rate, ok := flakeRates[event.Package]
+			if !ok {
+				return ""
+			}
+			return " " + FormatFlakeRate(rate)
+		}
+		fmtEvent := func(action string) (string, error) {
+			return fmt.Sprintf("%s  %s%s%s\n",
+				action, RelativePackagePath(event.Package), fmtElapsed(), fmtFlakeRate()), nil
+		}
+		withColor := colorEvent(event)
+		switch event.Action {
+		case ActionSkip:
+			return fmtEvent(withColor(results.skip))
+		case ActionPass:
+			return fmtEvent(withColor(results.pass))
+		case ActionFail:
+			return fmtEvent(withColor(results.fail))
+		}
+		return "", nil
 	}
-	return "", nil
 }
 
 func dotsFormat(event TestEvent, exec *Execution) (string, error) {
@@ -131,7 +158,7 @@ func dotsFormat(event TestEvent, exec *Execution) (string, error) {
 	case event.PackageEvent():
 		return "", nil
 	case event.Action == ActionRun && pkg.Total == 1:
-		return "[" + relativePackagePath(event.Package) + "]", nil
+		return "[" + RelativePackagePath(event.Package) + "]", nil
 	case event.Action == ActionPass:
 		return withColor("·"), nil
 	case event.Action == ActionFail:
@@ -154,8 +181,65 @@ func colorEvent(event TestEvent) func(format string, a ...interface{}) string {
 	return color.WhiteString
 }
 
+// FormatOptions used by NewEventFormatterWithOptions.
+type FormatOptions struct {
+	// Accessible replaces symbols with words, and formats which rewrite or
+	// append to a line with formats which print one line for each event.
+	Accessible bool
+	// FlakeRates are the fraction of previous runs in which each package had
+	// a flaky failure. The short format prints the rate next to each package
+	// with a rate.
+	FlakeRates map[string]float64
+	// Timestamps selects the timestamp printed at the start of each line.
+	// Defaults to TimestampNone.
+	Timestamps TimestampFormat
+	// Hyperlinks, when set, adds terminal hyperlinks to the file and line
+	// references in the output of tests.
+	Hyperlinks *Hyperlinks
+	// VerboseAfterFailure switches to the standard-verbose format after the
+	// first test or package fails.
+	VerboseAfterFailure bool
+	// SubtestCounts adds the number of subtests which passed, failed, and
+	// were skipped to the line printed for each test with subtests.
+	SubtestCounts bool
+}
+
+// FormatFlakeRate formats the fraction of runs with a flaky failure as an
+// approximate percent, for example ~3%.
+func FormatFlakeRate(rate float64) string {
+	percent := rate * 100
+	if percent > 0 && percent < 0.5 {
+		return "~<1%"
+	}
+	return fmt.Sprintf("~%.0f%%", percent)
+}
+
 // NewEventFormatter returns a formatter for printing events.
 func NewEventFormatter(format string) EventFormatter {
+	return NewEventFormatterWithOptions(format, FormatOptions{})
+}
+
+// NewEventFormatterWithOptions returns a formatter for printing events, using
+// the options to modify the output of the format.
+func NewEventFormatterWithOptions(format string, opts FormatOptions) EventFormatter {
+	formatter := newEventFormatter(format, opts)
+	if formatter == nil {
+		return nil
+	}
+	formatter = withSubtestCounts(formatter, opts.SubtestCounts)
+	formatter = withVerboseAfterFailure(formatter, opts.VerboseAfterFailure)
+	return withTimestamps(withHyperlinks(formatter, opts.Hyperlinks), opts.Timestamps)
+}
+
+func newEventFormatter(format string, opts FormatOptions) EventFormatter {
+	if opts.Accessible {
+		switch format {
+		case "dots":
+			return shortVerboseFormat
+		case "short":
+			return newShortFormat(shortWords, opts.FlakeRates)
+		}
+	}
 	switch format {
 	case "debug":
 		return debugFormat
@@ -168,7 +252,7 @@ func NewEventFormatter(format string) EventFormatter {
 	case "short-verbose":
 		return shortVerboseFormat
 	case "short":
-		return shortFormat
+		return newShortFormat(shortSymbols, opts.FlakeRates)
 	default:
 		return nil
 	}
@@ -2,9 +2,11 @@ package testjson
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/fatih/color"
 	gocmp "github.com/google/go-cmp/cmp"
 	"gotest.tools/assert"
 	"gotest.tools/assert/opt"
@@ -90,10 +92,12 @@ var expectedExecution = &Execution{
 				{Test: "TestSkipped"},
 				{Test: "TestSkippedWitLog"},
 			},
-			action: ActionFail,
+			action:  ActionFail,
+			elapsed: 11 * time.Millisecond,
 		},
 		"github.com/gotestyourself/gotestyourself/testjson/internal/badmain": {
-			action: ActionFail,
+			action:  ActionFail,
+			elapsed: 10 * time.Millisecond,
 		},
 	},
 }
@@ -101,12 +105,23 @@ var expectedExecution = &Execution{
 var cmpExecutionShallow = gocmp.Options{
 	gocmp.AllowUnexported(Execution{}, Package{}),
 	gocmp.FilterPath(stringPath("started"), opt.TimeWithThreshold(10*time.Second)),
+	gocmp.FilterPath(stringPath("buildOutput"), gocmp.Ignore()),
+	gocmp.FilterPath(stringPath("warningsLock"), gocmp.Ignore()),
+	gocmp.FilterPath(stringPath("firstEvent"), gocmp.Ignore()),
+	gocmp.FilterPath(stringPath("lastEvent"), gocmp.Ignore()),
 	cmpPackageShallow,
 }
 
 var cmpPackageShallow = gocmp.Options{
 	// TODO: use opt.PathField(Package{}, "output")
 	gocmp.FilterPath(stringPath("packages.output"), gocmp.Ignore()),
+	gocmp.FilterPath(stringPath("packages.running"), gocmp.Ignore()),
+	gocmp.FilterPath(stringPath("packages.assertions"), gocmp.Ignore()),
+	gocmp.FilterPath(stringPath("packages.errorLines"), gocmp.Ignore()),
+	gocmp.FilterPath(stringPath("packages.subtests"), gocmp.Ignore()),
+	gocmp.FilterPath(stringPath("packages.results"), gocmp.Ignore()),
+	gocmp.FilterPath(stringPath("packages.started"), gocmp.Ignore()),
+	gocmp.FilterPath(stringPath("packages.lifecycle"), gocmp.Ignore()),
 	gocmp.FilterPath(stringPath("packages.Passed"), gocmp.Ignore()),
 	gocmp.Comparer(func(x, y TestCase) bool {
 		return x.Test == y.Test
@@ -166,3 +181,139 @@ func TestScanTestOutputWithStandardQuietFormat(t *testing.T) {
 	golden.Assert(t, shim.err.String(), "standard-quiet-format.err")
 	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
 }
+
+func TestScanTestOutputWithAccessibleShortFormat(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	formatter := NewEventFormatterWithOptions("short", FormatOptions{Accessible: true})
+	shim := newFakeHandler(formatter, "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "short-format-accessible.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestShortFormat_FlakeRates(t *testing.T) {
+	formatter := NewEventFormatterWithOptions("short", FormatOptions{
+		FlakeRates: map[string]float64{"example.com/flaky": 0.125},
+	})
+
+	out, err := formatter(TestEvent{Action: ActionFail, Package: "example.com/flaky", Elapsed: 0.5}, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, out, color.RedString("✖")+"  example.com/flaky (500ms) ~12%\n")
+
+	out, err = formatter(TestEvent{Action: ActionPass, Package: "example.com/stable"}, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, out, color.GreenString("✓")+"  example.com/stable\n")
+}
+
+func TestFormatFlakeRate(t *testing.T) {
+	assert.Equal(t, FormatFlakeRate(0.03), "~3%")
+	assert.Equal(t, FormatFlakeRate(0.001), "~<1%")
+	assert.Equal(t, FormatFlakeRate(1), "~100%")
+}
+
+func TestNewEventFormatterWithOptions_Timestamps(t *testing.T) {
+	exec := NewExecution()
+	exec.started = time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	event := TestEvent{
+		Action:  ActionOutput,
+		Package: "example.com/pkg",
+		Test:    "TestOne",
+		Time:    exec.started.Add(1500 * time.Millisecond),
+		Output:  "one\n",
+	}
+
+	formatter := NewEventFormatterWithOptions("standard-verbose", FormatOptions{Timestamps: TimestampRelative})
+	out, err := formatter(event, exec)
+	assert.NilError(t, err)
+	assert.Equal(t, out, "    1.500s one\n")
+
+	formatter = NewEventFormatterWithOptions("standard-verbose", FormatOptions{Timestamps: TimestampISO})
+	out, err = formatter(event, exec)
+	assert.NilError(t, err)
+	assert.Equal(t, out, "2021-02-03T04:05:07.500Z one\n")
+
+	t.Run("appended lines", func(t *testing.T) {
+		formatter := withTimestamps(func(event TestEvent, _ *Execution) (string, error) {
+			return event.Output, nil
+		}, TimestampISO)
+		var lines []string
+		for _, output := range []string{"pkg ", "..", ".\nnext\n"} {
+			event.Output = output
+			out, err := formatter(event, exec)
+			assert.NilError(t, err)
+			lines = append(lines, out)
+		}
+		assert.DeepEqual(t, lines, []string{
+			"2021-02-03T04:05:07.500Z pkg ",
+			"..",
+			".\n2021-02-03T04:05:07.500Z next\n",
+		})
+	})
+}
+
+func TestNewEventFormatterWithOptions_VerboseAfterFailure(t *testing.T) {
+	events := []TestEvent{
+		{Action: ActionRun, Package: "pkg", Test: "TestOne"},
+		{Action: ActionOutput, Package: "pkg", Test: "TestOne", Output: "=== RUN   TestOne\n"},
+		{Action: ActionPass, Package: "pkg", Test: "TestOne"},
+		{Action: ActionRun, Package: "pkg", Test: "TestTwo"},
+		{Action: ActionOutput, Package: "pkg", Test: "TestTwo", Output: "=== RUN   TestTwo\n"},
+		{Action: ActionOutput, Package: "pkg", Test: "TestTwo", Output: "    two_test.go:9: broken\n"},
+		{Action: ActionFail, Package: "pkg", Test: "TestTwo"},
+		{Action: ActionRun, Package: "pkg", Test: "TestThree"},
+		{Action: ActionOutput, Package: "pkg", Test: "TestThree", Output: "=== RUN   TestThree\n"},
+		{Action: ActionPass, Package: "pkg", Test: "TestThree"},
+	}
+
+	exec := NewExecution()
+	formatter := NewEventFormatterWithOptions("dots", FormatOptions{VerboseAfterFailure: true})
+	var out strings.Builder
+	for _, event := range events {
+		exec.add(event)
+		text, err := formatter(event, exec)
+		assert.NilError(t, err)
+		out.WriteString(text)
+	}
+	expected := "[pkg]" + color.GreenString("·") + color.RedString("✖") + "\n=== RUN   TestTwo\n    two_test.go:9: broken\n=== RUN   TestThree\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestNewEventFormatterWithOptions_SubtestCounts(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	events := []TestEvent{
+		{Action: ActionRun, Package: "example.com/pkg", Test: "TestTable"},
+		{Action: ActionRun, Package: "example.com/pkg", Test: "TestTable/one"},
+		{Action: ActionPass, Package: "example.com/pkg", Test: "TestTable/one"},
+		{Action: ActionRun, Package: "example.com/pkg", Test: "TestTable/two"},
+		{Action: ActionSkip, Package: "example.com/pkg", Test: "TestTable/two"},
+		{Action: ActionRun, Package: "example.com/pkg", Test: "TestTable/three"},
+		{Action: ActionSkip, Package: "example.com/pkg", Test: "TestTable/three"},
+		{Action: ActionPass, Package: "example.com/pkg", Test: "TestTable"},
+		{Action: ActionRun, Package: "example.com/pkg", Test: "TestPlain"},
+		{Action: ActionPass, Package: "example.com/pkg", Test: "TestPlain"},
+	}
+
+	exec := NewExecution()
+	formatter := NewEventFormatterWithOptions("short-verbose", FormatOptions{SubtestCounts: true})
+	var out strings.Builder
+	for _, event := range events {
+		exec.add(event)
+		text, err := formatter(event, exec)
+		assert.NilError(t, err)
+		out.WriteString(text)
+	}
+	pass := color.GreenString("PASS")
+	expected := pass + " pkg.TestTable/one (0.00s)\n" +
+		pass + " pkg.TestTable (0.00s) (1 passed, 2 skipped)\n" +
+		pass + " pkg.TestPlain (0.00s)\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestTimestampFormat_IsValid(t *testing.T) {
+	assert.Assert(t, TimestampFormat("").IsValid())
+	assert.Assert(t, TimestampClock.IsValid())
+	assert.Assert(t, !TimestampFormat("unix").IsValid())
+}
@@ -0,0 +1,107 @@
+package testjson
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// DefaultHyperlinkURL is the URL template used by Hyperlinks when the
+// template is empty.
+const DefaultHyperlinkURL = "file://{{ .Path }}"
+
+// Hyperlinks adds terminal hyperlinks (OSC 8) to the file and line references
+// in the output of tests, so that a terminal which supports them can open the
+// file in an editor.
+type Hyperlinks struct {
+	// URL is executed with a HyperlinkData to create the URL of each link.
+	URL *template.Template
+}
+
+// HyperlinkData is the data used to execute Hyperlinks.URL.
+type HyperlinkData struct {
+	// Path is the absolute path of the file.
+	Path string
+	// Line is the line number in the file.
+	Line int
+}
+
+// NewHyperlinks parses the URL template of the links. An empty template uses
+// DefaultHyperlinkURL.
+func NewHyperlinks(url string) (*Hyperlinks, error) {
+	if url == "" {
+		url = DefaultHyperlinkURL
+	}
+	tmpl, err := template.New("hyperlink").Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	return &Hyperlinks{URL: tmpl}, nil
+}
+
+// fileReference matches a reference to a line of a Go file, as written by
+// t.Error, or in a stack trace.
+var fileReference = regexp.MustCompile(`(^|\s)([^\s:]+\.go):(\d+)`)
+
+// Link adds a hyperlink to each file and line reference in text, which is
+// the output of the package pkg. A relative file name is a file in the
+// directory of the package, so a relative file name from a package outside of
+// the module of the working directory is not changed.
+func (h *Hyperlinks) Link(pkg string, text string) string {
+	if h == nil || !strings.Contains(text, ".go:") {
+		return text
+	}
+	return fileReference.ReplaceAllStringFunc(text, func(ref string) string {
+		match := fileReference.FindStringSubmatch(ref)
+		prefix, file := match[1], match[2]
+		line, err := strconv.Atoi(match[3])
+		if err != nil {
+			return ref
+		}
+		file, ok := resolveFileReference(pkg, file)
+		if !ok {
+			return ref
+		}
+		var url strings.Builder
+		if err := h.URL.Execute(&url, HyperlinkData{Path: filepath.ToSlash(file), Line: line}); err != nil {
+			return ref
+		}
+		return prefix + osc8(url.String(), ref[len(prefix):])
+	})
+}
+
+// resolveFileReference returns the absolute path of a file referenced in the
+// output of the package pkg. A relative file name is a file in the directory
+// of the package. Returns false if the directory of the package is not known.
+func resolveFileReference(pkg string, file string) (string, bool) {
+	if filepath.IsAbs(file) {
+		return file, true
+	}
+	dir := PackageDir(pkg)
+	if dir == "" {
+		return "", false
+	}
+	return filepath.Join(dir, file), true
+}
+
+// osc8 returns text as a terminal hyperlink to url.
+func osc8(url, text string) string {
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// withHyperlinks returns a formatter which adds hyperlinks to the output of
+// tests printed by formatter.
+func withHyperlinks(formatter EventFormatter, hyperlinks *Hyperlinks) EventFormatter {
+	if hyperlinks == nil {
+		return formatter
+	}
+	return func(event TestEvent, exec *Execution) (string, error) {
+		text, err := formatter(event, exec)
+		if err != nil {
+			return text, err
+		}
+		return hyperlinks.Link(event.Package, text), nil
+	}
+}
@@ -0,0 +1,49 @@
+package testjson
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestHyperlinks_Link(t *testing.T) {
+	pkg := "gotest.tools/gotestsum/testjson"
+	dir := PackageDir(pkg)
+	assert.Assert(t, filepath.IsAbs(dir), dir)
+	assert.Equal(t, filepath.Base(dir), "testjson")
+
+	hyperlinks, err := NewHyperlinks("vscode://file{{ .Path }}:{{ .Line }}")
+	assert.NilError(t, err)
+
+	t.Run("relative file", func(t *testing.T) {
+		url := "vscode://file" + filepath.ToSlash(filepath.Join(dir, "format_test.go")) + ":42"
+		out := hyperlinks.Link(pkg, "    format_test.go:42: expected 1\n")
+		expected := "    \x1b]8;;" + url + "\x1b\\format_test.go:42\x1b]8;;\x1b\\: expected 1\n"
+		assert.Equal(t, out, expected)
+	})
+	t.Run("absolute file in stack trace", func(t *testing.T) {
+		out := hyperlinks.Link("example.com/other", "\t/src/other/one_test.go:9 +0x1d\n")
+		expected := "\t\x1b]8;;vscode://file/src/other/one_test.go:9\x1b\\/src/other/one_test.go:9\x1b]8;;\x1b\\ +0x1d\n"
+		assert.Equal(t, out, expected)
+	})
+	t.Run("package outside of the module", func(t *testing.T) {
+		text := "    one_test.go:9: failed\n"
+		assert.Equal(t, hyperlinks.Link("example.com/other", text), text)
+	})
+	t.Run("nil", func(t *testing.T) {
+		var hyperlinks *Hyperlinks
+		text := "    format_test.go:42: expected 1\n"
+		assert.Equal(t, hyperlinks.Link(pkg, text), text)
+	})
+}
+
+func TestNewHyperlinks(t *testing.T) {
+	hyperlinks, err := NewHyperlinks("")
+	assert.NilError(t, err)
+	out := hyperlinks.Link("example.com/other", "\t/src/one_test.go:9\n")
+	assert.Equal(t, out, "\t\x1b]8;;file:///src/one_test.go\x1b\\/src/one_test.go:9\x1b]8;;\x1b\\\n")
+
+	_, err = NewHyperlinks("{{ .Path")
+	assert.ErrorContains(t, err, "unclosed action")
+}
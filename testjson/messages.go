@@ -0,0 +1,171 @@
+package testjson
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Messages is a catalog of the human readable text printed by PrintSummary.
+// Fields which accept a count or duration are fmt format strings.
+type Messages struct {
+	HeadingSkipped string
+	HeadingFailed  string
+	HeadingErrors  string
+	HeadingNotRun  string
+	// HeadingFlaky is the heading of the failed runs of tests which passed
+	// when they were run again, printed with SummaryOptions.Flaky.
+	HeadingFlaky string
+	// HeadingSkipCategories is the heading of the number of skipped tests in
+	// each of SummaryOptions.SkipCategories.
+	HeadingSkipCategories string
+	// HeadingWarnings is the heading of SummaryOptions.Warnings.
+	HeadingWarnings string
+	// HeadingUncoveredChanges is the heading of
+	// SummaryOptions.UncoveredChanges.
+	HeadingUncoveredChanges string
+	// NotInCoverageProfile is the line of SummaryOptions.UncoveredChanges of
+	// a changed file which is not in the coverage profile. The argument is the
+	// name of the file.
+	NotInCoverageProfile string
+	// UncoveredChangedLines is the line of SummaryOptions.UncoveredChanges of
+	// a changed file with lines which were not covered. The arguments are the
+	// name of the file, the number of lines which were not covered, the
+	// number of changed lines, and the ranges of lines which were not
+	// covered.
+	UncoveredChangedLines string
+	// Done is the first word of the final line of the summary.
+	Done string
+	// Separator is printed between each of the counts on the final line.
+	Separator string
+	Tests     string
+	Skipped   string
+	Failure   string
+	Failures  string
+	NotRun    string
+	Flaky     string
+	Error     string
+	Errors    string
+	// Elapsed is printed at the end of the final line with the duration of
+	// the run.
+	Elapsed string
+	// Timing is printed after the final line when SummaryOptions.Timing is
+	// enabled. The arguments are the elapsed time of the run, the cumulative
+	// time of all packages, and the speedup from running packages in parallel.
+	Timing string
+	// SameBuildFailure is printed after a package which failed to build, when
+	// other packages failed with the same build output. The arguments are the
+	// number of other packages, and the list of their names.
+	SameBuildFailure string
+	// SameSkipMessage is printed after a skipped test, when other tests were
+	// skipped with the same message. The arguments are the number of other
+	// tests, and a sample of their names.
+	SameSkipMessage string
+	// SameSkipMessageOne is printed in place of SameSkipMessage when only one
+	// other test was skipped with the same message.
+	SameSkipMessageOne string
+}
+
+// EnglishMessages is the default Messages catalog.
+var EnglishMessages = Messages{
+	HeadingSkipped:          "Skipped",
+	HeadingFailed:           "Failed",
+	HeadingErrors:           "Errors",
+	HeadingNotRun:           "Not run",
+	HeadingFlaky:            "Flaky",
+	HeadingSkipCategories:   "Skipped by category",
+	HeadingWarnings:         "Warnings",
+	HeadingUncoveredChanges: "Uncovered changes",
+	NotInCoverageProfile:    "%s: not in the coverage profile",
+	UncoveredChangedLines:   "%s: %d of %d changed lines not covered: %s",
+	Done:                    "DONE",
+	Separator:               ", ",
+	Tests:                   "%d tests",
+	Skipped:                 "%d skipped",
+	Failure:                 "%d failure",
+	Failures:                "%d failures",
+	NotRun:                  "%d not run",
+	Flaky:                   "%d flaky",
+	Error:                   "%d error",
+	Errors:                  "%d errors",
+	Elapsed:                 " in %s",
+	Timing:                  "TIME %s elapsed, %s cumulative, %.2fx speedup",
+	SameBuildFailure:        "the same build failure in %d more packages: %s",
+	SameSkipMessage:         "the same skip message in %d more tests: %s",
+	SameSkipMessageOne:      "the same skip message in %d more test: %s",
+}
+
+// JapaneseMessages is the Japanese Messages catalog.
+var JapaneseMessages = Messages{
+	HeadingSkipped:          "スキップ",
+	HeadingFailed:           "失敗",
+	HeadingErrors:           "エラー",
+	HeadingNotRun:           "未実行",
+	HeadingFlaky:            "不安定",
+	HeadingSkipCategories:   "カテゴリ別スキップ",
+	HeadingWarnings:         "警告",
+	HeadingUncoveredChanges: "未カバーの変更",
+	NotInCoverageProfile:    "%s: カバレッジプロファイルにありません",
+	UncoveredChangedLines:   "%[1]s: 変更された %[3]d 行のうち %[2]d 行が未カバー: %[4]s",
+	Done:                    "完了",
+	Separator:               "、",
+	Tests:                   "テスト %d 件",
+	Skipped:                 "スキップ %d 件",
+	Failure:                 "失敗 %d 件",
+	Failures:                "失敗 %d 件",
+	NotRun:                  "未実行 %d 件",
+	Flaky:                   "不安定 %d 件",
+	Error:                   "エラー %d 件",
+	Errors:                  "エラー %d 件",
+	Elapsed:                 "（%s）",
+	Timing:                  "時間 経過 %s、累計 %s、並列化による高速化 %.2f 倍",
+	SameBuildFailure:        "同じビルド失敗 他 %d パッケージ: %s",
+	SameSkipMessage:         "同じスキップ理由 他 %d 件: %s",
+	SameSkipMessageOne:      "同じスキップ理由 他 %d 件: %s",
+}
+
+var catalogs = map[string]Messages{
+	"en": EnglishMessages,
+	"ja": JapaneseMessages,
+}
+
+// NewMessages returns the Messages catalog for a language, found with
+// Language. If there is no catalog for the language, returns false for the
+// second value.
+func NewMessages(lang string) (Messages, bool) {
+	lang, ok := Language(lang)
+	return catalogs[lang], ok
+}
+
+// Language returns the language from Languages which matches lang. The
+// language may be a two letter code (ex: ja), or a locale (ex: ja_JP.UTF-8).
+// If there is no catalog for the language, returns false for the second value.
+func Language(lang string) (string, bool) {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "_-."); i >= 0 {
+		lang = lang[:i]
+	}
+	_, ok := catalogs[lang]
+	return lang, ok
+}
+
+// Languages returns the sorted list of languages which have a catalog.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func (m Messages) count(count int, one, many string) string {
+	switch count {
+	case 0:
+		return ""
+	case 1:
+		return m.Separator + fmt.Sprintf(one, count)
+	default:
+		return m.Separator + fmt.Sprintf(many, count)
+	}
+}
@@ -9,15 +9,56 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 )
 
-func relativePackagePath(pkgpath string) string {
+// RelativePackagePath returns a package path relative to the module (or
+// GOPATH) of the working directory. Packages outside of the module are
+// returned unmodified.
+func RelativePackagePath(pkgpath string) string {
 	if pkgpath == pkgPathPrefix {
 		return "."
 	}
 	return strings.TrimPrefix(pkgpath, pkgPathPrefix+"/")
 }
 
+// PackageDir returns the directory of a package in the module (or GOPATH) of
+// the working directory, or an empty string for a package outside of it.
+func PackageDir(pkgpath string) string {
+	rel := RelativePackagePath(pkgpath)
+	if pkgPathPrefix == "" || rel == pkgpath {
+		return ""
+	}
+	pkgPathRootOnce.Do(func() {
+		pkgPathRoot = getPkgPathRoot()
+	})
+	if pkgPathRoot == "" {
+		return ""
+	}
+	return filepath.Join(pkgPathRoot, filepath.FromSlash(rel))
+}
+
+var (
+	pkgPathRootOnce sync.Once
+	// pkgPathRoot is the directory of the package path pkgPathPrefix.
+	pkgPathRoot string
+)
+
+// getPkgPathRoot returns the directory of the package path returned by
+// getPkgPathPrefix.
+func getPkgPathRoot() string {
+	cwd, _ := os.Getwd()
+	if isGoModuleEnabled() {
+		if filename := goModuleFilePath(cwd); filename != "" && getPkgPathPrefixFromGoModule(cwd) != "" {
+			return filepath.Dir(filename)
+		}
+	}
+	if getPkgPathPrefixGoPath(cwd) != "" {
+		return cwd
+	}
+	return ""
+}
+
 func getPkgPathPrefix() string {
 	cwd, _ := os.Getwd()
 	if isGoModuleEnabled() {
@@ -0,0 +1,60 @@
+package testjson
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxLineSize is the size of the longest line read from the stdout or
+// stderr of go test, when ScanConfig.MaxLineSize is not set. Test output may
+// include very long lines, like a JSON document logged by a test, and go test
+// -json writes each line of output as a single event.
+const DefaultMaxLineSize = 64 * 1024 * 1024
+
+// lineReader reads lines of any length, up to a maximum size.
+type lineReader struct {
+	reader *bufio.Reader
+	max    int
+}
+
+func newLineReader(in io.Reader, max int) *lineReader {
+	if max <= 0 {
+		max = DefaultMaxLineSize
+	}
+	return &lineReader{reader: bufio.NewReader(in), max: max}
+}
+
+// readLine returns the next line, without the line ending. A line longer than
+// the maximum size is truncated, and dropped is the number of bytes which were
+// discarded from the end of the line. Returns io.EOF when there are no more
+// lines.
+func (r *lineReader) readLine() (line []byte, dropped int, err error) {
+	for {
+		chunk, err := r.reader.ReadSlice('\n')
+		if err == nil {
+			chunk = bytes.TrimSuffix(chunk, []byte("\n"))
+		}
+		if room := r.max - len(line); len(chunk) > room {
+			dropped += len(chunk) - room
+			chunk = chunk[:room]
+		}
+		line = append(line, chunk...)
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && len(line) == 0 && dropped == 0:
+			return nil, 0, io.EOF
+		case err != nil && err != io.EOF:
+			return nil, 0, err
+		}
+		return bytes.TrimSuffix(line, []byte("\r")), dropped, nil
+	}
+}
+
+// truncatedMessage is appended to a line which was truncated.
+func truncatedMessage(dropped int) string {
+	return fmt.Sprintf("... [truncated %d bytes]", dropped)
+}
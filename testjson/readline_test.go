@@ -0,0 +1,85 @@
+package testjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestLineReader_ReadLine(t *testing.T) {
+	reader := newLineReader(strings.NewReader("one\r\ntwo\nthree is long\nfour"), 8)
+
+	var lines []string
+	var drops []int
+	for {
+		line, dropped, err := reader.readLine()
+		if err == io.EOF {
+			break
+		}
+		assert.NilError(t, err)
+		lines = append(lines, string(line))
+		drops = append(drops, dropped)
+	}
+	assert.DeepEqual(t, lines, []string{"one", "two", "three is", "four"})
+	assert.DeepEqual(t, drops, []int{0, 0, 5, 0})
+}
+
+func TestScanTestOutput_LongLines(t *testing.T) {
+	const size = 10 * 1024 * 1024
+	long := strings.Repeat("x", size) + "\n"
+	raw, err := json.Marshal(TestEvent{
+		Action:  ActionOutput,
+		Package: "pkg",
+		Test:    "TestLong",
+		Output:  long,
+	})
+	assert.NilError(t, err)
+	stdout := strings.Join([]string{
+		`{"Action":"run","Package":"pkg","Test":"TestLong"}`,
+		string(raw),
+		`{"Action":"fail","Package":"pkg","Test":"TestLong"}`,
+		`{"Action":"fail","Package":"pkg"}`,
+	}, "\n")
+	stderr := strings.Repeat("y", size) + "\n"
+
+	handler := &fakeHandler{formatter: standardVerboseFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  strings.NewReader(stdout),
+		Stderr:  strings.NewReader(stderr),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(exec.Package("pkg").Failed), 1)
+	assert.Equal(t, exec.Output("pkg", "TestLong"), long)
+	assert.Equal(t, handler.out.String(), long)
+	assert.Equal(t, len(exec.Errors()), 1)
+	assert.Equal(t, len(exec.Errors()[0]), size)
+}
+
+func TestScanTestOutput_TruncatedLines(t *testing.T) {
+	const size = 10 * 1024 * 1024
+	event := `{"Action":"output","Package":"pkg","Output":"` + strings.Repeat("x", size) + `"}`
+	stdout := event + "\n" + `{"Action":"pass","Package":"pkg"}` + "\n"
+	stderr := strings.Repeat("y", size) + "\n"
+
+	handler := &fakeHandler{formatter: standardVerboseFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:      strings.NewReader(stdout),
+		Stderr:      strings.NewReader(stderr),
+		Handler:     handler,
+		MaxLineSize: 1024 * 1024,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, exec.Package("pkg").Result(), ActionPass)
+	assert.DeepEqual(t, exec.Discrepancies(), []string{"1 lines of output were not valid test2json events"})
+
+	errors := exec.Errors()
+	assert.Equal(t, len(errors), 1)
+	assert.Assert(t, strings.HasSuffix(errors[0], "y"+truncatedMessage(size-1024*1024)))
+	assert.Assert(t, strings.Contains(handler.err.String(), "event longer than 1048576 bytes"))
+	assert.Assert(t, strings.Contains(handler.err.String(), truncatedMessage(len(event)-1024*1024)))
+}
@@ -0,0 +1,67 @@
+package testjson
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// RewriteRule replaces the text which matches Pattern in the output of tests.
+type RewriteRule struct {
+	Pattern *regexp.Regexp
+	// Replacement is the text used for each match, which may refer to the
+	// groups of Pattern, as in regexp.Regexp.ReplaceAllString.
+	Replacement string
+}
+
+// RewriteRules are applied, in order, to the output of every event, and every
+// line of stderr, before they are added to the Execution or handled by the
+// EventHandler, so that every report uses the same text. For example, a rule
+// can replace a random container ID, or an absolute path in the output.
+type RewriteRules []RewriteRule
+
+// Apply returns text with each rule applied, in order.
+func (r RewriteRules) Apply(text string) string {
+	for _, rule := range r {
+		text = rule.Pattern.ReplaceAllString(text, rule.Replacement)
+	}
+	return text
+}
+
+// rewriteEvent applies the rules to the output of event. The raw JSON of an
+// event with output which was changed is encoded again, so that it matches
+// the event.
+func (r RewriteRules) rewriteEvent(event TestEvent) TestEvent {
+	if len(r) == 0 || event.Output == "" {
+		return event
+	}
+	output := r.Apply(event.Output)
+	if output == event.Output {
+		return event
+	}
+	event.Output = output
+	if raw, err := rewriteRawOutput(event.raw, output); err == nil {
+		event.raw = raw
+	}
+	return event
+}
+
+// rewriteRawOutput replaces the Output of the raw JSON event. Other fields,
+// including fields which are not part of TestEvent, are kept. The name of the
+// field is matched without case, like json.Unmarshal.
+func rewriteRawOutput(raw []byte, output string) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+	for name := range fields {
+		if strings.EqualFold(name, "Output") {
+			fields[name] = value
+		}
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,58 @@
+package testjson
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// recordingHandler records the raw events and the stderr lines.
+type recordingHandler struct {
+	events []string
+	lines  []string
+}
+
+func (h *recordingHandler) Event(event TestEvent, _ *Execution) error {
+	h.events = append(h.events, string(event.Bytes()))
+	return nil
+}
+
+func (h *recordingHandler) Err(text string) error {
+	h.lines = append(h.lines, text)
+	return nil
+}
+
+func TestScanTestOutput_Rewrite(t *testing.T) {
+	stdout := strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"    one_test.go:9: container 3f2a9c1b0d7e exited\n"}
+{"Action":"fail","Package":"pkg","Test":"TestOne"}
+{"Action":"fail","Package":"pkg"}
+`)
+	stderr := strings.NewReader("/home/ci/src/pkg/one.go:3: broken\n")
+	handler := &recordingHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Handler: handler,
+		Rewrite: RewriteRules{
+			{Pattern: regexp.MustCompile(`container [0-9a-f]{12}`), Replacement: "container ID"},
+			{Pattern: regexp.MustCompile(`/home/ci/src/(\S+)`), Replacement: "./$1"},
+		},
+	})
+	assert.NilError(t, err)
+
+	assert.Equal(t, exec.Output("pkg", "TestOne"), "    one_test.go:9: container ID exited\n")
+	assert.Equal(t, handler.events[1],
+		`{"Action":"output","Output":"    one_test.go:9: container ID exited\n","Package":"pkg","Test":"TestOne"}`)
+	assert.Equal(t, handler.events[0], `{"Action":"run","Package":"pkg","Test":"TestOne"}`)
+	assert.DeepEqual(t, handler.lines, []string{"./pkg/one.go:3: broken"})
+	assert.DeepEqual(t, exec.Errors(), []string{"./pkg/one.go:3: broken"})
+}
+
+func TestRewriteRawOutput_FieldCase(t *testing.T) {
+	raw, err := rewriteRawOutput([]byte(`{"action":"output","output":"before\n"}`), "after\n")
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), `{"action":"output","output":"after\n"}`)
+}
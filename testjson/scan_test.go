@@ -0,0 +1,146 @@
+package testjson
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+// countingHandler counts the events and stderr lines, and returns err from
+// Event.
+type countingHandler struct {
+	events int
+	lines  int
+	err    error
+	// onErr is called for each line of stderr.
+	onErr func(lines int)
+}
+
+func (h *countingHandler) Event(TestEvent, *Execution) error {
+	h.events++
+	return h.err
+}
+
+func (h *countingHandler) Err(string) error {
+	h.lines++
+	if h.onErr != nil {
+		h.onErr(h.lines)
+	}
+	return nil
+}
+
+func TestScanTestOutput_StderrBurstDoesNotWaitForStdout(t *testing.T) {
+	const lines = 10 * scanBufferLines
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+
+	allLines := make(chan struct{})
+	handler := &countingHandler{onErr: func(n int) {
+		if n == lines {
+			close(allLines)
+		}
+	}}
+	go func() {
+		for i := 0; i < lines; i++ {
+			fmt.Fprintf(stderrWriter, "line %d\n", i)
+		}
+		stderrWriter.Close() // nolint: errcheck
+	}()
+	go func() {
+		// stdout is open, but has no events, until every line of stderr was
+		// handled
+		select {
+		case <-allLines:
+		case <-time.After(10 * time.Second):
+		}
+		fmt.Fprintln(stdoutWriter, `{"Action":"pass","Package":"pkg"}`)
+		stdoutWriter.Close() // nolint: errcheck
+	}()
+
+	exec, err := ScanTestOutput(ScanConfig{Stdout: stdoutReader, Stderr: stderrReader, Handler: handler})
+	assert.NilError(t, err)
+	assert.Equal(t, handler.lines, lines)
+	assert.Equal(t, len(exec.Errors()), lines)
+	assert.Equal(t, handler.events, 1)
+}
+
+func TestScanTestOutput_HandlerErrorDrainsStreams(t *testing.T) {
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	writers := make(chan error, 2)
+	go func() {
+		writers <- writeEvents(stdoutWriter, 5*scanBufferLines)
+	}()
+	go func() {
+		writers <- writeLines(stderrWriter, 5*scanBufferLines)
+	}()
+
+	handler := &countingHandler{err: errors.New("handler failed")}
+	_, err := ScanTestOutput(ScanConfig{Stdout: stdoutReader, Stderr: stderrReader, Handler: handler})
+	assert.Error(t, err, "handler failed")
+	assert.Equal(t, handler.events, 1)
+	// the writers finish because the streams are read until EOF
+	assert.NilError(t, <-writers)
+	assert.NilError(t, <-writers)
+}
+
+// TestScanTestOutput_Soak streams events and stderr lines through pipes at
+// the same time. Set GOTESTSUM_SOAK_BYTES to the number of bytes to write to
+// each pipe, for example 2000000000, to soak test with more data.
+func TestScanTestOutput_Soak(t *testing.T) {
+	size := 8 * 1000 * 1000
+	if value := os.Getenv("GOTESTSUM_SOAK_BYTES"); value != "" {
+		var err error
+		size, err = strconv.Atoi(value)
+		assert.NilError(t, err)
+	}
+	const eventSize = len(`{"Action":"output","Package":"pkg","Test":"TestSoak","Output":"0000000000\n"}` + "\n")
+	const lineSize = len("line 0000000000\n")
+	events, lines := size/eventSize, size/lineSize
+
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	writers := make(chan error, 2)
+	go func() {
+		writers <- writeEvents(stdoutWriter, events)
+	}()
+	go func() {
+		writers <- writeLines(stderrWriter, lines)
+	}()
+
+	handler := &countingHandler{}
+	exec, err := ScanTestOutput(ScanConfig{Stdout: stdoutReader, Stderr: stderrReader, Handler: handler})
+	assert.NilError(t, err)
+	assert.NilError(t, <-writers)
+	assert.NilError(t, <-writers)
+	assert.Equal(t, handler.events, events)
+	assert.Equal(t, handler.lines, lines)
+	assert.Equal(t, len(exec.Errors()), lines)
+}
+
+func writeEvents(out *io.PipeWriter, n int) error {
+	buf := bufio.NewWriter(out)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(buf, `{"Action":"output","Package":"pkg","Test":"TestSoak","Output":"%010d\n"}`+"\n", i)
+	}
+	err := buf.Flush()
+	out.Close() // nolint: errcheck
+	return err
+}
+
+func writeLines(out *io.PipeWriter, n int) error {
+	buf := bufio.NewWriter(out)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(buf, "line %010d\n", i)
+	}
+	err := buf.Flush()
+	out.Close() // nolint: errcheck
+	return err
+}
@@ -0,0 +1,112 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// SkipCategory is a named category of skipped tests. A skipped test is in the
+// category when the Pattern matches the output of the test, which includes the
+// message passed to t.Skip.
+type SkipCategory struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// SkipCategories are used to classify skipped tests. A test is in the first
+// category which matches its output.
+type SkipCategories []SkipCategory
+
+// Category returns the name of the first category which matches the output of
+// the skipped test, or an empty string if no category matches.
+func (c SkipCategories) Category(pkg *Package, test string) string {
+	if len(c) == 0 {
+		return ""
+	}
+	output := pkg.Output(test)
+	for _, category := range c {
+		if category.Pattern.MatchString(output) {
+			return category.Name
+		}
+	}
+	return ""
+}
+
+// SkipCategoryCount is the number of skipped tests in a SkipCategory.
+type SkipCategoryCount struct {
+	Name  string
+	Count int
+}
+
+// Count returns the number of skipped tests in each category, in the order
+// of the categories. Categories without any skipped tests are included with
+// a Count of 0.
+func (c SkipCategories) Count(exec *Execution) []SkipCategoryCount {
+	counts := make(map[string]int)
+	for _, tc := range exec.Skipped() {
+		if name := c.Category(exec.Package(tc.Package), tc.Test); name != "" {
+			counts[name]++
+		}
+	}
+	result := make([]SkipCategoryCount, 0, len(c))
+	seen := make(map[string]bool)
+	for _, category := range c {
+		if seen[category.Name] {
+			continue
+		}
+		seen[category.Name] = true
+		result = append(result, SkipCategoryCount{Name: category.Name, Count: counts[category.Name]})
+	}
+	return result
+}
+
+func writeSkipCategorySummary(out io.Writer, counts []SkipCategoryCount, msgs Messages) {
+	var lines []string
+	for _, count := range counts {
+		if count.Count > 0 {
+			lines = append(lines, fmt.Sprintf("%s: %d", count.Name, count.Count))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintln(out, color.YellowString("\n=== "+msgs.HeadingSkipCategories))
+	for _, line := range lines {
+		fmt.Fprintln(out, line)
+	}
+}
+
+// logLine matches a line written by t.Log or t.Skip, and captures the message
+// after the file name and line number of the call.
+var logLine = regexp.MustCompile(`^\s+\S+\.go:\d+: ?(.*)$`)
+
+// SkipReason returns the reason a test was skipped, the message passed to
+// t.Skip, from the output of the test. Go 1.14 and later write the message
+// before the --- SKIP line, earlier versions write it after the line. Returns
+// an empty string if the test was skipped without a message.
+func SkipReason(output string) string {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--- SKIP: ") {
+			continue
+		}
+		if i+1 < len(lines) {
+			if match := logLine.FindStringSubmatch(lines[i+1]); match != nil {
+				return strings.TrimSpace(match[1])
+			}
+		}
+		// the message is the last line written by the test before it was
+		// skipped, which is indented, unlike the === RUN line
+		for j := i - 1; j >= 0 && strings.TrimLeft(lines[j], " \t") != lines[j]; j-- {
+			if match := logLine.FindStringSubmatch(lines[j]); match != nil {
+				return strings.TrimSpace(match[1])
+			}
+		}
+		return ""
+	}
+	return ""
+}
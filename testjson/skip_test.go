@@ -0,0 +1,101 @@
+package testjson
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestSkipCategories(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"pkg": {
+				Total: 3,
+				Skipped: []TestCase{
+					{Package: "pkg", Test: "TestCloud"},
+					{Package: "pkg", Test: "TestWindows"},
+					{Package: "pkg", Test: "TestOther"},
+				},
+				output: map[string][]OutputLine{
+					"TestCloud":   multiLine("    cloud_test.go:10: AWS_SECRET_KEY is not set\n--- SKIP: TestCloud (0.00s)\n"),
+					"TestWindows": multiLine("    win_test.go:8: not supported on linux\n--- SKIP: TestWindows (0.00s)\n"),
+					"TestOther":   multiLine("--- SKIP: TestOther (0.00s)\n"),
+				},
+			},
+		},
+	}
+	categories := SkipCategories{
+		{Name: "missing-credential", Pattern: regexp.MustCompile(`_(KEY|TOKEN) is not set`)},
+		{Name: "not-supported-on-os", Pattern: regexp.MustCompile(`not supported on`)},
+		{Name: "quarantined", Pattern: regexp.MustCompile(`quarantined`)},
+	}
+
+	assert.Equal(t, categories.Category(exec.Package("pkg"), "TestCloud"), "missing-credential")
+	assert.Equal(t, categories.Category(exec.Package("pkg"), "TestOther"), "")
+	assert.DeepEqual(t, categories.Count(exec), []SkipCategoryCount{
+		{Name: "missing-credential", Count: 1},
+		{Name: "not-supported-on-os", Count: 1},
+		{Name: "quarantined", Count: 0},
+	})
+
+	out := new(bytes.Buffer)
+	err := PrintSummaryWithOptions(out, exec, SummaryOptions{SkipCategories: categories})
+	assert.NilError(t, err)
+	expected := `
+=== Skipped by category
+missing-credential: 1
+not-supported-on-os: 1
+
+DONE 3 tests, 3 skipped in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestSkipReason(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{
+			name:     "message before the skip line",
+			output:   "=== RUN   TestOne\n    one_test.go:12: requires docker\n--- SKIP: TestOne (0.00s)\n",
+			expected: "requires docker",
+		},
+		{
+			name:     "message after the skip line",
+			output:   "=== RUN   TestOne\n--- SKIP: TestOne (0.00s)\n\tone_test.go:12: requires docker\n",
+			expected: "requires docker",
+		},
+		{
+			name:     "subtest",
+			output:   "=== RUN   TestOne/sub\n        one_test.go:12: not on windows\n    --- SKIP: TestOne/sub (0.00s)\n",
+			expected: "not on windows",
+		},
+		{
+			name:     "log before the message",
+			output:   "=== RUN   TestOne\n    one_test.go:10: starting\n    one_test.go:12: requires docker\n--- SKIP: TestOne (0.00s)\n",
+			expected: "requires docker",
+		},
+		{
+			name:   "no message",
+			output: "=== RUN   TestOne\n--- SKIP: TestOne (0.00s)\n\tone_test.go:12: \n",
+		},
+		{
+			name:   "no skip line",
+			output: "=== RUN   TestOne\n    one_test.go:12: requires docker\n",
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, SkipReason(tc.output), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package testjson
+
+import (
+	"html"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// SourceLinks links the file and line references in the output of tests to
+// the source of the file at the tested commit, for example to the file in the
+// web UI of the repository host.
+type SourceLinks struct {
+	// URL is executed with a SourceLinkData to create the URL of each link.
+	URL *template.Template
+	// Commit is the commit of the source which was tested.
+	Commit string
+	// Root is the absolute path of the root directory of the repository. Files
+	// outside of Root, like the files of the standard library in a stack
+	// trace, are not linked.
+	Root string
+}
+
+// SourceLinkData is the data used to execute SourceLinks.URL.
+type SourceLinkData struct {
+	// Path is the path of the file relative to the root of the repository,
+	// with forward slashes.
+	Path string
+	// Line is the line number in the file.
+	Line int
+	// Commit is the commit of the source which was tested.
+	Commit string
+}
+
+// NewSourceLinks parses the URL template of the links.
+func NewSourceLinks(url string, commit string, root string) (*SourceLinks, error) {
+	tmpl, err := template.New("source").Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	return &SourceLinks{URL: tmpl, Commit: commit, Root: root}, nil
+}
+
+// HTML returns text, which is the output of the package pkg, escaped for
+// HTML, with each file and line reference as a link to the source. A nil
+// SourceLinks only escapes the text.
+func (s *SourceLinks) HTML(pkg string, text string) string {
+	if s == nil || !strings.Contains(text, ".go:") {
+		return html.EscapeString(text)
+	}
+	var out strings.Builder
+	var last int
+	for _, match := range fileReference.FindAllStringSubmatchIndex(text, -1) {
+		// the reference without the whitespace before it
+		start, end := match[4], match[1]
+		url, ok := s.link(pkg, text[match[4]:match[5]], text[match[6]:match[7]])
+		if !ok {
+			continue
+		}
+		out.WriteString(html.EscapeString(text[last:start]))
+		out.WriteString(`<a href="` + html.EscapeString(url) + `">`)
+		out.WriteString(html.EscapeString(text[start:end]))
+		out.WriteString(`</a>`)
+		last = end
+	}
+	out.WriteString(html.EscapeString(text[last:]))
+	return out.String()
+}
+
+// link returns the URL of the source of line of file, from the output of the
+// package pkg. Returns false if the file is not in the repository.
+func (s *SourceLinks) link(pkg string, file string, line string) (string, bool) {
+	lineNum, err := strconv.Atoi(line)
+	if err != nil {
+		return "", false
+	}
+	file, ok := resolveFileReference(pkg, file)
+	if !ok {
+		return "", false
+	}
+	rel, err := filepath.Rel(s.Root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	var url strings.Builder
+	data := SourceLinkData{Path: filepath.ToSlash(rel), Line: lineNum, Commit: s.Commit}
+	if err := s.URL.Execute(&url, data); err != nil {
+		return "", false
+	}
+	return url.String(), true
+}
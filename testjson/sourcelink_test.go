@@ -0,0 +1,25 @@
+package testjson
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestSourceLinks_HTML(t *testing.T) {
+	links, err := NewSourceLinks(
+		"https://github.com/org/repo/blob/{{ .Commit }}/{{ .Path }}#L{{ .Line }}",
+		"abc123", "/work/repo")
+	assert.NilError(t, err)
+
+	text := "    /work/repo/pkg/a_test.go:12: got <nil>\n" +
+		"\t/usr/local/go/src/testing/testing.go:1446 +0x10\n" +
+		"\tb.go:7: relative\n"
+	expected := `    <a href="https://github.com/org/repo/blob/abc123/pkg/a_test.go#L12">/work/repo/pkg/a_test.go:12</a>: got &lt;nil&gt;` + "\n" +
+		"\t/usr/local/go/src/testing/testing.go:1446 +0x10\n" +
+		"\tb.go:7: relative\n"
+	assert.Equal(t, links.HTML("example.com/unknown", text), expected)
+
+	var none *SourceLinks
+	assert.Equal(t, none.HTML("example.com/pkg", "a.go:1: <b>"), "a.go:1: &lt;b&gt;")
+}
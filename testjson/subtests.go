@@ -0,0 +1,29 @@
+package testjson
+
+import "strings"
+
+// withSubtestCounts returns a formatter which adds the counts of the subtests
+// of a test to the line printed by formatter when the test ends, for example
+// TestFoo (12 passed, 30 skipped). Formats which do not print a line for each
+// test are not changed.
+func withSubtestCounts(formatter EventFormatter, enabled bool) EventFormatter {
+	if !enabled {
+		return formatter
+	}
+	return func(event TestEvent, exec *Execution) (string, error) {
+		text, err := formatter(event, exec)
+		if err != nil || event.PackageEvent() || !strings.HasSuffix(text, "\n") {
+			return text, err
+		}
+		switch event.Action {
+		case ActionPass, ActionFail, ActionSkip:
+		default:
+			return text, nil
+		}
+		counts := exec.Package(event.Package).SubtestCounts(event.Test)
+		if counts.Total() == 0 {
+			return text, nil
+		}
+		return strings.TrimSuffix(text, "\n") + " (" + counts.String() + ")\n", nil
+	}
+}
@@ -3,12 +3,15 @@ package testjson
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/fatih/color"
+	"github.com/pkg/errors"
 )
 
 // Summary enumerates the sections which can be printed by PrintSummary
@@ -68,39 +71,402 @@ func NewSummary(value string) (Summary, bool) {
 // PrintSummary of a test Execution. Prints a section for each summary type
 // followed by a DONE line.
 func PrintSummary(out io.Writer, execution *Execution, opts Summary) error {
-	execSummary := newExecSummary(execution, opts)
-	if opts.Includes(SummarizeSkipped) {
-		writeTestCaseSummary(out, execSummary, formatSkipped())
+	return PrintSummaryWithOptions(out, execution, SummaryOptions{Sections: opts})
+}
+
+// SummaryOptions used by PrintSummaryWithOptions.
+type SummaryOptions struct {
+	// Sections of the summary to print.
+	Sections Summary
+	// Messages used for headings and the DONE line. Defaults to
+	// EnglishMessages.
+	Messages *Messages
+	// Timing prints a line with the elapsed time, the cumulative time of all
+	// packages, and the speedup from running packages in parallel.
+	Timing bool
+	// Warnings are printed in a section after the errors.
+	Warnings []string
+	// UncoveredChanges are the changed files with lines which were not
+	// covered by the tests, printed in a section after the warnings.
+	UncoveredChanges []string
+	// PackageRank is used to order the skipped and failed test cases. Test
+	// cases from packages with a lower rank are printed first. Test cases
+	// from packages with the same rank are sorted by package name.
+	PackageRank func(pkg string) int
+	// LineTemplate replaces the DONE line. It is executed with a SummaryLine.
+	LineTemplate *template.Template
+	// RunID is the identifier of the run, available to LineTemplate.
+	RunID string
+	// FlakeRates are the fraction of previous runs in which each package had
+	// a flaky failure. The rate is printed after each failed test case from a
+	// package with a rate.
+	FlakeRates map[string]float64
+	// SkipCategories are used to print the number of skipped tests in each
+	// category, in a section after the skipped tests.
+	SkipCategories SkipCategories
+	// FailureCategories are the categories of failed test cases, by
+	// TestCase.ID. The category is printed after each failed test case.
+	FailureCategories map[string]string
+	// Hyperlinks, when set, adds terminal hyperlinks to the file and line
+	// references in the output of failed and skipped tests.
+	Hyperlinks *Hyperlinks
+	// Flaky prints the failed runs of tests which passed when they were run
+	// again in their own section, instead of with the failed tests, and
+	// counts them as flaky, not failed, on the DONE line. It is used when the
+	// failed tests were rerun, for example with --rerun-fails.
+	Flaky bool
+}
+
+// SummaryLine is the data used to execute SummaryOptions.LineTemplate.
+type SummaryLine struct {
+	Total   int
+	Skipped int
+	Failed  int
+	NotRun  int
+	Errors  int
+	// Flaky is the number of tests which failed and then passed when they
+	// were run again.
+	Flaky int
+	// Elapsed is the elapsed time of the run, formatted as seconds.
+	Elapsed string
+	RunID   string
+	// Coverage is the average of the coverage of the packages which reported
+	// coverage with go test -cover, formatted as a percent, or empty if no
+	// package reported coverage. go test does not report the number of
+	// statements of a package, so the average is not weighted by the size of
+	// the packages.
+	Coverage string
+}
+
+func (o SummaryOptions) messages() Messages {
+	if o.Messages == nil {
+		return EnglishMessages
+	}
+	return *o.Messages
+}
+
+// ranked wraps the getter of conf so that test cases are sorted by
+// PackageRank.
+func (o SummaryOptions) ranked(conf testCaseFormatConfig) testCaseFormatConfig {
+	if o.PackageRank == nil {
+		return conf
+	}
+	getter := conf.getter
+	conf.getter = func(execution executionSummary) []TestCase {
+		testCases := getter(execution)
+		sort.SliceStable(testCases, func(i, j int) bool {
+			return o.PackageRank(testCases[i].Package) < o.PackageRank(testCases[j].Package)
+		})
+		return testCases
+	}
+	return conf
+}
+
+// withFlakeRates adds the flake rate of the package to each test case.
+func (o SummaryOptions) withFlakeRates(conf testCaseFormatConfig) testCaseFormatConfig {
+	if len(o.FlakeRates) == 0 {
+		return conf
+	}
+	conf.suffix = func(tc TestCase) string {
+		rate, ok := o.FlakeRates[tc.Package]
+		if !ok {
+			return ""
+		}
+		return " " + FormatFlakeRate(rate)
+	}
+	return conf
+}
+
+// withFailureCategories adds the category of the failure to each test case,
+// after any other suffix.
+func (o SummaryOptions) withFailureCategories(conf testCaseFormatConfig) testCaseFormatConfig {
+	if len(o.FailureCategories) == 0 {
+		return conf
+	}
+	suffix := conf.suffix
+	conf.suffix = func(tc TestCase) string {
+		var text string
+		if suffix != nil {
+			text = suffix(tc)
+		}
+		if category := o.FailureCategories[tc.ID()]; category != "" {
+			text += " [" + category + "]"
+		}
+		return text
+	}
+	return conf
+}
+
+// withoutFlaky removes the failed runs of tests which passed when they were
+// run again, when they are printed in their own section.
+func (o SummaryOptions) withoutFlaky(conf testCaseFormatConfig) testCaseFormatConfig {
+	if !o.Flaky {
+		return conf
+	}
+	getter := conf.getter
+	conf.getter = func(execution executionSummary) []TestCase {
+		flaky := make(map[TestCase]bool)
+		for _, tc := range execution.Flaky() {
+			flaky[tc] = true
+		}
+		var testCases []TestCase
+		for _, tc := range getter(execution) {
+			if !flaky[tc] {
+				testCases = append(testCases, tc)
+			}
+		}
+		return testCases
+	}
+	return conf
+}
+
+// failedCount returns the number of failed test cases, and the number of
+// those which are counted as flaky instead.
+func (o SummaryOptions) failedCount(execution *Execution) (int, int) {
+	failed := len(execution.Failed())
+	if !o.Flaky {
+		return failed, 0
+	}
+	flaky := len(execution.Flaky())
+	return failed - flaky, flaky
+}
+
+// withHyperlinks adds hyperlinks to the output of each test case.
+func (o SummaryOptions) withHyperlinks(conf testCaseFormatConfig) testCaseFormatConfig {
+	conf.hyperlinks = o.Hyperlinks
+	return conf
+}
+
+// withBuildFailures prints a package which failed to build only once for
+// each BuildFailure with more than one package, followed by the names of the
+// other packages, after any other suffix.
+func withBuildFailures(msgs Messages, conf testCaseFormatConfig) testCaseFormatConfig {
+	getter, suffix := conf.getter, conf.suffix
+	// others are the packages which are not printed, by the package which is
+	// printed for the same build failure.
+	var others map[string][]string
+	conf.getter = func(execution executionSummary) []TestCase {
+		others = make(map[string][]string)
+		group := make(map[string]int)
+		for i, failure := range execution.BuildFailures() {
+			if len(failure.Packages) < 2 {
+				continue
+			}
+			for _, pkg := range failure.Packages {
+				group[pkg] = i + 1
+			}
+		}
+
+		shown := make(map[int]string)
+		var testCases []TestCase
+		for _, tc := range getter(execution) {
+			index := group[tc.Package]
+			if tc.Test != "" || index == 0 {
+				testCases = append(testCases, tc)
+				continue
+			}
+			first, ok := shown[index]
+			if !ok {
+				shown[index] = tc.Package
+				testCases = append(testCases, tc)
+				continue
+			}
+			others[first] = append(others[first], RelativePackagePath(tc.Package))
+		}
+		return testCases
 	}
-	if opts.Includes(SummarizeFailed) {
-		writeTestCaseSummary(out, execSummary, formatFailed())
+	conf.suffix = func(tc TestCase) string {
+		var text string
+		if suffix != nil {
+			text = suffix(tc)
+		}
+		if names := others[tc.Package]; tc.Test == "" && len(names) > 0 {
+			text += " (" + fmt.Sprintf(msgs.SameBuildFailure, len(names), strings.Join(names, ", ")) + ")"
+		}
+		return text
+	}
+	return conf
+}
+
+// maxSameSkipMessageSample is the number of names printed after a skipped
+// test case for the other tests skipped with the same message.
+const maxSameSkipMessageSample = 3
+
+// withSkipMessages prints only the first of the skipped test cases which were
+// skipped with the same message, followed by the number of other test cases,
+// and a sample of their names, after any other suffix.
+func withSkipMessages(msgs Messages, conf testCaseFormatConfig) testCaseFormatConfig {
+	getter, suffix := conf.getter, conf.suffix
+	// others are the test cases which are not printed, by the ID of the test
+	// case which is printed for the same skip message.
+	var others map[string][]TestCase
+	conf.getter = func(execution executionSummary) []TestCase {
+		others = make(map[string][]TestCase)
+		shown := make(map[string]string)
+		var testCases []TestCase
+		for _, tc := range getter(execution) {
+			reason := SkipReason(execution.Output(tc.Package, tc.Test))
+			if reason == "" {
+				testCases = append(testCases, tc)
+				continue
+			}
+			first, ok := shown[reason]
+			if !ok {
+				shown[reason] = tc.ID()
+				testCases = append(testCases, tc)
+				continue
+			}
+			others[first] = append(others[first], tc)
+		}
+		return testCases
+	}
+	conf.suffix = func(tc TestCase) string {
+		var text string
+		if suffix != nil {
+			text = suffix(tc)
+		}
+		same := others[tc.ID()]
+		if len(same) == 0 {
+			return text
+		}
+		var names []string
+		for i, other := range same {
+			if i == maxSameSkipMessageSample {
+				names = append(names, "…")
+				break
+			}
+			names = append(names, RelativePackagePath(other.Package)+" "+other.Test)
+		}
+		format := msgs.SameSkipMessage
+		if len(same) == 1 {
+			format = msgs.SameSkipMessageOne
+		}
+		return text + " (" + fmt.Sprintf(format, len(same), strings.Join(names, ", ")) + ")"
+	}
+	return conf
+}
+
+// PrintSummaryWithOptions prints the summary of a test Execution using the
+// options to select the sections and the language of the summary.
+func PrintSummaryWithOptions(out io.Writer, execution *Execution, opts SummaryOptions) error {
+	msgs := opts.messages()
+	execSummary := newExecSummary(execution, opts.Sections)
+	if opts.Sections.Includes(SummarizeSkipped) {
+		writeTestCaseSummary(out, execSummary, opts.withHyperlinks(withSkipMessages(msgs, opts.ranked(formatSkipped(msgs)))))
+	}
+	if len(opts.SkipCategories) > 0 {
+		writeSkipCategorySummary(out, opts.SkipCategories.Count(execution), msgs)
+	}
+	if opts.Sections.Includes(SummarizeFailed) {
+		if opts.Flaky {
+			writeTestCaseSummary(out, execSummary, opts.withHyperlinks(opts.ranked(formatFlaky(msgs))))
+		}
+		writeTestCaseSummary(out, execSummary, opts.withHyperlinks(withBuildFailures(msgs,
+			opts.withFailureCategories(opts.withFlakeRates(opts.ranked(opts.withoutFlaky(formatFailed(msgs))))))))
+		writeTestCaseSummary(out, execSummary, opts.ranked(formatNotRun(msgs)))
 	}
 
 	errors := execution.Errors()
-	if opts.Includes(SummarizeErrors) {
-		writeErrorSummary(out, errors)
+	if opts.Sections.Includes(SummarizeErrors) {
+		writeErrorSummary(out, errors, msgs)
 	}
+	writeWarningSummary(out, opts.Warnings, msgs)
+	writeUncoveredChangesSummary(out, opts.UncoveredChanges, msgs)
 
-	fmt.Fprintf(out, "\n%s %d tests%s%s%s in %s\n",
-		"DONE", // TODO: maybe color this?
-		execution.Total(),
-		formatTestCount(len(execution.Skipped()), "skipped", ""),
-		formatTestCount(len(execution.Failed()), "failure", "s"),
-		formatTestCount(countErrors(errors), "error", "s"),
-		FormatDurationAsSeconds(execution.Elapsed(), 3))
+	if opts.LineTemplate != nil {
+		if err := writeSummaryLine(out, execution, opts); err != nil {
+			return err
+		}
+	} else {
+		writeDoneLine(out, execution, opts)
+	}
 
+	if opts.Timing {
+		writeTiming(out, execution, msgs)
+	}
 	return nil
 }
 
-func formatTestCount(count int, category string, pluralize string) string {
-	switch count {
-	case 0:
-		return ""
-	case 1:
-	default:
-		category += pluralize
+func writeDoneLine(out io.Writer, execution *Execution, opts SummaryOptions) {
+	msgs := opts.messages()
+	errors := execution.Errors()
+	failed, flaky := opts.failedCount(execution)
+	fmt.Fprintf(out, "\n%s %s%s%s%s%s%s%s\n",
+		msgs.Done, // TODO: maybe color this?
+		fmt.Sprintf(msgs.Tests, execution.Total()),
+		msgs.count(len(execution.Skipped()), msgs.Skipped, msgs.Skipped),
+		msgs.count(failed, msgs.Failure, msgs.Failures),
+		msgs.count(flaky, msgs.Flaky, msgs.Flaky),
+		msgs.count(len(execution.NotRun()), msgs.NotRun, msgs.NotRun),
+		msgs.count(countErrors(errors), msgs.Error, msgs.Errors),
+		fmt.Sprintf(msgs.Elapsed, FormatDurationAsSeconds(execution.Elapsed(), 3)))
+}
+
+func writeSummaryLine(out io.Writer, execution *Execution, opts SummaryOptions) error {
+	failed, _ := opts.failedCount(execution)
+	line := SummaryLine{
+		Total:   execution.Total(),
+		Skipped: len(execution.Skipped()),
+		Failed:  failed,
+		NotRun:  len(execution.NotRun()),
+		Errors:  countErrors(execution.Errors()),
+		Flaky:   countFlaky(execution),
+		Elapsed: FormatDurationAsSeconds(execution.Elapsed(), 3),
+		RunID:   opts.RunID,
 	}
-	return fmt.Sprintf(", %d %s", count, category)
+	if pct, ok := averageCoverage(execution); ok {
+		line.Coverage = fmt.Sprintf("%.1f%%", pct)
+	}
+	buf := new(strings.Builder)
+	if err := opts.LineTemplate.Execute(buf, line); err != nil {
+		return errors.Wrap(err, "failed to execute summary line template")
+	}
+	_, err := fmt.Fprintf(out, "\n%s\n", buf.String())
+	return err
+}
+
+// averageCoverage returns the average of the coverage of the packages which
+// reported coverage, and false if no package reported coverage.
+func averageCoverage(execution *Execution) (float64, bool) {
+	var total float64
+	var count int
+	for _, name := range execution.Packages() {
+		if pct, ok := execution.Package(name).Coverage(); ok {
+			total += pct
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return total / float64(count), true
+}
+
+// countFlaky returns the number of tests which both failed and passed.
+func countFlaky(execution *Execution) int {
+	var count int
+	for _, name := range execution.Packages() {
+		pkg := execution.Package(name)
+		for _, tc := range pkg.Passed {
+			if pkg.failed(tc.Test) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func writeTiming(out io.Writer, execution *Execution, msgs Messages) {
+	elapsed := execution.Elapsed()
+	cumulative := execution.CumulativeElapsed()
+	speedup := 0.0
+	if elapsed > 0 {
+		speedup = cumulative.Seconds() / elapsed.Seconds()
+	}
+	fmt.Fprintf(out, msgs.Timing+"\n",
+		FormatDurationAsSeconds(elapsed, 3),
+		FormatDurationAsSeconds(cumulative, 3),
+		speedup)
 }
 
 // FormatDurationAsSeconds formats a time.Duration as a float with an s suffix.
@@ -108,15 +474,33 @@ func FormatDurationAsSeconds(d time.Duration, precision int) string {
 	return fmt.Sprintf("%.[2]*[1]fs", d.Seconds(), precision)
 }
 
-func writeErrorSummary(out io.Writer, errors []string) {
+func writeErrorSummary(out io.Writer, errors []string, msgs Messages) {
 	if len(errors) > 0 {
-		fmt.Fprintln(out, color.MagentaString("\n=== Errors"))
+		fmt.Fprintln(out, color.MagentaString("\n=== "+msgs.HeadingErrors))
 	}
 	for _, err := range errors {
 		fmt.Fprintln(out, err)
 	}
 }
 
+func writeWarningSummary(out io.Writer, warnings []string, msgs Messages) {
+	if len(warnings) > 0 {
+		fmt.Fprintln(out, color.YellowString("\n=== "+msgs.HeadingWarnings))
+	}
+	for _, warning := range warnings {
+		fmt.Fprintln(out, warning)
+	}
+}
+
+func writeUncoveredChangesSummary(out io.Writer, changes []string, msgs Messages) {
+	if len(changes) > 0 {
+		fmt.Fprintln(out, color.YellowString("\n=== "+msgs.HeadingUncoveredChanges))
+	}
+	for _, change := range changes {
+		fmt.Fprintln(out, change)
+	}
+}
+
 // countErrors in stderr lines. Build errors may include multiple lines where
 // subsequent lines are indented.
 // FIXME: Panics will include multiple lines, and are still overcounted.
@@ -132,13 +516,17 @@ func countErrors(errors []string) int {
 }
 
 type executionSummary interface {
+	BuildFailures() []BuildFailure
 	Failed() []TestCase
+	Flaky() []TestCase
 	Skipped() []TestCase
+	NotRun() []TestCase
+	Output(pkg, test string) string
 	OutputLines(pkg, test string) []string
 }
 
 type noOutputSummary struct {
-	Execution
+	*Execution
 }
 
 func (s *noOutputSummary) OutputLines(_, _ string) []string {
@@ -149,7 +537,7 @@ func newExecSummary(execution *Execution, opts Summary) executionSummary {
 	if opts.Includes(SummarizeOutput) {
 		return execution
 	}
-	return &noOutputSummary{Execution: *execution}
+	return &noOutputSummary{Execution: execution}
 }
 
 func writeTestCaseSummary(out io.Writer, execution executionSummary, conf testCaseFormatConfig) {
@@ -159,16 +547,21 @@ func writeTestCaseSummary(out io.Writer, execution executionSummary, conf testCa
 	}
 	fmt.Fprintln(out, "\n=== "+conf.header)
 	for _, tc := range testCases {
-		fmt.Fprintf(out, "=== %s: %s %s (%s)\n",
+		var suffix string
+		if conf.suffix != nil {
+			suffix = conf.suffix(tc)
+		}
+		fmt.Fprintf(out, "=== %s: %s %s (%s)%s\n",
 			conf.prefix,
-			relativePackagePath(tc.Package),
+			RelativePackagePath(tc.Package),
 			tc.Test,
-			FormatDurationAsSeconds(tc.Elapsed, 2))
+			FormatDurationAsSeconds(tc.Elapsed, 2),
+			suffix)
 		for _, line := range execution.OutputLines(tc.Package, tc.Test) {
 			if isRunLine(line) || conf.filter(line) {
 				continue
 			}
-			fmt.Fprint(out, line)
+			fmt.Fprint(out, conf.hyperlinks.Link(tc.Package, line))
 		}
 		fmt.Fprintln(out)
 	}
@@ -179,12 +572,16 @@ type testCaseFormatConfig struct {
 	prefix string
 	filter func(string) bool
 	getter func(executionSummary) []TestCase
+	// suffix, when set, returns text printed after the heading of a test case.
+	suffix func(TestCase) string
+	// hyperlinks, when set, adds hyperlinks to the output of each test case.
+	hyperlinks *Hyperlinks
 }
 
-func formatFailed() testCaseFormatConfig {
+func formatFailed(msgs Messages) testCaseFormatConfig {
 	withColor := color.RedString
 	return testCaseFormatConfig{
-		header: withColor("Failed"),
+		header: withColor(msgs.HeadingFailed),
 		prefix: withColor("FAIL"),
 		filter: func(line string) bool {
 			return strings.HasPrefix(line, "--- FAIL: Test")
@@ -195,10 +592,24 @@ func formatFailed() testCaseFormatConfig {
 	}
 }
 
-func formatSkipped() testCaseFormatConfig {
+func formatFlaky(msgs Messages) testCaseFormatConfig {
+	withColor := color.CyanString
+	return testCaseFormatConfig{
+		header: withColor(msgs.HeadingFlaky),
+		prefix: withColor("FLAKY"),
+		filter: func(line string) bool {
+			return strings.HasPrefix(line, "--- FAIL: Test")
+		},
+		getter: func(execution executionSummary) []TestCase {
+			return execution.Flaky()
+		},
+	}
+}
+
+func formatSkipped(msgs Messages) testCaseFormatConfig {
 	withColor := color.YellowString
 	return testCaseFormatConfig{
-		header: withColor("Skipped"),
+		header: withColor(msgs.HeadingSkipped),
 		prefix: withColor("SKIP"),
 		filter: func(line string) bool {
 			return strings.HasPrefix(line, "--- SKIP: Test")
@@ -209,6 +620,20 @@ func formatSkipped() testCaseFormatConfig {
 	}
 }
 
+func formatNotRun(msgs Messages) testCaseFormatConfig {
+	withColor := color.MagentaString
+	return testCaseFormatConfig{
+		header: withColor(msgs.HeadingNotRun),
+		prefix: withColor("NOT RUN"),
+		filter: func(string) bool {
+			return false
+		},
+		getter: func(execution executionSummary) []TestCase {
+			return execution.NotRun()
+		},
+	}
+}
+
 func isRunLine(line string) bool {
 	return strings.HasPrefix(line, "=== RUN   Test")
 }
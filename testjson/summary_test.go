@@ -2,8 +2,10 @@ package testjson
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
+	"text/template"
 	"time"
 
 	"github.com/jonboulle/clockwork"
@@ -86,7 +88,7 @@ func TestPrintSummary_WithFailures(t *testing.T) {
 						Elapsed: 12 * time.Millisecond,
 					},
 				},
-				output: map[string][]string{
+				output: map[string][]OutputLine{
 					"TestFileDo": multiLine(`=== RUN   TestFileDo
 Some stdout/stderr here
 --- FAIL: TestFailDo (1.41s)
@@ -96,7 +98,7 @@ Some stdout/stderr here
 --- FAIL: TestFailDoError (0.01s)
 	do_test.go:50 assertion failed: expected nil error, got WHAT!
 `),
-					"": {"FAIL\n"},
+					"": outputLines("FAIL\n"),
 				},
 				action: ActionFail,
 			},
@@ -116,7 +118,7 @@ Some stdout/stderr here
 						Elapsed: 0,
 					},
 				},
-				output: map[string][]string{
+				output: map[string][]OutputLine{
 					"TestAlbatross": multiLine(`=== RUN   TestAlbatross
 --- FAIL: TestAlbatross (0.04s)
 `),
@@ -128,8 +130,8 @@ Some stdout/stderr here
 			},
 			"example.com/project/badmain": {
 				action: ActionFail,
-				output: map[string][]string{
-					"": {"sometimes main can exit 2\n"},
+				output: map[string][]OutputLine{
+					"": outputLines("sometimes main can exit 2\n"),
 				},
 			},
 		},
@@ -221,6 +223,442 @@ func patchClock() (clockwork.FakeClock, func()) {
 	return fake, func() { clock = clockwork.NewRealClock() }
 }
 
-func multiLine(s string) []string {
-	return strings.SplitAfter(s, "\n")
+func multiLine(s string) []OutputLine {
+	return outputLines(strings.SplitAfter(s, "\n")...)
+}
+
+func outputLines(lines ...string) []OutputLine {
+	output := make([]OutputLine, 0, len(lines))
+	for _, line := range lines {
+		output = append(output, OutputLine{Text: line})
+	}
+	return output
+}
+
+func TestPrintSummaryWithOptions_Messages(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"foo": {
+				Total:   3,
+				Skipped: []TestCase{{Package: "foo", Test: "TestSkip"}},
+			},
+		},
+		errors: []string{"pkg/file.go:99:12: missing ',' before newline"},
+	}
+	fake.Advance(2 * time.Second)
+	msgs, ok := NewMessages("ja_JP.UTF-8")
+	assert.Assert(t, ok)
+	err := PrintSummaryWithOptions(out, exec, SummaryOptions{
+		Sections: SummarizeErrors,
+		Messages: &msgs,
+	})
+	assert.NilError(t, err)
+
+	expected := `
+=== エラー
+pkg/file.go:99:12: missing ',' before newline
+
+完了 テスト 3 件、スキップ 1 件、エラー 1 件（2.000s）
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestNewMessages(t *testing.T) {
+	msgs, ok := NewMessages("EN")
+	assert.Assert(t, ok)
+	assert.Equal(t, msgs.Done, "DONE")
+
+	_, ok = NewMessages("xx")
+	assert.Assert(t, !ok)
+
+	lang, ok := Language("ja_JP.UTF-8")
+	assert.Assert(t, ok)
+	assert.Equal(t, lang, "ja")
+}
+
+func TestPrintSummaryWithOptions_Timing(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"foo": {Total: 2, elapsed: 3 * time.Second},
+			"bar": {
+				Total:  1,
+				Passed: []TestCase{{Elapsed: time.Second}},
+			},
+		},
+	}
+	fake.Advance(2 * time.Second)
+	err := PrintSummaryWithOptions(out, exec, SummaryOptions{Timing: true})
+	assert.NilError(t, err)
+
+	expected := `
+DONE 3 tests in 2.000s
+TIME 2.000s elapsed, 4.000s cumulative, 2.00x speedup
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithOptions_UncoveredChanges(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started:  fake.Now(),
+		packages: map[string]*Package{"foo": {Total: 1}},
+	}
+	err := PrintSummaryWithOptions(out, exec, SummaryOptions{
+		Warnings:         []string{"a warning"},
+		UncoveredChanges: []string{"pkg/foo.go: 2 of 5 changed lines not covered: 10-11"},
+	})
+	assert.NilError(t, err)
+
+	expected := `
+=== Warnings
+a warning
+
+=== Uncovered changes
+pkg/foo.go: 2 of 5 changed lines not covered: 10-11
+
+DONE 1 tests in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithOptions_PackageRank(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"alpha": {
+				Total:  1,
+				Failed: []TestCase{{Package: "alpha", Test: "TestA"}},
+			},
+			"beta": {
+				Total:  1,
+				Failed: []TestCase{{Package: "beta", Test: "TestB"}},
+			},
+		},
+	}
+	rank := func(pkg string) int {
+		if pkg == "beta" {
+			return 0
+		}
+		return 1
+	}
+	err := PrintSummaryWithOptions(out, exec, SummaryOptions{
+		Sections:    SummarizeFailed,
+		PackageRank: rank,
+	})
+	assert.NilError(t, err)
+
+	expected := `
+=== Failed
+=== FAIL: beta TestB (0.00s)
+
+=== FAIL: alpha TestA (0.00s)
+
+
+DONE 2 tests, 2 failures in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithOptions_FlakeRates(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"alpha": {
+				Total:  1,
+				Failed: []TestCase{{Package: "alpha", Test: "TestA"}},
+			},
+			"beta": {
+				Total:  1,
+				Failed: []TestCase{{Package: "beta", Test: "TestB"}},
+			},
+		},
+	}
+	err := PrintSummaryWithOptions(out, exec, SummaryOptions{
+		Sections:   SummarizeFailed,
+		FlakeRates: map[string]float64{"beta": 0.03},
+	})
+	assert.NilError(t, err)
+
+	expected := `
+=== Failed
+=== FAIL: alpha TestA (0.00s)
+
+=== FAIL: beta TestB (0.00s) ~3%
+
+
+DONE 2 tests, 2 failures in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithOptions_FailureCategories(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"alpha": {
+				Total:  1,
+				Failed: []TestCase{{Package: "alpha", Test: "TestA"}},
+			},
+			"beta": {
+				Total:  1,
+				Failed: []TestCase{{Package: "beta", Test: "TestB"}},
+			},
+		},
+	}
+	err := PrintSummaryWithOptions(out, exec, SummaryOptions{
+		Sections:          SummarizeFailed,
+		FlakeRates:        map[string]float64{"beta": 0.03},
+		FailureCategories: map[string]string{"alpha#TestA": "infra", "beta#TestB": "product"},
+	})
+	assert.NilError(t, err)
+
+	expected := `
+=== Failed
+=== FAIL: alpha TestA (0.00s) [infra]
+
+=== FAIL: beta TestB (0.00s) ~3% [product]
+
+
+DONE 2 tests, 2 failures in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithOptions_SameBuildFailure(t *testing.T) {
+	_, reset := patchClock()
+	defer reset()
+
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout: strings.NewReader(`{"ImportPath":"example.com/core","Action":"build-output","Output":"# example.com/core\n"}
+{"ImportPath":"example.com/core","Action":"build-output","Output":"core/core.go:5:2: undefined: missing\n"}
+{"ImportPath":"example.com/core","Action":"build-fail"}
+{"Action":"output","Package":"example.com/api","Output":"FAIL\texample.com/api [build failed]\n"}
+{"Action":"fail","Package":"example.com/api","FailedBuild":"example.com/core"}
+{"Action":"output","Package":"example.com/db","Output":"FAIL\texample.com/db [build failed]\n"}
+{"Action":"fail","Package":"example.com/db","FailedBuild":"example.com/core"}
+{"Action":"output","Package":"example.com/web","Output":"FAIL\texample.com/web [build failed]\n"}
+{"Action":"fail","Package":"example.com/web","FailedBuild":"example.com/core"}
+{"Action":"run","Package":"example.com/util","Test":"TestA"}
+{"Action":"fail","Package":"example.com/util","Test":"TestA"}
+{"Action":"fail","Package":"example.com/util"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &fakeHandler{formatter: standardQuietFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)},
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, exec.BuildFailures(), []BuildFailure{{
+		ImportPath: "example.com/core",
+		Output:     "# example.com/core\ncore/core.go:5:2: undefined: missing\n",
+		Packages:   []string{"example.com/api", "example.com/db", "example.com/web"},
+	}})
+
+	out := new(bytes.Buffer)
+	err = PrintSummaryWithOptions(out, exec, SummaryOptions{Sections: SummarizeFailed | SummarizeOutput})
+	assert.NilError(t, err)
+	expected := `
+=== Failed
+=== FAIL: example.com/api  (0.00s) (the same build failure in 2 more packages: example.com/db, example.com/web)
+# example.com/core
+core/core.go:5:2: undefined: missing
+FAIL	example.com/api [build failed]
+
+=== FAIL: example.com/util TestA (0.00s)
+
+`
+	assert.Assert(t, strings.HasPrefix(out.String(), expected), out.String())
+}
+
+func TestPrintSummaryWithOptions_SameSkipMessage(t *testing.T) {
+	_, reset := patchClock()
+	defer reset()
+
+	var events strings.Builder
+	for _, test := range []string{"TestA", "TestB", "TestC", "TestD", "TestE"} {
+		fmt.Fprintf(&events, `{"Action":"run","Package":"example.com/db","Test":"%[1]s"}
+{"Action":"output","Package":"example.com/db","Test":"%[1]s","Output":"=== RUN   %[1]s\n"}
+{"Action":"output","Package":"example.com/db","Test":"%[1]s","Output":"    db_test.go:12: integration env not configured\n"}
+{"Action":"output","Package":"example.com/db","Test":"%[1]s","Output":"--- SKIP: %[1]s (0.00s)\n"}
+{"Action":"skip","Package":"example.com/db","Test":"%[1]s"}
+`, test)
+	}
+	events.WriteString(`{"Action":"run","Package":"example.com/db","Test":"TestF"}
+{"Action":"output","Package":"example.com/db","Test":"TestF","Output":"    db_test.go:30: not on windows\n"}
+{"Action":"output","Package":"example.com/db","Test":"TestF","Output":"--- SKIP: TestF (0.00s)\n"}
+{"Action":"skip","Package":"example.com/db","Test":"TestF"}
+{"Action":"run","Package":"example.com/db","Test":"TestG"}
+{"Action":"output","Package":"example.com/db","Test":"TestG","Output":"    db_test.go:30: not on windows\n"}
+{"Action":"output","Package":"example.com/db","Test":"TestG","Output":"--- SKIP: TestG (0.00s)\n"}
+{"Action":"skip","Package":"example.com/db","Test":"TestG"}
+{"Action":"pass","Package":"example.com/db"}
+`)
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  strings.NewReader(events.String()),
+		Stderr:  strings.NewReader(""),
+		Handler: &fakeHandler{formatter: standardQuietFormat, out: new(bytes.Buffer), err: new(bytes.Buffer)},
+	})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	err = PrintSummaryWithOptions(out, exec, SummaryOptions{Sections: SummarizeSkipped | SummarizeOutput})
+	assert.NilError(t, err)
+	expected := `
+=== Skipped
+=== SKIP: example.com/db TestA (0.00s) (the same skip message in 4 more tests: example.com/db TestB, example.com/db TestC, example.com/db TestD, …)
+    db_test.go:12: integration env not configured
+
+=== SKIP: example.com/db TestF (0.00s) (the same skip message in 1 more test: example.com/db TestG)
+    db_test.go:30: not on windows
+
+`
+	assert.Assert(t, strings.HasPrefix(out.String(), expected), out.String())
+}
+
+func TestPrintSummaryWithOptions_NotRun(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"example.com/project/fs": {
+				Total:   2,
+				Passed:  []TestCase{{Package: "example.com/project/fs", Test: "TestA"}},
+				running: map[string]int{"TestB": 1},
+				output: map[string][]OutputLine{
+					"TestB": outputLines("=== RUN   TestB\n", "waiting for lock\n"),
+				},
+			},
+			"example.com/project/net": newPackage(),
+		},
+	}
+	fake.Advance(2 * time.Second)
+	err := PrintSummaryWithOptions(out, exec, SummaryOptions{Sections: SummarizeAll})
+	assert.NilError(t, err)
+
+	expected := `
+=== Not run
+=== NOT RUN: example.com/project/fs TestB (0.00s)
+waiting for lock
+
+=== NOT RUN: example.com/project/net  (0.00s)
+
+
+DONE 2 tests, 2 not run in 2.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithOptions_Flaky(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"alpha": {
+				Total:  3,
+				Failed: []TestCase{{Package: "alpha", Test: "TestA"}, {Package: "alpha", Test: "TestB"}},
+				Passed: []TestCase{{Package: "alpha", Test: "TestA"}},
+				results: map[string][]Action{
+					"TestA": {ActionFail, ActionPass},
+					"TestB": {ActionFail},
+				},
+				output: map[string][]OutputLine{
+					"TestA": outputLines("=== RUN   TestA\n", "    a_test.go:9: timed out\n", "--- FAIL: TestA (0.00s)\n"),
+				},
+			},
+		},
+	}
+	out := new(bytes.Buffer)
+	err := PrintSummaryWithOptions(out, exec, SummaryOptions{Sections: SummarizeAll, Flaky: true})
+	assert.NilError(t, err)
+
+	expected := `
+=== Flaky
+=== FLAKY: alpha TestA (0.00s)
+    a_test.go:9: timed out
+
+
+=== Failed
+=== FAIL: alpha TestB (0.00s)
+
+
+DONE 3 tests, 1 failure, 1 flaky in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+
+	out.Reset()
+	err = PrintSummaryWithOptions(out, exec, SummaryOptions{Sections: SummarizeNone})
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), "\nDONE 3 tests, 2 failures in 0.000s\n")
+}
+
+func TestPrintSummaryWithOptions_LineTemplate(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"foo": {
+				Total:  4,
+				Failed: []TestCase{{Package: "foo", Test: "TestFlaky"}},
+				Passed: []TestCase{
+					{Package: "foo", Test: "TestFlaky"},
+					{Package: "foo", Test: "TestOk"},
+				},
+				Skipped: []TestCase{{Package: "foo", Test: "TestSkip"}},
+			},
+		},
+	}
+	fake.Advance(2 * time.Second)
+	tmpl := template.Must(template.New("line").Parse(
+		"RESULT run={{.RunID}} total={{.Total}} failed={{.Failed}} flaky={{.Flaky}} " +
+			"skipped={{.Skipped}} elapsed={{.Elapsed}}"))
+	err := PrintSummaryWithOptions(out, exec, SummaryOptions{
+		LineTemplate: tmpl,
+		RunID:        "build-7",
+	})
+	assert.NilError(t, err)
+
+	expected := `
+RESULT run=build-7 total=4 failed=1 flaky=1 skipped=1 elapsed=2.000s
+`
+	assert.Equal(t, out.String(), expected)
+
+	exec.packages["bar"] = &Package{coverage: 80, hasCoverage: true}
+	exec.packages["baz"] = &Package{coverage: 65.5, hasCoverage: true}
+	tmpl = template.Must(template.New("line").Parse("RESULT total={{.Total}} coverage={{.Coverage}}"))
+	out.Reset()
+	err = PrintSummaryWithOptions(out, exec, SummaryOptions{LineTemplate: tmpl})
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), "\nRESULT total=4 coverage=72.8%\n")
 }
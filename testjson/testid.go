@@ -0,0 +1,40 @@
+package testjson
+
+import "strings"
+
+// testIDSeparator separates the package from the test name in a test ID. A
+// '#' is not valid in an import path, so the package can always be split from
+// the test name.
+const testIDSeparator = "#"
+
+// TestID returns the canonical identifier for a test. The ID is the package
+// path relative to the module (or GOPATH) of the working directory, followed
+// by a '#', followed by the full name of the test, including any subtest path.
+//
+// Example: internal/store#TestSave/with_timeout
+//
+// A package-level ID, for failures which are not attributed to a test, has an
+// empty test name.
+//
+// Any feature which stores or prints a reference to a test in a machine
+// readable form should use this ID, so that references are comparable between
+// reports.
+func TestID(pkg, test string) string {
+	return RelativePackagePath(pkg) + testIDSeparator + test
+}
+
+// ParseTestID splits a test ID created by TestID into the relative package
+// path and the test name. The test name may contain a '#' (go test adds #01
+// to duplicate subtest names), so the ID is split at the first '#'. Returns false if id is not a valid test ID.
+func ParseTestID(id string) (pkg string, test string, ok bool) {
+	i := strings.Index(id, testIDSeparator)
+	if i < 0 {
+		return "", "", false
+	}
+	return id[:i], id[i+len(testIDSeparator):], true
+}
+
+// ID returns the canonical identifier of the test case. See TestID.
+func (tc TestCase) ID() string {
+	return TestID(tc.Package, tc.Test)
+}
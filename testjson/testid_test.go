@@ -0,0 +1,29 @@
+package testjson
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestTestID(t *testing.T) {
+	defer patchPkgPathPrefix("example.com/project")()
+
+	id := TestID("example.com/project/store", "TestSave/with_timeout")
+	assert.Equal(t, id, "store#TestSave/with_timeout")
+
+	tc := TestCase{Package: "example.com/project", Test: "TestMain"}
+	assert.Equal(t, tc.ID(), ".#TestMain")
+
+	pkg, test, ok := ParseTestID(id)
+	assert.Assert(t, ok)
+	assert.Equal(t, pkg, "store")
+	assert.Equal(t, test, "TestSave/with_timeout")
+
+	_, test, ok = ParseTestID("store#TestSave/case#01")
+	assert.Assert(t, ok)
+	assert.Equal(t, test, "TestSave/case#01")
+
+	_, _, ok = ParseTestID("TestSave")
+	assert.Assert(t, !ok)
+}
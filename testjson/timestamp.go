@@ -0,0 +1,78 @@
+package testjson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TimestampFormat selects the timestamp printed at the start of each line by
+// an EventFormatter.
+type TimestampFormat string
+
+const (
+	// TimestampNone does not print a timestamp.
+	TimestampNone TimestampFormat = "none"
+	// TimestampRelative prints the time of the event since the start of the
+	// run, in seconds.
+	TimestampRelative TimestampFormat = "relative"
+	// TimestampClock prints the local wall clock time of the event.
+	TimestampClock TimestampFormat = "clock"
+	// TimestampISO prints the time of the event in UTC, in the ISO 8601
+	// format.
+	TimestampISO TimestampFormat = "iso"
+)
+
+// IsValid returns true if f is one of the timestamp formats, or empty.
+func (f TimestampFormat) IsValid() bool {
+	switch f {
+	case "", TimestampNone, TimestampRelative, TimestampClock, TimestampISO:
+		return true
+	}
+	return false
+}
+
+func (f TimestampFormat) format(event TestEvent, exec *Execution) string {
+	t := event.Time
+	if t.IsZero() {
+		t = clock.Now()
+	}
+	switch f {
+	case TimestampRelative:
+		return fmt.Sprintf("%9.3fs", t.Sub(exec.Started()).Seconds())
+	case TimestampClock:
+		return t.Local().Format("15:04:05.000")
+	case TimestampISO:
+		return t.UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+	return ""
+}
+
+// withTimestamps returns a formatter which prints a timestamp at the start of
+// each line printed by formatter. Formats like dots which append to a line
+// only have a timestamp at the start of the line.
+func withTimestamps(formatter EventFormatter, f TimestampFormat) EventFormatter {
+	if f == "" || f == TimestampNone {
+		return formatter
+	}
+	startOfLine := true
+	return func(event TestEvent, exec *Execution) (string, error) {
+		text, err := formatter(event, exec)
+		if err != nil || text == "" {
+			return text, err
+		}
+		prefix := f.format(event, exec) + " "
+
+		var buf strings.Builder
+		for _, line := range strings.SplitAfter(text, "\n") {
+			if line == "" {
+				continue
+			}
+			if startOfLine {
+				buf.WriteString(prefix)
+			}
+			buf.WriteString(line)
+			startOfLine = strings.HasSuffix(line, "\n")
+		}
+		return buf.String(), nil
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// tools are the subcommands of `gotestsum tool`.
+var tools = map[string]func(name string, args []string) error{
+	"bisect":          runBisect,
+	"junit-merge":     runJUnitMerge,
+	"junit-to-json":   runJUnitToJSON,
+	"junit-validate":  runJUnitValidate,
+	"replay":          runReplay,
+	"summary":         runSummary,
+	"trend":           runTrend,
+	"verify-superset": runVerifySuperset,
+}
+
+func toolNames() []string {
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func toolUsage(name string) {
+	fmt.Fprintf(os.Stderr, `Usage:
+    %s TOOL [flags]
+
+Tools:
+`, name)
+	for _, tool := range toolNames() {
+		fmt.Fprintf(os.Stderr, "    %s\n", tool)
+	}
+}
+
+// runTool runs the tool named by the first argument.
+func runTool(name string, args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		toolUsage(name)
+		return nil
+	}
+	tool, ok := tools[args[0]]
+	if !ok {
+		toolUsage(name)
+		return errors.Errorf("unknown tool %s", args[0])
+	}
+	return tool(name+" "+args[0], args[1:])
+}
+
+// parseToolFlags parses the flags of a tool and handles the help flag.
+// Returns false if the tool should exit without running.
+func parseToolFlags(flags *pflag.FlagSet, args []string) (bool, error) {
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return false, nil
+	case err != nil:
+		flags.Usage()
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"gotest.tools/gotestsum/internal/junitxml"
+)
+
+func runJUnitToJSON(name string, args []string) error {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage:
+    %s [flags] FILE...
+
+Convert JUnit XML files into go test -json events, and print the events to
+stdout. The output can be used by any tool which reads a --jsonfile, so that
+reports from other test runners can be combined with go test reports.
+
+Flags:
+`, name)
+		flags.PrintDefaults()
+	}
+	addMaxDocumentBytesFlag(flags)
+	if ok, err := parseToolFlags(flags, args); !ok {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("at least one file is required")
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, path := range flags.Args() {
+		suites, err := readJUnitFile(path)
+		if err != nil {
+			return err
+		}
+		for _, event := range junitxml.Events(suites) {
+			if err := encoder.Encode(event); err != nil {
+				return errors.Wrap(err, "failed to write event")
+			}
+		}
+	}
+	return nil
+}
+
+func runJUnitMerge(name string, args []string) error {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage:
+    %s [flags] FILE...
+
+Merge JUnit XML files, for example the reports from each shard of a test run,
+into a single file. Testsuites with the same name are combined into one
+testsuite.
+
+Flags:
+`, name)
+		flags.PrintDefaults()
+	}
+	output := flags.StringP("output", "o", "-", "path of the JUnit XML file to write, or - to write to stdout")
+	policy := flags.String("merge-policy", string(junitxml.MergeAll),
+		"how to merge testcases with the same name in a testsuite, one of: all, last, any-pass")
+	addMaxDocumentBytesFlag(flags)
+	if ok, err := parseToolFlags(flags, args); !ok {
+		return err
+	}
+	if !junitxml.MergePolicy(*policy).IsValid() {
+		return errors.Errorf("unknown --merge-policy %s, must be one of: all, last, any-pass", *policy)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("at least one file is required")
+	}
+
+	reports := make([]junitxml.JUnitTestSuites, 0, flags.NArg())
+	for _, path := range flags.Args() {
+		suites, err := readJUnitFile(path)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, suites)
+	}
+	merged := junitxml.MergeWithPolicy(junitxml.MergePolicy(*policy), reports...)
+
+	if *output == "-" {
+		return junitxml.WriteSuites(os.Stdout, merged)
+	}
+	out, err := os.Create(*output)
+	if err != nil {
+		return errors.Wrap(err, "failed to create JUnit XML file")
+	}
+	if err := junitxml.WriteSuites(out, merged); err != nil {
+		out.Close() // nolint: errcheck
+		return err
+	}
+	return errors.Wrap(out.Close(), "failed to close JUnit XML file")
+}
+
+func runJUnitValidate(name string, args []string) error {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage:
+    %s [flags] FILE...
+
+Check that JUnit XML files match the de-facto JUnit schema read by CI systems.
+Each file which is not valid is printed with the reason.
+
+Flags:
+`, name)
+		flags.PrintDefaults()
+	}
+	addMaxDocumentBytesFlag(flags)
+	if ok, err := parseToolFlags(flags, args); !ok {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("at least one file is required")
+	}
+
+	var invalid int
+	for _, path := range flags.Args() {
+		if err := validateReport(path, junitxml.Validate); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return errors.Errorf("%d of %d files are not valid JUnit XML", invalid, flags.NArg())
+	}
+	return nil
+}
+
+// addMaxDocumentBytesFlag adds the flag which limits the size of the JUnit XML
+// files which are read.
+func addMaxDocumentBytesFlag(flags *pflag.FlagSet) {
+	flags.Int64Var(&junitxml.MaxDocumentBytes, "max-document-bytes", junitxml.DefaultMaxDocumentBytes,
+		"maximum size in bytes of a JUnit XML file, or 0 for no limit")
+}
+
+func readJUnitFile(path string) (junitxml.JUnitTestSuites, error) {
+	in, err := openReport(path)
+	if err != nil {
+		return junitxml.JUnitTestSuites{}, err
+	}
+	defer in.Close() // nolint: errcheck
+	suites, err := junitxml.Read(in)
+	return suites, errors.Wrapf(err, "failed to read %s", path)
+}
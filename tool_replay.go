@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type replayOptions struct {
+	format        string
+	verboseFailed bool
+}
+
+func runReplay(name string, args []string) error {
+	opts := &replayOptions{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage:
+    %s [flags] FILE
+
+Print the output and summary of a file written by --jsonfile, as if the tests
+were running.
+
+Flags:
+`, name)
+		flags.PrintDefaults()
+	}
+	flags.StringVarP(&opts.format, "format", "f", "standard-quiet",
+		"print format of the test output")
+	flags.BoolVar(&opts.verboseFailed, "verbose-failed", false,
+		"use the standard-verbose format for packages which failed")
+	if ok, err := parseToolFlags(flags, args); !ok {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("one file is required")
+	}
+
+	raw, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		return errors.Wrap(err, "failed to read JSON file")
+	}
+	return replay(raw, opts)
+}
+
+func replay(raw []byte, opts *replayOptions) error {
+	formatter := testjson.NewEventFormatter(opts.format)
+	if formatter == nil {
+		return errors.Errorf("unknown format %s", opts.format)
+	}
+	sections := testjson.SummarizeAll
+	if opts.verboseFailed {
+		failed, err := failedPackages(raw)
+		if err != nil {
+			return err
+		}
+		formatter = verboseFailedFormatter(formatter, failed)
+		// the output of failed tests is already printed by the verbose format
+		sections &^= testjson.SummarizeOutput
+	}
+
+	handler := &eventHandler{formatter: formatter, out: os.Stdout, err: os.Stderr}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  bytes.NewReader(raw),
+		Stderr:  bytes.NewReader(nil),
+		Handler: handler,
+	})
+	if err != nil {
+		return err
+	}
+	return testjson.PrintSummary(os.Stdout, exec, sections)
+}
+
+// failedPackages returns the packages with a failed test, or which failed
+// without a failed test.
+func failedPackages(raw []byte) (map[string]bool, error) {
+	exec, err := testjson.ReadExecution(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	failed := make(map[string]bool)
+	for _, tc := range exec.Failed() {
+		failed[tc.Package] = true
+	}
+	return failed, nil
+}
+
+// verboseFailedFormatter returns a formatter which uses the standard-verbose
+// format for events from the failed packages, and formatter for all other
+// events.
+func verboseFailedFormatter(formatter testjson.EventFormatter, failed map[string]bool) testjson.EventFormatter {
+	verbose := testjson.NewEventFormatter("standard-verbose")
+	return func(event testjson.TestEvent, exec *testjson.Execution) (string, error) {
+		if failed[event.Package] {
+			return verbose(event, exec)
+		}
+		return formatter(event, exec)
+	}
+}
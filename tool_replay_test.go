@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestVerboseFailedFormatter(t *testing.T) {
+	raw := []byte(`{"Action":"run","Package":"pkg/ok","Test":"TestA"}
+{"Action":"output","Package":"pkg/ok","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"pass","Package":"pkg/ok","Test":"TestA"}
+{"Action":"pass","Package":"pkg/ok"}
+{"Action":"run","Package":"pkg/bad","Test":"TestB"}
+{"Action":"output","Package":"pkg/bad","Test":"TestB","Output":"=== RUN   TestB\n"}
+{"Action":"fail","Package":"pkg/bad","Test":"TestB"}
+{"Action":"fail","Package":"pkg/bad"}
+`)
+	failed, err := failedPackages(raw)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, failed, map[string]bool{"pkg/bad": true})
+
+	formatter := verboseFailedFormatter(testjson.NewEventFormatter("standard-quiet"), failed)
+	line := func(event testjson.TestEvent) string {
+		out, err := formatter(event, nil)
+		assert.NilError(t, err)
+		return out
+	}
+	assert.Equal(t, line(testjson.TestEvent{
+		Action: testjson.ActionOutput, Package: "pkg/ok", Test: "TestA", Output: "=== RUN   TestA\n",
+	}), "")
+	assert.Equal(t, line(testjson.TestEvent{
+		Action: testjson.ActionOutput, Package: "pkg/bad", Test: "TestB", Output: "=== RUN   TestB\n",
+	}), "=== RUN   TestB\n")
+}
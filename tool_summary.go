@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type summaryOptions struct {
+	jsonFile            string
+	noSummary           *noSummaryValue
+	summaryTiming       bool
+	summaryLineTemplate string
+	lang                string
+}
+
+func runSummary(name string, args []string) error {
+	opts := &summaryOptions{noSummary: newNoSummaryValue()}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage:
+    %s [flags]
+
+Print the summary of a file written by --jsonfile, without the output of
+the tests.
+
+Flags:
+`, name)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.jsonFile, "jsonfile", "",
+		"path to the file written by --jsonfile, or - to read from stdin")
+	flags.Var(opts.noSummary, "no-summary",
+		fmt.Sprintf("do not print summary of: %s", testjson.SummarizeAll.String()))
+	flags.StringVar(&opts.summaryLineTemplate, "summary-line-template", "",
+		"Go template used to print the last line of the summary, instead of the DONE line")
+	flags.BoolVar(&opts.summaryTiming, "summary-timing", false,
+		"print the elapsed time, cumulative package time, and parallel speedup")
+	flags.StringVar(&opts.lang, "lang", "en",
+		fmt.Sprintf("language of the summary, one of: %s",
+			strings.Join(testjson.Languages(), ", ")))
+	if ok, err := parseToolFlags(flags, args); !ok {
+		return err
+	}
+	if opts.jsonFile == "" {
+		flags.Usage()
+		return errors.New("--jsonfile is required")
+	}
+
+	in := io.Reader(os.Stdin)
+	if opts.jsonFile != "-" {
+		f, err := os.Open(opts.jsonFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read JSON file")
+		}
+		defer f.Close() // nolint: errcheck
+		in = f
+	}
+	return printSummary(os.Stdout, in, opts)
+}
+
+// printSummary prints the summary of the events read from in.
+func printSummary(out io.Writer, in io.Reader, opts *summaryOptions) error {
+	msgs, ok := testjson.NewMessages(opts.lang)
+	if !ok {
+		return errors.Errorf("unknown language %s", opts.lang)
+	}
+	lineTemplate, err := parseSummaryLineTemplate(opts.summaryLineTemplate)
+	if err != nil {
+		return err
+	}
+	exec, err := testjson.ReadExecution(in)
+	if err != nil {
+		return err
+	}
+	return testjson.PrintSummaryWithOptions(out, exec, testjson.SummaryOptions{
+		Sections:     opts.noSummary.value,
+		Messages:     &msgs,
+		Timing:       opts.summaryTiming,
+		LineTemplate: lineTemplate,
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestPrintSummary(t *testing.T) {
+	in := `{"Time":"2020-05-01T10:00:00Z","Action":"run","Package":"pkg/ok","Test":"TestA"}
+{"Time":"2020-05-01T10:00:01Z","Action":"pass","Package":"pkg/ok","Test":"TestA","Elapsed":1}
+{"Time":"2020-05-01T10:00:01Z","Action":"run","Package":"pkg/bad","Test":"TestB"}
+{"Time":"2020-05-01T10:00:01Z","Action":"output","Package":"pkg/bad","Test":"TestB","Output":"    b_test.go:9: wrong\n"}
+{"Time":"2020-05-01T10:00:03Z","Action":"fail","Package":"pkg/bad","Test":"TestB","Elapsed":2}
+{"Time":"2020-05-01T10:00:03Z","Action":"fail","Package":"pkg/bad","Elapsed":2}
+`
+	opts := &summaryOptions{noSummary: newNoSummaryValue(), lang: "en"}
+	out := new(bytes.Buffer)
+	assert.NilError(t, printSummary(out, strings.NewReader(in), opts))
+	expected := `
+=== Failed
+=== FAIL: pkg/bad TestB (2.00s)
+    b_test.go:9: wrong
+
+
+DONE 2 tests, 1 failure in 3.000s
+`
+	assert.Equal(t, out.String(), expected)
+
+	opts.summaryLineTemplate = "{{.Total}} tests, {{.Failed}} failed"
+	out.Reset()
+	assert.NilError(t, printSummary(out, strings.NewReader(in), opts))
+	assert.Assert(t, strings.HasSuffix(out.String(), "\n2 tests, 1 failed\n"), out.String())
+
+	opts.lang = "xx"
+	assert.ErrorContains(t, printSummary(out, strings.NewReader(in), opts), "unknown language xx")
+}
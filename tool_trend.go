@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"gotest.tools/gotestsum/internal/trend"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type trendOptions struct {
+	output string
+	lang   string
+}
+
+func setupTrendFlags(name string) (*pflag.FlagSet, *trendOptions) {
+	opts := &trendOptions{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage:
+    %s [flags] FILE_OR_DIR...
+
+Write an HTML report with the trend of pass rate, duration, and flaky tests
+from the files written by --jsonfile from multiple runs. When a directory is
+given, every file in the directory is read.
+
+Flags:
+`, name)
+		flags.PrintDefaults()
+	}
+	flags.StringVarP(&opts.output, "output", "o", "trend.html", "path of the HTML file to write")
+	flags.StringVar(&opts.lang, "lang", "en",
+		fmt.Sprintf("language of the report, one of: %s",
+			strings.Join(testjson.Languages(), ", ")))
+	return flags, opts
+}
+
+func runTrend(name string, args []string) error {
+	flags, opts := setupTrendFlags(name)
+	if ok, err := parseToolFlags(flags, args); !ok {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("at least one file or directory is required")
+	}
+	lang, ok := testjson.Language(opts.lang)
+	if !ok {
+		return errors.Errorf("unknown language %s", opts.lang)
+	}
+	msgs := trend.NewMessages(lang)
+
+	paths, err := expandDirs(flags.Args())
+	if err != nil {
+		return err
+	}
+	runs := make([]trend.Run, 0, len(paths))
+	for _, path := range paths {
+		run, err := readTrendRun(path)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+	}
+	trend.SortRuns(runs)
+
+	out, err := os.Create(opts.output)
+	if err != nil {
+		return errors.Wrap(err, "failed to create HTML file")
+	}
+	if err := trend.WriteHTML(out, runs, msgs); err != nil {
+		out.Close() // nolint: errcheck
+		return err
+	}
+	return errors.Wrap(out.Close(), "failed to close HTML file")
+}
+
+// expandDirs replaces any directory in paths with the files in the directory.
+func expandDirs(paths []string) ([]string, error) {
+	var result []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			result = append(result, path)
+			continue
+		}
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				result = append(result, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return result, nil
+}
+
+func readTrendRun(path string) (trend.Run, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return trend.Run{}, err
+	}
+	defer in.Close() // nolint: errcheck
+	return trend.ReadRun(filepath.Base(path), in)
+}
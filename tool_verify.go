@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func runVerifySuperset(name string, args []string) error {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage:
+    %s [flags] OLD_FILE NEW_FILE
+
+Compare two files written by --jsonfile, and fail if the new run is missing a
+test which ran in the old run, or has a failure which was not a failure in the
+old run. Used to verify an upgrade of the go toolchain, or of a dependency,
+where the same tests must run with the same results.
+
+Flags:
+`, name)
+		flags.PrintDefaults()
+	}
+	if ok, err := parseToolFlags(flags, args); !ok {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return errors.New("two files are required")
+	}
+
+	before, err := readExecution(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, err := readExecution(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	problems := supersetProblems(testResults(before), testResults(after))
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stdout, problem)
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("%d differences between %s and %s",
+			len(problems), flags.Arg(0), flags.Arg(1))
+	}
+	return nil
+}
+
+// readExecution reads the events from a file written by --jsonfile.
+func readExecution(path string) (*testjson.Execution, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read JSON file")
+	}
+	defer in.Close() // nolint: errcheck
+	return testjson.ReadExecution(in)
+}
+
+// testResults returns the result of each test by test ID. A package which
+// failed without a failed test is included with a package-level ID. A test
+// which failed in any attempt is a failure.
+func testResults(exec *testjson.Execution) map[string]testjson.Action {
+	results := make(map[string]testjson.Action)
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		for _, tc := range pkg.Passed {
+			results[tc.ID()] = testjson.ActionPass
+		}
+		for _, tc := range pkg.Skipped {
+			results[tc.ID()] = testjson.ActionSkip
+		}
+		for _, tc := range pkg.Failed {
+			results[tc.ID()] = testjson.ActionFail
+		}
+		if pkg.TestMainFailed() {
+			results[testjson.TestID(name, "")] = testjson.ActionFail
+		}
+	}
+	return results
+}
+
+// supersetProblems returns a description of each test which ran before, but
+// not after, and each test which failed after, but did not fail before.
+func supersetProblems(before, after map[string]testjson.Action) []string {
+	var problems []string
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			problems = append(problems, "missing: "+id)
+		}
+	}
+	for id, result := range after {
+		if result == testjson.ActionFail && before[id] != testjson.ActionFail {
+			problems = append(problems, "new failure: "+id)
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestSupersetProblems(t *testing.T) {
+	before, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"pkg/a","Test":"TestA"}
+{"Action":"pass","Package":"pkg/a","Test":"TestA"}
+{"Action":"run","Package":"pkg/a","Test":"TestB"}
+{"Action":"fail","Package":"pkg/a","Test":"TestB"}
+{"Action":"run","Package":"pkg/a","Test":"TestC"}
+{"Action":"pass","Package":"pkg/a","Test":"TestC"}
+{"Action":"run","Package":"pkg/a","Test":"TestD"}
+{"Action":"skip","Package":"pkg/a","Test":"TestD"}
+{"Action":"fail","Package":"pkg/a"}
+`))
+	assert.NilError(t, err)
+	after, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"pkg/a","Test":"TestA"}
+{"Action":"fail","Package":"pkg/a","Test":"TestA"}
+{"Action":"run","Package":"pkg/a","Test":"TestB"}
+{"Action":"fail","Package":"pkg/a","Test":"TestB"}
+{"Action":"run","Package":"pkg/a","Test":"TestD"}
+{"Action":"pass","Package":"pkg/a","Test":"TestD"}
+{"Action":"run","Package":"pkg/a","Test":"TestE"}
+{"Action":"pass","Package":"pkg/a","Test":"TestE"}
+{"Action":"fail","Package":"pkg/a"}
+{"Action":"output","Package":"pkg/b","Output":"panic: init\n"}
+{"Action":"fail","Package":"pkg/b"}
+`))
+	assert.NilError(t, err)
+
+	problems := supersetProblems(testResults(before), testResults(after))
+	assert.DeepEqual(t, problems, []string{
+		"missing: pkg/a#TestC",
+		"new failure: pkg/a#TestA",
+		"new failure: pkg/b#",
+	})
+	assert.Equal(t, len(supersetProblems(testResults(before), testResults(before))), 0)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/ndjson"
+)
+
+// validateReports reads the --junitfile, --junitfile-dir, and --ndjson-file
+// reports after they were written, and returns an error if any of them is
+// invalid. A --junitfile written to stdout is not validated.
+func validateReports(opts *options) error {
+	for _, spec := range junitFileSpecs(opts) {
+		if spec.stdout() {
+			continue
+		}
+		if err := validateReport(spec.path, junitValidator(opts, spec)); err != nil {
+			return err
+		}
+	}
+	for _, path := range opts.junitDirFiles {
+		if err := validateReport(path, junitValidator(opts, junitFileSpec{})); err != nil {
+			return err
+		}
+	}
+	if opts.ndjsonFile != "" {
+		return validateReport(opts.ndjsonFile, ndjson.Validate)
+	}
+	return nil
+}
+
+// junitValidator returns the function which validates a JUnit XML file in the
+// format of the file.
+func junitValidator(opts *options, spec junitFileSpec) func(io.Reader) error {
+	if junitFileConfig(opts, spec).Format == junitxml.FormatOpenTestReporting {
+		return junitxml.ValidateOpenTestReporting
+	}
+	return junitxml.Validate
+}
+
+func validateReport(filename string, validate func(io.Reader) error) error {
+	in, err := openReport(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to open report")
+	}
+	defer in.Close() // nolint: errcheck
+	return errors.Wrapf(validate(in), "invalid report %s", filename)
+}
+
+// openReport opens a report file, and decompresses it when the filename ends
+// in .gz.
+func openReport(filename string) (io.ReadCloser, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(filename, ".gz") {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close() // nolint: errcheck
+		return nil, err
+	}
+	return gzipFile{Reader: gz, file: f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (f gzipFile) Close() error {
+	if err := f.Reader.Close(); err != nil {
+		f.file.Close() // nolint: errcheck
+		return err
+	}
+	return f.file.Close()
+}
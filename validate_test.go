@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/fs"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestValidateReports(t *testing.T) {
+	dir := fs.NewDir(t, "validate-reports",
+		fs.WithFile("valid.xml", `<testsuites>
+<testsuite tests="1" name="example.com/pkg">
+<testcase classname="example.com/pkg" name="TestOne"></testcase>
+</testsuite>
+</testsuites>`),
+		fs.WithFile("truncated.xml", `<testsuites><testsuite name="example.com/pkg">`),
+		fs.WithFile("valid.ndjson",
+			`{"run_id":"1","package":"example.com/pkg","test_id":"example.com/pkg.TestOne","test":"TestOne","outcome":"pass","elapsed_seconds":0.1}`+"\n"),
+		fs.WithFile("unknown.ndjson",
+			`{"run_id":"1","package":"example.com/pkg","test_id":"example.com/pkg.TestOne","outcome":"flaked"}`+"\n"))
+	defer dir.Remove()
+
+	newOpts := func(junitfile, ndjsonFile string) *options {
+		opts := &options{junitFiles: newJUnitFileValue(""), junitFailuresOnly: newJUnitFileValue("")}
+		if junitfile != "" {
+			assert.NilError(t, opts.junitFiles.Set(dir.Join(junitfile)))
+		}
+		if ndjsonFile != "" {
+			opts.ndjsonFile = dir.Join(ndjsonFile)
+		}
+		return opts
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		assert.NilError(t, validateReports(newOpts("valid.xml", "valid.ndjson")))
+	})
+	t.Run("truncated xml", func(t *testing.T) {
+		err := validateReports(newOpts("truncated.xml", "valid.ndjson"))
+		assert.ErrorContains(t, err, "invalid report "+dir.Join("truncated.xml"))
+	})
+	t.Run("unknown outcome", func(t *testing.T) {
+		err := validateReports(newOpts("valid.xml", "unknown.ndjson"))
+		assert.ErrorContains(t, err, `line 1: unknown outcome "flaked"`)
+	})
+	t.Run("missing file", func(t *testing.T) {
+		err := validateReports(newOpts("", "missing.ndjson"))
+		assert.ErrorContains(t, err, "failed to open report")
+	})
+}
+
+func TestWriteJUnitFile(t *testing.T) {
+	exec, err := testjson.ReadExecution(strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg"}
+`))
+	assert.NilError(t, err)
+	dir := fs.NewDir(t, "write-junit-file")
+	defer dir.Remove()
+
+	for _, name := range []string{"junit.xml", "junit.xml.gz"} {
+		assert.NilError(t, writeJUnitFile(dir.Join(name), exec, junitxml.Config{}))
+	}
+	files, err := ioutil.ReadDir(dir.Path())
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 2, "temporary files were not removed")
+
+	raw, err := ioutil.ReadFile(dir.Join("junit.xml.gz"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(string(raw), "\x1f\x8b"), "not compressed with gzip")
+
+	opts := &options{junitFiles: newJUnitFileValue(""), junitFailuresOnly: newJUnitFileValue("")}
+	assert.NilError(t, opts.junitFiles.Set(dir.Join("junit.xml")))
+	assert.NilError(t, opts.junitFiles.Set(dir.Join("junit.xml.gz")))
+	assert.NilError(t, validateReports(opts))
+}